@@ -69,7 +69,7 @@ type AuthService interface {
 type AuthFilter struct {
 	// Filtering fields.
 	ID       *uuid.UUID `json:"id"`
-	UserID   *int       `json:"user_id"`
+	UserID   *uuid.UUID `json:"user_id"`
 	Source   *string    `json:"source"`
 	SourceID *string    `json:"source_id"`
 
@@ -78,3 +78,19 @@ type AuthFilter struct {
 	Page  int `json:"page"`
 	Limit int `json:"limit"`
 }
+
+// AccessToken returns a user's access token for an integration. Returns an
+// empty token if the user has not connected the integration.
+func AccessToken(ctx context.Context, s AuthService, userID uuid.UUID, integration string) (string, error) {
+	auths, _, err := s.GetAuths(ctx, AuthFilter{
+		UserID: &userID,
+		Source: &integration,
+		Limit:  1,
+	})
+	if err != nil {
+		return "", err
+	} else if len(auths) == 0 {
+		return "", nil
+	}
+	return auths[0].AccessToken, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/openapi"
+	"gopkg.in/yaml.v2"
+)
+
+// CommandIntegrations manages integration definitions. Unlike other commands,
+// it runs once without the components of the application.
+const CommandIntegrations = "integrations"
+
+// runIntegrations runs the integrations command with the arguments that follow
+// it. Currently the only subcommand is "import openapi <file>", which converts
+// an OpenAPI 3 or Swagger 2 document into a definition for the integrations
+// directory.
+func runIntegrations(args []string, stdout, stderr io.Writer) error {
+	if len(args) < 2 || args[0] != "import" || args[1] != "openapi" {
+		return fmt.Errorf("usage: flow integrations import openapi [flags] <file>")
+	}
+
+	fs := flag.NewFlagSet("flow integrations import openapi", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts openapi.Options
+	fs.StringVar(&opts.Key, "key", "", "integration key, derived from the document's title by default")
+	fs.StringVar(&opts.BaseURL, "base-url", "", "base URL of the API, taken from the document's servers by default")
+	output := fs.String("o", "", "file the definition is written to, stdout by default")
+	format := fs.String("format", "yaml", "format of the definition, yaml or json")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: flow integrations import openapi [flags] <file>")
+	} else if *format != "yaml" && *format != "json" {
+		return fmt.Errorf("unknown format: %q (expected %q or %q)", *format, "yaml", "json")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	integration, warnings, err := openapi.Import(data, opts)
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+	if flow.ErrorCode(err) == flow.EINVALID {
+		return fmt.Errorf("%s", flow.ErrorMessage(err))
+	} else if err != nil {
+		return err
+	}
+
+	buf, err := json.MarshalIndent(integration, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *format == "yaml" {
+		if buf, err = toYAML(buf); err != nil {
+			return err
+		}
+	} else {
+		buf = append(buf, '\n')
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(buf)
+		return err
+	}
+	return ioutil.WriteFile(*output, buf, 0666)
+}
+
+// toYAML converts a JSON document into YAML. Unlike converting through a map,
+// keys keep their order so that definitions read like the hand-written ones.
+// Empty values are omitted.
+func toYAML(buf []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// decodeOrdered decodes the next JSON value of dec, decoding objects as
+// ordered maps.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		var m yaml.MapSlice
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			if !isEmpty(v) {
+				m = append(m, yaml.MapItem{Key: key, Value: v})
+			}
+		}
+		_, err = dec.Token()
+		return m, err
+	case json.Delim('['):
+		var list []interface{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = dec.Token()
+		return list, err
+	case nil:
+		return nil, nil
+	}
+	if n, ok := tok.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return tok, nil
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case yaml.MapSlice:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// exitIntegrations runs the integrations command & exits.
+func exitIntegrations(args []string) {
+	if err := runIntegrations(args, os.Stdout, os.Stderr); err == flag.ErrHelp {
+		os.Exit(1)
+	} else if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -5,15 +5,25 @@ import (
 	"flag"
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/compat"
 	"github.com/openmesh/flow/eventbus"
+	"github.com/openmesh/flow/executor"
+	"github.com/openmesh/flow/file"
 	"github.com/openmesh/flow/inmem"
+	"github.com/openmesh/flow/native"
+	"github.com/openmesh/flow/native/plugin"
+	"github.com/openmesh/flow/native/utility"
 	"github.com/openmesh/flow/pg"
+	"github.com/openmesh/flow/poller"
+	"github.com/openmesh/flow/scheduler"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/openmesh/flow/http"
 
@@ -31,6 +41,11 @@ func main() {
 	signal.Notify(c, os.Interrupt)
 	go func() { <-c; cancel() }()
 
+	// Tool commands run once without the components of the application.
+	if len(os.Args) > 1 && os.Args[1] == CommandIntegrations {
+		exitIntegrations(os.Args[2:])
+	}
+
 	// Instantiate a new type to represent our application.
 	// This type lets us shared setup code with our end-to-end tests.
 	m := NewMain()
@@ -61,8 +76,26 @@ func main() {
 	}
 }
 
+// Commands select the components a process runs so that serving the API and
+// executing runs scale independently. Without a command, every component runs
+// in one process.
+const (
+	// Serves the API & webhooks. Runs started by events are queued for
+	// workers.
+	CommandServe = "serve"
+
+	// Executes runs claimed from the queue.
+	CommandWorker = "worker"
+
+	// Fires schedules & polls triggers. Runs are queued for workers.
+	CommandScheduler = "scheduler"
+)
+
 // Main represents the program.
 type Main struct {
+	// Command the program runs. Empty to run every component.
+	Command string
+
 	// Configuration path and parsed config data.
 	Config     Config
 	ConfigPath string
@@ -70,9 +103,38 @@ type Main struct {
 	// Postgres database used by the pg service implementations.
 	DB *pg.DB
 
+	// Loads integration definitions from files. Nil if no directory is
+	// configured, in which case only built-in integrations are available.
+	Integrations *file.IntegrationService
+
+	// Integrations implemented in Go, served along with the built-in
+	// integrations.
+	Registry *native.Registry
+
+	// Executables of out-of-process integrations, registered with Registry.
+	Plugins []*plugin.Plugin
+
 	// HTTP server for handling HTTP communication.
 	// SQLite services are attached to it before running.
 	HTTPServer *http.Server
+
+	// Executes workflow runs.
+	Executor *executor.Executor
+
+	// Starts workflow runs for events published on the event bus.
+	Dispatcher *executor.Dispatcher
+
+	// Polls triggers of APIs without webhooks for new items.
+	Poller *poller.Poller
+
+	// Fires schedule trigger nodes.
+	Scheduler *scheduler.Scheduler
+
+	// Subscribes to the triggers of native integrations.
+	Subscriber *native.Subscriber
+
+	// Serves the liveness of workers, which do not serve the API.
+	HealthServer *http.HealthServer
 }
 
 // NewMain returns a new instance of Main.
@@ -83,24 +145,74 @@ func NewMain() *Main {
 
 		DB:         pg.NewDB(""),
 		HTTPServer: http.NewServer(),
+		Executor:   executor.New(),
+		Dispatcher: executor.NewDispatcher(),
+		Poller:     poller.New(),
+		Scheduler:  scheduler.New(),
+		Subscriber: native.NewSubscriber(),
+
+		HealthServer: http.NewHealthServer(),
 	}
 }
 
 // Close gracefully stops the program.
+//
+// Every component is closed even if closing an earlier one fails, so that
+// in-flight runs are drained and the database is closed when the HTTP server
+// times out waiting for requests. Returns the first error.
 func (m *Main) Close() error {
+	var err error
+	keep := func(e error) {
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
 	// Close server if it has a value.
 	if m.HTTPServer != nil {
-		if err := m.HTTPServer.Close(); err != nil {
-			return err
-		}
+		keep(m.HTTPServer.Close())
+	}
+	// Close health server if it has a value.
+	if m.HealthServer != nil {
+		keep(m.HealthServer.Close())
+	}
+	// Stop firing schedules if the scheduler has a value.
+	if m.Scheduler != nil {
+		keep(m.Scheduler.Close())
+	}
+	// End native subscriptions if the subscriber has a value.
+	if m.Subscriber != nil {
+		keep(m.Subscriber.Close())
+	}
+	// Stop polling if the poller has a value.
+	if m.Poller != nil {
+		keep(m.Poller.Close())
+	}
+	// Stop dispatching events if the dispatcher has a value.
+	if m.Dispatcher != nil {
+		keep(m.Dispatcher.Close())
+	}
+	// Let in-flight runs finish before the database is closed. Runs that do
+	// not finish in time are persisted as interrupted.
+	if m.Executor != nil {
+		timeout := time.Duration(m.Config.Executor.DrainTimeout) * time.Second
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		keep(m.Executor.Drain(ctx))
+	}
+	// Stop plugins once no runs use them.
+	for _, p := range m.Plugins {
+		keep(p.Close())
+	}
+	// Stop watching integration definitions if they are loaded from files.
+	if m.Integrations != nil {
+		keep(m.Integrations.Close())
 	}
 	// Close DB connection if it has a value.
 	if m.DB != nil {
-		if err := m.DB.Close(); err != nil {
-			return err
-		}
+		keep(m.DB.Close())
 	}
-	return nil
+	return err
 }
 
 // ParseFlags parses the command line arguments & loads the config.
@@ -108,6 +220,16 @@ func (m *Main) Close() error {
 // This exists separately from the Run() function so that we can skip it
 // during end-to-end tests. Those tests will configure manually and call Run().
 func (m *Main) ParseFlags(ctx context.Context, args []string) error {
+	// An optional command precedes the flags.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case CommandServe, CommandWorker, CommandScheduler:
+			m.Command, args = args[0], args[1:]
+		default:
+			return fmt.Errorf("unknown command: %q (expected %q, %q or %q)", args[0], CommandServe, CommandWorker, CommandScheduler)
+		}
+	}
+
 	// Our flag set is very simple. It only includes a config path.
 	fs := flag.NewFlagSet("flow", flag.ContinueOnError)
 	fs.StringVar(&m.ConfigPath, "config", DefaultConfigPath, "config path")
@@ -145,20 +267,158 @@ func (m *Main) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("cannot open db: %w", err)
 	}
 
-	m.HTTPServer.Logger = createLogger()
+	logger := createLogger()
 	// requestCount, errorCount, requestDuration := setupMetrics()
 
 	// Initialize services.
 	eventBus := eventbus.New()
 	workflowService := pg.NewWorkflowService(m.DB)
+	nodeService := pg.NewNodeService(m.DB)
 	authService := pg.NewAuthService(m.DB)
-	integrationService := inmem.NewIntegrationService()
+	var integrationService flow.IntegrationService
+	hookService := pg.NewHookService(m.DB)
+	runService := pg.NewRunService(m.DB)
+	filterDecisionService := pg.NewFilterDecisionService(m.DB)
+	workerService := pg.NewWorkerService(m.DB)
+	waitService := pg.NewWaitService(m.DB)
+
+	// Native integrations are served along with the built-in ones.
+	m.Registry = native.NewRegistry()
+	m.Registry.Builtin = inmem.NewIntegrationService()
+	m.Registry.MustRegister(utility.New())
+	integrationService = m.Registry
+
+	// Plugins are started and registered as native integrations.
+	for _, c := range m.Config.Plugins {
+		path, err := expand(c.Path)
+		if err != nil {
+			return err
+		}
+		p := plugin.New(path, c.Args...)
+		p.Env = c.Env
+		p.MaxRestarts = c.MaxRestarts
+		p.Logger = logger
+		if c.Timeout > 0 {
+			p.Timeout = time.Duration(c.Timeout) * time.Second
+		}
+		if c.Restart != "" {
+			p.Restart = c.Restart
+		}
+		if err := p.Open(); err != nil {
+			return fmt.Errorf("cannot open plugin: %w", err)
+		}
+		m.Plugins = append(m.Plugins, p)
+		if err := m.Registry.Register(p); err != nil {
+			return fmt.Errorf("cannot register plugin %s: %w", path, err)
+		}
+	}
+
+	// Integrations other than the built-in ones are defined by files, which
+	// are reloaded when they change.
+	if dir := m.Config.Integrations.Dir; dir != "" {
+		m.Integrations = file.NewIntegrationService(dir)
+		m.Integrations.Builtin = integrationService
+		m.Integrations.Logger = logger
+		if err := m.Integrations.Open(); err != nil {
+			return fmt.Errorf("cannot load integrations: %w", err)
+		}
+		integrationService = m.Integrations
+	}
+
+	// Nodes are pinned to the versions of the integrations they are built
+	// against.
+	compatService := compat.NewNodeService(nodeService, integrationService)
+
+	// Deliveries are deduplicated in Postgres so that retries are detected
+	// across instances, unless configured to only remember them in memory.
+	var deliveryService flow.DeliveryService = pg.NewDeliveryService(m.DB)
+	if m.Config.Webhooks.DedupStore == "memory" {
+		deliveryService = inmem.NewDeliveryService()
+	}
+
+	// Components the command does not run are removed so that Close()
+	// skips them.
+	switch m.Command {
+	case CommandServe:
+		m.Poller, m.Scheduler, m.Subscriber, m.HealthServer = nil, nil, nil, nil
+	case CommandWorker:
+		m.HTTPServer, m.Dispatcher, m.Poller, m.Scheduler, m.Subscriber = nil, nil, nil, nil, nil
+	case CommandScheduler:
+		m.HTTPServer, m.HealthServer = nil, nil
+	default:
+		m.HealthServer = nil
+	}
+
+	// Initialize the executor which runs workflows. Processes that receive
+	// events but are not workers leave the runs they start to workers.
+	m.Executor.WorkflowService = workflowService
+	m.Executor.RunService = runService
+	m.Executor.IntegrationService = integrationService
+	m.Executor.AuthService = authService
+	m.Executor.WaitService = waitService
+	m.Executor.Registry = m.Registry
+	m.Executor.Logger = logger
+	if m.Executor.RequestClient, err = executor.NewRequestClient(m.Config.Requests.Allow); err != nil {
+		return fmt.Errorf("cannot configure requests: %w", err)
+	}
+	switch m.Command {
+	case CommandServe, CommandScheduler:
+		m.Executor.QueueOnly = true
+	default:
+		m.Executor.WorkerService = workerService
+		m.Executor.Concurrency = m.Config.Worker.Concurrency
+	}
+	if err := m.Executor.Open(); err != nil {
+		return fmt.Errorf("cannot open executor: %w", err)
+	}
+
+	// Workers only serve their liveness.
+	if m.HealthServer != nil {
+		m.HealthServer.Addr = m.Config.Worker.HealthAddr
+		m.HealthServer.Check = m.Executor.Check
+		if err := m.HealthServer.Open(); err != nil {
+			return fmt.Errorf("cannot open health server: %w", err)
+		}
+	}
+
+	// Start runs for trigger events published to the event bus.
+	if m.Dispatcher != nil {
+		m.Dispatcher.EventBus = eventBus
+		m.Dispatcher.IntegrationService = integrationService
+		m.Dispatcher.WorkflowService = workflowService
+		m.Dispatcher.FilterDecisionService = filterDecisionService
+		m.Dispatcher.WaitService = waitService
+		m.Dispatcher.Executor = m.Executor
+		m.Dispatcher.Logger = logger
+		if err := m.Dispatcher.Open(); err != nil {
+			return fmt.Errorf("cannot open dispatcher: %w", err)
+		}
+	}
+
+	if m.Poller != nil {
+		if err := m.openTriggers(eventBus, integrationService, workflowService, authService, waitService, logger); err != nil {
+			return err
+		}
+	}
+
+	if m.HTTPServer == nil {
+		return nil
+	}
 
 	// Attach underlying service to the HTTP server.
+	m.HTTPServer.Logger = logger
 	m.HTTPServer.EventBus = eventBus
 	m.HTTPServer.WorkflowService = workflowService
 	m.HTTPServer.AuthService = authService
+	m.HTTPServer.NodeService = compatService
 	m.HTTPServer.IntegrationService = integrationService
+	m.HTTPServer.CompatibilityService = compatService
+	m.HTTPServer.HookService = hookService
+	m.HTTPServer.RunService = runService
+	m.HTTPServer.DeliveryService = deliveryService
+	m.HTTPServer.FilterDecisionService = filterDecisionService
+	m.HTTPServer.WorkerService = workerService
+	m.HTTPServer.Executor = m.Executor
 
 	m.HTTPServer.RegisterRoute("/metrics", promhttp.Handler())
 
@@ -167,11 +427,63 @@ func (m *Main) Run(ctx context.Context) (err error) {
 	m.HTTPServer.Domain = m.Config.HTTP.Domain
 	m.HTTPServer.HashKey = m.Config.HTTP.HashKey
 	m.HTTPServer.BlockKey = m.Config.HTTP.BlockKey
+	m.HTTPServer.WebhookSecrets = m.Config.Webhooks.Secrets
+	m.HTTPServer.MaxBodySize = m.Config.Webhooks.MaxBodySize
 
 	if err := m.HTTPServer.Open(); err != nil {
 		return err
 	}
 
+	// Report the nodes broken by changes to their integrations.
+	incompatibilities, err := compatService.CheckNodes(flow.NewSystemContext(ctx), flow.NodeFilter{})
+	if err != nil {
+		_ = logger.Log("msg", "cannot check nodes", "err", err)
+	}
+	for _, inc := range incompatibilities {
+		_ = logger.Log("msg", "node is incompatible with the current version of its integration", "workflow", inc.WorkflowID, "node", inc.NodeID, "kind", inc.Kind, "automatic", inc.Automatic, "detail", inc.Message)
+	}
+
+	return nil
+}
+
+// openTriggers opens the poller, scheduler & subscriber, which publish the
+// events of polled, schedule & native triggers to the event bus. The scheduler also resumes
+// paused runs once their wait is due.
+func (m *Main) openTriggers(eventBus flow.EventBus, integrationService flow.IntegrationService, workflowService flow.WorkflowService, authService flow.AuthService, waitService flow.WaitService, logger log.Logger) error {
+	// Publish new items of polled triggers to the event bus.
+	m.Poller.EventBus = eventBus
+	m.Poller.IntegrationService = integrationService
+	m.Poller.WorkflowService = workflowService
+	m.Poller.AuthService = authService
+	m.Poller.PollService = pg.NewPollService(m.DB)
+	m.Poller.Logger = logger
+	if err := m.Poller.Open(); err != nil {
+		return fmt.Errorf("cannot open poller: %w", err)
+	}
+
+	// Publish the fires of schedule trigger nodes to the event bus.
+	m.Scheduler.EventBus = eventBus
+	m.Scheduler.WorkflowService = workflowService
+	m.Scheduler.ScheduleService = pg.NewScheduleService(m.DB)
+	m.Scheduler.WaitService = waitService
+	m.Scheduler.Logger = logger
+	if v := m.Config.Scheduler.CatchUp; v != "" {
+		m.Scheduler.CatchUp = v
+	}
+	if err := m.Scheduler.Open(); err != nil {
+		return fmt.Errorf("cannot open scheduler: %w", err)
+	}
+
+	// Publish the events of native triggers to the event bus.
+	m.Subscriber.Registry = m.Registry
+	m.Subscriber.EventBus = eventBus
+	m.Subscriber.WorkflowService = workflowService
+	m.Subscriber.AuthService = authService
+	m.Subscriber.Logger = logger
+	if err := m.Subscriber.Open(); err != nil {
+		return fmt.Errorf("cannot open subscriber: %w", err)
+	}
+
 	return nil
 }
 
@@ -181,6 +493,18 @@ const (
 
 	// DefaultDSN is the default datasource name.
 	DefaultDSN = "user=postgres password=postgres dbname=okount port=5432 sslmode=false host=localhost"
+
+	// DefaultDrainTimeout is the default time in seconds in-flight runs are
+	// given to finish on shutdown.
+	DefaultDrainTimeout = 30
+
+	// DefaultWorkerConcurrency is the default number of runs a worker
+	// executes at once.
+	DefaultWorkerConcurrency = 10
+
+	// DefaultWorkerHealthAddr is the default bind address of the health
+	// server of workers.
+	DefaultWorkerHealthAddr = ":8081"
 )
 
 // Config represents the CLI configuration file.
@@ -195,12 +519,80 @@ type Config struct {
 		HashKey  string `toml:"hash-key"`
 		BlockKey string `toml:"block-key"`
 	} `toml:"http"`
+
+	Webhooks struct {
+		// Signing secrets used to verify webhook deliveries, keyed by
+		// integration key.
+		Secrets map[string]string `toml:"secrets"`
+
+		// Maximum size in bytes of webhook bodies.
+		MaxBodySize int64 `toml:"max-body-size"`
+
+		// Store used to deduplicate retried deliveries. Either "postgres"
+		// or "memory".
+		DedupStore string `toml:"dedup-store"`
+	} `toml:"webhooks"`
+
+	Scheduler struct {
+		// Catch-up policy of schedules that do not set one. One of "skip",
+		// "once" or "all".
+		CatchUp string `toml:"catch-up"`
+	} `toml:"scheduler"`
+
+	Executor struct {
+		// Time in seconds in-flight runs are given to finish on shutdown.
+		DrainTimeout int `toml:"drain-timeout"`
+	} `toml:"executor"`
+
+	Integrations struct {
+		// Directory of the YAML & JSON files defining integrations. Only
+		// built-in integrations are available if empty.
+		Dir string `toml:"dir"`
+	} `toml:"integrations"`
+
+	Requests struct {
+		// Private addresses & hosts that nodes of the HTTP integration may
+		// call, as CIDRs, IP addresses, host names or wildcards such as
+		// "*.internal.example.com". Others are blocked.
+		Allow []string `toml:"allow"`
+	} `toml:"requests"`
+
+	// Executables of out-of-process integrations.
+	Plugins []struct {
+		Path string   `toml:"path"`
+		Args []string `toml:"args"`
+
+		// Environment variables of the executable, as "KEY=value".
+		Env []string `toml:"env"`
+
+		// Time in seconds the plugin has to answer a request.
+		Timeout int `toml:"timeout"`
+
+		// Restart policy of the plugin. One of "always", "on-failure" or
+		// "never".
+		Restart string `toml:"restart"`
+
+		// Maximum number of consecutive restarts. Zero means no limit.
+		MaxRestarts int `toml:"max-restarts"`
+	} `toml:"plugins"`
+
+	Worker struct {
+		// Maximum number of runs a worker executes at once. Zero means no
+		// limit.
+		Concurrency int `toml:"concurrency"`
+
+		// Bind address of the health server of workers.
+		HealthAddr string `toml:"health-addr"`
+	} `toml:"worker"`
 }
 
 // DefaultConfig returns a new instance of Config with defaults set.
 func DefaultConfig() Config {
 	var config Config
 	config.DB.DSN = DefaultDSN
+	config.Executor.DrainTimeout = DefaultDrainTimeout
+	config.Worker.Concurrency = DefaultWorkerConcurrency
+	config.Worker.HealthAddr = DefaultWorkerHealthAddr
 	return config
 }
 
@@ -0,0 +1,147 @@
+package flow
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Limits of code nodes. Scripts run in an embedded Starlark interpreter
+// within the executor's process, so they are bounded in length, in the number
+// of steps they execute, in time & in the memory they allocate, and the data
+// they read, produce & log is bounded in size.
+const (
+	// Maximum length of a script in bytes.
+	MaxCodeLength = 64 << 10
+	// Maximum number of interpreter steps a script may execute.
+	MaxCodeSteps = 10000000
+	// Maximum time a script may run.
+	CodeTimeout = 5 * time.Second
+	// Maximum number of bytes a script may allocate for strings, integers &
+	// containers. Allocations are counted per script as they are made, so
+	// memory the script no longer uses still counts.
+	MaxCodeMemory = 128 << 20
+	// Maximum size of a script's inputs & outputs encoded as JSON.
+	MaxCodeDataSize = 1 << 20
+	// Maximum number of lines a script may log, and their total size.
+	MaxCodeLogLines = 100
+	MaxCodeLogSize  = 16 << 10
+)
+
+// Params of code nodes that configure the node rather than being passed to
+// its script.
+const (
+	CodeParamCode    = "code"
+	CodeParamOutputs = "outputs"
+)
+
+// CodeModuleJSON is the name under which the JSON module is available to
+// scripts.
+const CodeModuleJSON = "json"
+
+func init() {
+	// Scripts are allowed top-level loops & while statements, as they are
+	// bounded by the step limit rather than by the language.
+	resolve.AllowGlobalReassign = true
+	resolve.AllowRecursion = true
+}
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CompileCode parses & resolves the Starlark script of a code node. Scripts
+// may use the given input names, the JSON module as "json" and the Starlark
+// built-ins. Scripts are instrumented to count their allocations, so they must
+// be run with CodeBuiltins. Returns EINVALID if the script is too long, cannot
+// be compiled or loads other modules, which scripts have no access to.
+func CompileCode(code string, inputs []string) (*starlark.Program, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, Errorf(EINVALID, "Code is required.")
+	} else if len(code) > MaxCodeLength {
+		return nil, Errorf(EINVALID, "Code cannot be longer than %d bytes.", MaxCodeLength)
+	}
+
+	predeclared := make(map[string]bool)
+	for name := range CodeBuiltins() {
+		predeclared[name] = true
+	}
+	for _, name := range inputs {
+		predeclared[name] = true
+	}
+	f, err := syntax.Parse("code.star", code, 0)
+	if err != nil {
+		return nil, Errorf(EINVALID, "Code is invalid: %s", err)
+	}
+	instrumentCode(f)
+	prog, err := starlark.FileProgram(f, func(name string) bool { return predeclared[name] })
+	if err != nil {
+		return nil, Errorf(EINVALID, "Code is invalid: %s", err)
+	} else if prog.NumLoads() > 0 {
+		return nil, Errorf(EINVALID, "Code cannot load modules.")
+	}
+	return prog, nil
+}
+
+// CodeOutputs parses the comma-separated names of the globals a script sets
+// that become the outputs of its node.
+func CodeOutputs(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		} else if !identifierRegexp.MatchString(name) {
+			return nil, Errorf(EINVALID, "Output '%s' is not a valid name.", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, Errorf(EINVALID, "Code outputs are required.")
+	}
+	return names, nil
+}
+
+// IsCodeInput returns true if a param of a code node is passed to its script.
+func IsCodeInput(key string) bool {
+	return key != CodeParamCode && key != CodeParamOutputs
+}
+
+// validateCodeParams returns an error if the script or outputs of a code node
+// are missing or invalid, or if one of its inputs cannot be used as a name
+// within the script. Scripts & outputs are validated when the node is saved,
+// so they cannot be references.
+func validateCodeParams(params []*Param) error {
+	var code, outputs *Param
+	var inputs []string
+	for _, p := range params {
+		switch {
+		case p.Key == CodeParamCode:
+			code = p
+		case p.Key == CodeParamOutputs:
+			outputs = p
+		case !identifierRegexp.MatchString(p.Key):
+			return Errorf(EINVALID, "Input '%s' is not a valid name.", p.Key)
+		case p.Key == CodeModuleJSON:
+			return Errorf(EINVALID, "Input '%s' is reserved.", p.Key)
+		default:
+			inputs = append(inputs, p.Key)
+		}
+	}
+
+	if code == nil {
+		return Errorf(EINVALID, "Code is required.")
+	} else if code.Type == ParamTypeReference {
+		return Errorf(EINVALID, "Code cannot be a reference.")
+	} else if outputs == nil {
+		return Errorf(EINVALID, "Code outputs are required.")
+	} else if outputs.Type == ParamTypeReference {
+		return Errorf(EINVALID, "Code outputs cannot be a reference.")
+	}
+	if _, err := CodeOutputs(outputs.Value); err != nil {
+		return err
+	}
+	_, err := CompileCode(code.Value, inputs)
+	return err
+}
@@ -0,0 +1,529 @@
+package flow
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// The memory of a script is limited by counting what it allocates on its own
+// thread, so scripts running at once do not affect each other's limits.
+//
+// Scripts are instrumented when they are compiled so that the operations that
+// can build values larger than the values they are given, such as "x" * n,
+// s + s, "%s" % x or sep.join(items), are counted before they allocate. The
+// built-ins that build strings & containers, such as list(range(n)), the
+// methods that grow lists & dicts, such as l.extend(l), and the functions of
+// the JSON module are counted as well. Other operations, such as slicing,
+// build values no larger than existing ones in a single step and are bounded
+// by the step limit.
+
+// Names of the functions instrumented scripts call. They are not identifiers
+// so that scripts cannot use or redefine them.
+const (
+	// $binary(op, x, y) evaluates x op y.
+	codeBinary = "$binary"
+	// $augmented(op, x, y) counts x op= y and returns y.
+	codeAugmented = "$augmented"
+	// $method(x, name, *args, **kwargs) calls x.name(*args, **kwargs).
+	codeMethod = "$method"
+)
+
+// codeOperators are the binary operators whose results are counted.
+var codeOperators = map[string]syntax.Token{
+	"+": syntax.PLUS,
+	"*": syntax.STAR,
+	"%": syntax.PERCENT,
+}
+
+// codeAugmentedOperators maps augmented assignments to their operators.
+var codeAugmentedOperators = map[syntax.Token]string{
+	syntax.PLUS_EQ:    "+",
+	syntax.STAR_EQ:    "*",
+	syntax.PERCENT_EQ: "%",
+}
+
+// codeMethods are the methods that build strings larger than the values they
+// are given, that grow lists & dicts, or that copy their elements.
+var codeMethods = map[string]bool{
+	// Strings.
+	"format":  true,
+	"join":    true,
+	"replace": true,
+	// Lists.
+	"append": true,
+	"extend": true,
+	"insert": true,
+	// Dicts.
+	"items":      true,
+	"keys":       true,
+	"setdefault": true,
+	"update":     true,
+	"values":     true,
+}
+
+// codeAllocatingBuiltins are the built-ins that build strings & containers.
+var codeAllocatingBuiltins = []string{"dict", "enumerate", "list", "repr", "reversed", "sorted", "str", "tuple", "zip"}
+
+// valueSize is the number of bytes counted for each element of a container.
+const valueSize = 16
+
+// maxIntSize is the maximum size of a product of integers in bytes. Products
+// of larger integers take longer than the time limit to compute and cannot be
+// interrupted.
+const maxIntSize = 64 << 10
+
+// codeAllocsKey is the thread-local key of a script's allocations.
+const codeAllocsKey = "flow.allocs"
+
+type codeAllocs struct {
+	n, max int
+}
+
+// SetCodeMemoryLimit limits the number of bytes the script run by a thread may
+// allocate. Scripts that exceed it fail with ETOOLARGE.
+func SetCodeMemoryLimit(thread *starlark.Thread, max int) {
+	thread.SetLocal(codeAllocsKey, &codeAllocs{max: max})
+}
+
+// allocate counts bytes allocated by the script of a thread. Returns ETOOLARGE
+// if the script exceeds its memory limit.
+func allocate(thread *starlark.Thread, n int) error {
+	a, _ := thread.Local(codeAllocsKey).(*codeAllocs)
+	if a == nil || n <= 0 {
+		return nil
+	}
+	if n > a.max-a.n {
+		a.n = a.max
+		return Errorf(ETOOLARGE, "Code exceeded %d bytes of memory.", a.max)
+	}
+	a.n += n
+	return nil
+}
+
+// CodeBuiltins returns the values scripts are run with besides their inputs:
+// the JSON module, the built-ins that count their allocations and the
+// functions instrumented scripts call.
+func CodeBuiltins() starlark.StringDict {
+	builtins := starlark.StringDict{
+		CodeModuleJSON: codeJSONModule,
+		codeBinary:     starlark.NewBuiltin("operator", callBinary),
+		codeAugmented:  starlark.NewBuiltin("operator", callAugmented),
+		codeMethod:     starlark.NewBuiltin("method", callMethod),
+	}
+	for _, name := range codeAllocatingBuiltins {
+		builtins[name] = countBuiltin(starlark.Universe[name].(*starlark.Builtin))
+	}
+	return builtins
+}
+
+// codeJSONModule is the JSON module with the size of the values its functions
+// build counted.
+var codeJSONModule = &starlarkstruct.Module{
+	Name: "json",
+	Members: starlark.StringDict{
+		"encode": countResult(starlarkjson.Module.Members["encode"].(*starlark.Builtin)),
+		"decode": countArgs(starlarkjson.Module.Members["decode"].(*starlark.Builtin)),
+		"indent": countResult(starlarkjson.Module.Members["indent"].(*starlark.Builtin)),
+	},
+}
+
+// countBuiltin returns a built-in that counts the strings & containers built
+// by fn. Containers are counted before they are built from the length of the
+// first argument, such as the range of list(range(n)).
+func countBuiltin(fn *starlark.Builtin) *starlark.Builtin {
+	if fn.Name() == "str" || fn.Name() == "repr" {
+		return countResult(fn)
+	}
+	return starlark.NewBuiltin(fn.Name(), func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		n := len(kwargs)
+		if len(args) > 0 {
+			n += starlark.Len(args[0])
+		}
+		if err := allocate(thread, mulSize(n, valueSize)); err != nil {
+			return nil, err
+		}
+		return starlark.Call(thread, fn, args, kwargs)
+	})
+}
+
+// countResult returns a built-in that counts the size of the values fn
+// returns. It is used for functions whose results are no larger than a
+// multiple of their arguments.
+func countResult(fn *starlark.Builtin) *starlark.Builtin {
+	return starlark.NewBuiltin(fn.Name(), func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		v, err := starlark.Call(thread, fn, args, kwargs)
+		if err != nil {
+			return nil, err
+		}
+		return v, allocate(thread, sizeOf(v))
+	})
+}
+
+// countArgs returns a built-in that counts the size of its arguments before
+// calling fn, such as the text decoded by json.decode.
+func countArgs(fn *starlark.Builtin) *starlark.Builtin {
+	return starlark.NewBuiltin(fn.Name(), func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		n := 0
+		for _, arg := range args {
+			n = addSize(n, sizeOf(arg))
+		}
+		if err := allocate(thread, n); err != nil {
+			return nil, err
+		}
+		return starlark.Call(thread, fn, args, kwargs)
+	})
+}
+
+func callBinary(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var op string
+	var x, y starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 3, &op, &x, &y); err != nil {
+		return nil, err
+	}
+	n := binarySize(op, x, y)
+	if _, ok := x.(starlark.Int); ok && op == "*" && n > maxIntSize {
+		if _, ok := y.(starlark.Int); ok {
+			return nil, Errorf(ETOOLARGE, "Code integers cannot be larger than %d bytes.", maxIntSize)
+		}
+	}
+	if err := allocate(thread, n); err != nil {
+		return nil, err
+	}
+	return starlark.Binary(codeOperators[op], x, y)
+}
+
+func callAugmented(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var op string
+	var x, y starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 3, &op, &x, &y); err != nil {
+		return nil, err
+	}
+	// Lists are extended in place by +=.
+	n := binarySize(op, x, y)
+	if _, ok := x.(*starlark.List); ok && op == "+" {
+		n = sizeOf(y)
+	}
+	return y, allocate(thread, n)
+}
+
+func callMethod(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) < 2 {
+		return nil, starlark.UnpackPositionalArgs(b.Name(), args, nil, 2)
+	}
+	recv, name, args := args[0], string(args[1].(starlark.String)), args[2:]
+
+	x, ok := recv.(starlark.HasAttrs)
+	if !ok {
+		return nil, fmt.Errorf("%s has no .%s field or method", recv.Type(), name)
+	}
+	fn, err := x.Attr(name)
+	if err != nil {
+		return nil, err
+	} else if fn == nil {
+		return nil, fmt.Errorf("%s has no .%s field or method", recv.Type(), name)
+	}
+
+	var n int
+	switch recv := recv.(type) {
+	case starlark.String:
+		n = methodSize(string(recv), name, args, kwargs)
+	case *starlark.List, *starlark.Dict:
+		n = containerMethodSize(recv, name, args, kwargs)
+	}
+	if err := allocate(thread, n); err != nil {
+		return nil, err
+	}
+	return starlark.Call(thread, fn, args, kwargs)
+}
+
+// binarySize returns the number of bytes x op y allocates.
+func binarySize(op string, x, y starlark.Value) int {
+	switch op {
+	case "+":
+		return addSize(sizeOf(x), sizeOf(y))
+	case "*":
+		// Repeats of sequences, or products of integers.
+		if n, ok := x.(starlark.Int); ok {
+			return repeatSize(y, n)
+		} else if n, ok := y.(starlark.Int); ok {
+			return repeatSize(x, n)
+		}
+	case "%":
+		if format, ok := x.(starlark.String); ok {
+			values := starlark.Tuple{y}
+			if t, ok := y.(starlark.Tuple); ok {
+				values = t
+			}
+			return formatSize(string(format), "%", values, nil)
+		}
+	}
+	return 0
+}
+
+// repeatSize returns the number of bytes x * n allocates.
+func repeatSize(x starlark.Value, n starlark.Int) int {
+	if _, ok := x.(starlark.Int); ok {
+		return addSize(sizeOf(x), sizeOf(n))
+	}
+	count, ok := n.Int64()
+	if !ok {
+		count = math.MaxInt64
+	}
+	return mulSize(sizeOf(x), int(count))
+}
+
+// methodSize returns the number of bytes a string method allocates.
+func methodSize(s, name string, args starlark.Tuple, kwargs []starlark.Tuple) int {
+	switch name {
+	case "format":
+		return formatSize(s, "{", args, kwargs)
+
+	case "join":
+		if len(args) == 0 {
+			return 0
+		}
+		iter := starlark.Iterate(args[0])
+		if iter == nil {
+			return 0
+		}
+		defer iter.Done()
+		// Elements other than strings fail the join, so counting stops
+		// at the first one.
+		n, count := 0, 0
+		var v starlark.Value
+		for iter.Next(&v) {
+			elem, ok := v.(starlark.String)
+			if !ok {
+				break
+			}
+			n, count = addSize(n, len(elem)), count+1
+		}
+		return addSize(n, mulSize(len(s), count))
+
+	case "replace":
+		if len(args) < 2 {
+			return 0
+		}
+		old, _ := starlark.AsString(args[0])
+		new, _ := starlark.AsString(args[1])
+		count := strings.Count(s, old)
+		if len(args) > 2 {
+			if n, ok := args[2].(starlark.Int); ok {
+				if max, ok := n.Int64(); ok && max >= 0 && max < int64(count) {
+					count = int(max)
+				}
+			}
+		}
+		if len(new) <= len(old) {
+			return len(s)
+		}
+		return addSize(len(s), mulSize(count, len(new)-len(old)))
+	}
+	return 0
+}
+
+// containerMethodSize returns the number of bytes a method of a list or dict
+// allocates: the elements it adds, or the elements it copies into a new list.
+func containerMethodSize(recv starlark.Value, name string, args starlark.Tuple, kwargs []starlark.Tuple) int {
+	switch name {
+	case "append", "insert", "setdefault":
+		return valueSize
+	case "extend":
+		if len(args) > 0 {
+			return mulSize(starlark.Len(args[0]), valueSize)
+		}
+	case "update":
+		n := len(kwargs)
+		if len(args) > 0 {
+			n = addSize(n, starlark.Len(args[0]))
+		}
+		return mulSize(n, valueSize)
+	case "items", "keys", "values":
+		return mulSize(starlark.Len(recv), valueSize)
+	}
+	return 0
+}
+
+// formatSize returns the number of bytes formatting values with a format
+// allocates at most: each placeholder, introduced by marker, may be replaced
+// by the longest value.
+func formatSize(format, marker string, args starlark.Tuple, kwargs []starlark.Tuple) int {
+	longest := 0
+	for _, v := range args {
+		if n := textSize(v); n > longest {
+			longest = n
+		}
+	}
+	for _, kv := range kwargs {
+		if n := textSize(kv[1]); n > longest {
+			longest = n
+		}
+	}
+	return addSize(len(format), mulSize(strings.Count(format, marker), longest))
+}
+
+// textSize returns the length of a value as text.
+func textSize(v starlark.Value) int {
+	if s, ok := v.(starlark.String); ok {
+		return len(s)
+	}
+	return len(v.String())
+}
+
+// sizeOf returns the number of bytes a value holds, not counting the values
+// it contains.
+func sizeOf(v starlark.Value) int {
+	switch v := v.(type) {
+	case starlark.String:
+		return len(v)
+	case starlark.Int:
+		if _, ok := v.Int64(); ok {
+			return 0
+		}
+		return v.BigInt().BitLen() / 8
+	case *starlark.List, starlark.Tuple, *starlark.Dict:
+		return mulSize(starlark.Len(v), valueSize)
+	}
+	return 0
+}
+
+// addSize & mulSize add & multiply sizes, saturating instead of overflowing.
+func addSize(a, b int) int {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func mulSize(a, b int) int {
+	if a <= 0 || b <= 0 {
+		return 0
+	} else if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// instrumentCode rewrites the operations of a script that can allocate more
+// than the size of their operands into calls of the functions that count
+// their allocations.
+func instrumentCode(f *syntax.File) {
+	syntax.Walk(f, func(n syntax.Node) bool {
+		// Children are replaced before they are walked, so that the
+		// operands of replaced operations are instrumented as well.
+		switch n := n.(type) {
+		case *syntax.ExprStmt:
+			n.X = instrumentExpr(n.X)
+		case *syntax.IfStmt:
+			n.Cond = instrumentExpr(n.Cond)
+		case *syntax.WhileStmt:
+			n.Cond = instrumentExpr(n.Cond)
+		case *syntax.ForStmt:
+			n.X = instrumentExpr(n.X)
+		case *syntax.ReturnStmt:
+			n.Result = instrumentExpr(n.Result)
+		case *syntax.AssignStmt:
+			n.RHS = instrumentExpr(n.RHS)
+			if op, ok := codeAugmentedOperators[n.Op]; ok {
+				n.RHS = codeCall(codeAugmented, n.OpPos, codeString(op, n.OpPos), copyTarget(n.LHS, n.OpPos), n.RHS)
+			}
+		case *syntax.BinaryExpr:
+			n.X, n.Y = instrumentExpr(n.X), instrumentExpr(n.Y)
+		case *syntax.UnaryExpr:
+			n.X = instrumentExpr(n.X)
+		case *syntax.CallExpr:
+			n.Fn = instrumentExpr(n.Fn)
+			for i := range n.Args {
+				n.Args[i] = instrumentExpr(n.Args[i])
+			}
+		case *syntax.DotExpr:
+			n.X = instrumentExpr(n.X)
+		case *syntax.IndexExpr:
+			n.X, n.Y = instrumentExpr(n.X), instrumentExpr(n.Y)
+		case *syntax.SliceExpr:
+			n.X, n.Lo, n.Hi, n.Step = instrumentExpr(n.X), instrumentExpr(n.Lo), instrumentExpr(n.Hi), instrumentExpr(n.Step)
+		case *syntax.ParenExpr:
+			n.X = instrumentExpr(n.X)
+		case *syntax.ListExpr:
+			for i := range n.List {
+				n.List[i] = instrumentExpr(n.List[i])
+			}
+		case *syntax.TupleExpr:
+			for i := range n.List {
+				n.List[i] = instrumentExpr(n.List[i])
+			}
+		case *syntax.DictEntry:
+			n.Key, n.Value = instrumentExpr(n.Key), instrumentExpr(n.Value)
+		case *syntax.CondExpr:
+			n.Cond, n.True, n.False = instrumentExpr(n.Cond), instrumentExpr(n.True), instrumentExpr(n.False)
+		case *syntax.Comprehension:
+			n.Body = instrumentExpr(n.Body)
+		case *syntax.ForClause:
+			n.X = instrumentExpr(n.X)
+		case *syntax.IfClause:
+			n.Cond = instrumentExpr(n.Cond)
+		case *syntax.LambdaExpr:
+			n.Body = instrumentExpr(n.Body)
+		}
+		return true
+	})
+}
+
+// instrumentExpr replaces an operation that can allocate more than the size
+// of its operands with a call that counts its allocations.
+func instrumentExpr(e syntax.Expr) syntax.Expr {
+	switch e := e.(type) {
+	case *syntax.BinaryExpr:
+		for op, tok := range codeOperators {
+			if e.Op == tok {
+				return codeCall(codeBinary, e.OpPos, codeString(op, e.OpPos), e.X, e.Y)
+			}
+		}
+	case *syntax.CallExpr:
+		if dot, ok := e.Fn.(*syntax.DotExpr); ok && codeMethods[dot.Name.Name] {
+			args := append([]syntax.Expr{dot.X, codeString(dot.Name.Name, dot.NamePos)}, e.Args...)
+			return codeCall(codeMethod, e.Lparen, args...)
+		}
+	}
+	return e
+}
+
+// copyTarget returns a copy of the target of an augmented assignment to be
+// evaluated as an operand. Targets whose evaluation could have side effects
+// are not copied and are passed as None.
+func copyTarget(e syntax.Expr, pos syntax.Position) syntax.Expr {
+	switch e := e.(type) {
+	case *syntax.Ident:
+		return &syntax.Ident{NamePos: e.NamePos, Name: e.Name}
+	case *syntax.Literal:
+		c := *e
+		return &c
+	case *syntax.DotExpr:
+		if x := copyTarget(e.X, pos); x != nil {
+			return &syntax.DotExpr{X: x, Dot: e.Dot, NamePos: e.NamePos, Name: &syntax.Ident{NamePos: e.Name.NamePos, Name: e.Name.Name}}
+		}
+	case *syntax.IndexExpr:
+		x, y := copyTarget(e.X, pos), copyTarget(e.Y, pos)
+		if x != nil && y != nil {
+			return &syntax.IndexExpr{X: x, Lbrack: e.Lbrack, Y: y, Rbrack: e.Rbrack}
+		}
+	}
+	return &syntax.Ident{NamePos: pos, Name: "None"}
+}
+
+// codeCall returns a call of a function instrumented scripts call.
+func codeCall(name string, pos syntax.Position, args ...syntax.Expr) *syntax.CallExpr {
+	return &syntax.CallExpr{Fn: &syntax.Ident{NamePos: pos, Name: name}, Lparen: pos, Args: args, Rparen: pos}
+}
+
+// codeString returns a string literal.
+func codeString(s string, pos syntax.Position) *syntax.Literal {
+	return &syntax.Literal{Token: syntax.STRING, TokenPos: pos, Raw: strconv.Quote(s), Value: s}
+}
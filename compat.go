@@ -0,0 +1,289 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of incompatibilities between a node and the current version of its
+// integration.
+const (
+	// The version the node is pinned to is no longer defined, so the node
+	// can only be checked against the current version.
+	IncompatibilityVersionMissing = "version_missing"
+	// The node's trigger or action was removed.
+	IncompatibilityActionRemoved = "action_removed"
+	// The node's trigger or action was renamed.
+	IncompatibilityActionRenamed = "action_renamed"
+	// An input the node sets was removed.
+	IncompatibilityInputRemoved = "input_removed"
+	// An input the node sets was renamed.
+	IncompatibilityInputRenamed = "input_renamed"
+	// An input the node does not set became required.
+	IncompatibilityInputRequired = "input_required"
+)
+
+// Incompatibility describes a change to an integration that affects a node
+// pinned to an earlier version of it.
+type Incompatibility struct {
+	WorkflowID  uuid.UUID `json:"workflow_id"`
+	NodeID      uuid.UUID `json:"node_id"`
+	Integration string    `json:"integration"`
+	Action      string    `json:"action"`
+
+	// Versions the node is pinned to & would be upgraded to.
+	FromVersion int `json:"from_version"`
+	ToVersion   int `json:"to_version"`
+
+	// One of the Incompatibility constants.
+	Kind string `json:"kind"`
+
+	// Key of the affected input, if any.
+	Input string `json:"input,omitempty"`
+
+	// New key of a renamed trigger, action or input.
+	RenamedTo string `json:"renamed_to,omitempty"`
+
+	Message string `json:"message"`
+
+	// Automatic is true if upgrading the node resolves the incompatibility.
+	// Otherwise the node must be edited.
+	Automatic bool `json:"automatic"`
+}
+
+// CompatibilityService checks nodes against the current versions of their
+// integrations and upgrades them.
+type CompatibilityService interface {
+	// Returns the incompatibilities of the nodes matching the filter with
+	// the current versions of their integrations.
+	CheckNodes(ctx context.Context, filter NodeFilter) ([]Incompatibility, error)
+
+	// Migrates a node to the current version of its integration. Returns
+	// ECONFLICT if an incompatibility cannot be resolved automatically.
+	UpgradeNode(ctx context.Context, id uuid.UUID) (*Node, error)
+}
+
+// CheckNode returns the incompatibilities of a node with the current version
+// of its integration. The pinned version is nil if it is no longer defined.
+func CheckNode(node *Node, pinned, current *Integration) []Incompatibility {
+	_, incompatibilities := migrateNode(node, pinned, current)
+	return incompatibilities
+}
+
+// UpgradeNode returns the update that migrates a node to the current version
+// of its integration. Renamed triggers, actions & inputs are renamed, params of
+// removed inputs are dropped and newly required inputs are set to their
+// defaults. The pinned version is nil if it is no longer defined. Returns
+// ECONFLICT listing the incompatibilities that cannot be resolved
+// automatically.
+func UpgradeNode(node *Node, pinned, current *Integration) (NodeUpdate, error) {
+	upd, incompatibilities := migrateNode(node, pinned, current)
+
+	var manual []string
+	for _, inc := range incompatibilities {
+		if !inc.Automatic {
+			manual = append(manual, inc.Message)
+		}
+	}
+	if len(manual) > 0 {
+		return NodeUpdate{}, Errorf(ECONFLICT, "Node cannot be upgraded to version %d of '%s': %s", current.Version, current.Key, strings.Join(manual, " "))
+	}
+	return upd, nil
+}
+
+// migrateNode returns the update that migrates a node to the current version
+// of its integration along with the incompatibilities it resolves or cannot
+// resolve.
+func migrateNode(node *Node, pinned, current *Integration) (NodeUpdate, []Incompatibility) {
+	version := current.Version
+	upd := NodeUpdate{IntegrationVersion: &version}
+	if pinned != nil && pinned.Version == current.Version {
+		return upd, nil
+	}
+
+	var incompatibilities []Incompatibility
+	add := func(kind, input, renamedTo string, automatic bool, format string, args ...interface{}) {
+		incompatibilities = append(incompatibilities, Incompatibility{
+			WorkflowID:  node.WorkflowID,
+			NodeID:      node.ID,
+			Integration: node.Integration,
+			Action:      node.Action,
+			FromVersion: node.IntegrationVersion,
+			ToVersion:   current.Version,
+			Kind:        kind,
+			Input:       input,
+			RenamedTo:   renamedTo,
+			Message:     fmt.Sprintf(format, args...),
+			Automatic:   automatic,
+		})
+	}
+
+	// Without the pinned version, the node is checked as if it were built
+	// against the current version.
+	var from *endpoint
+	if pinned == nil {
+		add(IncompatibilityVersionMissing, "", "", true, "Version %d of '%s' is no longer defined.", node.IntegrationVersion, node.Integration)
+		if from = current.endpoint(node.Action); from == nil {
+			add(IncompatibilityActionRemoved, "", "", false, "'%s' is not a trigger or action of '%s'.", node.Action, node.Integration)
+			return upd, incompatibilities
+		}
+	} else if from = pinned.endpoint(node.Action); from == nil {
+		// The node was invalid for its pinned version already.
+		return upd, nil
+	}
+
+	to := current.successor(from)
+	if to == nil {
+		add(IncompatibilityActionRemoved, "", "", false, "%s '%s' was removed.", strings.Title(from.kind), node.Action)
+		return upd, incompatibilities
+	} else if to.key != node.Action {
+		add(IncompatibilityActionRenamed, "", to.key, true, "%s '%s' was renamed to '%s'.", strings.Title(from.kind), node.Action, to.key)
+		upd.Action = to.key
+	}
+
+	// Params of inputs declared by the pinned version are renamed or
+	// dropped. Other params are kept as they are.
+	set := make(map[string]bool)
+	params := make([]*Param, 0, len(node.Params))
+	changed := false
+	for _, param := range node.Params {
+		if from.input(param.Key) == nil {
+			set[param.Key] = true
+			params = append(params, param)
+			continue
+		}
+
+		input := to.successorInput(param.Key)
+		if input == nil {
+			add(IncompatibilityInputRemoved, param.Key, "", true, "Input '%s' was removed.", param.Key)
+			changed = true
+			continue
+		}
+		p := *param
+		if input.Key != param.Key {
+			add(IncompatibilityInputRenamed, param.Key, input.Key, true, "Input '%s' was renamed to '%s'.", param.Key, input.Key)
+			p.Key, changed = input.Key, true
+		}
+		set[p.Key] = true
+		params = append(params, &p)
+	}
+
+	for _, input := range to.inputs {
+		if !input.Required || set[input.Key] {
+			continue
+		}
+		// Inputs the node was already missing are not caused by the
+		// upgrade.
+		if old := from.predecessorInput(input); old != nil && old.Required && pinned != nil {
+			continue
+		}
+		if input.Default == "" {
+			add(IncompatibilityInputRequired, input.Key, "", false, "Input '%s' is now required.", input.Key)
+			continue
+		}
+		add(IncompatibilityInputRequired, input.Key, "", true, "Input '%s' is now required and is set to its default.", input.Key)
+		params = append(params, &Param{Key: input.Key, Value: input.Default, Type: ParamTypeValue})
+		changed = true
+	}
+
+	if changed {
+		upd.Params = params
+	}
+	return upd, incompatibilities
+}
+
+// endpoint is a trigger or action of an integration.
+type endpoint struct {
+	kind     string // "trigger" or "action"
+	key      string
+	replaces []string
+	inputs   []InputField
+}
+
+// endpoint returns the trigger or action with the given key, or nil.
+func (i *Integration) endpoint(key string) *endpoint {
+	for _, e := range i.endpoints() {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// successor returns the trigger or action of the same kind that has the key
+// of an endpoint of an earlier version or replaces it. Returns nil if the
+// endpoint was removed.
+func (i *Integration) successor(e *endpoint) *endpoint {
+	var renamed *endpoint
+	for _, s := range i.endpoints() {
+		if s.kind != e.kind {
+			continue
+		} else if s.key == e.key {
+			return s
+		} else if renamed == nil && contains(s.replaces, e.key) {
+			renamed = s
+		}
+	}
+	return renamed
+}
+
+func (i *Integration) endpoints() []*endpoint {
+	endpoints := make([]*endpoint, 0, len(i.Triggers)+len(i.Actions))
+	for _, t := range i.Triggers {
+		endpoints = append(endpoints, &endpoint{kind: "trigger", key: t.Key, replaces: t.Replaces, inputs: t.Inputs})
+	}
+	for _, a := range i.Actions {
+		endpoints = append(endpoints, &endpoint{kind: "action", key: a.Key, replaces: a.Replaces, inputs: a.Inputs})
+	}
+	return endpoints
+}
+
+// input returns the input with the given key, or nil.
+func (e *endpoint) input(key string) *InputField {
+	for j := range e.inputs {
+		if e.inputs[j].Key == key {
+			return &e.inputs[j]
+		}
+	}
+	return nil
+}
+
+// successorInput returns the input that has the key of an input of an
+// earlier version or replaces it. Returns nil if the input was removed.
+func (e *endpoint) successorInput(key string) *InputField {
+	if input := e.input(key); input != nil {
+		return input
+	}
+	for j := range e.inputs {
+		if contains(e.inputs[j].Replaces, key) {
+			return &e.inputs[j]
+		}
+	}
+	return nil
+}
+
+// predecessorInput returns the input of an earlier version that a current
+// input has the key of or replaces. Returns nil if the input is new.
+func (e *endpoint) predecessorInput(input InputField) *InputField {
+	if old := e.input(input.Key); old != nil {
+		return old
+	}
+	for _, key := range input.Replaces {
+		if old := e.input(key); old != nil {
+			return old
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
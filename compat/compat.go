@@ -0,0 +1,165 @@
+// Package compat pins nodes to the versions of the integrations they are built
+// against, validates their params against those versions, and checks &
+// upgrades them when their integrations change.
+package compat
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// NodeService wraps a node service to pin the nodes it creates & updates to
+// the current versions of their integrations and validate their params. It
+// also implements flow.CompatibilityService.
+type NodeService struct {
+	flow.NodeService
+	IntegrationService flow.IntegrationService
+}
+
+// NewNodeService returns a new instance of NodeService that wraps nodes.
+func NewNodeService(nodes flow.NodeService, integrations flow.IntegrationService) *NodeService {
+	return &NodeService{
+		NodeService:        nodes,
+		IntegrationService: integrations,
+	}
+}
+
+// CreateNode creates a node pinned to the current version of its integration.
+// Returns EINVALID if its params do not match the inputs of its action.
+func (s *NodeService) CreateNode(ctx context.Context, node *flow.Node) error {
+	version, err := s.currentVersion(ctx, node.Integration)
+	if err != nil {
+		return err
+	}
+	node.IntegrationVersion = version
+	if err := s.validateParams(ctx, node.Integration, node.Action, version, node.Params); err != nil {
+		return err
+	}
+	return s.NodeService.CreateNode(ctx, node)
+}
+
+// UpdateNode updates a node. Nodes whose integration, action or params are
+// updated are pinned to the current version of their integration, as they
+// are edited against it, and their params are validated against it. Other
+// updates keep the node's version unless the update sets one.
+func (s *NodeService) UpdateNode(ctx context.Context, id uuid.UUID, upd flow.NodeUpdate) (*flow.Node, error) {
+	if upd.Integration != "" || upd.Action != "" || upd.Params != nil || upd.IntegrationVersion != nil {
+		node, err := s.NodeService.GetNodeByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		integration, action, params := node.Integration, node.Action, node.Params
+		if upd.Integration != "" {
+			integration = upd.Integration
+		}
+		if upd.Action != "" {
+			action = upd.Action
+		}
+		if upd.Params != nil {
+			params = upd.Params
+		}
+
+		if upd.IntegrationVersion == nil {
+			version, err := s.currentVersion(ctx, integration)
+			if err != nil {
+				return nil, err
+			}
+			upd.IntegrationVersion = &version
+		}
+		if err := s.validateParams(ctx, integration, action, *upd.IntegrationVersion, params); err != nil {
+			return nil, err
+		}
+	}
+	return s.NodeService.UpdateNode(ctx, id, upd)
+}
+
+// CheckNodes returns the incompatibilities of the nodes matching the filter
+// with the current versions of their integrations. Nodes of integrations that
+// no longer exist are skipped.
+func (s *NodeService) CheckNodes(ctx context.Context, filter flow.NodeFilter) ([]flow.Incompatibility, error) {
+	nodes, _, err := s.NodeService.GetNodes(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	incompatibilities := make([]flow.Incompatibility, 0)
+	for _, node := range nodes {
+		pinned, current, err := s.versions(ctx, node)
+		if flow.ErrorCode(err) == flow.ENOTFOUND {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		incompatibilities = append(incompatibilities, flow.CheckNode(node, pinned, current)...)
+	}
+	return incompatibilities, nil
+}
+
+// UpgradeNode migrates a node to the current version of its integration.
+// Returns ECONFLICT if an incompatibility cannot be resolved automatically.
+func (s *NodeService) UpgradeNode(ctx context.Context, id uuid.UUID) (*flow.Node, error) {
+	node, err := s.NodeService.GetNodeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	pinned, current, err := s.versions(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	upd, err := flow.UpgradeNode(node, pinned, current)
+	if err != nil {
+		return nil, err
+	}
+	return s.NodeService.UpdateNode(ctx, id, upd)
+}
+
+// versions returns the version of a node's integration it is pinned to, or
+// nil if that version is no longer defined, and the current version. Returns
+// ENOTFOUND if the integration does not exist.
+func (s *NodeService) versions(ctx context.Context, node *flow.Node) (pinned, current *flow.Integration, err error) {
+	if current, err = s.IntegrationService.GetIntegrationByKey(ctx, node.Integration); err != nil {
+		return nil, nil, err
+	}
+	if pinned, err = s.IntegrationService.GetIntegrationVersion(ctx, node.Integration, node.IntegrationVersion); flow.ErrorCode(err) == flow.ENOTFOUND {
+		return nil, current, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return pinned, current, nil
+}
+
+// validateParams validates the params of a node against the inputs of its
+// action or trigger in a version of its integration. Params of integrations,
+// versions, actions & triggers that are not defined are not validated.
+func (s *NodeService) validateParams(ctx context.Context, integration, action string, version int, params []*flow.Param) error {
+	app, err := s.IntegrationService.GetIntegrationVersion(ctx, integration, version)
+	if flow.ErrorCode(err) == flow.ENOTFOUND {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if a, err := app.GetAction(action); err == nil {
+		return flow.ValidateParams(a.Inputs, params)
+	} else if t, err := app.GetTrigger(action); err == nil {
+		return flow.ValidateParams(t.Inputs, params)
+	}
+	return nil
+}
+
+// currentVersion returns the current version of an integration. Nodes of
+// unknown integrations are pinned to the default version.
+func (s *NodeService) currentVersion(ctx context.Context, key string) (int, error) {
+	app, err := s.IntegrationService.GetIntegrationByKey(ctx, key)
+	if flow.ErrorCode(err) == flow.ENOTFOUND {
+		return flow.DefaultIntegrationVersion, nil
+	} else if err != nil {
+		return 0, err
+	} else if app.Version == 0 {
+		return flow.DefaultIntegrationVersion, nil
+	}
+	return app.Version, nil
+}
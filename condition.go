@@ -0,0 +1,228 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition operators.
+const (
+	OperatorEquals             = "equals"
+	OperatorNotEquals          = "not_equals"
+	OperatorContains           = "contains"
+	OperatorRegex              = "regex"
+	OperatorGreaterThan        = "gt"
+	OperatorGreaterThanOrEqual = "gte"
+	OperatorLessThan           = "lt"
+	OperatorLessThanOrEqual    = "lte"
+	OperatorExists             = "exists"
+	OperatorNotExists          = "not_exists"
+)
+
+// Condition is a predicate over a JSON document. A condition is either a group
+// of conditions that must all (And) or partly (Or) hold, or a comparison of the
+// value at Path with Value.
+type Condition struct {
+	And []*Condition `json:"and,omitempty"`
+	Or  []*Condition `json:"or,omitempty"`
+
+	// Path of the compared value within the document, e.g. "body.action".
+	Path     string      `json:"path,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// Validate returns an error if the condition cannot be evaluated.
+func (c *Condition) Validate() error {
+	if len(c.And) > 0 || len(c.Or) > 0 {
+		if c.Path != "" || c.Operator != "" {
+			return Errorf(EINVALID, "Condition groups cannot have a path or operator.")
+		}
+		for _, group := range [][]*Condition{c.And, c.Or} {
+			for _, sub := range group {
+				if sub == nil {
+					return Errorf(EINVALID, "Condition groups cannot contain empty conditions.")
+				} else if err := sub.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if _, err := ParsePath(c.Path); err != nil {
+		return Errorf(EINVALID, "Condition path '%s' is invalid.", c.Path)
+	}
+
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals, OperatorContains,
+		OperatorGreaterThan, OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual,
+		OperatorExists, OperatorNotExists:
+	case OperatorRegex:
+		if _, err := regexp.Compile(fmt.Sprint(c.Value)); err != nil {
+			return Errorf(EINVALID, "Condition regex '%v' is invalid.", c.Value)
+		}
+	default:
+		return Errorf(EINVALID, "Condition operator '%s' is not supported.", c.Operator)
+	}
+	return nil
+}
+
+// Evaluate returns true if the condition holds for a document. Empty groups
+// hold.
+func (c *Condition) Evaluate(doc interface{}) (bool, error) {
+	if len(c.And) > 0 || len(c.Or) > 0 {
+		for _, sub := range c.And {
+			if ok, err := sub.Evaluate(doc); err != nil || !ok {
+				return false, err
+			}
+		}
+		if len(c.Or) == 0 {
+			return true, nil
+		}
+		for _, sub := range c.Or {
+			if ok, err := sub.Evaluate(doc); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	actual, found, err := Lookup(doc, c.Path)
+	if err != nil {
+		return false, err
+	}
+	found = found && actual != nil
+
+	switch c.Operator {
+	case OperatorExists:
+		return found, nil
+	case OperatorNotExists:
+		return !found, nil
+	case OperatorNotEquals:
+		return !found || !valuesEqual(actual, c.Value), nil
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	switch c.Operator {
+	case OperatorEquals:
+		return valuesEqual(actual, c.Value), nil
+
+	case OperatorContains:
+		switch v := actual.(type) {
+		case string:
+			return strings.Contains(v, fmt.Sprint(c.Value)), nil
+		case []interface{}:
+			for _, elem := range v {
+				if valuesEqual(elem, c.Value) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			_, ok := v[fmt.Sprint(c.Value)]
+			return ok, nil
+		}
+		return false, nil
+
+	case OperatorRegex:
+		re, err := regexp.Compile(fmt.Sprint(c.Value))
+		if err != nil {
+			return false, Errorf(EINVALID, "Condition regex '%v' is invalid.", c.Value)
+		}
+		return re.MatchString(stringValue(actual)), nil
+
+	case OperatorGreaterThan, OperatorGreaterThanOrEqual, OperatorLessThan, OperatorLessThanOrEqual:
+		a, ok := numberValue(actual)
+		if !ok {
+			return false, nil
+		}
+		b, ok := numberValue(c.Value)
+		if !ok {
+			return false, nil
+		}
+		switch c.Operator {
+		case OperatorGreaterThan:
+			return a > b, nil
+		case OperatorGreaterThanOrEqual:
+			return a >= b, nil
+		case OperatorLessThan:
+			return a < b, nil
+		default:
+			return a <= b, nil
+		}
+	}
+	return false, Errorf(EINVALID, "Condition operator '%s' is not supported.", c.Operator)
+}
+
+// Scan implements sql.Scanner so that conditions can be read from JSON
+// columns.
+func (c *Condition) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, c)
+	case string:
+		return json.Unmarshal([]byte(src), c)
+	}
+	return fmt.Errorf("cannot scan %T into condition", src)
+}
+
+// valuesEqual compares two JSON values. Numbers are compared numerically and
+// scalars of different types by their string form, so that "42" equals 42.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := a.(float64); ok {
+		if y, ok := numberValue(b); ok {
+			return x == y
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if isScalar(a) && isScalar(b) {
+		return stringValue(a) == stringValue(b)
+	}
+	return false
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool, int, int64:
+		return true
+	}
+	return false
+}
+
+// stringValue converts a JSON value to a string. Numbers are formatted without
+// exponents.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// numberValue converts a JSON number or numeric string to a float.
+func numberValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,117 @@
+package flow
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCondition_Evaluate(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"action": "opened",
+		"number": 42,
+		"id": "42",
+		"title": "Fix the build",
+		"labels": ["bug", "ci"],
+		"user": {"login": "octocat"},
+		"draft": false,
+		"assignee": null,
+		"items": [{"price": 5}, {"price": 12}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		condition Condition
+		want      bool
+		code      string
+	}{
+		{"Equals", Condition{Path: "action", Operator: OperatorEquals, Value: "opened"}, true, ""},
+		{"EqualsOther", Condition{Path: "action", Operator: OperatorEquals, Value: "closed"}, false, ""},
+		{"EqualsNumberString", Condition{Path: "number", Operator: OperatorEquals, Value: "42"}, true, ""},
+		{"EqualsStringNumber", Condition{Path: "id", Operator: OperatorEquals, Value: float64(42)}, true, ""},
+		{"EqualsBool", Condition{Path: "draft", Operator: OperatorEquals, Value: false}, true, ""},
+		{"EqualsMissing", Condition{Path: "missing", Operator: OperatorEquals, Value: "x"}, false, ""},
+		{"NotEquals", Condition{Path: "action", Operator: OperatorNotEquals, Value: "closed"}, true, ""},
+		{"NotEqualsMissing", Condition{Path: "missing", Operator: OperatorNotEquals, Value: "x"}, true, ""},
+		{"ContainsString", Condition{Path: "title", Operator: OperatorContains, Value: "build"}, true, ""},
+		{"ContainsArray", Condition{Path: "labels", Operator: OperatorContains, Value: "bug"}, true, ""},
+		{"ContainsArrayOther", Condition{Path: "labels", Operator: OperatorContains, Value: "docs"}, false, ""},
+		{"ContainsKey", Condition{Path: "user", Operator: OperatorContains, Value: "login"}, true, ""},
+		{"ContainsNumber", Condition{Path: "number", Operator: OperatorContains, Value: "4"}, false, ""},
+		{"Regex", Condition{Path: "title", Operator: OperatorRegex, Value: "^Fix"}, true, ""},
+		{"RegexNumber", Condition{Path: "number", Operator: OperatorRegex, Value: `^\d+$`}, true, ""},
+		{"RegexInvalid", Condition{Path: "title", Operator: OperatorRegex, Value: "("}, false, EINVALID},
+		{"GreaterThan", Condition{Path: "number", Operator: OperatorGreaterThan, Value: 41}, true, ""},
+		{"GreaterThanEqual", Condition{Path: "number", Operator: OperatorGreaterThan, Value: 42}, false, ""},
+		{"GreaterThanOrEqual", Condition{Path: "number", Operator: OperatorGreaterThanOrEqual, Value: 42}, true, ""},
+		{"LessThanString", Condition{Path: "id", Operator: OperatorLessThan, Value: " 100 "}, true, ""},
+		{"LessThanOrEqual", Condition{Path: "number", Operator: OperatorLessThanOrEqual, Value: 41}, false, ""},
+		{"CompareNotNumber", Condition{Path: "title", Operator: OperatorGreaterThan, Value: 1}, false, ""},
+		{"Index", Condition{Path: "items[1].price", Operator: OperatorGreaterThan, Value: 10}, true, ""},
+		{"Exists", Condition{Path: "user.login", Operator: OperatorExists}, true, ""},
+		{"ExistsNull", Condition{Path: "assignee", Operator: OperatorExists}, false, ""},
+		{"NotExists", Condition{Path: "user.email", Operator: OperatorNotExists}, true, ""},
+		{"InvalidPath", Condition{Path: "items[", Operator: OperatorExists}, false, EINVALID},
+		{"InvalidOperator", Condition{Path: "action", Operator: "like"}, false, EINVALID},
+		{"Empty", Condition{}, false, EINVALID},
+		{"And", Condition{And: []*Condition{
+			{Path: "action", Operator: OperatorEquals, Value: "opened"},
+			{Path: "draft", Operator: OperatorEquals, Value: false},
+		}}, true, ""},
+		{"AndFails", Condition{And: []*Condition{
+			{Path: "action", Operator: OperatorEquals, Value: "opened"},
+			{Path: "draft", Operator: OperatorEquals, Value: true},
+		}}, false, ""},
+		{"Or", Condition{Or: []*Condition{
+			{Path: "action", Operator: OperatorEquals, Value: "closed"},
+			{Path: "labels", Operator: OperatorContains, Value: "ci"},
+		}}, true, ""},
+		{"OrFails", Condition{Or: []*Condition{
+			{Path: "action", Operator: OperatorEquals, Value: "closed"},
+			{Path: "labels", Operator: OperatorContains, Value: "docs"},
+		}}, false, ""},
+		{"AndOr", Condition{
+			And: []*Condition{{Path: "action", Operator: OperatorEquals, Value: "opened"}},
+			Or:  []*Condition{{Path: "number", Operator: OperatorLessThan, Value: 10}},
+		}, false, ""},
+		{"Nested", Condition{Or: []*Condition{
+			{And: []*Condition{{Path: "missing", Operator: OperatorExists}}},
+			{And: []*Condition{{Path: "user.login", Operator: OperatorEquals, Value: "octocat"}}},
+		}}, true, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.condition.Evaluate(doc)
+			if code := ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			} else if got != tt.want {
+				t.Fatalf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_Validate(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		condition Condition
+		code      string
+	}{
+		{"Comparison", Condition{Path: "a.b[0]", Operator: OperatorEquals, Value: 1}, ""},
+		{"Exists", Condition{Path: "a", Operator: OperatorExists}, ""},
+		{"Group", Condition{And: []*Condition{{Path: "a", Operator: OperatorExists}}}, ""},
+		{"InvalidPath", Condition{Path: "a..b", Operator: OperatorExists}, EINVALID},
+		{"InvalidOperator", Condition{Path: "a", Operator: "like"}, EINVALID},
+		{"InvalidRegex", Condition{Path: "a", Operator: OperatorRegex, Value: "["}, EINVALID},
+		{"GroupWithPath", Condition{Path: "a", And: []*Condition{{Path: "a", Operator: OperatorExists}}}, EINVALID},
+		{"GroupWithNil", Condition{Or: []*Condition{nil}}, EINVALID},
+		{"GroupWithInvalid", Condition{Or: []*Condition{{Path: "a", Operator: "like"}}}, EINVALID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ErrorCode(tt.condition.Validate()); code != tt.code {
+				t.Fatalf("error code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
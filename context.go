@@ -15,6 +15,12 @@ type contextKey int
 const (
 	// Stores the current logged in user in the context.
 	userContextKey = contextKey(iota + 1)
+
+	// Flags the context as belonging to an internal process.
+	systemContextKey
+
+	// Stores the idempotency key of the node being executed.
+	idempotencyKeyContextKey
 )
 
 // NewContextWithUser returns a new context with the given user ID.
@@ -28,3 +34,29 @@ func UserIDFromContext(ctx context.Context) uuid.UUID {
 	return id
 }
 
+// NewSystemContext returns a new context for internal processes, such as the
+// executor, that act on resources regardless of which user owns them.
+func NewSystemContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemContextKey, true)
+}
+
+// IsSystemContext returns true if the context belongs to an internal process.
+func IsSystemContext(ctx context.Context) bool {
+	v, _ := ctx.Value(systemContextKey).(bool)
+	return v
+}
+
+// NewContextWithIdempotencyKey returns a new context with the idempotency key
+// of the node being executed. Runners pass the key on to the APIs they call
+// so that nodes executed more than once, such as when an interrupted run is
+// resumed, only take effect once.
+func NewContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the node being
+// executed. Returns an empty string if the context does not have one.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey).(string)
+	return key
+}
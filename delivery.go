@@ -0,0 +1,102 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// DefaultDeliveryTTL is the time a delivery is remembered when an idempotency
+// key does not specify its own TTL.
+const DefaultDeliveryTTL = 24 * time.Hour
+
+// DeliveryLease is the time a delivery may be processed for. Retries of a
+// delivery whose processing has not finished by then, e.g. because the server
+// stopped, are processed again rather than rejected until the delivery
+// expires.
+const DeliveryLease = time.Minute
+
+// IdempotencyKey describes where the idempotency key of a webhook delivery is
+// found. If both a header and a path are set, the header takes precedence.
+type IdempotencyKey struct {
+	// Request header carrying the key, e.g. "X-GitHub-Delivery" or
+	// "Idempotency-Key".
+	Header string `json:"header,omitempty"`
+
+	// Path of the key within the decoded body, e.g. "event_id".
+	Path string `json:"path,omitempty"`
+
+	// Time in seconds that deliveries are remembered for.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// TTL returns the time deliveries are remembered for.
+func (k *IdempotencyKey) TTL() time.Duration {
+	if k.TTLSeconds > 0 {
+		return time.Duration(k.TTLSeconds) * time.Second
+	}
+	return DefaultDeliveryTTL
+}
+
+// Extract returns the idempotency key of a delivery. Returns false if the
+// delivery does not carry a key.
+func (k *IdempotencyKey) Extract(payload *WebhookPayload) (string, bool, error) {
+	if k.Header != "" {
+		v, ok := payload.Headers[http.CanonicalHeaderKey(k.Header)]
+		return v, ok && v != "", nil
+	}
+	if k.Path == "" {
+		return "", false, nil
+	}
+
+	v, ok, err := Lookup(payload.Body, k.Path)
+	if err != nil || !ok || v == nil {
+		return "", false, err
+	}
+	if s, ok := v.(string); ok {
+		return s, s != "", nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", false, err
+	}
+	return string(buf), true, nil
+}
+
+// Delivery represents a webhook delivery that has been accepted. Deliveries
+// are remembered until they expire so that retries of the same delivery can
+// be answered with the original response.
+type Delivery struct {
+	Key string `json:"key" db:"key"`
+
+	// Response returned for the original delivery. Nil while the delivery is
+	// still being processed.
+	Response *json.RawMessage `json:"response" db:"response"`
+
+	// Time until which the delivery is being processed. Deliveries without
+	// a response are forgotten once it has passed.
+	LockedUntil *time.Time `json:"locked_until" db:"locked_until"`
+
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DeliveryService represents a service for deduplicating webhook deliveries.
+type DeliveryService interface {
+	// Retrieves an unexpired delivery by key. Returns ENOTFOUND if the
+	// delivery does not exist or has expired.
+	GetDeliveryByKey(ctx context.Context, key string) (*Delivery, error)
+
+	// Records a new delivery. Returns ECONFLICT if an unexpired delivery with
+	// the same key exists, unless it has no response & its lease has passed.
+	CreateDelivery(ctx context.Context, delivery *Delivery) error
+
+	// Sets the response of a delivery once it has been processed and
+	// releases its lease.
+	UpdateDelivery(ctx context.Context, key string, response json.RawMessage) error
+
+	// Forgets a delivery, e.g. if it failed to be processed and should be
+	// accepted again when retried.
+	DeleteDelivery(ctx context.Context, key string) error
+}
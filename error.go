@@ -16,7 +16,10 @@ const (
 	EINVALID        = "invalid"
 	ENOTFOUND       = "not_found"
 	ENOTIMPLEMENTED = "not_implemented"
+	ETOOLARGE       = "too_large"
 	EUNAUTHORIZED   = "unauthorized"
+	EUNAVAILABLE    = "unavailable"
+	EUNSUPPORTED    = "unsupported_media_type"
 )
 
 // Error represents an application-specific error. Application errors can be
@@ -32,6 +35,16 @@ type Error struct {
 
 	// Human-readable error message.
 	Message string
+
+	// Errors of the individual fields of invalid input, if any.
+	Fields []FieldError
+}
+
+// FieldError describes why the value of a single input field is invalid.
+// Messages follow the field's key, as in "count must be a number".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error implements the error interface. Not used by the application otherwise.
@@ -63,6 +76,16 @@ func ErrorMessage(err error) string {
 	return "Internal error."
 }
 
+// ErrorFields unwraps an application error and returns the errors of its
+// fields. Returns nil if the error does not concern individual fields.
+func ErrorFields(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
 // Errorf is a helper function to return an Error with a given code and formatted message.
 func Errorf(code string, format string, args ...interface{}) *Error {
 	return &Error{
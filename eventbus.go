@@ -1,10 +1,21 @@
 package flow
 
+import "github.com/google/uuid"
+
 type Event struct {
 	Payload interface{}
 	Topic   string
 }
 
+// NodeEvent is the payload of an event addressed to a single workflow's
+// trigger node rather than every workflow listening on the topic, such as an
+// item polled with the connection of the workflow's owner.
+type NodeEvent struct {
+	WorkflowID uuid.UUID
+	NodeID     uuid.UUID
+	Payload    interface{}
+}
+
 type Channel chan Event
 
 type EventBus interface {
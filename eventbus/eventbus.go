@@ -7,7 +7,7 @@ import (
 
 func New() flow.EventBus {
 	return &eventBus{
-		subscribers: nil,
+		subscribers: make(map[string][]flow.Channel),
 		rm:          sync.RWMutex{},
 	}
 }
@@ -36,8 +36,8 @@ func (b *eventBus) Publish(topic string, payload interface{}) error {
 			for _, ch := range channels {
 				ch <- ev
 			}
-		}(flow.Event{Payload: payload}, channels)
+		}(flow.Event{Payload: payload, Topic: topic}, channels)
 	}
-	b.rm.Unlock()
+	b.rm.RUnlock()
 	return nil
 }
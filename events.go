@@ -1,10 +1,12 @@
 package flow
 
+import "context"
+
 type EventSource struct {
 }
 
 type Runner interface {
-	Run(inputs map[string]interface{}) (map[string]interface{}, error)
+	Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error)
 }
 
 // Consider data implementation
@@ -0,0 +1,226 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openmesh/flow"
+)
+
+// runner returns the flow.Runner that executes a node's action. Core actions
+// that evaluate conditions or expressions and HTTP requests whose bodies are
+// templates see the run's scope.
+func (e *Executor) runner(ctx context.Context, wf *flow.Workflow, node *flow.Node, scope map[string]interface{}) (flow.Runner, error) {
+	if node.Integration == flow.IntegrationCore {
+		switch node.Action {
+		case flow.ActionRespond:
+			return respondRunner{}, nil
+		case flow.ActionIf:
+			return ifRunner{scope: scope}, nil
+		case flow.ActionSwitch:
+			return switchRunner{}, nil
+		case flow.ActionTransform:
+			return transformRunner{scope: scope}, nil
+		case flow.ActionCode:
+			return codeRunner{}, nil
+		}
+		return nil, flow.Errorf(flow.ENOTFOUND, "Action '%s' not found for integration '%s'.", node.Action, node.Integration)
+	}
+	if node.Integration == flow.IntegrationHTTP {
+		switch node.Action {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			return &requestRunner{
+				client: e.RequestClient,
+				method: node.Action,
+				scope:  scope,
+				token: func(ctx context.Context, integration string) (string, error) {
+					return flow.AccessToken(ctx, e.AuthService, wf.UserID, integration)
+				},
+			}, nil
+		}
+		return nil, flow.Errorf(flow.ENOTFOUND, "Action '%s' not found for integration '%s'.", node.Action, node.Integration)
+	}
+
+	if e.Registry != nil {
+		if app, err := e.Registry.GetNativeIntegration(node.Integration); err == nil {
+			token, err := flow.AccessToken(ctx, e.AuthService, wf.UserID, node.Integration)
+			if err != nil {
+				return nil, err
+			}
+			return &nativeRunner{
+				app: app,
+				req: flow.ActionRequest{
+					Action:     node.Action,
+					Token:      token,
+					WorkflowID: wf.ID,
+					NodeID:     node.ID,
+				},
+			}, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+
+	integration, err := e.pinnedIntegration(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	action, err := integration.GetAction(node.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := flow.AccessToken(ctx, e.AuthService, wf.UserID, integration.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpRunner{
+		client:  e.Client,
+		baseURL: integration.BaseURL,
+		action:  action,
+		auth:    integration.Auth,
+		token:   token,
+	}, nil
+}
+
+// pinnedIntegration returns the version of a node's integration the node is
+// pinned to. Nodes pinned to versions that are no longer defined are executed
+// against the current version.
+func (e *Executor) pinnedIntegration(ctx context.Context, node *flow.Node) (*flow.Integration, error) {
+	if node.IntegrationVersion > 0 {
+		integration, err := e.IntegrationService.GetIntegrationVersion(ctx, node.Integration, node.IntegrationVersion)
+		if err == nil {
+			return integration, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+	return e.IntegrationService.GetIntegrationByKey(ctx, node.Integration)
+}
+
+// respondRunner implements the core respond action. Its inputs become its
+// output.
+type respondRunner struct{}
+
+func (respondRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	return inputs, nil
+}
+
+// httpRunner implements an integration action declared by its method and
+// endpoint by calling the integration's API.
+type httpRunner struct {
+	client  *http.Client
+	baseURL string
+	action  *flow.Action
+	auth    *flow.IntegrationAuth
+	token   string
+}
+
+func (r *httpRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	method, encodeJSON := parseActionMethod(r.action.Method)
+
+	// Substitute inputs referenced as "{key}" within the endpoint. The
+	// remaining inputs are sent as the query or body.
+	params := make(map[string]interface{}, len(inputs))
+	endpoint := r.action.Endpoint
+	for k, v := range inputs {
+		placeholder := "{" + k + "}"
+		if strings.Contains(endpoint, placeholder) {
+			endpoint = strings.Replace(endpoint, placeholder, url.PathEscape(fmt.Sprint(v)), -1)
+			continue
+		}
+		params[k] = v
+	}
+
+	u, err := url.Parse(r.baseURL + endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	var body []byte
+	var contentType string
+	switch {
+	case method == http.MethodGet || method == http.MethodDelete:
+		q := u.Query()
+		for k, v := range params {
+			q.Set(k, fmt.Sprint(v))
+		}
+		u.RawQuery = q.Encode()
+	case encodeJSON:
+		if body, err = json.Marshal(params); err != nil {
+			return nil, err
+		}
+		contentType = "application/json"
+	default:
+		form := url.Values{}
+		for k, v := range params {
+			form.Set(k, fmt.Sprint(v))
+		}
+		body, contentType = []byte(form.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	r.auth.Apply(req, r.token)
+	// Repeated executions of a node send the same key so that the API can
+	// ignore all but the first.
+	if key := flow.IdempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &flow.StatusError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("%s %s: unexpected status %d", method, u.Path, resp.StatusCode),
+		}
+	}
+
+	var doc interface{}
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, &doc); err != nil {
+			return nil, fmt.Errorf("cannot decode response: %w", err)
+		}
+	}
+
+	if len(r.action.Outputs) > 0 {
+		return extractOutputs(r.action.Outputs, doc)
+	} else if m, ok := doc.(map[string]interface{}); ok {
+		return m, nil
+	}
+	return map[string]interface{}{"body": doc}, nil
+}
+
+// parseActionMethod converts an action method such as "JSON_HTTP_POST" into
+// an HTTP method and whether the request body is encoded as JSON.
+func parseActionMethod(method string) (string, bool) {
+	encodeJSON := strings.HasPrefix(method, "JSON_")
+	method = strings.TrimPrefix(method, "JSON_")
+	method = strings.TrimPrefix(method, "HTTP_")
+	if method == "" {
+		method = http.MethodGet
+	}
+	return strings.ToUpper(method), encodeJSON
+}
@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/pkg/workflow"
+)
+
+// ifRunner implements the core if action. The condition is either given as a
+// whole by the "condition" input, whose paths are references into the run's
+// scope, or as a comparison of the "value" & "compare" inputs by "operator".
+type ifRunner struct {
+	scope map[string]interface{}
+}
+
+func (r ifRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	cond, doc := &flow.Condition{}, interface{}(r.scope)
+	if v, ok := inputs["condition"]; ok {
+		if err := decodeCondition(v, cond); err != nil {
+			return nil, err
+		}
+	} else {
+		operator, _ := inputs["operator"].(string)
+		if operator == "" {
+			return nil, flow.Errorf(flow.EINVALID, "Either a condition or an operator is required.")
+		}
+		cond.Path, cond.Operator, cond.Value = "value", operator, inputs["compare"]
+		doc = inputs
+	}
+
+	if err := cond.Validate(); err != nil {
+		return nil, err
+	}
+	result, err := cond.Evaluate(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := flow.BranchFalse
+	if result {
+		branch = flow.BranchTrue
+	}
+	return map[string]interface{}{"result": result, "branch": branch}, nil
+}
+
+// switchRunner implements the core switch action. Its branch is the value of
+// its "value" input.
+type switchRunner struct{}
+
+func (switchRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	v, ok := inputs["value"]
+	if !ok {
+		return nil, flow.Errorf(flow.EINVALID, "A value is required.")
+	}
+	return map[string]interface{}{"value": v, "branch": stringify(v)}, nil
+}
+
+// decodeCondition decodes a condition given as a JSON string or as a decoded
+// JSON object.
+func decodeCondition(v interface{}, cond *flow.Condition) error {
+	buf, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf = string(b)
+	}
+	if err := json.Unmarshal([]byte(buf), cond); err != nil {
+		return flow.Errorf(flow.EINVALID, "Condition is not valid JSON.")
+	}
+	return nil
+}
+
+// isBranching returns true if a node selects which of its edges are followed.
+func isBranching(node *flow.Node) bool {
+	return node.Integration == flow.IntegrationCore &&
+		(node.Action == flow.ActionIf || node.Action == flow.ActionSwitch)
+}
+
+// followed returns the IDs of the children whose edges are followed once a node
+// has run successfully. Branching nodes follow their unlabeled edges and those
+// labeled with their branch, or those labeled with flow.BranchDefault if no
+// edge is labeled with the branch. Loops follow the edges that do not lead
+// into their body. Other nodes follow all their edges. Error edges are never
+// followed.
+func followed(gn *workflow.Node, output map[string]interface{}) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(gn.Children))
+	branch, _ := output["branch"].(string)
+	switch node := gn.Value.(*flow.Node); {
+	case isLoop(node):
+		for id, child := range gn.Children {
+			if label := gn.Label(child); label != flow.BranchEach && label != flow.BranchError {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	case !isBranching(node):
+		for id, child := range gn.Children {
+			if gn.Label(child) != flow.BranchError {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	matched := false
+	for _, label := range gn.Labels {
+		if label == branch {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		branch = flow.BranchDefault
+	}
+
+	for id, child := range gn.Children {
+		if label := gn.Label(child); label != flow.BranchError && (label == "" || label == branch) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// errorEdges returns the IDs of the children whose edges are followed when a
+// node fails.
+func errorEdges(gn *workflow.Node) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, child := range gn.Children {
+		if gn.Label(child) == flow.BranchError {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// stringify converts a JSON value to a string. Numbers are formatted without
+// exponents.
+func stringify(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// checkpoints returns the recorded results of a run's nodes, keyed by node and
+// loop item. Every node result is persisted as soon as the node completes, so
+// the results of a resumed run are the checkpoints it continues from. Nodes
+// recorded as cancelled are executed again.
+func checkpoints(run *flow.Run) map[string]*flow.NodeRun {
+	m := make(map[string]*flow.NodeRun, len(run.Nodes))
+	for _, nodeRun := range run.Nodes {
+		switch nodeRun.Status {
+		case flow.NodeRunStatusSucceeded, flow.NodeRunStatusFailed, flow.NodeRunStatusSkipped:
+			m[checkpointKey(nodeRun.NodeID, nodeRun.Item)] = nodeRun
+		}
+	}
+	return m
+}
+
+// checkpointKey returns the key of a node's result within a run. Nodes in the
+// body of a loop have a result for each item.
+func checkpointKey(nodeID uuid.UUID, item *int) string {
+	if item == nil {
+		return nodeID.String()
+	}
+	return nodeID.String() + "/" + strconv.Itoa(*item)
+}
+
+// restore returns the outcome of a node as recorded before the run was
+// resumed.
+func restore(nodeRun *flow.NodeRun) (map[string]interface{}, error) {
+	if nodeRun.Status == flow.NodeRunStatusFailed {
+		message := "node failed"
+		if nodeRun.Error != nil {
+			message = *nodeRun.Error
+		}
+		// Error edges see the status code that failed the last attempt.
+		if n := len(nodeRun.Attempts); n > 0 && nodeRun.Attempts[n-1].StatusCode != nil {
+			return nil, &flow.StatusError{StatusCode: *nodeRun.Attempts[n-1].StatusCode, Message: message}
+		}
+		return nil, errors.New(message)
+	}
+
+	var output map[string]interface{}
+	if len(nodeRun.Output) > 0 {
+		if err := json.Unmarshal(nodeRun.Output, &output); err != nil {
+			return nil, fmt.Errorf("cannot decode output of node %s: %w", nodeRun.NodeID, err)
+		}
+	}
+	return output, nil
+}
+
+// idempotencyKey returns the key passed to the action of a node. The key is
+// the same every time the node is executed for a run and loop item, so APIs
+// can recognise calls repeated by retries or by resuming the run.
+func idempotencyKey(runID, nodeID uuid.UUID, item *int) string {
+	return uuid.NewSHA1(runID, []byte(checkpointKey(nodeID, item))).String()
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openmesh/flow"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+)
+
+// codeRunner implements the core code action. Its "code" input is a Starlark
+// script and its "outputs" input names the globals the script sets that
+// become the node's output. Every other input is passed to the script as a
+// global of the same name. Scripts have no access to the filesystem, the
+// network or other modules, and lines they print are logged to the attempt.
+type codeRunner struct{}
+
+func (codeRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	code, _ := inputs[flow.CodeParamCode].(string)
+	outputs, err := flow.CodeOutputs(stringify(inputs[flow.CodeParamOutputs]))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range inputs {
+		if flow.IsCodeInput(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	prog, err := flow.CompileCode(code, names)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := logsFromContext(ctx)
+	thread := &starlark.Thread{
+		Name:  "code",
+		Print: func(_ *starlark.Thread, msg string) { logs.add(msg) },
+	}
+	thread.SetMaxExecutionSteps(flow.MaxCodeSteps)
+
+	// Inputs are copied as plain JSON values so that the script cannot see
+	// or change the values held by the run. They are bounded in size so they
+	// do not count towards the script's memory.
+	predeclared := flow.CodeBuiltins()
+	var size int
+	for _, name := range names {
+		buf, err := json.Marshal(inputs[name])
+		if err != nil {
+			return nil, err
+		} else if size += len(buf); size > flow.MaxCodeDataSize {
+			return nil, flow.Errorf(flow.ETOOLARGE, "Code inputs exceed %d bytes.", flow.MaxCodeDataSize)
+		}
+		v, err := starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(buf)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		predeclared[name] = v
+	}
+
+	flow.SetCodeMemoryLimit(thread, flow.MaxCodeMemory)
+
+	type result struct {
+		globals starlark.StringDict
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("code failed: %v", p)}
+			}
+		}()
+		globals, err := prog.Init(thread, predeclared)
+		done <- result{globals: globals, err: err}
+	}()
+
+	timer := time.NewTimer(flow.CodeTimeout)
+	defer timer.Stop()
+
+	// Scripts that time out or are stopped are cancelled, which ends them at
+	// their next step.
+	var res result
+	select {
+	case res = <-done:
+	case <-timer.C:
+		thread.Cancel("time limit exceeded")
+		return nil, fmt.Errorf("code did not finish within %s", flow.CodeTimeout)
+	case <-ctx.Done():
+		thread.Cancel(ctx.Err().Error())
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		var evalErr *starlark.EvalError
+		if flow.ErrorCode(res.err) == flow.ETOOLARGE {
+			return nil, flow.Errorf(flow.ETOOLARGE, "%s", flow.ErrorMessage(res.err))
+		} else if errors.As(res.err, &evalErr) {
+			return nil, flow.Errorf(flow.EINVALID, "Code failed: %s", evalErr.Backtrace())
+		}
+		return nil, flow.Errorf(flow.EINVALID, "Code failed: %s", res.err)
+	}
+
+	output := make(map[string]interface{}, len(outputs))
+	size = 0
+	for _, name := range outputs {
+		v, ok := res.globals[name]
+		if !ok {
+			return nil, flow.Errorf(flow.EINVALID, "Code did not set output '%s'.", name)
+		}
+		s, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Output '%s' cannot be encoded as JSON: %s", name, err)
+		}
+		buf := []byte(string(s.(starlark.String)))
+		if size += len(buf); size > flow.MaxCodeDataSize {
+			return nil, flow.Errorf(flow.ETOOLARGE, "Code outputs exceed %d bytes.", flow.MaxCodeDataSize)
+		}
+		var value interface{}
+		if err := json.Unmarshal(buf, &value); err != nil {
+			return nil, err
+		}
+		output[name] = value
+	}
+	return output, nil
+}
+
+// logBuffer collects the lines logged by a node's action during an attempt.
+// Lines beyond the log limits are dropped.
+type logBuffer struct {
+	mu        sync.Mutex
+	lines     []string
+	size      int
+	truncated bool
+}
+
+// add appends a line to the buffer unless the buffer is full.
+func (b *logBuffer) add(line string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated {
+		return
+	} else if len(b.lines) >= flow.MaxCodeLogLines || b.size+len(line) > flow.MaxCodeLogSize {
+		b.lines, b.truncated = append(b.lines, "... further logs were dropped"), true
+		return
+	}
+	b.lines, b.size = append(b.lines, line), b.size+len(line)
+}
+
+// Lines returns a copy of the logged lines.
+func (b *logBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) == 0 {
+		return nil
+	}
+	return append([]string(nil), b.lines...)
+}
+
+type logsContextKey struct{}
+
+// newContextWithLogs returns a context that actions log lines to.
+func newContextWithLogs(ctx context.Context, logs *logBuffer) context.Context {
+	return context.WithValue(ctx, logsContextKey{}, logs)
+}
+
+// logsFromContext returns the log buffer of a context. Returns nil, which
+// discards lines, if the context has none.
+func logsFromContext(ctx context.Context) *logBuffer {
+	logs, _ := ctx.Value(logsContextKey{}).(*logBuffer)
+	return logs
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openmesh/flow"
+)
+
+func TestCodeRunner_Memory(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		code string
+	}{
+		{"Repeat", `x = "a" * (1 << 30)`},
+		{"Concat", "x = 'a'\nfor i in range(40):\n    x = x + x"},
+		{"AugmentedConcat", "x = [0]\nfor i in range(40):\n    x += x"},
+		{"Extend", "x = [0]\nfor i in range(26):\n    x.extend(x)"},
+		{"Replace", "x = 'a' * 1000\nfor i in range(20):\n    x = x.replace('a', 'aa')"},
+		{"Join", "x = 'ab'\nfor i in range(40):\n    x = x.join([x, x])"},
+		{"Format", "x = 'a'\nfor i in range(40):\n    x = '%s%s' % (x, x)"},
+		{"List", "x = list(range(1 << 30))"},
+		{"Int", "x = 2\nfor i in range(40):\n    x = x * x"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := codeRunner{}.Run(context.Background(), map[string]interface{}{
+				flow.CodeParamCode:    tt.code,
+				flow.CodeParamOutputs: "x",
+			})
+			if code := flow.ErrorCode(err); code != flow.ETOOLARGE {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, flow.ETOOLARGE, err)
+			}
+		})
+	}
+}
+
+func TestCodeRunner_Run(t *testing.T) {
+	output, err := codeRunner{}.Run(context.Background(), map[string]interface{}{
+		flow.CodeParamCode: `
+words = text.split()
+counts = {}
+for w in words:
+    counts[w] = counts.get(w, 0) + 1
+items = []
+items.extend(sorted(counts.keys()))
+summary = "%d words: %s" % (len(words), ", ".join(items))
+`,
+		flow.CodeParamOutputs: "summary",
+		"text":                "b a b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := output["summary"], "3 words: a, b"; got != want {
+		t.Fatalf("summary = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// DefaultRequestTimeout is the time HTTP nodes wait for a response unless the
+// node sets a shorter timeout.
+const DefaultRequestTimeout = 30 * time.Second
+
+// blockedNetworks are the address ranges HTTP nodes cannot connect to unless
+// allowed: private, loopback, link-local & otherwise reserved ranges, which
+// would let users reach services internal to the instance's network.
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// NewRequestClient returns the HTTP client used by HTTP nodes, which call URLs
+// chosen by users. The client refuses to connect to blocked addresses unless
+// allowed by an entry of allow, which is either a CIDR, an IP address, a host
+// name or a wildcard such as "*.internal.example.com". Addresses are checked
+// once resolved, so host names cannot be rebound to blocked addresses, and the
+// client does not use a proxy that would connect on its behalf.
+func NewRequestClient(allow []string) (*http.Client, error) {
+	d := &guardedDialer{dialer: &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			d.networks = append(d.networks, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			d.networks = append(d.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else if entry != "" && !strings.ContainsAny(entry, "/:") {
+			d.hosts = append(d.hosts, entry)
+		} else {
+			return nil, fmt.Errorf("invalid allowed address: %q", entry)
+		}
+	}
+
+	return &http.Client{
+		Timeout: DefaultRequestTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           d.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			// Credentials are not sent to other origins. The client
+			// only drops the standard authorization headers itself.
+			if !sameOrigin(req.URL, via[0].URL) {
+				for _, name := range credentialHeadersFromContext(req.Context()) {
+					req.Header.Del(name)
+				}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// guardedDialer dials the resolved addresses of a host that are not blocked.
+type guardedDialer struct {
+	dialer   *net.Dialer
+	networks []*net.IPNet // allowed despite being blocked
+	hosts    []string     // allowed to resolve to blocked addresses
+}
+
+func (d *guardedDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	allowHost := d.allowsHost(host)
+	err = flow.Errorf(flow.EINVALID, "Host '%s' resolves to a blocked address.", host)
+	for _, ip := range ips {
+		if !allowHost && d.blocks(ip.IP) {
+			continue
+		}
+		// The resolved address is dialed so that it cannot change between
+		// the check and the connection.
+		conn, dialErr := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		err = dialErr
+	}
+	return nil, err
+}
+
+// allowsHost returns true if a host name is allowed to resolve to blocked
+// addresses.
+func (d *guardedDialer) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range d.hosts {
+		if allowed == host {
+			return true
+		} else if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocks returns true if an address is blocked and not explicitly allowed.
+func (d *guardedDialer) blocks(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range d.networks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
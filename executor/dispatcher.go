@@ -0,0 +1,259 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/openmesh/flow"
+)
+
+// DefaultSyncInterval is the interval at which the dispatcher subscribes to
+// the topics of triggers added to the catalog since it opened, such as when
+// integration definitions are reloaded.
+const DefaultSyncInterval = 5 * time.Second
+
+// Dispatcher listens for trigger events on the event bus and starts a run of
+// every workflow with a source node listening on the event's topic. Events
+// that do not match the filter of a source node do not start a run. Events
+// also resolve the waits of paused runs awaiting a matching event.
+type Dispatcher struct {
+	EventBus              flow.EventBus
+	IntegrationService    flow.IntegrationService
+	WorkflowService       flow.WorkflowService
+	FilterDecisionService flow.FilterDecisionService
+	WaitService           flow.WaitService
+	Executor              flow.Executor
+
+	Logger log.Logger
+
+	// Interval at which new trigger topics are subscribed.
+	SyncInterval time.Duration
+
+	mu     sync.Mutex
+	topics map[string]bool // subscribed topics
+
+	events flow.Channel
+	done   chan struct{}
+}
+
+// NewDispatcher returns a new instance of Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Logger:       log.NewNopLogger(),
+		SyncInterval: DefaultSyncInterval,
+		topics:       make(map[string]bool),
+		events:       make(flow.Channel),
+		done:         make(chan struct{}),
+	}
+}
+
+// Open subscribes to the topic of every trigger in the integration catalog and
+// begins dispatching events. Triggers added to the catalog later are
+// subscribed by Sync.
+func (d *Dispatcher) Open() error {
+	if err := d.Sync(); err != nil {
+		return err
+	}
+
+	go d.loop()
+	return nil
+}
+
+// Close stops dispatching events.
+func (d *Dispatcher) Close() error {
+	close(d.done)
+	return nil
+}
+
+// Sync subscribes to the topics of the triggers in the integration catalog
+// that are not subscribed yet. Topics of triggers removed from the catalog
+// stay subscribed as events on them do not match any node.
+func (d *Dispatcher) Sync() error {
+	ctx := flow.NewSystemContext(context.Background())
+
+	integrations, _, err := d.IntegrationService.GetIntegrations(ctx, flow.GetIntegrationsRequest{})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, integration := range integrations {
+		for _, trigger := range integration.Triggers {
+			topic := flow.TriggerTopic(integration.Key, trigger.Key)
+			if d.topics[topic] {
+				continue
+			}
+			if err := d.EventBus.Subscribe(topic, d.events); err != nil {
+				return err
+			}
+			d.topics[topic] = true
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) loop() {
+	ticker := time.NewTicker(d.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			if err := d.Sync(); err != nil {
+				_ = d.Logger.Log("msg", "cannot subscribe to triggers", "err", err)
+			}
+		case ev := <-d.events:
+			if err := d.dispatch(ev); err != nil {
+				_ = d.Logger.Log("msg", "cannot dispatch event", "topic", ev.Topic, "err", err)
+			}
+		}
+	}
+}
+
+// dispatch starts a run for each source node listening on the event's topic.
+// Events addressed to a single node only start a run of that node.
+func (d *Dispatcher) dispatch(ev flow.Event) error {
+	ctx := flow.NewSystemContext(context.Background())
+
+	if err := d.resume(ctx, ev); err != nil {
+		_ = d.Logger.Log("msg", "cannot resume waiting runs", "topic", ev.Topic, "err", err)
+	}
+
+	if e, ok := ev.Payload.(*flow.NodeEvent); ok {
+		wf, err := d.WorkflowService.GetWorkflowByID(ctx, e.WorkflowID)
+		if err != nil {
+			return err
+		}
+		for _, node := range wf.Nodes {
+			if node.ID == e.NodeID {
+				return d.start(ctx, ev.Topic, wf, node, e.Payload)
+			}
+		}
+		return flow.Errorf(flow.ENOTFOUND, "Node not found.")
+	}
+	integration, trigger := flow.ParseTriggerTopic(ev.Topic)
+
+	workflows, _, err := d.WorkflowService.GetWorkflows(ctx, flow.WorkflowFilter{Trigger: &ev.Topic})
+	if err != nil {
+		return err
+	}
+
+	for _, wf := range workflows {
+		for _, node := range wf.Nodes {
+			if node.Integration != integration || node.Action != trigger || len(node.ParentIDs) > 0 {
+				continue
+			}
+			if err := d.start(ctx, ev.Topic, wf, node, ev.Payload); err != nil {
+				_ = d.Logger.Log("msg", "cannot start run", "workflow", wf.ID, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+// resume resolves the pending waits on the event's topic whose condition the
+// payload matches. Events addressed to a single node only resolve waits of
+// that node's workflow.
+func (d *Dispatcher) resume(ctx context.Context, ev flow.Event) error {
+	if d.WaitService == nil {
+		return nil
+	}
+
+	waits, _, err := d.WaitService.GetWaits(ctx, flow.WaitFilter{Topic: &ev.Topic, Pending: true})
+	if err != nil || len(waits) == 0 {
+		return err
+	}
+
+	payload := ev.Payload
+	e, isNodeEvent := payload.(*flow.NodeEvent)
+	if isNodeEvent {
+		payload = e.Payload
+	}
+	buf, doc, err := normalize(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, wait := range waits {
+		if isNodeEvent && wait.WorkflowID != e.WorkflowID {
+			continue
+		}
+		if wait.Condition != nil {
+			if matched, err := wait.Condition.Evaluate(doc); err != nil || !matched {
+				continue
+			}
+		}
+		if _, err := d.WaitService.ResolveWait(ctx, wait.ID, flow.WaitUpdate{Event: buf}); err != nil && flow.ErrorCode(err) != flow.ECONFLICT {
+			_ = d.Logger.Log("msg", "cannot resolve wait", "run", wait.RunID, "err", err)
+		}
+	}
+	return nil
+}
+
+// start starts a run of a workflow from a source node if the payload matches
+// the node's filter.
+func (d *Dispatcher) start(ctx context.Context, topic string, wf *flow.Workflow, node *flow.Node, payload interface{}) error {
+	if node.Filter != nil {
+		matched, err := d.filter(ctx, topic, wf, node, payload)
+		if err != nil {
+			return err
+		} else if !matched {
+			return nil
+		}
+	}
+
+	_, err := d.Executor.StartRun(ctx, flow.RunRequest{
+		WorkflowID:    wf.ID,
+		TriggerNodeID: node.ID,
+		Payload:       payload,
+	})
+	return err
+}
+
+// filter evaluates a node's filter against the payload of an event and records
+// the decision. Filters see the payload as the workflow would, so that paths
+// match the JSON field names of the payload.
+func (d *Dispatcher) filter(ctx context.Context, topic string, wf *flow.Workflow, node *flow.Node, payload interface{}) (bool, error) {
+	buf, doc, err := normalize(payload)
+	if err != nil {
+		return false, err
+	}
+
+	decision := &flow.FilterDecision{
+		WorkflowID: wf.ID,
+		NodeID:     node.ID,
+		Topic:      topic,
+		Payload:    buf,
+	}
+	if decision.Matched, err = node.Filter.Evaluate(doc); err != nil {
+		msg := err.Error()
+		decision.Matched, decision.Error = false, &msg
+	}
+
+	if d.FilterDecisionService != nil {
+		if err := d.FilterDecisionService.CreateFilterDecision(ctx, decision); err != nil {
+			_ = d.Logger.Log("msg", "cannot record filter decision", "workflow", wf.ID, "node", node.ID, "err", err)
+		}
+	}
+	return decision.Matched, nil
+}
+
+// normalize encodes a payload as JSON and decodes it back into plain JSON
+// values, so that paths match the JSON field names of the payload.
+func normalize(payload interface{}) (json.RawMessage, interface{}, error) {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, nil, err
+	}
+	return buf, doc, nil
+}
@@ -0,0 +1,805 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/pkg/workflow"
+)
+
+// Executor executes workflow runs by walking the workflow's graph from the
+// trigger node and running each node's action.
+type Executor struct {
+	WorkflowService    flow.WorkflowService
+	RunService         flow.RunService
+	IntegrationService flow.IntegrationService
+	AuthService        flow.AuthService
+
+	// Records the heartbeats of the instance as a worker, if set.
+	WorkerService flow.WorkerService
+
+	// Persists the waits of runs paused by delay & wait-for-event nodes.
+	WaitService flow.WaitService
+
+	// Runs the actions of integrations implemented in Go, if set.
+	Registry flow.NativeRegistry
+
+	// HTTP client used by actions that call external APIs.
+	Client *http.Client
+
+	// HTTP client used by nodes of the HTTP integration, which call URLs
+	// chosen by users. Defaults to a client that cannot reach private
+	// addresses, as returned by NewRequestClient().
+	RequestClient *http.Client
+
+	Logger log.Logger
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+
+	// Returns a random number in [0, 1) used to jitter retries. Defaults to
+	// rand.Float64(). Can be mocked for tests.
+	Rand func() float64
+
+	// Identifies the instance as the owner of the runs it claims from the
+	// queue of unfinished runs. Defaults to the host name and a random suffix.
+	ID string
+
+	// Time a claimed run is reserved for. Claims are renewed while the run
+	// executes, so runs of an instance that stops without finishing them are
+	// resumed by another instance once the lease expires.
+	Lease time.Duration
+
+	// Interval at which the queue is polled for unfinished runs.
+	PollInterval time.Duration
+
+	// Maximum number of runs claimed from the queue that execute at once.
+	// Zero means no limit.
+	Concurrency int
+
+	// If set, runs started by StartRun are left in the queue for workers to
+	// execute and the queue is not polled. Runs started by ExecuteRun still
+	// execute on this instance as their caller waits for them.
+	QueueOnly bool
+
+	// Runs executing on this instance, keyed by run ID.
+	mu       sync.Mutex
+	active   map[uuid.UUID]*activeRun
+	draining bool
+	wg       sync.WaitGroup
+
+	// Time the instance was opened & of its last successful heartbeat.
+	startedAt   time.Time
+	heartbeatAt time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+	stopped   chan struct{} // closed once the loop has returned
+}
+
+// activeRun is a run executing on this instance.
+type activeRun struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Closed once the outcome of the run has been recorded.
+	done chan struct{}
+
+	// Status the run ends with once it has been stopped.
+	mu     sync.Mutex
+	reason string
+}
+
+// stop stops the run. The run ends with the status of the first reason it was
+// stopped for.
+func (a *activeRun) stop(reason string) {
+	a.mu.Lock()
+	if a.reason == "" {
+		a.reason = reason
+	}
+	a.mu.Unlock()
+	a.cancel()
+}
+
+// stopped returns the reason the run was stopped for. Returns an empty string
+// if the run has not been stopped.
+func (a *activeRun) stopped() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.reason
+}
+
+// New returns a new instance of Executor.
+func New() *Executor {
+	requestClient, _ := NewRequestClient(nil)
+	return &Executor{
+		Client:        http.DefaultClient,
+		RequestClient: requestClient,
+		Logger:        log.NewNopLogger(),
+		Now:           time.Now,
+		Rand:          rand.Float64,
+		ID:            instanceID(),
+
+		Lease:        DefaultLease,
+		PollInterval: DefaultPollInterval,
+
+		active:  make(map[uuid.UUID]*activeRun),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// StartRun creates a pending run and executes it in the background.
+func (e *Executor) StartRun(ctx context.Context, req flow.RunRequest) (*flow.Run, error) {
+	if e.QueueOnly {
+		return e.enqueue(ctx, req)
+	}
+
+	active, wf, run, err := e.begin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := e.execute(wf, run, active); err != nil {
+			_ = e.Logger.Log("msg", "run failed", "run", run.ID, "err", err)
+		}
+	}()
+
+	return run, nil
+}
+
+// ExecuteRun creates a run and executes it before returning. The run is not
+// stopped if the caller's context is cancelled.
+func (e *Executor) ExecuteRun(ctx context.Context, req flow.RunRequest) (*flow.Run, error) {
+	active, wf, run, err := e.begin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.execute(wf, run, active); err != nil {
+		_ = e.Logger.Log("msg", "run failed", "run", run.ID, "err", err)
+	}
+	return run, nil
+}
+
+// CancelRun stops a run that has not finished. Runs executing on this instance
+// are stopped before their current node finishes and the call blocks until
+// the outcome has been recorded. Other runs are marked as cancelled, which
+// removes them from the queue. Instances executing them stop once they fail
+// to renew their claim.
+func (e *Executor) CancelRun(ctx context.Context, id uuid.UUID) (*flow.Run, error) {
+	// Verify that the run exists and belongs to the user.
+	run, err := e.RunService.GetRunByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch run.Status {
+	case flow.RunStatusPending, flow.RunStatusRunning, flow.RunStatusInterrupted, flow.RunStatusWaiting:
+	default:
+		return nil, flow.Errorf(flow.ECONFLICT, "Run has already finished.")
+	}
+
+	e.mu.Lock()
+	active := e.active[id]
+	e.mu.Unlock()
+
+	if active != nil {
+		active.stop(flow.RunStatusCancelled)
+		select {
+		case <-active.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return e.RunService.GetRunByID(ctx, id)
+	}
+
+	// Runs called by the run are cancelled with it.
+	if err := e.cancelCalls(flow.NewSystemContext(ctx), id); err != nil {
+		return nil, err
+	}
+
+	finishedAt := e.Now()
+	cancelled, err := e.RunService.UpdateRun(flow.NewSystemContext(ctx), id, flow.RunUpdate{
+		Status:     stringPtr(flow.RunStatusCancelled),
+		Error:      stringPtr(errCancelled),
+		FinishedAt: &finishedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := e.resumeCaller(flow.NewSystemContext(ctx), cancelled); err != nil {
+		return nil, err
+	}
+	return e.RunService.GetRunByID(ctx, id)
+}
+
+// Drain stops accepting new runs and waits for the runs executing on this
+// instance to finish. Runs that have not finished once ctx is done are
+// interrupted and recorded as such so that they can be resumed.
+func (e *Executor) Drain(ctx context.Context) error {
+	e.mu.Lock()
+	e.draining = true
+	opened := !e.startedAt.IsZero()
+	e.mu.Unlock()
+
+	// Heartbeats keep renewing the claims of draining runs. The loop stops
+	// once they have finished and the worker is then removed.
+	defer e.closeOnce.Do(func() {
+		close(e.done)
+		if opened {
+			<-e.stopped
+			e.deregister()
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.mu.Lock()
+	for _, active := range e.active {
+		active.stop(flow.RunStatusInterrupted)
+	}
+	e.mu.Unlock()
+
+	// Runners stop with their context, so interrupted runs only take as long
+	// as recording their outcome.
+	select {
+	case <-done:
+		return nil
+	case <-time.After(InterruptTimeout):
+		return fmt.Errorf("runs did not stop within %s of being interrupted", InterruptTimeout)
+	}
+}
+
+// errCancelled is the error of cancelled runs.
+const errCancelled = "run was cancelled"
+
+// InterruptTimeout is the time given to interrupted runs to record their
+// outcome when the executor is drained.
+const InterruptTimeout = 10 * time.Second
+
+// begin creates a run for a request and registers it as executing on this
+// instance. Returns EUNAVAILABLE once the executor is draining.
+func (e *Executor) begin(ctx context.Context, req flow.RunRequest) (*activeRun, *flow.Workflow, *flow.Run, error) {
+	ctx = flow.NewSystemContext(ctx)
+
+	if e.isDraining() {
+		return nil, nil, nil, flow.Errorf(flow.EUNAVAILABLE, "Runs cannot be started during shutdown.")
+	}
+
+	wf, run, err := e.createRun(ctx, req, true)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return e.track(wf, run), wf, run, nil
+}
+
+// enqueue creates a run for a request without claiming it, so that the next
+// worker to poll the queue executes it.
+func (e *Executor) enqueue(ctx context.Context, req flow.RunRequest) (*flow.Run, error) {
+	if e.isDraining() {
+		return nil, flow.Errorf(flow.EUNAVAILABLE, "Runs cannot be started during shutdown.")
+	}
+
+	_, run, err := e.createRun(flow.NewSystemContext(ctx), req, false)
+	return run, err
+}
+
+// track registers a run as executing on this instance.
+func (e *Executor) track(wf *flow.Workflow, run *flow.Run) *activeRun {
+	// The run outlives the request that started it.
+	runCtx, cancel := context.WithCancel(flow.NewSystemContext(context.Background()))
+	if timeout := wf.Timeout(); timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, timeout)
+	}
+	active := &activeRun{ctx: runCtx, cancel: cancel, done: make(chan struct{})}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// A run created while the executor started draining is interrupted
+	// straight away so that it is resumed rather than lost.
+	if e.draining {
+		active.reason = flow.RunStatusInterrupted
+		cancel()
+	}
+	e.active[run.ID] = active
+	e.wg.Add(1)
+	return active
+}
+
+// end unregisters a run once its outcome has been recorded.
+func (e *Executor) end(run *flow.Run, active *activeRun) {
+	active.cancel()
+	close(active.done)
+
+	e.mu.Lock()
+	delete(e.active, run.ID)
+	e.mu.Unlock()
+	e.wg.Done()
+}
+
+func (e *Executor) isDraining() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.draining
+}
+
+// createRun loads the workflow for a request and persists a new run for it.
+// If claim is set, the run is claimed by this instance as it is created so that
+// other instances do not execute it as well.
+func (e *Executor) createRun(ctx context.Context, req flow.RunRequest, claim bool) (*flow.Workflow, *flow.Run, error) {
+	wf, err := e.WorkflowService.GetWorkflowByID(ctx, req.WorkflowID)
+	if err != nil {
+		return nil, nil, err
+	} else if wf.GetNode(req.TriggerNodeID) == nil {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Node %s is not part of the workflow.", req.TriggerNodeID)
+	}
+
+	input, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Payload cannot be encoded as JSON.")
+	}
+
+	run := &flow.Run{
+		WorkflowID:    wf.ID,
+		TriggerNodeID: req.TriggerNodeID,
+		Input:         input,
+		Status:        flow.RunStatusPending,
+		ParentRunID:   req.ParentRunID,
+		ParentNodeID:  req.ParentNodeID,
+		ParentItem:    req.ParentItem,
+	}
+	if claim {
+		claimedUntil := e.Now().Add(e.Lease)
+		run.ClaimedBy, run.ClaimedUntil = e.ID, &claimedUntil
+	}
+	if err := e.RunService.CreateRun(ctx, run); err != nil {
+		return nil, nil, err
+	}
+
+	// Store files uploaded to webhooks so that they outlive the request.
+	// Each run gets its own copy, as a delivery can trigger several runs.
+	if p, ok := req.Payload.(*flow.WebhookPayload); ok {
+		for _, file := range p.Files {
+			attachment := *file
+			attachment.ID, attachment.RunID = uuid.Nil, run.ID
+			if err := e.RunService.CreateAttachment(ctx, &attachment); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return wf, run, nil
+}
+
+// execute runs every node reachable from the run's trigger node in
+// topological order. Unless the workflow continues on error, execution stops
+// at the first failure that is not handled by an error edge.
+//
+// Nodes run with the context of the active run, which is done once the run is
+// stopped or times out. Results are recorded with a separate context so that
+// the outcome of stopped runs is still persisted.
+func (e *Executor) execute(wf *flow.Workflow, run *flow.Run, active *activeRun) error {
+	defer e.end(run, active)
+	ctx := flow.NewSystemContext(context.Background())
+
+	// Resumed runs keep the time they were first started.
+	startedAt := e.Now()
+	if run.StartedAt != nil {
+		startedAt = *run.StartedAt
+	}
+	if _, err := e.RunService.UpdateRun(ctx, run.ID, flow.RunUpdate{
+		Status:    stringPtr(flow.RunStatusRunning),
+		StartedAt: &startedAt,
+	}); err != nil {
+		return err
+	}
+	run.Status, run.StartedAt = flow.RunStatusRunning, &startedAt
+
+	runErr := e.walk(ctx, active, wf, run)
+
+	// Runs whose claim was lost are recorded by the instance or request
+	// that took them over.
+	if active.stopped() == reasonReleased {
+		return nil
+	}
+
+	// Record the outcome of the run. Interrupted & waiting runs have not
+	// finished.
+	finishedAt := e.Now()
+	upd := flow.RunUpdate{
+		Status:     stringPtr(flow.RunStatusSucceeded),
+		FinishedAt: &finishedAt,
+	}
+	switch reason := active.stopped(); {
+	case runErr == nil:
+	case reason == flow.RunStatusInterrupted:
+		upd.Status, upd.Error, upd.FinishedAt = stringPtr(reason), stringPtr("run was interrupted by a shutdown"), nil
+	case reason == flow.RunStatusWaiting:
+		upd.Status, upd.FinishedAt = stringPtr(reason), nil
+	case reason == flow.RunStatusCancelled:
+		upd.Status, upd.Error = stringPtr(reason), stringPtr(errCancelled)
+	case active.ctx.Err() == context.DeadlineExceeded:
+		runErr = fmt.Errorf("run timed out after %s: %w", wf.Timeout(), runErr)
+		upd.Status, upd.Error = stringPtr(flow.RunStatusFailed), stringPtr(runErr.Error())
+	default:
+		upd.Status, upd.Error = stringPtr(flow.RunStatusFailed), stringPtr(runErr.Error())
+	}
+	if _, err := e.RunService.UpdateRun(ctx, run.ID, upd); err != nil {
+		return err
+	}
+	run.Status, run.Error, run.FinishedAt = *upd.Status, upd.Error, upd.FinishedAt
+
+	// Runs called by a sub-workflow node resume their caller if it paused
+	// while waiting for them.
+	if run.Finished() {
+		if err := e.resumeCaller(ctx, run); err != nil {
+			_ = e.Logger.Log("msg", "cannot resume calling run", "run", run.ID, "err", err)
+		}
+	}
+	if active.stopped() != "" {
+		return nil
+	}
+
+	return runErr
+}
+
+// walk executes the nodes of a workflow that descend from the run's trigger
+// node and records each node's result on the run.
+func (e *Executor) walk(ctx context.Context, active *activeRun, wf *flow.Workflow, run *flow.Run) error {
+	// The payload is read back from the run's input so that nodes see plain
+	// JSON values regardless of how the trigger represented it.
+	var payload interface{}
+	if err := json.Unmarshal(run.Input, &payload); err != nil {
+		return fmt.Errorf("cannot decode run input: %w", err)
+	}
+
+	g, err := buildGraph(wf)
+	if err != nil {
+		return err
+	}
+	order, err := g.TopologicalSort()
+	if err != nil {
+		return err
+	}
+
+	trigger, err := g.GetNode(run.TriggerNodeID)
+	if err != nil {
+		return err
+	}
+	reachable := g.Descendants(trigger)
+	reachable[trigger.ID] = trigger
+
+	waits, err := e.waits(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	w := &walker{
+		Executor:    e,
+		store:       ctx,
+		active:      active,
+		wf:          wf,
+		run:         run,
+		graph:       g,
+		order:       order,
+		trigger:     trigger,
+		payload:     payload,
+		checkpoints: checkpoints(run),
+		waits:       waits,
+	}
+
+	// Outputs of executed nodes are kept in scope, keyed by node ID, so that
+	// reference params of later nodes can resolve them.
+	scope := map[string]interface{}{"trigger": payload}
+
+	return w.walk(active.ctx, w.subset(reachable), map[uuid.UUID]bool{trigger.ID: true}, scope, nil)
+}
+
+// walker executes the nodes of a run's graph.
+type walker struct {
+	*Executor
+	store   context.Context // context results are recorded with
+	active  *activeRun
+	wf      *flow.Workflow
+	run     *flow.Run
+	graph   *workflow.Graph
+	order   []*workflow.Node
+	trigger *workflow.Node
+	payload interface{}
+
+	// Results of the nodes that completed before the run was resumed.
+	checkpoints map[string]*flow.NodeRun
+
+	// Waits of the nodes that paused the run, keyed like checkpoints.
+	waits map[string]*flow.Wait
+
+	// Guards run.Nodes, which is appended to by concurrent loop items.
+	mu sync.Mutex
+}
+
+// walk executes nodes in topological order, starting with the nodes in next.
+// Nodes only run if a parent that has run followed the edge to them. Nodes on
+// branches that were not taken are recorded as skipped. The bodies of loops
+// are executed by their loop node rather than by the walk. Item is the index
+// of the loop item the nodes are executed for, if any.
+//
+// Failures of nodes with error edges are handled by following those edges.
+// Other failures stop the walk, unless the workflow continues on error in
+// which case the walk fails once every other node has finished. The walk stops
+// as soon as ctx is done.
+func (w *walker) walk(ctx context.Context, nodes []*workflow.Node, next map[uuid.UUID]bool, scope map[string]interface{}, item *int) error {
+	var failures []string
+	for _, gn := range withoutLoopBodies(w.graph, nodes) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		node := gn.Value.(*flow.Node)
+		nodeRun := &flow.NodeRun{
+			RunID:     w.run.ID,
+			NodeID:    node.ID,
+			Item:      item,
+			StartedAt: w.Now(),
+		}
+
+		// Nodes that completed before the run was resumed are not executed
+		// again. Their recorded outcome is restored instead.
+		checkpoint := w.checkpoints[checkpointKey(node.ID, item)]
+
+		if !next[node.ID] {
+			if checkpoint != nil {
+				continue
+			}
+			nodeRun.Status, nodeRun.FinishedAt = flow.NodeRunStatusSkipped, nodeRun.StartedAt
+			if err := w.record(nodeRun); err != nil {
+				return err
+			}
+			continue
+		}
+		// Skipped nodes that are now reached belong to a workflow that has
+		// changed since the run started.
+		if checkpoint != nil && checkpoint.Status == flow.NodeRunStatusSkipped {
+			checkpoint = nil
+		}
+
+		var output map[string]interface{}
+		var err error
+		switch {
+		case checkpoint != nil:
+			output, err = restore(checkpoint)
+		case node.ID == w.trigger.ID:
+			output, err = w.triggerOutput(ctx, node, w.payload)
+		case isLoop(node):
+			output, err = w.loop(ctx, gn, scope)
+		case isWait(node):
+			output, err = w.wait(ctx, node, scope, item)
+		case isSubWorkflow(node):
+			output, err = w.subWorkflow(ctx, node, scope, item)
+		default:
+			key := idempotencyKey(w.run.ID, node.ID, item)
+			output, nodeRun.Attempts, err = w.attempt(flow.NewContextWithIdempotencyKey(ctx, key), node, scope)
+		}
+		nodeRun.FinishedAt = w.Now()
+
+		// Nodes interrupted by a shutdown or a pause are not recorded so that
+		// they run again once the run is resumed.
+		if ctx.Err() != nil && checkpoint == nil {
+			switch w.active.stopped() {
+			case flow.RunStatusInterrupted, flow.RunStatusWaiting, reasonReleased:
+				return ctx.Err()
+			case flow.RunStatusCancelled:
+				if err != nil {
+					nodeRun.Status, nodeRun.Error = flow.NodeRunStatusCancelled, stringPtr(err.Error())
+					if err := w.record(nodeRun); err != nil {
+						return err
+					}
+					return ctx.Err()
+				}
+			}
+		}
+
+		if err != nil {
+			nodeRun.Status, nodeRun.Error = flow.NodeRunStatusFailed, stringPtr(err.Error())
+			if handlers := errorEdges(gn); len(handlers) > 0 {
+				scope[node.ID.String()] = errorOutput(err)
+				for _, id := range handlers {
+					next[id] = true
+				}
+			}
+		} else {
+			nodeRun.Status = flow.NodeRunStatusSucceeded
+			scope[node.ID.String()] = output
+			for _, id := range followed(gn, output) {
+				next[id] = true
+			}
+			if nodeRun.Output, err = json.Marshal(output); err != nil {
+				return fmt.Errorf("cannot encode output of node %s: %w", node.ID, err)
+			}
+		}
+
+		if checkpoint == nil {
+			if err := w.record(nodeRun); err != nil {
+				return err
+			}
+		}
+
+		if nodeRun.Status == flow.NodeRunStatusFailed && len(errorEdges(gn)) == 0 {
+			failure := fmt.Sprintf("node %s failed: %s", node.ID, *nodeRun.Error)
+			if w.wf.ErrorMode != flow.ErrorModeContinue {
+				return errors.New(failure)
+			}
+			failures = append(failures, failure)
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// record persists the result of a node and adds it to the run. Results are
+// persisted even if the run has been stopped.
+func (w *walker) record(nodeRun *flow.NodeRun) error {
+	if err := w.RunService.CreateNodeRun(w.store, nodeRun); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.run.Nodes = append(w.run.Nodes, nodeRun)
+	w.mu.Unlock()
+	return nil
+}
+
+// subset returns the nodes of a set in topological order.
+func (w *walker) subset(set map[uuid.UUID]*workflow.Node) []*workflow.Node {
+	nodes := make([]*workflow.Node, 0, len(set))
+	for _, gn := range w.order {
+		if _, ok := set[gn.ID]; ok {
+			nodes = append(nodes, gn)
+		}
+	}
+	return nodes
+}
+
+// runNode resolves a node's params against the scope and runs its action.
+func (e *Executor) runNode(ctx context.Context, wf *flow.Workflow, node *flow.Node, scope map[string]interface{}) (map[string]interface{}, error) {
+	inputs, err := e.nodeInputs(ctx, node, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := e.runner(ctx, wf, node, scope)
+	if err != nil {
+		return nil, err
+	}
+	return runner.Run(ctx, inputs)
+}
+
+// triggerOutput returns the output of a trigger node for a payload. If the
+// trigger declares output fields, each field is extracted from the payload by
+// its path. Otherwise the payload is the output.
+func (e *Executor) triggerOutput(ctx context.Context, node *flow.Node, payload interface{}) (map[string]interface{}, error) {
+	var trigger *flow.Trigger
+	if integration, err := e.pinnedIntegration(ctx, node); err == nil {
+		trigger, _ = integration.GetTrigger(node.Action)
+	}
+
+	if trigger == nil || len(trigger.Outputs) == 0 {
+		if m, ok := payload.(map[string]interface{}); ok {
+			return m, nil
+		}
+		return map[string]interface{}{"payload": payload}, nil
+	}
+	return extractOutputs(trigger.Outputs, payload)
+}
+
+// buildGraph converts the nodes and edges of a workflow into a graph.
+func buildGraph(wf *flow.Workflow) (*workflow.Graph, error) {
+	g := workflow.NewGraph()
+	for _, n := range wf.Nodes {
+		if err := g.AddNode(workflow.NewNodeWithID(n.ID, n)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, n := range wf.Nodes {
+		tail, err := g.GetNode(n.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, childID := range n.ChildrenIDs {
+			head, err := g.GetNode(*childID)
+			if err != nil {
+				return nil, err
+			}
+			if err := g.AddLabeledEdge(tail, head, n.EdgeLabels[*childID]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g, nil
+}
+
+// nodeInputs resolves a node's params against the scope and validates them
+// against the input fields of its action, coercing them to the fields' types
+// and applying defaults. Inputs of actions that are not defined are not
+// validated, as their runner reports them.
+func (e *Executor) nodeInputs(ctx context.Context, node *flow.Node, scope map[string]interface{}) (map[string]interface{}, error) {
+	inputs, err := resolveParams(node.Params, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	integration, err := e.pinnedIntegration(ctx, node)
+	if flow.ErrorCode(err) == flow.ENOTFOUND {
+		return inputs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	action, err := integration.GetAction(node.Action)
+	if err != nil {
+		return inputs, nil
+	}
+	return flow.CoerceInputs(action.Inputs, inputs)
+}
+
+// resolveParams converts a node's params into the inputs of its action.
+// Reference params are resolved as paths into the scope.
+func resolveParams(params []*flow.Param, scope map[string]interface{}) (map[string]interface{}, error) {
+	inputs := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		switch p.Type {
+		case flow.ParamTypeReference:
+			v, ok, err := flow.Lookup(scope, p.Value)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, flow.Errorf(flow.EINVALID, "Reference '%s' of param '%s' could not be resolved.", p.Value, p.Key)
+			}
+			inputs[p.Key] = v
+		default:
+			inputs[p.Key] = p.Value
+		}
+	}
+	return inputs, nil
+}
+
+// extractOutputs builds an output map by looking up each output field's path
+// within a document. Fields whose path does not exist are omitted.
+func extractOutputs(fields []flow.OutputField, doc interface{}) (map[string]interface{}, error) {
+	output := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, ok, err := flow.Lookup(doc, f.Path)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			output[f.Key] = v
+		}
+	}
+	return output, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/pkg/workflow"
+)
+
+// isLoop returns true if a node executes a body of nodes for each item of an
+// array.
+func isLoop(node *flow.Node) bool {
+	return node.Integration == flow.IntegrationCore && node.Action == flow.ActionForEach
+}
+
+// loopBody returns the nodes executed for each item of a loop node. These are
+// the heads of the loop's edges labeled with flow.BranchEach along with every
+// node that descends from them.
+func loopBody(g *workflow.Graph, gn *workflow.Node) map[uuid.UUID]*workflow.Node {
+	body := make(map[uuid.UUID]*workflow.Node)
+	for id, child := range gn.Children {
+		if gn.Label(child) != flow.BranchEach {
+			continue
+		}
+		body[id] = child
+		for id, n := range g.Descendants(child) {
+			body[id] = n
+		}
+	}
+	return body
+}
+
+// withoutLoopBodies returns the nodes that are not part of the body of a loop
+// node within the given nodes.
+func withoutLoopBodies(g *workflow.Graph, nodes []*workflow.Node) []*workflow.Node {
+	bodies := make(map[uuid.UUID]bool)
+	for _, gn := range nodes {
+		if isLoop(gn.Value.(*flow.Node)) {
+			for id := range loopBody(g, gn) {
+				bodies[id] = true
+			}
+		}
+	}
+
+	result := make([]*workflow.Node, 0, len(nodes))
+	for _, gn := range nodes {
+		if !bodies[gn.ID] {
+			result = append(result, gn)
+		}
+	}
+	return result
+}
+
+// loopResult is the outcome of executing a loop's body for one item.
+type loopResult struct {
+	output interface{}
+	err    error
+}
+
+// loop implements the core for-each action. The loop's body is executed for
+// each item of its "items" input, at most "concurrency" items at a time. While
+// an item is executed, the loop's entry in scope holds the item and its index
+// so that nodes of the body can reference them.
+//
+// The output holds the result of each item in order. The result of an item is
+// the output of the body's last node, or an object of outputs keyed by node ID
+// if the body ends in several nodes. Items that fail have a null result and
+// are listed with their error, without stopping the other items.
+func (w *walker) loop(ctx context.Context, gn *workflow.Node, scope map[string]interface{}) (map[string]interface{}, error) {
+	node := gn.Value.(*flow.Node)
+	inputs, err := w.nodeInputs(ctx, node, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := inputs["items"].([]interface{})
+	if !ok {
+		return nil, flow.Errorf(flow.EINVALID, "Items of for-each node must be an array.")
+	}
+	concurrency := 1
+	if v, ok := inputs["concurrency"]; ok {
+		if concurrency, err = strconv.Atoi(stringify(v)); err != nil || concurrency < 1 {
+			return nil, flow.Errorf(flow.EINVALID, "Concurrency of for-each node must be a positive integer.")
+		}
+	}
+	if concurrency > flow.MaxForEachConcurrency {
+		concurrency = flow.MaxForEachConcurrency
+	}
+
+	body := w.subset(loopBody(w.graph, gn))
+	var sinks []*workflow.Node
+	for _, n := range body {
+		if n.OutDegree() == 0 {
+			sinks = append(sinks, n)
+		}
+	}
+
+	results := make([]loopResult, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = w.loopItem(ctx, gn, body, sinks, scope, items[i], i)
+		}(i)
+	}
+	wg.Wait()
+
+	outputs := make([]interface{}, len(items))
+	errs := make([]interface{}, 0)
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, map[string]interface{}{"index": i, "error": r.err.Error()})
+			continue
+		}
+		outputs[i] = r.output
+	}
+	return map[string]interface{}{
+		"results": outputs,
+		"errors":  errs,
+		"count":   len(items),
+		"failed":  len(errs),
+	}, nil
+}
+
+// loopItem executes a loop's body for one item.
+func (w *walker) loopItem(ctx context.Context, gn *workflow.Node, body, sinks []*workflow.Node, scope map[string]interface{}, item interface{}, index int) loopResult {
+	// Each item has its own scope so that items executed concurrently do
+	// not see each other's outputs.
+	itemScope := make(map[string]interface{}, len(scope)+len(body))
+	for k, v := range scope {
+		itemScope[k] = v
+	}
+	itemScope[gn.ID.String()] = map[string]interface{}{"item": item, "index": index}
+
+	next := make(map[uuid.UUID]bool)
+	for id, child := range gn.Children {
+		if gn.Label(child) == flow.BranchEach {
+			next[id] = true
+		}
+	}
+
+	if err := w.walk(ctx, body, next, itemScope, &index); err != nil {
+		return loopResult{err: err}
+	}
+
+	if len(sinks) == 1 {
+		return loopResult{output: itemScope[sinks[0].ID.String()]}
+	}
+	outputs := make(map[string]interface{}, len(sinks))
+	for _, sink := range sinks {
+		if v, ok := itemScope[sink.ID.String()]; ok {
+			outputs[sink.ID.String()] = v
+		}
+	}
+	return loopResult{output: outputs}
+}
@@ -0,0 +1,20 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/openmesh/flow"
+)
+
+// nativeRunner implements an action of a native integration by handing it to
+// the integration's Go implementation.
+type nativeRunner struct {
+	app flow.NativeIntegration
+	req flow.ActionRequest
+}
+
+func (r *nativeRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	req := r.req
+	req.Inputs = inputs
+	return r.app.Run(ctx, req)
+}
@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// Default settings of the run queue.
+const (
+	DefaultLease        = 30 * time.Second
+	DefaultPollInterval = 5 * time.Second
+)
+
+// claimLimit is the maximum number of runs claimed per poll.
+const claimLimit = 10
+
+// reasonReleased stops runs whose claim has been lost, such as runs cancelled
+// by another instance. Their outcome is left to whoever took them over.
+const reasonReleased = "released"
+
+// Open begins resuming unfinished runs from the queue and recording the
+// heartbeats of the instance, which renew the claims of the runs executing on
+// it.
+func (e *Executor) Open() error {
+	if e.Lease <= 0 || e.PollInterval <= 0 {
+		return fmt.Errorf("lease & poll interval must be positive")
+	} else if e.Concurrency < 0 {
+		return fmt.Errorf("concurrency cannot be negative")
+	}
+
+	e.mu.Lock()
+	e.startedAt, e.heartbeatAt = e.Now(), e.Now()
+	e.mu.Unlock()
+
+	go e.loop()
+	return nil
+}
+
+// Check returns an error if the instance has not recorded a heartbeat within
+// its lease, such as when it cannot reach the database. Runs claimed by an
+// instance that fails the check may be resumed by other instances.
+func (e *Executor) Check() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.draining {
+		return fmt.Errorf("executor is shutting down")
+	} else if since := e.Now().Sub(e.heartbeatAt); since > e.Lease {
+		return fmt.Errorf("no heartbeat for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (e *Executor) loop() {
+	defer close(e.stopped)
+
+	// Instances that only queue runs do not poll. The ticker is stopped
+	// rather than left nil so that the select below stays uniform.
+	poll := time.NewTicker(e.PollInterval)
+	defer poll.Stop()
+	if e.QueueOnly {
+		poll.Stop()
+	}
+	// Claims are renewed well before they expire so that a slow renewal does
+	// not let another instance resume a run that is still executing.
+	heartbeat := time.NewTicker(e.Lease / 3)
+	defer heartbeat.Stop()
+
+	// Runs left unfinished by a previous process are resumed on startup.
+	ctx := flow.NewSystemContext(context.Background())
+	if err := e.heartbeat(ctx); err != nil {
+		_ = e.Logger.Log("msg", "cannot record heartbeat", "err", err)
+	}
+	if !e.QueueOnly {
+		if err := e.Resume(ctx); err != nil {
+			_ = e.Logger.Log("msg", "cannot resume runs", "err", err)
+		}
+	}
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-heartbeat.C:
+			if err := e.heartbeat(ctx); err != nil {
+				_ = e.Logger.Log("msg", "cannot record heartbeat", "err", err)
+			}
+		case <-poll.C:
+			if err := e.Resume(ctx); err != nil {
+				_ = e.Logger.Log("msg", "cannot resume runs", "err", err)
+			}
+		}
+	}
+}
+
+// Resume claims unfinished runs from the queue and executes them in the
+// background. Runs continue from the results of the nodes that completed
+// before they were interrupted, so nodes are executed at least once.
+func (e *Executor) Resume(ctx context.Context) error {
+	if e.isDraining() {
+		return nil
+	}
+
+	// Workers only claim as many runs as they have capacity for.
+	limit := claimLimit
+	if e.Concurrency > 0 {
+		if free := e.Concurrency - e.activeRuns(); free < limit {
+			limit = free
+		}
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	runs, err := e.RunService.ClaimRuns(ctx, e.ID, e.Lease, limit)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		// A run whose claim expired while it executed here is claimed
+		// again rather than executed twice.
+		if e.isActive(run.ID) {
+			continue
+		}
+
+		wf, err := e.WorkflowService.GetWorkflowByID(ctx, run.WorkflowID)
+		if err != nil {
+			_ = e.Logger.Log("msg", "cannot resume run", "run", run.ID, "err", err)
+			continue
+		}
+
+		active := e.track(wf, run)
+		go func(run *flow.Run) {
+			if err := e.execute(wf, run, active); err != nil {
+				_ = e.Logger.Log("msg", "run failed", "run", run.ID, "err", err)
+			}
+		}(run)
+	}
+	return nil
+}
+
+// heartbeat renews the claims on the runs executing on this instance and
+// records the heartbeat of the worker.
+func (e *Executor) heartbeat(ctx context.Context) error {
+	if err := e.renew(ctx); err != nil {
+		return err
+	}
+
+	if e.WorkerService != nil {
+		e.mu.Lock()
+		worker := &flow.Worker{
+			ID:          e.ID,
+			Concurrency: e.Concurrency,
+			ActiveRuns:  len(e.active),
+			StartedAt:   e.startedAt,
+		}
+		e.mu.Unlock()
+		if err := e.WorkerService.SaveWorker(ctx, worker); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	e.heartbeatAt = e.Now()
+	e.mu.Unlock()
+	return nil
+}
+
+// deregister removes the worker of this instance once it has stopped.
+func (e *Executor) deregister() {
+	if e.WorkerService == nil {
+		return
+	}
+	ctx := flow.NewSystemContext(context.Background())
+	if err := e.WorkerService.DeleteWorker(ctx, e.ID); err != nil && flow.ErrorCode(err) != flow.ENOTFOUND {
+		_ = e.Logger.Log("msg", "cannot remove worker", "err", err)
+	}
+}
+
+func (e *Executor) activeRuns() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.active)
+}
+
+func (e *Executor) isActive(id uuid.UUID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active[id] != nil
+}
+
+// renew extends the claims on the runs executing on this instance. Runs whose
+// claim has been lost are stopped.
+func (e *Executor) renew(ctx context.Context) error {
+	e.mu.Lock()
+	ids := make([]uuid.UUID, 0, len(e.active))
+	for id := range e.active {
+		ids = append(ids, id)
+	}
+	e.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	held, err := e.RunService.RenewRunClaims(ctx, e.ID, ids, e.Lease)
+	if err != nil {
+		return err
+	}
+	isHeld := make(map[uuid.UUID]bool, len(held))
+	for _, id := range held {
+		isHeld[id] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range ids {
+		if active := e.active[id]; active != nil && !isHeld[id] {
+			active.stop(reasonReleased)
+		}
+	}
+	return nil
+}
+
+// instanceID returns an identifier for this process that is unique among the
+// instances sharing the queue.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "flow"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+}
@@ -0,0 +1,538 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openmesh/flow"
+)
+
+// requestRunner implements the actions of the HTTP integration, which send a
+// request with the node's method to the URL of its "url" input. Requests are
+// sent with the client returned by NewRequestClient() so that users cannot
+// reach internal services.
+type requestRunner struct {
+	client *http.Client
+	method string
+
+	// Scope the placeholders of body templates are resolved against.
+	scope map[string]interface{}
+
+	// Returns the workflow owner's access token for an integration.
+	token func(ctx context.Context, integration string) (string, error)
+}
+
+func (r *requestRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	u, err := requestURL(inputs)
+	if err != nil {
+		return nil, err
+	}
+	header, err := requestHeader(inputs)
+	if err != nil {
+		return nil, err
+	}
+	body, err := r.requestBody(inputs, header)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := r.authenticate(ctx, inputs, header)
+	if err != nil {
+		return nil, err
+	}
+	// Credentials are only sent to the origin of the request. Redirects to
+	// other origins drop them and next links to other origins are not
+	// followed.
+	ctx = newContextWithCredentialHeaders(ctx, credentials)
+	if header.Get("Accept") == "" {
+		header.Set("Accept", "application/json, */*;q=0.5")
+	}
+	// Repeated executions of a node send the same key so that the API can
+	// ignore all but the first.
+	if key := flow.IdempotencyKeyFromContext(ctx); key != "" && header.Get("Idempotency-Key") == "" {
+		header.Set("Idempotency-Key", key)
+	}
+
+	rule := flow.DefaultStatusRule
+	if v, ok := inputs["success_status"]; ok {
+		rule = flow.StatusRule(stringify(v))
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	format := flow.ResponseFormatAuto
+	if v, ok := inputs["response_format"]; ok {
+		switch format = stringify(v); format {
+		case flow.ResponseFormatAuto, flow.ResponseFormatJSON, flow.ResponseFormatText, flow.ResponseFormatBinary:
+		default:
+			return nil, flow.Errorf(flow.EINVALID, "Unknown response format '%s'.", format)
+		}
+	}
+	p, err := parsePagination(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every page counts towards the limit of the size of the responses.
+	remaining := int64(flow.MaxResponseSize)
+	var items []interface{}
+	var output map[string]interface{}
+	pages := 0
+	u = p.first(u)
+	for {
+		resp, err := r.send(ctx, u, header, body, &remaining)
+		if err != nil {
+			return nil, err
+		}
+		pages++
+
+		if !rule.Matches(resp.StatusCode) {
+			return nil, &flow.StatusError{
+				StatusCode: resp.StatusCode,
+				Message:    fmt.Sprintf("%s %s: unexpected status %d", r.method, u.Path, resp.StatusCode),
+			}
+		} else if output, err = parseResponse(resp, format); err != nil {
+			return nil, err
+		}
+		if p.scheme == flow.PaginationNone {
+			return output, nil
+		}
+
+		pageItems, err := p.items(output["body"])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+		if pages >= p.maxPages {
+			break
+		}
+		next, err := p.next(u, resp, output["body"], len(pageItems))
+		if err != nil {
+			return nil, err
+		} else if next == nil {
+			break
+		} else if !sameOrigin(next, u) {
+			return nil, flow.Errorf(flow.EINVALID, "Next page '%s' is not on the origin of the request.", next.Redacted())
+		}
+		u = next
+	}
+
+	if items == nil {
+		items = []interface{}{}
+	}
+	output["items"], output["pages"] = items, pages
+	return output, nil
+}
+
+// response is a response whose body has been read.
+type response struct {
+	*http.Response
+	body []byte
+}
+
+// send sends a request and reads its response, which may not be larger than
+// the remaining size.
+func (r *requestRunner) send(ctx context.Context, u *url.URL, header http.Header, body []byte, remaining *int64) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(io.LimitReader(resp.Body, *remaining+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(buf)) > *remaining {
+		return nil, flow.Errorf(flow.ETOOLARGE, "Responses exceed %d bytes.", flow.MaxResponseSize)
+	}
+	*remaining -= int64(len(buf))
+	return &response{Response: resp, body: buf}, nil
+}
+
+// requestURL returns the URL of the "url" input with the params of the
+// "query" input added to its query.
+func requestURL(inputs map[string]interface{}) (*url.URL, error) {
+	u, err := url.Parse(stringify(inputs["url"]))
+	if err != nil || inputs["url"] == nil {
+		return nil, flow.Errorf(flow.EINVALID, "A valid URL is required.")
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, flow.Errorf(flow.EINVALID, "URL must use http or https.")
+	} else if u.Host == "" {
+		return nil, flow.Errorf(flow.EINVALID, "URL must have a host.")
+	}
+
+	if v, ok := inputs["query"]; ok {
+		params, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, flow.Errorf(flow.EINVALID, "Query must be an object.")
+		}
+		q := u.Query()
+		for k, v := range params {
+			if values, ok := v.([]interface{}); ok {
+				for _, v := range values {
+					q.Add(k, stringify(v))
+				}
+				continue
+			}
+			q.Set(k, stringify(v))
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u, nil
+}
+
+// requestHeader returns the headers of the "headers" input.
+func requestHeader(inputs map[string]interface{}) (http.Header, error) {
+	header := make(http.Header)
+	v, ok := inputs["headers"]
+	if !ok {
+		return header, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, flow.Errorf(flow.EINVALID, "Headers must be an object.")
+	}
+	for k, v := range m {
+		header.Set(k, stringify(v))
+	}
+	return header, nil
+}
+
+// templatePlaceholder matches the placeholders of body templates, such as
+// "{{ trigger.user.id }}".
+var templatePlaceholder = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// requestBody returns the body of a request. Bodies are either rendered from
+// the "body_template" input or the "body" input encoded as JSON, unless it is
+// a string which is sent as is. The content type defaults to JSON.
+func (r *requestRunner) requestBody(inputs map[string]interface{}, header http.Header) ([]byte, error) {
+	tmpl, hasTemplate := inputs["body_template"]
+	v, hasBody := inputs["body"]
+	if !hasTemplate && !hasBody {
+		return nil, nil
+	}
+
+	if v, ok := inputs["content_type"]; ok {
+		header.Set("Content-Type", stringify(v))
+	} else if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+
+	if hasTemplate {
+		return renderTemplate(stringify(tmpl), r.scope, isJSON(header.Get("Content-Type")))
+	} else if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(v)
+}
+
+// renderTemplate replaces the placeholders of a template with the values at
+// their path within the scope. Values are encoded as JSON within JSON
+// templates, so that "{{ trigger.name }}" renders as a quoted string, and are
+// otherwise formatted as text.
+func renderTemplate(tmpl string, scope map[string]interface{}, encodeJSON bool) ([]byte, error) {
+	var err error
+	rendered := templatePlaceholder.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		path := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		v, ok, lookupErr := flow.Lookup(scope, path)
+		if lookupErr != nil {
+			err = lookupErr
+			return ""
+		} else if !ok {
+			err = flow.Errorf(flow.EINVALID, "Placeholder '%s' of body template could not be resolved.", path)
+			return ""
+		}
+		if !encodeJSON {
+			return stringify(v)
+		}
+		buf, encodeErr := json.Marshal(v)
+		if encodeErr != nil {
+			err = encodeErr
+		}
+		return string(buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(rendered), nil
+}
+
+// authenticate sets the authorization of a request according to the "auth"
+// input. Returns the names of the headers that hold credentials.
+func (r *requestRunner) authenticate(ctx context.Context, inputs map[string]interface{}, header http.Header) ([]string, error) {
+	auth := flow.RequestAuthNone
+	if v, ok := inputs["auth"]; ok {
+		auth = stringify(v)
+	}
+
+	switch auth {
+	case flow.RequestAuthNone:
+		return nil, nil
+	case flow.RequestAuthBasic:
+		req := &http.Request{Header: header}
+		req.SetBasicAuth(stringify(inputs["username"]), stringify(inputs["password"]))
+		return []string{"Authorization"}, nil
+	case flow.RequestAuthBearer:
+		token, _ := inputs["token"].(string)
+		if token == "" {
+			return nil, flow.Errorf(flow.EINVALID, "A token is required for bearer auth.")
+		}
+		header.Set("Authorization", "Bearer "+token)
+		return []string{"Authorization"}, nil
+	case flow.RequestAuthHeader:
+		name, _ := inputs["auth_header"].(string)
+		if name == "" {
+			return nil, flow.Errorf(flow.EINVALID, "A header name is required for header auth.")
+		}
+		header.Set(name, stringify(inputs["auth_value"]))
+		return []string{name}, nil
+	case flow.RequestAuthIntegration:
+		integration, _ := inputs["auth_integration"].(string)
+		if integration == "" {
+			return nil, flow.Errorf(flow.EINVALID, "An integration is required for integration auth.")
+		}
+		token, err := r.token(ctx, integration)
+		if err != nil {
+			return nil, err
+		} else if token == "" {
+			return nil, flow.Errorf(flow.EUNAUTHORIZED, "Integration '%s' is not connected.", integration)
+		}
+		header.Set("Authorization", "Bearer "+token)
+		return []string{"Authorization"}, nil
+	}
+	return nil, flow.Errorf(flow.EINVALID, "Unknown auth '%s'.", auth)
+}
+
+// sameOrigin returns true if two URLs have the same scheme & host.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+type credentialHeadersContextKey struct{}
+
+// newContextWithCredentialHeaders returns a context holding the names of the
+// headers of a request that hold credentials.
+func newContextWithCredentialHeaders(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, credentialHeadersContextKey{}, names)
+}
+
+// credentialHeadersFromContext returns the names of the headers of a request
+// that hold credentials.
+func credentialHeadersFromContext(ctx context.Context) []string {
+	names, _ := ctx.Value(credentialHeadersContextKey{}).([]string)
+	return names
+}
+
+// parseResponse returns the output of a response: its status, headers & body
+// parsed according to the format.
+func parseResponse(resp *response, format string) (map[string]interface{}, error) {
+	contentType := resp.Header.Get("Content-Type")
+	if format == flow.ResponseFormatAuto {
+		switch mediaType, _, _ := mime.ParseMediaType(contentType); {
+		case len(resp.body) == 0:
+			format = flow.ResponseFormatText
+		case isJSON(contentType):
+			format = flow.ResponseFormatJSON
+		case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "xml"),
+			mediaType == "application/javascript", mediaType == "application/x-www-form-urlencoded":
+			format = flow.ResponseFormatText
+		default:
+			format = flow.ResponseFormatBinary
+		}
+	}
+
+	var body interface{}
+	switch format {
+	case flow.ResponseFormatJSON:
+		if len(resp.body) > 0 {
+			if err := json.Unmarshal(resp.body, &body); err != nil {
+				return nil, fmt.Errorf("cannot decode response: %w", err)
+			}
+		}
+	case flow.ResponseFormatText:
+		body = string(resp.body)
+	case flow.ResponseFormatBinary:
+		body = base64.StdEncoding.EncodeToString(resp.body)
+	}
+
+	headers := make(map[string]interface{}, len(resp.Header))
+	for k, v := range resp.Header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	return map[string]interface{}{
+		"status":       resp.StatusCode,
+		"headers":      headers,
+		"content_type": contentType,
+		"body":         body,
+	}, nil
+}
+
+// isJSON returns true if a content type is JSON, such as "application/json"
+// or "application/problem+json".
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// pagination describes how the pages of a paginated request are fetched.
+type pagination struct {
+	scheme   string
+	maxPages int
+
+	// Path of the items within each page. Empty if pages are arrays of
+	// items.
+	itemsPath string
+
+	cursorPath  string
+	cursorParam string
+
+	pageParam string
+	page      int // number of the current page
+}
+
+// parsePagination returns the pagination of a request from the "pagination"
+// input & the inputs of its scheme.
+func parsePagination(inputs map[string]interface{}) (*pagination, error) {
+	p := &pagination{scheme: flow.PaginationNone, maxPages: flow.DefaultPages}
+	if v, ok := inputs["pagination"]; ok {
+		p.scheme = stringify(v)
+	}
+	if v, ok := inputs["max_pages"]; ok {
+		n, err := strconv.Atoi(stringify(v))
+		if err != nil || n < 1 || n > flow.MaxPages {
+			return nil, flow.Errorf(flow.EINVALID, "Max pages must be between 1 and %d.", flow.MaxPages)
+		}
+		p.maxPages = n
+	}
+	p.itemsPath, _ = inputs["items_path"].(string)
+
+	switch p.scheme {
+	case flow.PaginationNone, flow.PaginationLink:
+	case flow.PaginationCursor:
+		p.cursorPath, _ = inputs["cursor_path"].(string)
+		p.cursorParam, _ = inputs["cursor_param"].(string)
+		if p.cursorPath == "" || p.cursorParam == "" {
+			return nil, flow.Errorf(flow.EINVALID, "A cursor path and cursor param are required for cursor pagination.")
+		}
+	case flow.PaginationPage:
+		p.pageParam, p.page = "page", 1
+		if v, ok := inputs["page_param"]; ok {
+			p.pageParam = stringify(v)
+		}
+		if v, ok := inputs["page_start"]; ok {
+			n, err := strconv.Atoi(stringify(v))
+			if err != nil {
+				return nil, flow.Errorf(flow.EINVALID, "Page start must be a number.")
+			}
+			p.page = n
+		}
+	default:
+		return nil, flow.Errorf(flow.EINVALID, "Unknown pagination '%s'.", p.scheme)
+	}
+	return p, nil
+}
+
+// items returns the items of a page.
+func (p *pagination) items(body interface{}) ([]interface{}, error) {
+	v := body
+	if p.itemsPath != "" {
+		var ok bool
+		var err error
+		if v, ok, err = flow.Lookup(body, p.itemsPath); err != nil {
+			return nil, err
+		} else if !ok || v == nil {
+			return nil, nil
+		}
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, flow.Errorf(flow.EINVALID, "Items of page must be an array.")
+	}
+	return items, nil
+}
+
+// next returns the URL of the page following a response. Returns nil if the
+// response is the last page.
+func (p *pagination) next(u *url.URL, resp *response, body interface{}, n int) (*url.URL, error) {
+	switch p.scheme {
+	case flow.PaginationLink:
+		link := nextLink(resp.Header.Values("Link"))
+		if link == "" {
+			return nil, nil
+		}
+		next, err := u.Parse(link)
+		if err != nil {
+			return nil, fmt.Errorf("invalid next link: %w", err)
+		}
+		return next, nil
+	case flow.PaginationCursor:
+		cursor, ok, err := flow.Lookup(body, p.cursorPath)
+		if err != nil {
+			return nil, err
+		} else if !ok || cursor == nil || stringify(cursor) == "" {
+			return nil, nil
+		}
+		return withQueryParam(u, p.cursorParam, stringify(cursor)), nil
+	case flow.PaginationPage:
+		if n == 0 {
+			return nil, nil
+		}
+		p.page++
+		return withQueryParam(u, p.pageParam, strconv.Itoa(p.page)), nil
+	}
+	return nil, nil
+}
+
+// first returns the URL of the first page.
+func (p *pagination) first(u *url.URL) *url.URL {
+	if p.scheme == flow.PaginationPage {
+		return withQueryParam(u, p.pageParam, strconv.Itoa(p.page))
+	}
+	return u
+}
+
+// nextLinkRegexp matches a link with the "next" relation within a Link
+// header.
+var nextLinkRegexp = regexp.MustCompile(`<([^>]*)>[^,]*;\s*rel="?([^",]*\s)?next(\s[^",]*)?"?`)
+
+// nextLink returns the URL of the "next" relation of Link headers. Returns an
+// empty string if there is none.
+func nextLink(values []string) string {
+	for _, v := range values {
+		for _, link := range strings.Split(v, ",") {
+			if m := nextLinkRegexp.FindStringSubmatch(link); m != nil {
+				return strings.TrimSpace(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// withQueryParam returns a copy of a URL with a query param set.
+func withQueryParam(u *url.URL, key, value string) *url.URL {
+	next := *u
+	q := next.Query()
+	q.Set(key, value)
+	next.RawQuery = q.Encode()
+	return &next
+}
@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/openmesh/flow"
+)
+
+// recorder is a test server that records the credentials of the requests it
+// receives.
+type recorder struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	credentials []string
+}
+
+func newRecorder(t *testing.T, handler http.HandlerFunc) *recorder {
+	r := &recorder{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.credentials = append(r.credentials, req.Header.Get("X-Api-Key")+req.Header.Get("Authorization"))
+		r.mu.Unlock()
+		handler(w, req)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *recorder) requests() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.credentials...)
+}
+
+func newTestRequestRunner(t *testing.T) *requestRunner {
+	client, err := NewRequestClient([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &requestRunner{client: client, method: http.MethodGet}
+}
+
+func TestRequestRunner_Redirect(t *testing.T) {
+	other := newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	origin := newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL+"/ok", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+
+	for _, tt := range []struct {
+		name   string
+		auth   map[string]interface{}
+		origin []string
+		other  []string
+	}{
+		{"HeaderSameOrigin", map[string]interface{}{"auth": flow.RequestAuthHeader, "auth_header": "X-Api-Key", "auth_value": "secret"}, []string{"secret", "secret"}, nil},
+		{"HeaderOtherOrigin", map[string]interface{}{"auth": flow.RequestAuthHeader, "auth_header": "X-Api-Key", "auth_value": "secret"}, []string{"secret"}, []string{""}},
+		{"BearerOtherOrigin", map[string]interface{}{"auth": flow.RequestAuthBearer, "token": "secret"}, []string{"Bearer secret"}, []string{""}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			before, otherBefore := len(origin.requests()), len(other.requests())
+			path := "/same"
+			if tt.other != nil {
+				path = "/other"
+			}
+			inputs := map[string]interface{}{"url": origin.URL + path}
+			for k, v := range tt.auth {
+				inputs[k] = v
+			}
+			if _, err := newTestRequestRunner(t).Run(context.Background(), inputs); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := origin.requests()[before:]; !equalStrings(got, tt.origin) {
+				t.Fatalf("origin credentials = %q, want %q", got, tt.origin)
+			} else if got := other.requests()[otherBefore:]; !equalStrings(got, tt.other) {
+				t.Fatalf("other credentials = %q, want %q", got, tt.other)
+			}
+		})
+	}
+}
+
+func TestRequestRunner_NextLink(t *testing.T) {
+	other := newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[3]`))
+	})
+	origin := newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/same":
+			w.Header().Set("Link", `</last>; rel="next"`)
+		case "/other":
+			w.Header().Set("Link", "<"+other.URL+`/last>; rel="next"`)
+		}
+		_, _ = w.Write([]byte(`[1, 2]`))
+	})
+
+	inputs := func(path string) map[string]interface{} {
+		return map[string]interface{}{
+			"url":         origin.URL + path,
+			"pagination":  flow.PaginationLink,
+			"auth":        flow.RequestAuthHeader,
+			"auth_header": "X-Api-Key",
+			"auth_value":  "secret",
+		}
+	}
+
+	output, err := newTestRequestRunner(t).Run(context.Background(), inputs("/same"))
+	if err != nil {
+		t.Fatal(err)
+	} else if pages := output["pages"]; pages != 2 {
+		t.Fatalf("pages = %v, want 2", pages)
+	}
+
+	_, err = newTestRequestRunner(t).Run(context.Background(), inputs("/other"))
+	if code := flow.ErrorCode(err); code != flow.EINVALID {
+		t.Fatalf("error code = %q, want %q (err: %v)", code, flow.EINVALID, err)
+	} else if n := len(other.requests()); n != 0 {
+		t.Fatalf("other origin received %d requests, want 0", n)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// attempt runs a node's action, retrying failures according to the node's
+// retry policy. Returns the history of attempts along with the outcome of the
+// last attempt.
+func (w *walker) attempt(ctx context.Context, node *flow.Node, scope map[string]interface{}) (map[string]interface{}, flow.Attempts, error) {
+	maxAttempts := 1
+	if node.Retry != nil && node.Retry.MaxAttempts > 1 {
+		maxAttempts = node.Retry.MaxAttempts
+	}
+
+	var attempts flow.Attempts
+	for n := 1; ; n++ {
+		a := &flow.Attempt{Number: n, StartedAt: w.Now()}
+		logs := &logBuffer{}
+		output, err := w.runAttempt(newContextWithLogs(ctx, logs), node, scope)
+		a.FinishedAt, a.Logs = w.Now(), logs.Lines()
+		attempts = append(attempts, a)
+		if err == nil {
+			return output, attempts, nil
+		}
+
+		a.Error = stringPtr(err.Error())
+		if code := statusCode(err); code != 0 {
+			a.StatusCode = &code
+		}
+		// Stopped runs are not retried.
+		if n >= maxAttempts || !node.Retry.Retryable(err) || ctx.Err() != nil {
+			return nil, attempts, err
+		}
+
+		select {
+		case <-time.After(node.Retry.Delay(n+1, w.Rand())):
+		case <-ctx.Done():
+			return nil, attempts, ctx.Err()
+		}
+	}
+}
+
+// runAttempt runs a node's action once, limited to the node's timeout.
+func (w *walker) runAttempt(ctx context.Context, node *flow.Node, scope map[string]interface{}) (map[string]interface{}, error) {
+	timeout := node.Timeout()
+	if timeout <= 0 {
+		return w.runNode(ctx, w.wf, node, scope)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := w.runNode(attemptCtx, w.wf, node, scope)
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("node timed out after %s", timeout)
+	}
+	return output, err
+}
+
+// errorOutput returns the output of a failed node as seen by the nodes of its
+// error edges.
+func errorOutput(err error) map[string]interface{} {
+	output := map[string]interface{}{
+		"error": err.Error(),
+		"code":  flow.ErrorCode(err),
+	}
+	if code := statusCode(err); code != 0 {
+		output["status_code"] = code
+	}
+	return output
+}
+
+// statusCode returns the HTTP status code that caused an error. Returns zero
+// if the error was not caused by an error status.
+func statusCode(err error) int {
+	var e *flow.StatusError
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
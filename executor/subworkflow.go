@@ -0,0 +1,349 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// isSubWorkflow returns true if a node calls another workflow.
+func isSubWorkflow(node *flow.Node) bool {
+	return node.Integration == flow.IntegrationCore && node.Action == flow.ActionSubWorkflow
+}
+
+// subWorkflow implements the core sub-workflow action. The workflow of the
+// "workflow_id" input is run from its source node, or from the node of the
+// "trigger_node_id" input, with the "input" input as its trigger payload.
+//
+// Synchronous calls run the called workflow on this instance and output its
+// result. If the called run pauses or is interrupted, this run waits for it to
+// finish without holding on to the executor. Asynchronous calls output the ID
+// of the started run. Calls are linked to the run & node that made them, so a
+// resumed run does not call the workflow again.
+func (w *walker) subWorkflow(ctx context.Context, node *flow.Node, scope map[string]interface{}, item *int) (map[string]interface{}, error) {
+	if wait := w.waits[checkpointKey(node.ID, item)]; wait != nil && wait.ChildRunID != nil {
+		if !wait.Resolved() {
+			w.active.stop(flow.RunStatusWaiting)
+			return nil, errWaiting
+		}
+		child, err := w.RunService.GetRunByID(w.store, *wait.ChildRunID)
+		if err != nil {
+			return nil, err
+		}
+		return w.callOutput(child)
+	}
+
+	inputs, err := w.nodeInputs(ctx, node, scope)
+	if err != nil {
+		return nil, err
+	}
+	mode := flow.SubWorkflowModeSync
+	if v, ok := inputs["mode"]; ok {
+		mode = stringify(v)
+	}
+	switch mode {
+	case flow.SubWorkflowModeSync, flow.SubWorkflowModeAsync:
+	default:
+		return nil, flow.Errorf(flow.EINVALID, "Mode of sub-workflow node must be '%s' or '%s'.", flow.SubWorkflowModeSync, flow.SubWorkflowModeAsync)
+	}
+
+	// A run resumed after its call started continues with the called run.
+	if child, err := w.called(node, item); err != nil {
+		return nil, err
+	} else if child != nil {
+		if mode == flow.SubWorkflowModeAsync {
+			return map[string]interface{}{"run_id": child.ID.String(), "status": child.Status}, nil
+		} else if child.Finished() {
+			return w.callOutput(child)
+		}
+		return w.awaitCall(node, item, child)
+	}
+
+	req, err := w.callRequest(node, item, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == flow.SubWorkflowModeAsync {
+		child, err := w.StartRun(w.store, req)
+		if err != nil {
+			return nil, w.unavailable(err)
+		}
+		return map[string]interface{}{"run_id": child.ID.String(), "status": child.Status}, nil
+	}
+
+	active, wf, child, err := w.begin(w.store, req)
+	if err != nil {
+		return nil, w.unavailable(err)
+	}
+
+	// The called run stops with this run. Runs that are cancelled or time out
+	// cancel it, while runs that are otherwise stopped leave it to be resumed.
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			switch w.active.stopped() {
+			case flow.RunStatusCancelled, "":
+				active.stop(flow.RunStatusCancelled)
+			default:
+				active.stop(flow.RunStatusInterrupted)
+			}
+		case <-stopped:
+		}
+	}()
+	err = w.execute(wf, child, active)
+	close(stopped)
+	if err != nil && !child.Finished() {
+		return nil, err
+	}
+
+	if child.Finished() {
+		return w.callOutput(child)
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return w.awaitCall(node, item, child)
+}
+
+// unavailable interrupts the run if a call failed because the executor is
+// shutting down, so that the call is made again once the run is resumed.
+func (w *walker) unavailable(err error) error {
+	if flow.ErrorCode(err) == flow.EUNAVAILABLE {
+		w.active.stop(flow.RunStatusInterrupted)
+	}
+	return err
+}
+
+// called returns the run started by a node for a loop item before the run was
+// resumed. Returns nil if the node has not started a run.
+func (w *walker) called(node *flow.Node, item *int) (*flow.Run, error) {
+	runs, _, err := w.RunService.GetRuns(w.store, flow.RunFilter{ParentRunID: &w.run.ID, ParentNodeID: &node.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if (run.ParentItem == nil && item == nil) || (run.ParentItem != nil && item != nil && *run.ParentItem == *item) {
+			return w.RunService.GetRunByID(w.store, run.ID)
+		}
+	}
+	return nil, nil
+}
+
+// callRequest builds the request of a sub-workflow call. Only workflows of the
+// same user may be called. Calls that would nest deeper than
+// flow.MaxCallDepth or call a workflow that is already part of the chain of
+// calls are rejected, so that workflows cannot recurse.
+func (w *walker) callRequest(node *flow.Node, item *int, inputs map[string]interface{}) (flow.RunRequest, error) {
+	id, err := uuid.Parse(stringify(inputs["workflow_id"]))
+	if err != nil {
+		return flow.RunRequest{}, flow.Errorf(flow.EINVALID, "Workflow of sub-workflow node must be a workflow ID.")
+	}
+	wf, err := w.WorkflowService.GetWorkflowByID(w.store, id)
+	if err != nil {
+		return flow.RunRequest{}, err
+	} else if wf.UserID != w.wf.UserID {
+		return flow.RunRequest{}, flow.Errorf(flow.ENOTFOUND, "Workflow not found.")
+	}
+
+	chain, err := w.callChain()
+	if err != nil {
+		return flow.RunRequest{}, err
+	} else if len(chain) > flow.MaxCallDepth {
+		return flow.RunRequest{}, flow.Errorf(flow.EINVALID, "Sub-workflows cannot be nested more than %d levels deep.", flow.MaxCallDepth)
+	}
+	for _, workflowID := range chain {
+		if workflowID == wf.ID {
+			return flow.RunRequest{}, flow.Errorf(flow.EINVALID, "Workflow %s cannot call itself.", wf.ID)
+		}
+	}
+
+	trigger, err := callTrigger(wf, inputs)
+	if err != nil {
+		return flow.RunRequest{}, err
+	}
+
+	payload, ok := inputs["input"]
+	if !ok {
+		payload = map[string]interface{}{}
+	}
+	return flow.RunRequest{
+		WorkflowID:    wf.ID,
+		TriggerNodeID: trigger.ID,
+		Payload:       payload,
+		ParentRunID:   &w.run.ID,
+		ParentNodeID:  &node.ID,
+		ParentItem:    item,
+	}, nil
+}
+
+// callChain returns the IDs of the workflows of this run and of the runs that
+// called it, starting with this run. At most flow.MaxCallDepth callers are
+// followed.
+func (w *walker) callChain() ([]uuid.UUID, error) {
+	chain := []uuid.UUID{w.wf.ID}
+	parentID := w.run.ParentRunID
+	for parentID != nil && len(chain) <= flow.MaxCallDepth {
+		runs, _, err := w.RunService.GetRuns(w.store, flow.RunFilter{ID: parentID})
+		if err != nil {
+			return nil, err
+		} else if len(runs) == 0 {
+			break
+		}
+		chain = append(chain, runs[0].WorkflowID)
+		parentID = runs[0].ParentRunID
+	}
+	return chain, nil
+}
+
+// callTrigger returns the node a called workflow runs from. This is the node
+// of the "trigger_node_id" input or else the workflow's only source node.
+func callTrigger(wf *flow.Workflow, inputs map[string]interface{}) (*flow.Node, error) {
+	if v, ok := inputs["trigger_node_id"]; ok {
+		id, err := uuid.Parse(stringify(v))
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Trigger of sub-workflow node must be a node ID.")
+		} else if node := wf.GetNode(id); node != nil {
+			return node, nil
+		}
+		return nil, flow.Errorf(flow.EINVALID, "Node %s is not part of workflow %s.", id, wf.ID)
+	}
+
+	var trigger *flow.Node
+	for _, node := range wf.Nodes {
+		if len(node.ParentIDs) > 0 {
+			continue
+		} else if trigger != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Workflow %s has several source nodes. A trigger node is required.", wf.ID)
+		}
+		trigger = node
+	}
+	if trigger == nil {
+		return nil, flow.Errorf(flow.EINVALID, "Workflow %s has no source node.", wf.ID)
+	}
+	return trigger, nil
+}
+
+// awaitCall pauses the run until a called run has finished.
+func (w *walker) awaitCall(node *flow.Node, item *int, child *flow.Run) (map[string]interface{}, error) {
+	if w.WaitService == nil {
+		return nil, flow.Errorf(flow.EUNSUPPORTED, "Runs cannot pause without a wait service.")
+	}
+
+	wait := &flow.Wait{
+		RunID:      w.run.ID,
+		WorkflowID: w.wf.ID,
+		NodeID:     node.ID,
+		Item:       item,
+		ChildRunID: &child.ID,
+	}
+	if err := w.WaitService.CreateWait(w.store, wait); err != nil {
+		return nil, err
+	}
+
+	// The called run may have finished before the wait existed, in which
+	// case nothing else resolves it.
+	latest, err := w.RunService.GetRunByID(w.store, child.ID)
+	if err != nil {
+		return nil, err
+	} else if latest.Finished() {
+		if _, err := w.WaitService.ResolveWait(w.store, wait.ID, flow.WaitUpdate{}); err != nil && flow.ErrorCode(err) != flow.ECONFLICT {
+			return nil, err
+		}
+		return w.callOutput(latest)
+	}
+
+	w.active.stop(flow.RunStatusWaiting)
+	return nil, errWaiting
+}
+
+// callOutput returns the output of a sub-workflow node once the called run has
+// finished. Its result is the output of the last respond node of the called
+// workflow, or else the outputs of the workflow's last nodes keyed by node ID.
+// Nodes fail if the called run did not succeed.
+func (w *walker) callOutput(child *flow.Run) (map[string]interface{}, error) {
+	if child.Status != flow.RunStatusSucceeded {
+		message := child.Status
+		if child.Error != nil {
+			message = *child.Error
+		}
+		return nil, fmt.Errorf("sub-workflow run %s %s: %s", child.ID, child.Status, message)
+	}
+
+	wf, err := w.WorkflowService.GetWorkflowByID(w.store, child.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	outputs := make(map[string]interface{})
+	for _, nodeRun := range child.Nodes {
+		node := wf.GetNode(nodeRun.NodeID)
+		if node == nil || nodeRun.Status != flow.NodeRunStatusSucceeded || nodeRun.Item != nil {
+			continue
+		}
+		var output interface{}
+		if len(nodeRun.Output) > 0 {
+			if err := json.Unmarshal(nodeRun.Output, &output); err != nil {
+				return nil, fmt.Errorf("cannot decode output of node %s: %w", node.ID, err)
+			}
+		}
+		if node.Integration == flow.IntegrationCore && node.Action == flow.ActionRespond {
+			result = output
+		} else if len(node.ChildrenIDs) == 0 {
+			outputs[node.ID.String()] = output
+		}
+	}
+	if result == nil {
+		result = outputs
+	}
+
+	return map[string]interface{}{
+		"run_id": child.ID.String(),
+		"status": child.Status,
+		"output": result,
+	}, nil
+}
+
+// resumeCaller resolves the wait of the run that called a run once it has
+// finished, so that the caller is resumed.
+func (e *Executor) resumeCaller(ctx context.Context, run *flow.Run) error {
+	if run.ParentRunID == nil || e.WaitService == nil {
+		return nil
+	}
+
+	waits, _, err := e.WaitService.GetWaits(ctx, flow.WaitFilter{ChildRunID: &run.ID, Pending: true})
+	if err != nil {
+		return err
+	}
+	for _, wait := range waits {
+		if _, err := e.WaitService.ResolveWait(ctx, wait.ID, flow.WaitUpdate{}); err != nil && flow.ErrorCode(err) != flow.ECONFLICT {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelCalls cancels the unfinished runs a run waits for.
+func (e *Executor) cancelCalls(ctx context.Context, id uuid.UUID) error {
+	if e.WaitService == nil {
+		return nil
+	}
+
+	waits, _, err := e.WaitService.GetWaits(ctx, flow.WaitFilter{RunID: &id})
+	if err != nil {
+		return err
+	}
+	for _, wait := range waits {
+		if wait.ChildRunID == nil || wait.Resolved() {
+			continue
+		}
+		if _, err := e.CancelRun(ctx, *wait.ChildRunID); err != nil && flow.ErrorCode(err) != flow.ECONFLICT {
+			return err
+		}
+	}
+	return nil
+}
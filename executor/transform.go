@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// transformSlots limits the number of transforms evaluated at once to
+// flow.MaxConcurrentTransforms.
+var transformSlots = make(chan struct{}, flow.MaxConcurrentTransforms)
+
+// transformRunner implements the core transform action. Its "expression" input
+// is a JMESPath expression evaluated against the "input" input, or against the
+// run's scope if no input is given. In the scope the trigger's payload is
+// "trigger" and the outputs of earlier nodes are keyed by node ID.
+type transformRunner struct {
+	scope map[string]interface{}
+}
+
+func (r transformRunner) Run(ctx context.Context, inputs map[string]interface{}) (map[string]interface{}, error) {
+	expression, _ := inputs["expression"].(string)
+	compiled, err := flow.CompileTransform(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := inputs["input"]
+	if !ok {
+		doc = r.scope
+	}
+	// The document is copied as plain JSON values so that the evaluation
+	// cannot see or change the values held by the run.
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	} else if len(buf) > flow.MaxTransformDocumentSize {
+		return nil, flow.Errorf(flow.ETOOLARGE, "Transform input exceeds %d bytes.", flow.MaxTransformDocumentSize)
+	}
+	if compiled.Memory(len(buf)) > flow.MaxTransformMemory {
+		return nil, flow.Errorf(flow.ETOOLARGE, "Transform can use more than %d bytes of memory on its input.", flow.MaxTransformMemory)
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(flow.TransformTimeout)
+	defer timer.Stop()
+
+	// Evaluations cannot be interrupted, so one that times out holds its slot
+	// until it finishes. Their memory is bounded, so they finish in bounded
+	// time.
+	select {
+	case transformSlots <- struct{}{}:
+	case <-timer.C:
+		return nil, flow.Errorf(flow.EUNAVAILABLE, "Too many transforms are running.")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-transformSlots }()
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("transform failed: %v", p)}
+			}
+		}()
+		v, err := compiled.Search(doc)
+		done <- result{value: v, err: err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-timer.C:
+		return nil, fmt.Errorf("transform did not finish within %s", flow.TransformTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, flow.Errorf(flow.EINVALID, "Transform failed: %s", res.err)
+	}
+
+	if buf, err = json.Marshal(res.value); err != nil {
+		return nil, err
+	} else if len(buf) > flow.MaxTransformDocumentSize {
+		return nil, flow.Errorf(flow.ETOOLARGE, "Transform result exceeds %d bytes.", flow.MaxTransformDocumentSize)
+	}
+	return map[string]interface{}{"result": res.value}, nil
+}
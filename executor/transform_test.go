@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openmesh/flow"
+)
+
+func TestTransformRunner_Run(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		expression string
+		input      interface{}
+		code       string
+	}{
+		{"Field", "a.b", map[string]interface{}{"a": map[string]interface{}{"b": "c"}}, ""},
+		{"Duplicate", "[@, @] | [@, @]", map[string]interface{}{"a": "b"}, ""},
+		{"DuplicateLarge", strings.Repeat("[@, @] | ", 7) + "@", map[string]interface{}{"a": strings.Repeat("b", 600<<10)}, flow.ETOOLARGE},
+		{"InputTooLarge", "a", map[string]interface{}{"a": strings.Repeat("b", flow.MaxTransformDocumentSize)}, flow.ETOOLARGE},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := transformRunner{}.Run(context.Background(), map[string]interface{}{
+				"expression": tt.expression,
+				"input":      tt.input,
+			})
+			if code := flow.ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			}
+		})
+	}
+}
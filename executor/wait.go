@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// errWaiting is returned by nodes that pause the run until their wait is
+// resolved.
+var errWaiting = errors.New("run is waiting")
+
+// isWait returns true if a node pauses the run.
+func isWait(node *flow.Node) bool {
+	return node.Integration == flow.IntegrationCore &&
+		(node.Action == flow.ActionDelay || node.Action == flow.ActionWaitForEvent)
+}
+
+// wait implements the core delay & wait-for-event actions. The first time the
+// node executes, its wait is persisted and the run is stopped so that it
+// pauses rather than holding on to an executor. Once the wait has been
+// resolved the run is resumed and the node outputs the outcome of the wait.
+func (w *walker) wait(ctx context.Context, node *flow.Node, scope map[string]interface{}, item *int) (map[string]interface{}, error) {
+	if wait := w.waits[checkpointKey(node.ID, item)]; wait != nil {
+		if !wait.Resolved() {
+			w.active.stop(flow.RunStatusWaiting)
+			return nil, errWaiting
+		}
+		return waitOutput(wait)
+	}
+
+	inputs, err := w.nodeInputs(ctx, node, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := &flow.Wait{
+		RunID:      w.run.ID,
+		WorkflowID: w.wf.ID,
+		NodeID:     node.ID,
+		Item:       item,
+	}
+	switch node.Action {
+	case flow.ActionDelay:
+		until, err := delayUntil(inputs, w.Now())
+		if err != nil {
+			return nil, err
+		}
+		// Delays that have already elapsed do not pause the run.
+		if !until.After(w.Now()) {
+			return map[string]interface{}{"until": until.UTC().Format(time.RFC3339)}, nil
+		}
+		wait.ResumeAt = &until
+	case flow.ActionWaitForEvent:
+		if err := awaitEvent(inputs, w.Now(), wait); err != nil {
+			return nil, err
+		}
+	}
+
+	if w.WaitService == nil {
+		return nil, flow.Errorf(flow.EUNSUPPORTED, "Runs cannot pause without a wait service.")
+	}
+	if err := w.WaitService.CreateWait(w.store, wait); err != nil {
+		return nil, err
+	}
+	w.active.stop(flow.RunStatusWaiting)
+	return nil, errWaiting
+}
+
+// delayUntil returns the time a delay ends. Delays either last for the
+// "duration" input, given as a Go duration such as "1h30m" or as a number of
+// seconds, or until the time of the "until" input in RFC 3339 format.
+func delayUntil(inputs map[string]interface{}, now time.Time) (time.Time, error) {
+	if v, ok := inputs["until"]; ok {
+		until, err := time.Parse(time.RFC3339, stringify(v))
+		if err != nil {
+			return time.Time{}, flow.Errorf(flow.EINVALID, "Until of delay node must be an RFC 3339 timestamp.")
+		}
+		return until, nil
+	}
+	if v, ok := inputs["duration"]; ok {
+		d, err := parseDuration(v)
+		if err != nil || d < 0 {
+			return time.Time{}, flow.Errorf(flow.EINVALID, "Duration of delay node must be a positive duration.")
+		}
+		return now.Add(d), nil
+	}
+	return time.Time{}, flow.Errorf(flow.EINVALID, "Either a duration or an until time is required.")
+}
+
+// awaitEvent sets the topic, condition & timeout of a wait-for-event node's
+// wait from its inputs. The event is awaited on the topic of the trigger given
+// by the "integration" & "trigger" inputs. Its payload must match the
+// "condition" input, if any, and have the value of the "match_value" input at
+// "match_path", which correlates the event with the run.
+func awaitEvent(inputs map[string]interface{}, now time.Time, wait *flow.Wait) error {
+	integration, _ := inputs["integration"].(string)
+	trigger, _ := inputs["trigger"].(string)
+	if integration == "" || trigger == "" {
+		return flow.Errorf(flow.EINVALID, "An integration and trigger are required.")
+	}
+	topic := flow.TriggerTopic(integration, trigger)
+	wait.Topic = &topic
+
+	var conditions []*flow.Condition
+	if v, ok := inputs["condition"]; ok {
+		cond := &flow.Condition{}
+		if err := decodeCondition(v, cond); err != nil {
+			return err
+		}
+		conditions = append(conditions, cond)
+	}
+	if v, ok := inputs["match_path"]; ok {
+		value, ok := inputs["match_value"]
+		if !ok {
+			return flow.Errorf(flow.EINVALID, "A match value is required with a match path.")
+		}
+		conditions = append(conditions, &flow.Condition{Path: stringify(v), Operator: flow.OperatorEquals, Value: value})
+	}
+	switch len(conditions) {
+	case 0:
+	case 1:
+		wait.Condition = conditions[0]
+	default:
+		wait.Condition = &flow.Condition{And: conditions}
+	}
+	if wait.Condition != nil {
+		if err := wait.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := inputs["timeout"]; ok {
+		d, err := parseDuration(v)
+		if err != nil || d <= 0 {
+			return flow.Errorf(flow.EINVALID, "Timeout of wait-for-event node must be a positive duration.")
+		}
+		resumeAt := now.Add(d)
+		wait.ResumeAt = &resumeAt
+	}
+	return nil
+}
+
+// parseDuration parses a Go duration string or a number of seconds.
+func parseDuration(v interface{}) (time.Duration, error) {
+	s := stringify(v)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// waits returns the waits of a run keyed by node and loop item.
+func (e *Executor) waits(ctx context.Context, run *flow.Run) (map[string]*flow.Wait, error) {
+	m := make(map[string]*flow.Wait)
+	if e.WaitService == nil {
+		return m, nil
+	}
+
+	waits, _, err := e.WaitService.GetWaits(ctx, flow.WaitFilter{RunID: &run.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, wait := range waits {
+		m[checkpointKey(wait.NodeID, wait.Item)] = wait
+	}
+	return m, nil
+}
+
+// waitOutput returns the output of a node whose wait has been resolved.
+func waitOutput(wait *flow.Wait) (map[string]interface{}, error) {
+	if wait.Topic == nil {
+		return map[string]interface{}{"until": wait.ResumeAt.UTC().Format(time.RFC3339)}, nil
+	}
+
+	var event interface{}
+	if len(wait.Event) > 0 {
+		if err := json.Unmarshal(wait.Event, &event); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{"event": event, "timed_out": wait.TimedOut}, nil
+}
@@ -0,0 +1,330 @@
+// Package file implements services backed by files on disk.
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/openmesh/flow"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultReloadInterval is the interval at which the directory of integration
+// definitions is checked for changes.
+const DefaultReloadInterval = 2 * time.Second
+
+// IntegrationService serves integration definitions loaded from the YAML &
+// JSON files of a directory, one integration per file, along with the
+// integrations built into the application. Definitions are validated against
+// the format of integrations/schema.json when they are loaded, and are
+// reloaded whenever a file of the directory changes.
+//
+// Files may define earlier versions of an integration so that nodes pinned to
+// them keep working and can be checked & upgraded. The highest version of each
+// integration is its current version.
+type IntegrationService struct {
+	// Directory the definitions are loaded from.
+	Dir string
+
+	// Serves the integrations implemented by the application itself, such
+	// as the core integration. Definitions cannot use their keys.
+	Builtin flow.IntegrationService
+
+	Logger log.Logger
+
+	// Interval at which the directory is checked for changes.
+	ReloadInterval time.Duration
+
+	mu       sync.RWMutex
+	apps     []*flow.Integration                  // current versions
+	versions map[string]map[int]*flow.Integration // every version by key
+	snapshot string                               // names, sizes & modification times of the loaded files
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewIntegrationService returns a new instance of IntegrationService that
+// loads definitions from dir.
+func NewIntegrationService(dir string) *IntegrationService {
+	return &IntegrationService{
+		Dir:            dir,
+		Logger:         log.NewNopLogger(),
+		ReloadInterval: DefaultReloadInterval,
+		done:           make(chan struct{}),
+	}
+}
+
+// Open loads the definitions and begins watching the directory for changes.
+// Returns an error describing every invalid definition if any fails to load.
+func (s *IntegrationService) Open() error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() { defer s.wg.Done(); s.watch() }()
+	return nil
+}
+
+// Close stops watching the directory for changes.
+func (s *IntegrationService) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return nil
+}
+
+// watch reloads the definitions whenever the files of the directory change.
+// Definitions that fail to load are reported and the previous definitions
+// are kept until the files are fixed.
+func (s *IntegrationService) watch() {
+	ticker := time.NewTicker(s.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			snapshot, err := s.snapshotDir()
+			if err != nil {
+				_ = s.Logger.Log("msg", "cannot read integrations", "dir", s.Dir, "err", err)
+				continue
+			}
+			s.mu.RLock()
+			changed := snapshot != s.snapshot
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := s.Reload(); err != nil {
+				_ = s.Logger.Log("msg", "cannot reload integrations", "dir", s.Dir, "err", err)
+				// Invalid files are reported once rather than on
+				// every tick.
+				s.mu.Lock()
+				s.snapshot = snapshot
+				s.mu.Unlock()
+				continue
+			}
+			_ = s.Logger.Log("msg", "reloaded integrations", "dir", s.Dir)
+		}
+	}
+}
+
+// Reload loads the definitions of the directory. The loaded definitions only
+// replace the current ones if every definition is valid.
+func (s *IntegrationService) Reload() error {
+	snapshot, err := s.snapshotDir()
+	if err != nil {
+		return err
+	}
+	apps, versions, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.apps, s.versions, s.snapshot = apps, versions, snapshot
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *IntegrationService) GetIntegrations(ctx context.Context, req flow.GetIntegrationsRequest) ([]*flow.Integration, int, error) {
+	var apps []*flow.Integration
+	if s.Builtin != nil {
+		builtin, _, err := s.Builtin.GetIntegrations(ctx, flow.GetIntegrationsRequest{})
+		if err != nil {
+			return nil, 0, err
+		}
+		apps = append(apps, builtin...)
+	}
+
+	s.mu.RLock()
+	apps = append(apps, s.apps...)
+	s.mu.RUnlock()
+
+	matches, total := flow.FilterIntegrations(apps, req)
+	return matches, total, nil
+}
+
+func (s *IntegrationService) GetIntegrationByKey(ctx context.Context, key string) (*flow.Integration, error) {
+	if s.Builtin != nil {
+		if app, err := s.Builtin.GetIntegrationByKey(ctx, key); err == nil {
+			return app, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, app := range s.apps {
+		if app.Key == key {
+			return app, nil
+		}
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Integration '%s' not found.", key)
+}
+
+func (s *IntegrationService) GetIntegrationVersion(ctx context.Context, key string, version int) (*flow.Integration, error) {
+	if s.Builtin != nil {
+		if app, err := s.Builtin.GetIntegrationVersion(ctx, key, version); err == nil {
+			return app, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if app := s.versions[key][version]; app != nil {
+		return app, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Version %d of integration '%s' not found.", version, key)
+}
+
+// load reads & validates every definition of the directory. Returns the
+// current version of each integration along with every version by key.
+// Returns an error listing each file that fails to load.
+func (s *IntegrationService) load() ([]*flow.Integration, map[string]map[int]*flow.Integration, error) {
+	names, err := s.files()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	builtin := make(map[string]bool)
+	if s.Builtin != nil {
+		apps, _, err := s.Builtin.GetIntegrations(context.Background(), flow.GetIntegrationsRequest{})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, app := range apps {
+			builtin[app.Key] = true
+		}
+	}
+
+	var keys []string
+	var errs []string
+	versions := make(map[string]map[int]*flow.Integration)
+	defined := make(map[string]string) // file defining each key & version
+	for _, name := range names {
+		app, err := readDefinition(filepath.Join(s.Dir, name))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		id := fmt.Sprintf("%s@%d", app.Key, app.Version)
+		switch {
+		case builtin[app.Key]:
+			errs = append(errs, fmt.Sprintf("%s: key: '%s' is the key of a built-in integration.", name, app.Key))
+		case defined[id] != "":
+			errs = append(errs, fmt.Sprintf("%s: version: %d of '%s' is already defined by %s.", name, app.Version, app.Key, defined[id]))
+		default:
+			defined[id] = name
+			if versions[app.Key] == nil {
+				versions[app.Key] = make(map[int]*flow.Integration)
+				keys = append(keys, app.Key)
+			}
+			versions[app.Key][app.Version] = app
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Invalid integration definitions:\n%s", strings.Join(errs, "\n"))
+	}
+
+	apps := make([]*flow.Integration, 0, len(keys))
+	for _, key := range keys {
+		var current *flow.Integration
+		for _, app := range versions[key] {
+			if current == nil || app.Version > current.Version {
+				current = app
+			}
+		}
+		apps = append(apps, current)
+	}
+	return apps, versions, nil
+}
+
+// readDefinition reads & validates the definition of a file. Fields that are
+// not part of the format are rejected so that misspelt fields are reported.
+func readDefinition(path string) (*flow.Integration, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if buf, err = yaml.YAMLToJSON(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	var app flow.Integration
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&app); err != nil {
+		return nil, err
+	} else if dec.More() {
+		return nil, fmt.Errorf("only one integration may be defined per file")
+	}
+	if err := app.Validate(); err != nil {
+		return nil, fmt.Errorf("%s", flow.ErrorMessage(err))
+	}
+	if app.Version == 0 {
+		app.Version = flow.DefaultIntegrationVersion
+	}
+	return &app, nil
+}
+
+// files returns the names of the definition files of the directory, sorted by
+// name. Other files such as the schema are ignored.
+func (s *IntegrationService) files() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == "schema.json" {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// snapshotDir returns a summary of the definition files of the directory
+// that changes whenever a file is added, removed or modified.
+func (s *IntegrationService) snapshotDir() (string, error) {
+	names, err := s.files()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, name := range names {
+		fi, err := os.Stat(filepath.Join(s.Dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d\n", name, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
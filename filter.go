@@ -0,0 +1,50 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FilterDecision records whether a trigger event matched the filter of a
+// workflow's trigger node. Decisions are kept so that users can see why an
+// event did or did not start a run.
+type FilterDecision struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+	NodeID     uuid.UUID `json:"node_id" db:"node_id"`
+
+	// Topic of the event & the payload the filter was evaluated against.
+	Topic   string          `json:"topic" db:"topic"`
+	Payload json.RawMessage `json:"payload" db:"payload"`
+
+	// Whether the event matched. Events whose filter failed to evaluate do
+	// not match and carry the error.
+	Matched bool    `json:"matched" db:"matched"`
+	Error   *string `json:"error" db:"error"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// FilterDecisionService represents a service for recording filter decisions.
+type FilterDecisionService interface {
+	// Retrieves a list of decisions by filter, most recent first. Also
+	// returns the total count of matching decisions which may differ from
+	// the returned results if filter.Limit is specified.
+	GetFilterDecisions(ctx context.Context, filter FilterDecisionFilter) ([]*FilterDecision, int, error)
+
+	// Records a decision. On success, the decision.ID is set to the new ID.
+	CreateFilterDecision(ctx context.Context, decision *FilterDecision) error
+}
+
+// FilterDecisionFilter represents a filter passed to GetFilterDecisions().
+type FilterDecisionFilter struct {
+	WorkflowID *uuid.UUID `json:"workflow_id"`
+	NodeID     *uuid.UUID `json:"node_id"`
+	Matched    *bool      `json:"matched"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
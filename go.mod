@@ -3,6 +3,7 @@ module github.com/openmesh/flow
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/Microsoft/go-winio v0.4.16 // indirect
 	github.com/containerd/containerd v1.4.4 // indirect
 	github.com/docker/distribution v2.7.1+incompatible // indirect
@@ -16,6 +17,7 @@ require (
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/securecookie v1.1.1
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/jmoiron/sqlx v1.3.1
 	github.com/lib/pq v1.2.0
 	github.com/moby/term v0.0.0-20201216013528-df9cb8a40635 // indirect
@@ -23,5 +25,9 @@ require (
 	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/opencontainers/image-spec v1.0.1 // indirect
 	github.com/prometheus/client_golang v1.3.0
+	github.com/robfig/cron/v3 v3.0.1
+	go.starlark.net v0.0.0-20210223155950-e043a3d3c984
+	gopkg.in/yaml.v2 v2.2.8
 	gotest.tools/v3 v3.0.3 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
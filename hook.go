@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Hook modes.
+const (
+	// Deliveries are accepted immediately and the run executes in the
+	// background.
+	HookModeAsync = "async"
+
+	// Deliveries block until the run finishes and respond with the output of
+	// the hook's respond node.
+	HookModeSync = "sync"
+)
+
+// Hook represents a unique webhook URL for a workflow's trigger node.
+// Deliveries to the hook's URL only start runs of its workflow.
+type Hook struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+	NodeID     uuid.UUID `json:"node_id" db:"node_id"`
+
+	// Unguessable token that makes up the hook's URL. Rotating the hook
+	// replaces the token and invalidates the previous URL.
+	Token string `json:"token" db:"token"`
+
+	// One of the HookMode constants. Sync hooks respond with the output of
+	// the node referenced by RespondNodeID.
+	Mode          string     `json:"mode" db:"mode"`
+	RespondNodeID *uuid.UUID `json:"respond_node_id" db:"respond_node_id"`
+
+	// Timestamps of creation & last update.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Validate returns an error if the hook contains invalid fields.
+func (h *Hook) Validate() error {
+	switch h.Mode {
+	case HookModeAsync:
+	case HookModeSync:
+		if h.RespondNodeID == nil {
+			return Errorf(EINVALID, "Sync hooks require a respond node.")
+		}
+	default:
+		return Errorf(EINVALID, "Invalid hook mode '%s'.", h.Mode)
+	}
+	return nil
+}
+
+// HookService represents a service for managing workflow hooks.
+type HookService interface {
+	// Retrieves a hook by its token. The token is the credential for the hook
+	// so no user is required. Returns ENOTFOUND if no hook has the token.
+	GetHookByToken(ctx context.Context, token string) (*Hook, error)
+
+	// Retrieves a list of hooks by filter. Also returns the total count of
+	// matching hooks which may differ from returned results if filter.Limit is
+	// specified.
+	GetHooks(ctx context.Context, filter HookFilter) ([]*Hook, int, error)
+
+	// Creates a new hook for a trigger node and generates its token. Returns
+	// EUNAUTHORIZED if the current user does not own the workflow.
+	CreateHook(ctx context.Context, hook *Hook) error
+
+	// Updates the mode of a workflow's hook. Returns ENOTFOUND if the hook
+	// does not belong to the workflow or the current user does not own it.
+	UpdateHook(ctx context.Context, workflowID, id uuid.UUID, upd HookUpdate) (*Hook, error)
+
+	// Replaces the token of a workflow's hook. Returns ENOTFOUND if the hook
+	// does not belong to the workflow or the current user does not own it.
+	RotateHook(ctx context.Context, workflowID, id uuid.UUID) (*Hook, error)
+
+	// Permanently deletes a workflow's hook. Returns ENOTFOUND if the hook
+	// does not belong to the workflow or the current user does not own it.
+	DeleteHook(ctx context.Context, workflowID, id uuid.UUID) error
+}
+
+// HookUpdate represents a set of fields to be updated via UpdateHook().
+type HookUpdate struct {
+	Mode          *string    `json:"mode"`
+	RespondNodeID *uuid.UUID `json:"respond_node_id"`
+}
+
+// HookFilter represents a filter passed to GetHooks().
+type HookFilter struct {
+	ID         *uuid.UUID `json:"id"`
+	WorkflowID *uuid.UUID `json:"workflow_id"`
+	NodeID     *uuid.UUID `json:"node_id"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
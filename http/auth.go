@@ -85,7 +85,7 @@ func makeEncodeSignUpResponse(sc *securecookie.SecureCookie) func(context.Contex
 		if err != nil {
 			val := err.Error()
 			fmt.Println(val)
-			encodeError(ctx, flow.Errorf(flow.EINTERNAL, "failed to encode cookie: %w", err), w)
+			encodeError(ctx, flow.Errorf(flow.EINTERNAL, "failed to encode cookie: %v", err), w)
 			return nil
 		}
 
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+	"net/http"
+)
+
+// addWorkflowCompatibilityRoutes adds the route that checks the nodes of a
+// workflow against the current versions of their integrations.
+func (s *Server) addWorkflowCompatibilityRoutes(r *mux.Router, opts []kithttp.ServerOption) {
+	checkWorkflowHandler := kithttp.NewServer(
+		makeCheckWorkflowEndpoint(s.CompatibilityService),
+		decodeCheckWorkflowRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r.Handle("/v1/workflows/{id}/compatibility", s.authenticate(checkWorkflowHandler)).Methods("GET")
+}
+
+// addNodeCompatibilityRoutes adds the route that upgrades a node to the
+// current version of its integration.
+func (s *Server) addNodeCompatibilityRoutes(r *mux.Router, opts []kithttp.ServerOption) {
+	upgradeNodeHandler := kithttp.NewServer(
+		makeUpgradeNodeEndpoint(s.CompatibilityService),
+		decodeUpgradeNodeRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r.Handle("/v1/nodes/{id}/upgrade", s.authenticate(upgradeNodeHandler)).Methods("POST")
+}
+
+////////////////////
+// Check workflow //
+////////////////////
+
+type checkWorkflowRequest struct {
+	WorkflowID uuid.UUID
+}
+
+type checkWorkflowResponse struct {
+	Data       []flow.Incompatibility `json:"data"`
+	TotalItems int                    `json:"total_items"`
+}
+
+// makeCheckWorkflowEndpoint returns an endpoint that calls CheckNodes on a flow.CompatibilityService for the nodes of
+// a workflow.
+func makeCheckWorkflowEndpoint(s flow.CompatibilityService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(checkWorkflowRequest)
+		incompatibilities, err := s.CheckNodes(ctx, flow.NodeFilter{WorkflowID: &req.WorkflowID})
+		if err != nil {
+			return nil, err
+		}
+
+		return checkWorkflowResponse{
+			Data:       incompatibilities,
+			TotalItems: len(incompatibilities),
+		}, nil
+	}
+}
+
+// decodeCheckWorkflowRequest takes a http.Request and converts it into a checkWorkflowRequest. It returns an error if
+// the ID cannot be parsed.
+func decodeCheckWorkflowRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req checkWorkflowRequest
+	var err error
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+//////////////////
+// Upgrade node //
+//////////////////
+
+type upgradeNodeRequest struct {
+	ID uuid.UUID
+}
+
+// makeUpgradeNodeEndpoint returns an endpoint that calls UpgradeNode on a flow.CompatibilityService.
+func makeUpgradeNodeEndpoint(s flow.CompatibilityService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(upgradeNodeRequest)
+		return s.UpgradeNode(ctx, req.ID)
+	}
+}
+
+// decodeUpgradeNodeRequest takes a http.Request and converts it into an upgradeNodeRequest. It returns an error if
+// the ID cannot be parsed.
+func decodeUpgradeNodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req upgradeNodeRequest
+	var err error
+
+	req.ID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
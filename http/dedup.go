@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// deduplicator ensures that retried webhook deliveries only trigger runs once.
+// Retries are answered with the response of the original delivery.
+type deduplicator struct {
+	integrations flow.IntegrationService
+	deliveries   flow.DeliveryService
+
+	// Returns the current time. Can be mocked for tests.
+	now func() time.Time
+}
+
+func newDeduplicator(integrations flow.IntegrationService, deliveries flow.DeliveryService) *deduplicator {
+	return &deduplicator{
+		integrations: integrations,
+		deliveries:   deliveries,
+		now:          time.Now,
+	}
+}
+
+// trigger returns the idempotency key of an integration's trigger. Returns nil
+// if the trigger is unknown or does not deduplicate deliveries.
+func (d *deduplicator) trigger(ctx context.Context, integration, trigger string) *flow.IdempotencyKey {
+	if d == nil || d.deliveries == nil {
+		return nil
+	}
+	i, err := d.integrations.GetIntegrationByKey(ctx, integration)
+	if err != nil {
+		return nil
+	}
+	t, err := i.GetTrigger(trigger)
+	if err != nil {
+		return nil
+	}
+	return t.Idempotency
+}
+
+// do calls fn unless the delivery has been received before within the key's
+// TTL, in which case the original response is returned as a json.RawMessage.
+// Returns ECONFLICT if the original delivery is still being processed. A
+// delivery whose processing does not finish within flow.DeliveryLease is
+// processed again when retried. The scope separates keys of different
+// webhooks.
+func (d *deduplicator) do(ctx context.Context, scope string, key *flow.IdempotencyKey, payload *flow.WebhookPayload, fn func() (interface{}, error)) (interface{}, error) {
+	if key == nil {
+		return fn()
+	}
+
+	id, ok, err := key.Extract(payload)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return fn()
+	}
+
+	now := d.now()
+	lockedUntil := now.Add(flow.DeliveryLease)
+	delivery := flow.Delivery{
+		Key:         scope + ":" + id,
+		LockedUntil: &lockedUntil,
+		ExpiresAt:   now.Add(key.TTL()),
+	}
+	if err := d.deliveries.CreateDelivery(ctx, &delivery); flow.ErrorCode(err) == flow.ECONFLICT {
+		return d.replay(ctx, delivery.Key)
+	} else if err != nil {
+		return nil, err
+	}
+
+	response, err := fn()
+	if err != nil {
+		// Forget the delivery so that a retry is processed again.
+		_ = d.deliveries.DeleteDelivery(ctx, delivery.Key)
+		return nil, err
+	}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.deliveries.UpdateDelivery(ctx, delivery.Key, buf); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// replay returns the response of a delivery that has already been received.
+func (d *deduplicator) replay(ctx context.Context, key string) (interface{}, error) {
+	original, err := d.deliveries.GetDeliveryByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if original.Response == nil {
+		return nil, flow.Errorf(flow.ECONFLICT, "Delivery is still being processed.")
+	}
+	return *original.Response, nil
+}
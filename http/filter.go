@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+)
+
+// addFilterDecisionRoutes registers the endpoint for listing the filter
+// decisions of a workflow on the workflow router.
+func (s *Server) addFilterDecisionRoutes(r *mux.Router, opts []kithttp.ServerOption) {
+	getFilterDecisionsHandler := kithttp.NewServer(
+		makeGetFilterDecisionsEndpoint(s.FilterDecisionService),
+		decodeGetFilterDecisionsRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r.Handle("/v1/workflows/{id}/filter-decisions", s.authenticate(getFilterDecisionsHandler)).Methods("GET")
+}
+
+//////////////////////////
+// Get filter decisions //
+//////////////////////////
+
+type getFilterDecisionsRequest struct {
+	WorkflowID uuid.UUID
+	NodeID     *uuid.UUID
+	Matched    *bool
+	Page       int
+	Limit      int
+}
+
+type getFilterDecisionsResponse struct {
+	Data       []*flow.FilterDecision `json:"data"`
+	TotalItems int                    `json:"total_items"`
+}
+
+// makeGetFilterDecisionsEndpoint returns an endpoint that calls GetFilterDecisions on a flow.FilterDecisionService.
+func makeGetFilterDecisionsEndpoint(s flow.FilterDecisionService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getFilterDecisionsRequest)
+		decisions, total, err := s.GetFilterDecisions(ctx, flow.FilterDecisionFilter{
+			WorkflowID: &req.WorkflowID,
+			NodeID:     req.NodeID,
+			Matched:    req.Matched,
+			Page:       req.Page,
+			Limit:      req.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return getFilterDecisionsResponse{
+			Data:       decisions,
+			TotalItems: total,
+		}, nil
+	}
+}
+
+func decodeGetFilterDecisionsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getFilterDecisionsRequest
+	var err error
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.URL.Query()
+	if val := q.Get("node_id"); val != "" {
+		id, err := uuid.Parse(val)
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'node_id'.")
+		}
+		req.NodeID = &id
+	}
+	if val := q.Get("matched"); val != "" {
+		matched, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'matched'.")
+		}
+		req.Matched = &matched
+	}
+	if val := q.Get("page"); val != "" {
+		if req.Page, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'page'.")
+		}
+	}
+	if val := q.Get("limit"); val != "" {
+		if req.Limit, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'limit'.")
+		}
+	}
+
+	return req, nil
+}
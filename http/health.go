@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HealthServer serves the liveness & metrics of processes that do not serve
+// the API, such as workers.
+type HealthServer struct {
+	ln     net.Listener
+	server *http.Server
+
+	// Bind address for the server's listener.
+	Addr string
+
+	// Returns an error if the process is not healthy.
+	Check func() error
+}
+
+// NewHealthServer returns a new instance of HealthServer.
+func NewHealthServer() *HealthServer {
+	s := &HealthServer{server: &http.Server{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/metrics", promhttp.Handler())
+	s.server.Handler = mux
+
+	return s
+}
+
+// Open begins serving on the bind address in the background.
+func (s *HealthServer) Open() (err error) {
+	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
+		return err
+	}
+	go s.server.Serve(s.ln)
+	return nil
+}
+
+// Close gracefully shuts down the server.
+func (s *HealthServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+func (s *HealthServer) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	if s.Check != nil {
+		if err := s.Check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	_, _ = w.Write([]byte("Healthy"))
+}
@@ -0,0 +1,406 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) makeHookHandler() http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	ingestHookHandler := kithttp.NewServer(
+		makeIngestHookEndpoint(s.HookService, s.WorkflowService, s.Executor, newDeduplicator(s.IntegrationService, s.DeliveryService)),
+		decodeIngestHookRequest,
+		encodeIngestHookResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/v1/hooks/{token}", limitBody(s.maxBodySize(), ingestHookHandler)).Methods("POST")
+
+	return r
+}
+
+// addHookRoutes registers the endpoints for managing a workflow's hooks on the
+// workflow router.
+func (s *Server) addHookRoutes(r *mux.Router, opts []kithttp.ServerOption) {
+	createHookHandler := kithttp.NewServer(
+		makeCreateHookEndpoint(s.HookService),
+		decodeCreateHookRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getHooksHandler := kithttp.NewServer(
+		makeGetHooksEndpoint(s.HookService),
+		decodeGetHooksRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	updateHookHandler := kithttp.NewServer(
+		makeUpdateHookEndpoint(s.HookService),
+		decodeUpdateHookRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	rotateHookHandler := kithttp.NewServer(
+		makeRotateHookEndpoint(s.HookService),
+		decodeHookIDRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	deleteHookHandler := kithttp.NewServer(
+		makeDeleteHookEndpoint(s.HookService),
+		decodeHookIDRequest,
+		encodeEmptyResponse,
+		opts...,
+	)
+
+	r.Handle("/v1/workflows/{id}/hooks", s.authenticate(createHookHandler)).Methods("POST")
+	r.Handle("/v1/workflows/{id}/hooks", s.authenticate(getHooksHandler)).Methods("GET")
+	r.Handle("/v1/workflows/{id}/hooks/{hook_id}", s.authenticate(updateHookHandler)).Methods("PUT")
+	r.Handle("/v1/workflows/{id}/hooks/{hook_id}", s.authenticate(deleteHookHandler)).Methods("DELETE")
+	r.Handle("/v1/workflows/{id}/hooks/{hook_id}/rotate", s.authenticate(rotateHookHandler)).Methods("POST")
+}
+
+/////////////////
+// Ingest hook //
+/////////////////
+
+type ingestHookRequest struct {
+	Token   string
+	Payload *flow.WebhookPayload
+}
+
+// ingestHookResponse is returned by sync hooks. It carries the finished run
+// and the hook's respond node.
+type ingestHookResponse struct {
+	Run           *flow.Run `json:"run"`
+	RespondNodeID uuid.UUID `json:"respond_node_id"`
+}
+
+// makeIngestHookEndpoint returns an endpoint that starts a run of the workflow
+// that owns the hook. Sync hooks wait for the run to finish. Retried
+// deliveries only start one run if the hook's trigger defines an idempotency
+// key.
+func makeIngestHookEndpoint(hooks flow.HookService, workflows flow.WorkflowService, executor flow.Executor, dedup *deduplicator) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ingestHookRequest)
+
+		hook, err := hooks.GetHookByToken(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := hookIdempotencyKey(ctx, workflows, dedup, hook)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := dedup.do(ctx, "hook:"+hook.ID.String(), key, req.Payload, func() (interface{}, error) {
+			return ingestHook(ctx, executor, hook, req.Payload)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Replayed responses of sync hooks are decoded so that the respond
+		// node's output is written again.
+		if raw, ok := resp.(json.RawMessage); ok && hook.Mode == flow.HookModeSync {
+			var replay ingestHookResponse
+			if err := json.Unmarshal(raw, &replay); err != nil {
+				return nil, err
+			}
+			return replay, nil
+		}
+		return resp, nil
+	}
+}
+
+// ingestHook starts a run for a hook delivery.
+func ingestHook(ctx context.Context, executor flow.Executor, hook *flow.Hook, payload *flow.WebhookPayload) (interface{}, error) {
+	runReq := flow.RunRequest{
+		WorkflowID:    hook.WorkflowID,
+		TriggerNodeID: hook.NodeID,
+		Payload:       payload,
+	}
+
+	if hook.Mode != flow.HookModeSync {
+		run, err := executor.StartRun(ctx, runReq)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "success", "run_id": run.ID}, nil
+	}
+
+	run, err := executor.ExecuteRun(ctx, runReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ingestHookResponse{Run: run}
+	if hook.RespondNodeID != nil {
+		resp.RespondNodeID = *hook.RespondNodeID
+	}
+	return resp, nil
+}
+
+// hookIdempotencyKey returns the idempotency key of the trigger node a hook
+// starts runs from.
+func hookIdempotencyKey(ctx context.Context, workflows flow.WorkflowService, dedup *deduplicator, hook *flow.Hook) (*flow.IdempotencyKey, error) {
+	if dedup == nil || dedup.deliveries == nil {
+		return nil, nil
+	}
+
+	wf, err := workflows.GetWorkflowByID(flow.NewSystemContext(ctx), hook.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	node := wf.GetNode(hook.NodeID)
+	if node == nil {
+		return nil, nil
+	}
+	return dedup.trigger(ctx, node.Integration, node.Action), nil
+}
+
+func decodeIngestHookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	payload, err := decodeWebhookPayload(r)
+	if err != nil {
+		return nil, err
+	}
+	return ingestHookRequest{Token: mux.Vars(r)["token"], Payload: payload}, nil
+}
+
+// encodeIngestHookResponse writes the output of a sync hook's respond node as
+// the HTTP response. The node's "status", "headers" and "body" inputs set the
+// status code, headers and JSON body respectively.
+func encodeIngestHookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(ingestHookResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+
+	if resp.Run.Status == flow.RunStatusFailed {
+		encodeError(ctx, flow.Errorf(flow.EINTERNAL, "Run %s failed.", resp.Run.ID), w)
+		return nil
+	}
+
+	nodeRun := resp.Run.NodeRun(resp.RespondNodeID)
+	if nodeRun == nil || nodeRun.Status != flow.NodeRunStatusSucceeded {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	var output struct {
+		Status  interface{}            `json:"status"`
+		Headers map[string]interface{} `json:"headers"`
+		Body    interface{}            `json:"body"`
+	}
+	if err := json.Unmarshal(nodeRun.Output, &output); err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if output.Status != nil {
+		code, err := strconv.Atoi(fmt.Sprint(output.Status))
+		if err != nil || code < 100 || code > 599 {
+			encodeError(ctx, flow.Errorf(flow.EINVALID, "Respond node returned an invalid status code."), w)
+			return nil
+		}
+		status = code
+	}
+
+	for k, v := range output.Headers {
+		w.Header().Set(k, fmt.Sprint(v))
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(output.Body)
+}
+
+/////////////////
+// Create hook //
+/////////////////
+
+type createHookRequest struct {
+	WorkflowID    uuid.UUID  `json:"-"`
+	NodeID        uuid.UUID  `json:"node_id"`
+	Mode          string     `json:"mode"`
+	RespondNodeID *uuid.UUID `json:"respond_node_id"`
+}
+
+// makeCreateHookEndpoint returns an endpoint that calls CreateHook on a flow.HookService.
+func makeCreateHookEndpoint(s flow.HookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createHookRequest)
+		hook := flow.Hook{
+			WorkflowID:    req.WorkflowID,
+			NodeID:        req.NodeID,
+			Mode:          req.Mode,
+			RespondNodeID: req.RespondNodeID,
+		}
+		err := s.CreateHook(ctx, &hook)
+		return hook, err
+	}
+}
+
+// decodeCreateHookRequest takes a http.Request and converts it into a createHookRequest. It returns an error if the
+// JSON body cannot be encoded or the workflow ID cannot be parsed.
+func decodeCreateHookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req createHookRequest
+	var err error
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, flow.Errorf(flow.EINVALID, "Failed to encode JSON body.")
+	}
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+///////////////
+// Get hooks //
+///////////////
+
+type getHooksRequest struct {
+	WorkflowID uuid.UUID
+}
+
+type getHooksResponse struct {
+	Data       []*flow.Hook `json:"data"`
+	TotalItems int          `json:"total_items"`
+}
+
+// makeGetHooksEndpoint returns an endpoint that calls GetHooks on a flow.HookService.
+func makeGetHooksEndpoint(s flow.HookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getHooksRequest)
+		hooks, total, err := s.GetHooks(ctx, flow.HookFilter{WorkflowID: &req.WorkflowID})
+		if err != nil {
+			return nil, err
+		}
+
+		return getHooksResponse{
+			Data:       hooks,
+			TotalItems: total,
+		}, nil
+	}
+}
+
+func decodeGetHooksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getHooksRequest
+	var err error
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+/////////////////
+// Update hook //
+/////////////////
+
+type updateHookRequest struct {
+	WorkflowID    uuid.UUID  `json:"-"`
+	ID            uuid.UUID  `json:"-"`
+	Mode          *string    `json:"mode"`
+	RespondNodeID *uuid.UUID `json:"respond_node_id"`
+}
+
+// makeUpdateHookEndpoint returns an endpoint that calls UpdateHook on a flow.HookService.
+func makeUpdateHookEndpoint(s flow.HookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateHookRequest)
+		upd := flow.HookUpdate{
+			Mode:          req.Mode,
+			RespondNodeID: req.RespondNodeID,
+		}
+		return s.UpdateHook(ctx, req.WorkflowID, req.ID, upd)
+	}
+}
+
+func decodeUpdateHookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req updateHookRequest
+	var err error
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, flow.Errorf(flow.EINVALID, "Failed to encode JSON body.")
+	}
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	req.ID, err = uuidFromVar(r, "hook_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+////////////////////////
+// Rotate/delete hook //
+////////////////////////
+
+type hookIDRequest struct {
+	WorkflowID uuid.UUID
+	ID         uuid.UUID
+}
+
+// makeRotateHookEndpoint returns an endpoint that calls RotateHook on a flow.HookService.
+func makeRotateHookEndpoint(s flow.HookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(hookIDRequest)
+		return s.RotateHook(ctx, req.WorkflowID, req.ID)
+	}
+}
+
+// makeDeleteHookEndpoint returns an endpoint that calls DeleteHook on a flow.HookService.
+func makeDeleteHookEndpoint(s flow.HookService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(hookIDRequest)
+		return nil, s.DeleteHook(ctx, req.WorkflowID, req.ID)
+	}
+}
+
+func decodeHookIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req hookIDRequest
+	var err error
+
+	req.WorkflowID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+	req.ID, err = uuidFromVar(r, "hook_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
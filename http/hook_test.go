@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+)
+
+func TestDecodeHookRequests(t *testing.T) {
+	workflowID, hookID := uuid.New(), uuid.New()
+	vars := map[string]string{"id": workflowID.String(), "hook_id": hookID.String()}
+
+	r := mux.SetURLVars(httptest.NewRequest("POST", "/", nil), vars)
+	req, err := decodeHookIDRequest(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := req.(hookIDRequest); got.WorkflowID != workflowID || got.ID != hookID {
+		t.Fatalf("request = %+v, want workflow %s & hook %s", got, workflowID, hookID)
+	}
+
+	r = mux.SetURLVars(httptest.NewRequest("PUT", "/", strings.NewReader(`{"mode": "sync"}`)), vars)
+	req, err = decodeUpdateHookRequest(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := req.(updateHookRequest); got.WorkflowID != workflowID || got.ID != hookID {
+		t.Fatalf("request = %+v, want workflow %s & hook %s", got, workflowID, hookID)
+	}
+
+	r = mux.SetURLVars(httptest.NewRequest("POST", "/", nil), map[string]string{"id": "workflow", "hook_id": hookID.String()})
+	if _, err := decodeHookIDRequest(context.Background(), r); flow.ErrorCode(err) != flow.EINVALID {
+		t.Fatalf("error code = %q, want %q (err: %v)", flow.ErrorCode(err), flow.EINVALID, err)
+	}
+}
@@ -23,6 +23,9 @@ type Session struct {
 // ErrorResponse represents a JSON structure for error output.
 type ErrorResponse struct {
 	Error string `json:"error"`
+
+	// Errors of the individual fields of invalid input, if any.
+	Fields []flow.FieldError `json:"fields,omitempty"`
 }
 
 // encodeError prints & optionally logs an error message.
@@ -42,7 +45,7 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	// Print user message to response.
 	w.Header().Set("Content-type", "application/json")
 	w.WriteHeader(ErrorStatusCode(code))
-	_ = json.NewEncoder(w).Encode(&ErrorResponse{Error: message})
+	_ = json.NewEncoder(w).Encode(&ErrorResponse{Error: message, Fields: flow.ErrorFields(err)})
 }
 
 // errorer is implemented by all concrete response types that may contain
@@ -87,7 +90,10 @@ var codes = map[string]int{
 	flow.EINVALID:        http.StatusBadRequest,
 	flow.ENOTFOUND:       http.StatusNotFound,
 	flow.ENOTIMPLEMENTED: http.StatusNotImplemented,
+	flow.ETOOLARGE:       http.StatusRequestEntityTooLarge,
 	flow.EUNAUTHORIZED:   http.StatusUnauthorized,
+	flow.EUNAVAILABLE:    http.StatusServiceUnavailable,
+	flow.EUNSUPPORTED:    http.StatusUnsupportedMediaType,
 	flow.EINTERNAL:       http.StatusInternalServerError,
 }
 
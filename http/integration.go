@@ -2,30 +2,60 @@ package http
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/transport"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 	"github.com/openmesh/flow"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func (s *Server) makeIntegrationHandler() http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
 		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(populateIfNoneMatch),
 	}
 
 	getIntegrationsHandler := kithttp.NewServer(
 		makeGetIntegrationsEndpoint(s.IntegrationService),
 		decodeGetIntegrationsRequest,
-		encodeResponse,
+		encodeCacheableResponse,
+		opts...,
+	)
+
+	getIntegrationByKeyHandler := kithttp.NewServer(
+		makeGetIntegrationByKeyEndpoint(s.IntegrationService),
+		decodeGetIntegrationByKeyRequest,
+		encodeCacheableResponse,
+		opts...,
+	)
+
+	getIntegrationVersionHandler := kithttp.NewServer(
+		makeGetIntegrationVersionEndpoint(s.IntegrationService),
+		decodeGetIntegrationVersionRequest,
+		encodeCacheableResponse,
+		opts...,
+	)
+
+	getActionHandler := kithttp.NewServer(
+		makeGetActionEndpoint(s.IntegrationService),
+		decodeGetActionRequest,
+		encodeCacheableResponse,
 		opts...,
 	)
 
 	r := mux.NewRouter()
 
 	r.Handle("/v1/integrations", getIntegrationsHandler).Methods("GET")
+	r.Handle("/v1/integrations/{key}", getIntegrationByKeyHandler).Methods("GET")
+	r.Handle("/v1/integrations/{key}/versions/{version}", getIntegrationVersionHandler).Methods("GET")
+	r.Handle("/v1/integrations/{key}/actions/{action}", getActionHandler).Methods("GET")
 
 	return r
 }
@@ -34,22 +64,196 @@ func (s *Server) makeIntegrationHandler() http.Handler {
 // Get integrations //
 //////////////////////
 
-type getIntegrationsRequest struct{}
+type getIntegrationsRequest struct {
+	Search     *string
+	Category   *string
+	AuthScheme *string
+	Page       int
+	Limit      int
+}
 
-func decodeGetIntegrationsRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	return getIntegrationsRequest{}, nil
+type getIntegrationsResponse struct {
+	Data       []*flow.Integration `json:"data"`
+	TotalItems int                 `json:"total_items"`
 }
 
+// makeGetIntegrationsEndpoint returns an endpoint that calls GetIntegrations on a flow.IntegrationService.
 func makeGetIntegrationsEndpoint(s flow.IntegrationService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		apps, total, err := s.GetIntegrations(ctx, flow.GetIntegrationsRequest{})
+		req := request.(getIntegrationsRequest)
+		apps, total, err := s.GetIntegrations(ctx, flow.GetIntegrationsRequest{
+			Search:     req.Search,
+			Category:   req.Category,
+			AuthScheme: req.AuthScheme,
+			Page:       req.Page,
+			Limit:      req.Limit,
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		return map[string]interface{}{
-			"data":        apps,
-			"total_items": total,
+		return getIntegrationsResponse{
+			Data:       apps,
+			TotalItems: total,
 		}, nil
 	}
 }
+
+// decodeGetIntegrationsRequest takes a http.Request and converts it into a getIntegrationsRequest. The "q" parameter
+// searches the labels & descriptions of integrations and "auth" filters them by auth scheme.
+func decodeGetIntegrationsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getIntegrationsRequest
+	var err error
+
+	q := r.URL.Query()
+	if val := q.Get("q"); val != "" {
+		req.Search = &val
+	}
+	if val := q.Get("category"); val != "" {
+		req.Category = &val
+	}
+	if val := q.Get("auth"); val != "" {
+		req.AuthScheme = &val
+	}
+	if val := q.Get("page"); val != "" {
+		if req.Page, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'page'.")
+		}
+	}
+	if val := q.Get("limit"); val != "" {
+		if req.Limit, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'limit'.")
+		}
+	}
+
+	return req, nil
+}
+
+////////////////////////////
+// Get integration by key //
+////////////////////////////
+
+type getIntegrationByKeyRequest struct {
+	Key string
+}
+
+// makeGetIntegrationByKeyEndpoint returns an endpoint that calls GetIntegrationByKey on a flow.IntegrationService.
+func makeGetIntegrationByKeyEndpoint(s flow.IntegrationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getIntegrationByKeyRequest)
+		return s.GetIntegrationByKey(ctx, req.Key)
+	}
+}
+
+// decodeGetIntegrationByKeyRequest takes a http.Request and converts it into a getIntegrationByKeyRequest.
+func decodeGetIntegrationByKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getIntegrationByKeyRequest{Key: mux.Vars(r)["key"]}, nil
+}
+
+/////////////////////////////
+// Get integration version //
+/////////////////////////////
+
+type getIntegrationVersionRequest struct {
+	Key     string
+	Version int
+}
+
+// makeGetIntegrationVersionEndpoint returns an endpoint that calls GetIntegrationVersion on a flow.IntegrationService.
+func makeGetIntegrationVersionEndpoint(s flow.IntegrationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getIntegrationVersionRequest)
+		return s.GetIntegrationVersion(ctx, req.Key, req.Version)
+	}
+}
+
+// decodeGetIntegrationVersionRequest takes a http.Request and converts it into a getIntegrationVersionRequest. It
+// returns an error if the version is not a number.
+func decodeGetIntegrationVersionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'version'.")
+	}
+	return getIntegrationVersionRequest{Key: vars["key"], Version: version}, nil
+}
+
+////////////////
+// Get action //
+////////////////
+
+type getActionRequest struct {
+	Key    string
+	Action string
+}
+
+// makeGetActionEndpoint returns an endpoint that looks up an action of an integration from a flow.IntegrationService.
+func makeGetActionEndpoint(s flow.IntegrationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getActionRequest)
+		app, err := s.GetIntegrationByKey(ctx, req.Key)
+		if err != nil {
+			return nil, err
+		}
+		return app.GetAction(req.Action)
+	}
+}
+
+// decodeGetActionRequest takes a http.Request and converts it into a getActionRequest.
+func decodeGetActionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return getActionRequest{Key: vars["key"], Action: vars["action"]}, nil
+}
+
+///////////
+// ETags //
+///////////
+
+type ifNoneMatchContextKey struct{}
+
+// populateIfNoneMatch adds the If-None-Match header of a request to its context
+// so that encodeCacheableResponse can compare it to the ETag of the response.
+func populateIfNoneMatch(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, ifNoneMatchContextKey{}, r.Header.Get("If-None-Match"))
+}
+
+// encodeCacheableResponse encodes a response like encodeResponse and tags it
+// with an ETag derived from its body. Responds with 304 Not Modified if the
+// request's If-None-Match header matches the ETag. Clients must revalidate
+// cached responses, as integration definitions may be reloaded at any time.
+func encodeCacheableResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(buf)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if ifNoneMatch, _ := ctx.Value(ifNoneMatchContextKey{}).(string); etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// etagMatches returns true if an If-None-Match header matches an ETag. Weak
+// comparison is used, as for GET requests.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
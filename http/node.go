@@ -11,7 +11,6 @@ import (
 	"github.com/openmesh/flow"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 func (s *Server) makeNodeHandler() http.Handler {
@@ -37,13 +36,13 @@ func (s *Server) makeNodeHandler() http.Handler {
 	deleteNodeHandler := kithttp.NewServer(
 		makeDeleteNodeEndpoint(s.NodeService),
 		decodeDeleteNodeRequest,
-		encodeResponse,
+		encodeEmptyResponse,
 		opts...,
 	)
 
 	getNodeByIDHandler := kithttp.NewServer(
 		makeGetNodeByIDEndpoint(s.NodeService),
-		decodeDeleteNodeRequest,
+		decodeGetNodeByIDRequest,
 		encodeResponse,
 		opts...,
 	)
@@ -63,6 +62,8 @@ func (s *Server) makeNodeHandler() http.Handler {
 	r.Handle("/v1/nodes/{id}", s.authenticate(getNodeByIDHandler)).Methods("GET")
 	r.Handle("/v1/nodes/", s.authenticate(getNodesHandler)).Methods("GET")
 
+	s.addNodeCompatibilityRoutes(r, opts)
+
 	return r
 }
 
@@ -71,13 +72,16 @@ func (s *Server) makeNodeHandler() http.Handler {
 /////////////////
 
 type createNodeRequest struct {
-	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
-	WorkflowID  uuid.UUID    `json:"workflow_id" db:"workflow_id"`
-	Integration string       `json:"integration" db:"integration"`
-	Action      string       `json:"action" db:"action"`
-	ParentIDs   []*uuid.UUID `json:"parent_ids" db:"-"`
-	ChildrenIDs []*uuid.UUID `json:"children_ids" db:"-"`
+	WorkflowID  uuid.UUID             `json:"workflow_id"`
+	Integration string                `json:"integration"`
+	Action      string                `json:"action"`
+	Params      []*createParamRequest `json:"params"`
+	ParentIDs   []*uuid.UUID          `json:"parent_ids"`
+	ChildrenIDs []*uuid.UUID          `json:"children_ids"`
+	EdgeLabels  map[uuid.UUID]string  `json:"edge_labels"`
+	Filter      *flow.Condition       `json:"filter"`
+	Retry       *flow.RetryPolicy     `json:"retry"`
+	Timeout     int                   `json:"timeout_seconds"`
 }
 
 type createParamRequest struct {
@@ -90,25 +94,41 @@ func makeCreateNodeEndpoint(s flow.NodeService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(createNodeRequest)
 		node := flow.Node{
+			WorkflowID:  req.WorkflowID,
 			Integration: req.Integration,
 			Action:      req.Action,
+			Params:      makeParams(req.Params),
 			ParentIDs:   req.ParentIDs,
 			ChildrenIDs: req.ChildrenIDs,
-		}
+			EdgeLabels:  req.EdgeLabels,
+			Filter:      req.Filter,
+			Retry:       req.Retry,
 
-		//for _, param := range req.Params {
-		//	node.Params = append(node.Params, &flow.Param{
-		//		Key:   param.Key,
-		//		Value: param.Value,
-		//		Type:  param.Type,
-		//	})
-		//}
+			TimeoutSeconds: req.Timeout,
+		}
 
 		err := s.CreateNode(ctx, &node)
 		return node, err
 	}
 }
 
+// makeParams converts requested params into node params. Returns nil if no
+// params are requested.
+func makeParams(reqs []*createParamRequest) []*flow.Param {
+	if reqs == nil {
+		return nil
+	}
+	params := make([]*flow.Param, 0, len(reqs))
+	for _, param := range reqs {
+		params = append(params, &flow.Param{
+			Key:   param.Key,
+			Value: param.Value,
+			Type:  param.Type,
+		})
+	}
+	return params
+}
+
 func decodeCreateNodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req createNodeRequest
 
@@ -119,17 +139,21 @@ func decodeCreateNodeRequest(_ context.Context, r *http.Request) (interface{}, e
 	return req, nil
 }
 
-/////////////////////
-// Update workflow //
-/////////////////////
+/////////////////
+// Update node //
+/////////////////
 
 type updateNodeRequest struct {
-	ID          uuid.UUID    `json:"id"`
-	WorkflowID  uuid.UUID    `json:"workflow_id"`
-	Integration string       `json:"integration"`
-	Action      string       `json:"action"`
-	ParentIDs   []*uuid.UUID `json:"parent_ids" db:"-"`
-	ChildrenIDs []*uuid.UUID `json:"children_ids" db:"-"`
+	ID          uuid.UUID             `json:"id"`
+	Integration string                `json:"integration"`
+	Action      string                `json:"action"`
+	Params      []*createParamRequest `json:"params"`
+	ParentIDs   []*uuid.UUID          `json:"parent_ids"`
+	ChildrenIDs []*uuid.UUID          `json:"children_ids"`
+	EdgeLabels  map[uuid.UUID]string  `json:"edge_labels"`
+	Filter      *flow.Condition       `json:"filter"`
+	Retry       *flow.RetryPolicy     `json:"retry"`
+	Timeout     *int                  `json:"timeout_seconds"`
 }
 
 // makeUpdateNodeEndpoint returns an endpoint that calls UpdateNode on a flow.NodeService.
@@ -139,8 +163,14 @@ func makeUpdateNodeEndpoint(s flow.NodeService) endpoint.Endpoint {
 		upd := flow.NodeUpdate{
 			Integration: req.Integration,
 			Action:      req.Action,
+			Params:      makeParams(req.Params),
 			ParentIDs:   req.ParentIDs,
 			ChildrenIDs: req.ChildrenIDs,
+			EdgeLabels:  req.EdgeLabels,
+			Filter:      req.Filter,
+			Retry:       req.Retry,
+
+			TimeoutSeconds: req.Timeout,
 		}
 		return s.UpdateNode(ctx, req.ID, upd)
 	}
@@ -176,7 +206,7 @@ type deleteNodeRequest struct {
 func makeDeleteNodeEndpoint(s flow.NodeService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(deleteNodeRequest)
-		return s.DeleteNode(ctx, req.ID), nil
+		return nil, s.DeleteNode(ctx, req.ID)
 	}
 }
 
@@ -229,7 +259,7 @@ func decodeGetNodeByIDRequest(_ context.Context, r *http.Request) (interface{},
 ///////////////
 
 type getNodesRequest struct {
-	WorkflowID *uuid.UUID `json:"id"`
+	WorkflowID *uuid.UUID `json:"workflow_id"`
 	Page       int        `json:"page"`
 	Limit      int        `json:"limit"`
 }
@@ -261,28 +291,28 @@ func makeGetNodesEndpoint(s flow.NodeService) endpoint.Endpoint {
 }
 
 func decodeGetNodesRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	req := getWorkflowsRequest{}
+	req := getNodesRequest{}
 
-	vars := mux.Vars(r)
-	if val, ok := vars["page"]; ok {
+	q := r.URL.Query()
+	if val := q.Get("page"); val != "" {
 		if parsed, err := strconv.Atoi(val); err != nil {
 			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'page'.")
 		} else {
 			req.Page = parsed
 		}
 	}
-	if val, ok := vars["limit"]; ok {
+	if val := q.Get("limit"); val != "" {
 		if parsed, err := strconv.Atoi(val); err != nil {
 			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'limit'.")
 		} else {
-			req.Page = parsed
+			req.Limit = parsed
 		}
 	}
-	if val, ok := vars["workflow_id"]; ok {
+	if val := q.Get("workflow_id"); val != "" {
 		if id, err := uuid.Parse(val); err != nil {
 			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'workflow_id'.")
 		} else {
-			req.ID = &id
+			req.WorkflowID = &id
 		}
 	}
 
@@ -0,0 +1,295 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openmesh/flow"
+)
+
+// DefaultMaxBodySize is the maximum size of a webhook body when the server
+// does not set its own limit.
+const DefaultMaxBodySize = 10 << 20
+
+// limitBody wraps a handler so that request bodies larger than the limit fail
+// to read.
+func limitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads a request body. Returns ETOOLARGE if the body exceeds the
+// limit set by limitBody.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if err.Error() == "http: request body too large" {
+			return nil, flow.Errorf(flow.ETOOLARGE, "Request body is too large.")
+		}
+		return nil, flow.Errorf(flow.EINVALID, "Failed to read request body.")
+	}
+	return body, nil
+}
+
+// decodeWebhookPayload decodes a webhook delivery into a normalized payload
+// according to its content type. Returns EUNSUPPORTED if the content type
+// cannot be decoded.
+func decodeWebhookPayload(r *http.Request) (*flow.WebhookPayload, error) {
+	raw, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &flow.WebhookPayload{
+		Method:      r.Method,
+		ContentType: r.Header.Get("Content-Type"),
+		Headers:     make(map[string]string, len(r.Header)),
+		Query:       valuesToMap(r.URL.Query()),
+	}
+	for k := range r.Header {
+		if !isSensitiveHeader(k) {
+			payload.Headers[k] = r.Header.Get(k)
+		}
+	}
+
+	// Bodies without a content type are decoded as JSON, which was the only
+	// format accepted before, and rejected if they are not.
+	if payload.ContentType == "" {
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload.Body); err != nil {
+				return nil, flow.Errorf(flow.EUNSUPPORTED, "Missing Content-Type header.")
+			}
+			payload.RawBody = string(raw)
+		}
+		return payload, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(payload.ContentType)
+	if err != nil {
+		return nil, flow.Errorf(flow.EUNSUPPORTED, "Invalid Content-Type header.")
+	}
+
+	if mediaType == "multipart/form-data" {
+		if payload.Body, payload.Files, err = decodeMultipart(raw, params["boundary"]); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+
+	payload.RawBody = string(raw)
+	switch {
+	case isJSONMediaType(mediaType):
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload.Body); err != nil {
+				return nil, flow.Errorf(flow.EINVALID, "Failed to decode JSON body.")
+			}
+		}
+
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Failed to decode form body.")
+		}
+		payload.Body = valuesToMap(values)
+
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		if payload.Body, err = decodeXML(raw); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Failed to decode XML body.")
+		}
+
+	case strings.HasPrefix(mediaType, "text/"):
+		payload.Body = string(raw)
+
+	default:
+		return nil, flow.Errorf(flow.EUNSUPPORTED, "Unsupported content type '%s'.", mediaType)
+	}
+
+	return payload, nil
+}
+
+// isJSONMediaType returns true for JSON media types, such as application/json
+// or application/vnd.api+json.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// webhookEvent returns the event payload published for a delivery to
+// /v1/webhooks. JSON deliveries, including those without a content type, are
+// published as their decoded body.
+func webhookEvent(payload *flow.WebhookPayload) interface{} {
+	if payload.ContentType == "" {
+		return payload.Body
+	}
+	if mediaType, _, err := mime.ParseMediaType(payload.ContentType); err == nil && isJSONMediaType(mediaType) {
+		return payload.Body
+	}
+	return payload
+}
+
+// sensitiveHeaders are request headers that carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":                true,
+	"Proxy-Authorization":          true,
+	"Cookie":                       true,
+	flow.DefaultSharedSecretHeader: true,
+}
+
+// sensitiveHeaderWords are words in the names of headers that carry secrets or
+// signatures, such as X-Hub-Signature-256, Stripe-Signature or X-Gitlab-Token.
+var sensitiveHeaderWords = []string{"signature", "secret", "token", "password", "api-key", "apikey"}
+
+// isSensitiveHeader returns true if a header carries a credential or a
+// signature. These are left out of webhook payloads, which are stored with the
+// runs & filter decisions of the deliveries.
+func isSensitiveHeader(name string) bool {
+	if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeMultipart decodes the fields of a multipart body into an object and
+// its files into attachments.
+func decodeMultipart(raw []byte, boundary string) (map[string]interface{}, []*flow.Attachment, error) {
+	if boundary == "" {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Missing multipart boundary.")
+	}
+
+	values := url.Values{}
+	var files []*flow.Attachment
+
+	mr := multipart.NewReader(bytes.NewReader(raw), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, flow.Errorf(flow.EINVALID, "Failed to decode multipart body.")
+		}
+
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, nil, flow.Errorf(flow.EINVALID, "Failed to decode multipart body.")
+		}
+
+		if part.FileName() == "" {
+			values.Add(part.FormName(), string(data))
+			continue
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		// Attachments are given an ID when they are stored with each of
+		// the runs the delivery triggers.
+		files = append(files, &flow.Attachment{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+			Data:        data,
+		})
+	}
+
+	return valuesToMap(values), files, nil
+}
+
+// valuesToMap converts URL values into an object. Keys with a single value
+// map to a string and keys with multiple values map to an array.
+func valuesToMap(values url.Values) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		if len(v) == 1 {
+			m[k] = v[0]
+			continue
+		}
+		arr := make([]interface{}, len(v))
+		for i := range v {
+			arr[i] = v[i]
+		}
+		m[k] = arr
+	}
+	return m
+}
+
+// decodeXML decodes an XML document into an object keyed by the root element.
+// Attributes are keyed with an "@" prefix, text content of elements that also
+// have attributes or children is keyed by "#text" and repeated elements become
+// arrays.
+func decodeXML(raw []byte) (interface{}, error) {
+	d := xml.NewDecoder(bytes.NewReader(raw))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := decodeXMLElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{start.Name.Local: value}, nil
+		}
+	}
+}
+
+func decodeXMLElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	elem := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		elem["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			key := t.Name.Local
+			switch prev := elem[key].(type) {
+			case nil:
+				elem[key] = child
+			case []interface{}:
+				elem[key] = append(prev, child)
+			default:
+				elem[key] = []interface{}{prev, child}
+			}
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(elem) == 0 {
+				return s, nil
+			} else if s != "" {
+				elem["#text"] = s
+			}
+			return elem, nil
+		}
+	}
+}
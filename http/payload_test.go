@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+func TestDecodeMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	_ = w.WriteField("subject", "Invoice")
+	_ = w.WriteField("to", "a@example.com")
+	_ = w.WriteField("to", "b@example.com")
+	fw, _ := w.CreateFormFile("attachment", "invoice.pdf")
+	_, _ = fw.Write([]byte("%PDF-1.4"))
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="attachment"; filename="notes.txt"`)
+	h.Set("Content-Type", "text/plain")
+	fw, _ = w.CreatePart(h)
+	_, _ = fw.Write([]byte("notes"))
+	_ = w.Close()
+	body := buf.Bytes()
+
+	for _, tt := range []struct {
+		name     string
+		raw      []byte
+		boundary string
+		values   map[string]interface{}
+		files    []*flow.Attachment
+		code     string
+	}{
+		{
+			name:     "Form",
+			raw:      body,
+			boundary: w.Boundary(),
+			values: map[string]interface{}{
+				"subject": "Invoice",
+				"to":      []interface{}{"a@example.com", "b@example.com"},
+			},
+			files: []*flow.Attachment{
+				{Field: "attachment", Filename: "invoice.pdf", ContentType: "application/octet-stream", Size: 8, Data: []byte("%PDF-1.4")},
+				{Field: "attachment", Filename: "notes.txt", ContentType: "text/plain", Size: 5, Data: []byte("notes")},
+			},
+		},
+		{
+			name:     "Empty",
+			raw:      []byte("--b--\r\n"),
+			boundary: "b",
+			values:   map[string]interface{}{},
+		},
+		{
+			name:     "MissingBoundary",
+			raw:      body,
+			boundary: "",
+			code:     flow.EINVALID,
+		},
+		{
+			name:     "WrongBoundary",
+			raw:      body,
+			boundary: "other",
+			code:     flow.EINVALID,
+		},
+		{
+			name:     "Truncated",
+			raw:      body[:len(body)/2],
+			boundary: w.Boundary(),
+			code:     flow.EINVALID,
+		},
+		{
+			name:     "NotMultipart",
+			raw:      []byte(strings.Repeat("x", 64)),
+			boundary: "b",
+			code:     flow.EINVALID,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			values, files, err := decodeMultipart(tt.raw, tt.boundary)
+			if code := flow.ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			} else if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(values, tt.values) {
+				t.Fatalf("values = %#v, want %#v", values, tt.values)
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Fatalf("files = %+v, want %+v", files, tt.files)
+			}
+			// Attachments are only given an ID when they are stored
+			// with a run.
+			for _, f := range files {
+				if f.ID != uuid.Nil {
+					t.Fatalf("attachment ID = %s, want none", f.ID)
+				}
+			}
+		})
+	}
+}
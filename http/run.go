@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+)
+
+func (s *Server) makeRunHandler() http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	getRunByIDHandler := kithttp.NewServer(
+		makeGetRunByIDEndpoint(s.RunService),
+		decodeGetRunByIDRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	getRunsHandler := kithttp.NewServer(
+		makeGetRunsEndpoint(s.RunService),
+		decodeGetRunsRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	cancelRunHandler := kithttp.NewServer(
+		makeCancelRunEndpoint(s.Executor),
+		decodeCancelRunRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/v1/runs", s.authenticate(getRunsHandler)).Methods("GET")
+	r.Handle("/v1/runs/{id}", s.authenticate(getRunByIDHandler)).Methods("GET")
+	r.Handle("/v1/runs/{id}/cancel", s.authenticate(cancelRunHandler)).Methods("POST")
+
+	return r
+}
+
+///////////////////
+// Get run by ID //
+///////////////////
+
+type getRunByIDRequest struct {
+	ID uuid.UUID
+}
+
+// makeGetRunByIDEndpoint returns an endpoint that calls GetRunByID on a flow.RunService.
+func makeGetRunByIDEndpoint(s flow.RunService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getRunByIDRequest)
+		return s.GetRunByID(ctx, req.ID)
+	}
+}
+
+// decodeGetRunByIDRequest takes a http.Request and converts it into a getRunByIDRequest. It returns an error if the ID
+// cannot be parsed.
+func decodeGetRunByIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getRunByIDRequest
+	var err error
+
+	req.ID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+//////////////
+// Get runs //
+//////////////
+
+type getRunsRequest struct {
+	WorkflowID  *uuid.UUID
+	ParentRunID *uuid.UUID
+	Status      *string
+	Page        int
+	Limit       int
+}
+
+type getRunsResponse struct {
+	Data       []*flow.Run `json:"data"`
+	TotalItems int         `json:"total_items"`
+}
+
+// makeGetRunsEndpoint returns an endpoint that calls GetRuns on a flow.RunService.
+func makeGetRunsEndpoint(s flow.RunService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getRunsRequest)
+		runs, total, err := s.GetRuns(ctx, flow.RunFilter{
+			WorkflowID:  req.WorkflowID,
+			ParentRunID: req.ParentRunID,
+			Status:      req.Status,
+			Page:        req.Page,
+			Limit:       req.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return getRunsResponse{
+			Data:       runs,
+			TotalItems: total,
+		}, nil
+	}
+}
+
+// decodeGetRunsRequest takes a http.Request and converts it into a getRunsRequest. Runs started by a sub-workflow
+// node are listed by the ID of the run that started them.
+func decodeGetRunsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getRunsRequest
+	var err error
+
+	q := r.URL.Query()
+	if val := q.Get("workflow_id"); val != "" {
+		id, err := uuid.Parse(val)
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'workflow_id'.")
+		}
+		req.WorkflowID = &id
+	}
+	if val := q.Get("parent_run_id"); val != "" {
+		id, err := uuid.Parse(val)
+		if err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'parent_run_id'.")
+		}
+		req.ParentRunID = &id
+	}
+	if val := q.Get("status"); val != "" {
+		req.Status = &val
+	}
+	if val := q.Get("page"); val != "" {
+		if req.Page, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'page'.")
+		}
+	}
+	if val := q.Get("limit"); val != "" {
+		if req.Limit, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'limit'.")
+		}
+	}
+
+	return req, nil
+}
+
+////////////////
+// Cancel run //
+////////////////
+
+type cancelRunRequest struct {
+	ID uuid.UUID
+}
+
+// makeCancelRunEndpoint returns an endpoint that calls CancelRun on a flow.Executor.
+func makeCancelRunEndpoint(e flow.Executor) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(cancelRunRequest)
+		return e.CancelRun(ctx, req.ID)
+	}
+}
+
+// decodeCancelRunRequest takes a http.Request and converts it into a cancelRunRequest. It returns an error if the ID
+// cannot be parsed.
+func decodeCancelRunRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req cancelRunRequest
+	var err error
+
+	req.ID, err = uuidFromVar(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
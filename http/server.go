@@ -6,6 +6,7 @@ import (
 	"github.com/gorilla/securecookie"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -31,14 +32,27 @@ type Server struct {
 	HashKey  string
 	BlockKey string
 
+	// Webhook signing secrets keyed by integration key.
+	WebhookSecrets map[string]string
+
+	// Maximum size in bytes of webhook bodies. Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+
 	Logger log.Logger
 
-	EventBus           flow.EventBus
-	WebhookService     flow.WebhookService
-	WorkflowService    flow.WorkflowService
-	AuthService        flow.AuthService
-	NodeService        flow.NodeService
-	IntegrationService flow.IntegrationService
+	EventBus              flow.EventBus
+	WebhookService        flow.WebhookService
+	WorkflowService       flow.WorkflowService
+	AuthService           flow.AuthService
+	NodeService           flow.NodeService
+	IntegrationService    flow.IntegrationService
+	CompatibilityService  flow.CompatibilityService
+	HookService           flow.HookService
+	RunService            flow.RunService
+	DeliveryService       flow.DeliveryService
+	FilterDecisionService flow.FilterDecisionService
+	WorkerService         flow.WorkerService
+	Executor              flow.Executor
 }
 
 func NewServer() *Server {
@@ -79,10 +93,7 @@ func (s *Server) Open() (err error) {
 	// Begin serving requests on the listener. We use Serve() instead of
 	// ListenAndServe() because it allows us to check for listen errors (such
 	// as trying to use an already open port) synchronously.
-	err = s.server.Serve(s.ln)
-	if err != nil {
-		return err
-	}
+	go s.server.Serve(s.ln)
 
 	return nil
 }
@@ -100,9 +111,23 @@ func (s *Server) RegisterRoute(path string, handler http.Handler) {
 	s.mux.Handle(path, handler)
 }
 
+// maxBodySize returns the maximum size of webhook bodies.
+func (s *Server) maxBodySize() int64 {
+	if s.MaxBodySize > 0 {
+		return s.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+// isWebhookPath returns true if the path receives webhook deliveries whose
+// bodies must be left unread until they are verified and decoded.
+func isWebhookPath(path string) bool {
+	return strings.HasPrefix(path, "/v1/webhooks/") || strings.HasPrefix(path, "/v1/hooks/")
+}
+
 func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	// Override method for forms passing "_method" value.
-	if r.Method == http.MethodPost {
+	if r.Method == http.MethodPost && !isWebhookPath(r.URL.Path) {
 		switch v := r.PostFormValue("_method"); v {
 		case http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete:
 			r.Method = v
@@ -110,7 +135,8 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Allow CORS
-	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "If-None-Match"})
+	exposedHeaders := handlers.ExposedHeaders([]string{"ETag"})
 	allowedOrigins := handlers.AllowedOrigins([]string{"http://localhost:3000"})
 	allowedMethods := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE"})
 
@@ -119,15 +145,22 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 		allowedOrigins,
 		allowedHeaders,
 		allowedMethods,
+		exposedHeaders,
 		handlers.AllowCredentials(),
 	)(s.mux).ServeHTTP(w, r)
 }
 
 func (s *Server) configureHandlers() {
 	s.mux.Handle("/v1/workflows/", s.makeWorkflowHandler())
-	s.mux.Handle("/v1/webhooks/", makeWebhookHandlers(s.EventBus, s.Logger))
+	s.mux.Handle("/v1/nodes/", s.makeNodeHandler())
+	s.mux.Handle("/v1/webhooks/", s.makeWebhookHandler())
+	s.mux.Handle("/v1/hooks/", s.makeHookHandler())
+	s.mux.Handle("/v1/runs", s.makeRunHandler())
+	s.mux.Handle("/v1/runs/", s.makeRunHandler())
+	s.mux.Handle("/v1/workers", s.makeWorkerHandler())
 	s.mux.Handle("/v1/auth/", makeAuthHandler(s.AuthService, s.sc, s.Logger))
 	s.mux.Handle("/v1/integrations", s.makeIntegrationHandler())
+	s.mux.Handle("/v1/integrations/", s.makeIntegrationHandler())
 }
 
 func (s *Server) authenticate(next http.Handler) http.Handler {
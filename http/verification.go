@@ -0,0 +1,297 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+)
+
+// webhookVerifier authenticates webhook deliveries using the verification
+// scheme declared by the integration or trigger that a topic addresses.
+type webhookVerifier struct {
+	integrations flow.IntegrationService
+
+	// Signing secrets keyed by integration key.
+	secrets map[string]string
+
+	// Returns the current time. Used to enforce replay windows.
+	now func() time.Time
+
+	// Signatures of timestamped deliveries that have already been accepted.
+	replays *replayCache
+}
+
+func newWebhookVerifier(integrations flow.IntegrationService, secrets map[string]string) *webhookVerifier {
+	return &webhookVerifier{
+		integrations: integrations,
+		secrets:      secrets,
+		now:          time.Now,
+		replays:      newReplayCache(),
+	}
+}
+
+// verify wraps a webhook handler and rejects deliveries that fail the
+// verification scheme for their topic with a 401 response.
+func (v *webhookVerifier) verify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, secret, err := v.lookup(r)
+		if err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		} else if scheme == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Read the raw body so that signatures are computed over the exact
+		// bytes that were sent, then restore it for the next handler.
+		body, err := readBody(r)
+		if err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if err := v.check(scheme, secret, r.Header, body); err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCRC responds to Twitter's challenge-response checks which are sent
+// as GET requests to the webhook URL.
+func (v *webhookVerifier) handleCRC(w http.ResponseWriter, r *http.Request) {
+	scheme, secret, err := v.lookup(r)
+	if err != nil {
+		encodeError(r.Context(), err, w)
+		return
+	} else if scheme == nil || scheme.Scheme != flow.VerificationSchemeTwitter {
+		encodeError(r.Context(), flow.Errorf(flow.ENOTFOUND, "Topic does not accept CRC checks."), w)
+		return
+	}
+
+	token := r.URL.Query().Get("crc_token")
+	if token == "" {
+		encodeError(r.Context(), flow.Errorf(flow.EINVALID, "Missing 'crc_token' query parameter."), w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"response_token": "sha256=" + base64.StdEncoding.EncodeToString(sign(secret, []byte(token))),
+	})
+}
+
+// lookup returns the verification scheme and secret for the topic of a
+// request. A nil scheme means deliveries to the topic are not verified.
+func (v *webhookVerifier) lookup(r *http.Request) (*flow.WebhookVerification, string, error) {
+	integrationKey, triggerKey := flow.ParseTriggerTopic(mux.Vars(r)["topic"])
+
+	// Topics that do not address a known integration have no scheme to
+	// enforce.
+	integration, err := v.integrations.GetIntegrationByKey(r.Context(), integrationKey)
+	if flow.ErrorCode(err) == flow.ENOTFOUND {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	var trigger *flow.Trigger
+	if triggerKey != "" {
+		if trigger, err = integration.GetTrigger(triggerKey); err != nil {
+			return nil, "", err
+		}
+	}
+
+	scheme := integration.WebhookVerification(trigger)
+	if scheme == nil {
+		return nil, "", nil
+	}
+
+	secret := v.secrets[integration.Key]
+	if secret == "" {
+		return nil, "", flow.Errorf(flow.EUNAUTHORIZED, "No webhook secret is configured for integration '%s'.", integration.Key)
+	}
+	return scheme, secret, nil
+}
+
+// check verifies a delivery's headers and body against a scheme.
+func (v *webhookVerifier) check(scheme *flow.WebhookVerification, secret string, h http.Header, body []byte) error {
+	switch scheme.Scheme {
+	case flow.VerificationSchemeGitHub:
+		return checkHexSignature(h, "X-Hub-Signature-256", "sha256=", secret, body)
+
+	case flow.VerificationSchemeTwitter:
+		sig := h.Get("X-Twitter-Webhooks-Signature")
+		if sig == "" {
+			return flow.Errorf(flow.EUNAUTHORIZED, "Missing X-Twitter-Webhooks-Signature header.")
+		}
+		got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil || !hmac.Equal(got, sign(secret, body)) {
+			return flow.Errorf(flow.EUNAUTHORIZED, "Invalid X-Twitter-Webhooks-Signature header.")
+		}
+		return nil
+
+	case flow.VerificationSchemeSharedSecret:
+		header := scheme.Header
+		if header == "" {
+			header = flow.DefaultSharedSecretHeader
+		}
+		got := h.Get(header)
+		if got == "" {
+			return flow.Errorf(flow.EUNAUTHORIZED, "Missing %s header.", header)
+		} else if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			return flow.Errorf(flow.EUNAUTHORIZED, "Invalid %s header.", header)
+		}
+		return nil
+
+	case flow.VerificationSchemeSlack:
+		ts := h.Get("X-Slack-Request-Timestamp")
+		if ts == "" {
+			return flow.Errorf(flow.EUNAUTHORIZED, "Missing X-Slack-Request-Timestamp header.")
+		}
+		if err := v.checkTimestamp(ts, scheme.Tolerance()); err != nil {
+			return err
+		}
+		signed := append([]byte("v0:"+ts+":"), body...)
+		if err := checkHexSignature(h, "X-Slack-Signature", "v0=", secret, signed); err != nil {
+			return err
+		}
+		return v.checkReplay(ts+h.Get("X-Slack-Signature"), scheme.Tolerance())
+
+	case flow.VerificationSchemeStripe:
+		return v.checkStripe(h, secret, body, scheme.Tolerance())
+	}
+
+	return flow.Errorf(flow.EINTERNAL, "Unknown webhook verification scheme '%s'.", scheme.Scheme)
+}
+
+// checkStripe verifies a Stripe-Signature header of the form
+// "t=<timestamp>,v1=<signature>[,v1=<signature>...]".
+func (v *webhookVerifier) checkStripe(h http.Header, secret string, body []byte, tolerance time.Duration) error {
+	header := h.Get("Stripe-Signature")
+	if header == "" {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Missing Stripe-Signature header.")
+	}
+
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sigs = append(sigs, kv[1])
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Invalid Stripe-Signature header.")
+	}
+	if err := v.checkTimestamp(ts, tolerance); err != nil {
+		return err
+	}
+
+	expected := sign(secret, append([]byte(ts+"."), body...))
+	for _, sig := range sigs {
+		if got, err := hex.DecodeString(sig); err == nil && hmac.Equal(got, expected) {
+			return v.checkReplay(ts+sig, tolerance)
+		}
+	}
+	return flow.Errorf(flow.EUNAUTHORIZED, "Invalid Stripe-Signature header.")
+}
+
+// checkTimestamp rejects deliveries signed outside of the replay window.
+func (v *webhookVerifier) checkTimestamp(ts string, tolerance time.Duration) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Invalid webhook timestamp.")
+	}
+	age := v.now().Sub(time.Unix(sec, 0))
+	if age > tolerance || age < -tolerance {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Webhook timestamp is outside of the allowed window.")
+	}
+	return nil
+}
+
+// checkReplay rejects a signature that has already been accepted within the
+// replay window.
+func (v *webhookVerifier) checkReplay(key string, tolerance time.Duration) error {
+	now := v.now()
+	if !v.replays.add(key, now, now.Add(tolerance)) {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Webhook delivery has already been received.")
+	}
+	return nil
+}
+
+// checkHexSignature compares a hex encoded HMAC-SHA256 signature in a header
+// against the signature of a payload.
+func checkHexSignature(h http.Header, header, prefix, secret string, payload []byte) error {
+	sig := h.Get(header)
+	if sig == "" {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Missing %s header.", header)
+	} else if !strings.HasPrefix(sig, prefix) {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Invalid %s header.", header)
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(sig, prefix))
+	if err != nil || !hmac.Equal(got, sign(secret, payload)) {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Invalid %s header.", header)
+	}
+	return nil
+}
+
+// sign returns the HMAC-SHA256 of a payload.
+func sign(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// replayCache remembers keys until they expire.
+type replayCache struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{keys: make(map[string]time.Time)}
+}
+
+// add stores a key until the given expiry. Returns false if the key is
+// already present and has not expired.
+func (c *replayCache) add(key string, now, expiry time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, exp := range c.keys {
+		if now.After(exp) {
+			delete(c.keys, k)
+		}
+	}
+
+	if _, ok := c.keys[key]; ok {
+		return false
+	}
+	c.keys[key] = expiry
+	return true
+}
@@ -2,9 +2,7 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
-	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/transport"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
@@ -12,34 +10,48 @@ import (
 	"net/http"
 )
 
-func makeWebhookHandlers(evb flow.EventBus, logger log.Logger) http.Handler {
+func (s *Server) makeWebhookHandler() http.Handler {
 	opts := []kithttp.ServerOption{
-		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
 		kithttp.ServerErrorEncoder(encodeError),
 	}
 
 	ingestWebhookHandler := kithttp.NewServer(
-		makeIngestWebhookEndpoint(evb),
+		makeIngestWebhookEndpoint(s.EventBus, newDeduplicator(s.IntegrationService, s.DeliveryService)),
 		decodeIngestWebhookRequest,
 		encodeResponse,
 		opts...,
 	)
 
+	verifier := newWebhookVerifier(s.IntegrationService, s.WebhookSecrets)
+
 	r := mux.NewRouter()
 
-	r.Handle("/v1/webhooks/{topic}", ingestWebhookHandler).Methods("POST")
+	r.Handle("/v1/webhooks/{topic}", limitBody(s.maxBodySize(), verifier.verify(ingestWebhookHandler))).Methods("POST")
+	r.HandleFunc("/v1/webhooks/{topic}", verifier.handleCRC).Methods("GET")
 
 	return r
 }
 
-func makeIngestWebhookEndpoint(evb flow.EventBus) endpoint.Endpoint {
+// makeIngestWebhookEndpoint returns an endpoint that publishes a delivery to
+// the event bus. Retried deliveries are only published once if the trigger
+// defines an idempotency key. JSON deliveries publish their decoded body, as
+// they did before other content types were accepted, so that workflows keep
+// addressing trigger fields by the same paths. Other deliveries publish the
+// normalized payload.
+func makeIngestWebhookEndpoint(evb flow.EventBus, dedup *deduplicator) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(flow.Event)
-		err := evb.Publish(req.Topic, req.Payload)
-		if err != nil {
-			return map[string]string{"status": "failed"}, err
-		}
-		return map[string]string{"status": "success"}, nil
+		payload := req.Payload.(*flow.WebhookPayload)
+
+		integration, trigger := flow.ParseTriggerTopic(req.Topic)
+		key := dedup.trigger(ctx, integration, trigger)
+		return dedup.do(ctx, "webhook:"+req.Topic, key, payload, func() (interface{}, error) {
+			if err := evb.Publish(req.Topic, webhookEvent(payload)); err != nil {
+				return map[string]string{"status": "failed"}, err
+			}
+			return map[string]string{"status": "success"}, nil
+		})
 	}
 }
 
@@ -50,8 +62,8 @@ func decodeIngestWebhookRequest(_ context.Context, r *http.Request) (interface{}
 		return nil, flow.Errorf(flow.EINVALID, "bad route")
 	}
 
-	var payload interface{}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	payload, err := decodeWebhookPayload(r)
+	if err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/openmesh/flow"
+)
+
+func (s *Server) makeWorkerHandler() http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	getWorkersHandler := kithttp.NewServer(
+		makeGetWorkersEndpoint(s.WorkerService),
+		decodeGetWorkersRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/v1/workers", s.authenticate(getWorkersHandler)).Methods("GET")
+
+	return r
+}
+
+/////////////////
+// Get workers //
+/////////////////
+
+type getWorkersRequest struct {
+	Page  int
+	Limit int
+}
+
+type getWorkersResponse struct {
+	Data       []*flow.Worker `json:"data"`
+	TotalItems int            `json:"total_items"`
+}
+
+// makeGetWorkersEndpoint returns an endpoint that calls GetWorkers on a flow.WorkerService.
+func makeGetWorkersEndpoint(s flow.WorkerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getWorkersRequest)
+		workers, total, err := s.GetWorkers(ctx, flow.WorkerFilter{
+			Page:  req.Page,
+			Limit: req.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return getWorkersResponse{
+			Data:       workers,
+			TotalItems: total,
+		}, nil
+	}
+}
+
+func decodeGetWorkersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getWorkersRequest
+	var err error
+
+	q := r.URL.Query()
+	if val := q.Get("page"); val != "" {
+		if req.Page, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'page'.")
+		}
+	}
+	if val := q.Get("limit"); val != "" {
+		if req.Limit, err = strconv.Atoi(val); err != nil {
+			return nil, flow.Errorf(flow.EINVALID, "Invalid value provided for parameter 'limit'.")
+		}
+	}
+
+	return req, nil
+}
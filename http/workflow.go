@@ -62,6 +62,10 @@ func (s *Server) makeWorkflowHandler() http.Handler {
 	r.Handle("/v1/workflows/{id}", s.authenticate(getWorkflowByIDHandler)).Methods("GET")
 	r.Handle("/v1/workflows/", s.authenticate(getWorkflowsHandler)).Methods("GET")
 
+	s.addHookRoutes(r, opts)
+	s.addFilterDecisionRoutes(r, opts)
+	s.addWorkflowCompatibilityRoutes(r, opts)
+
 	return r
 }
 
@@ -72,6 +76,8 @@ func (s *Server) makeWorkflowHandler() http.Handler {
 type createWorkflowRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	ErrorMode   string `json:"error_mode"`
+	Timeout     int    `json:"timeout_seconds"`
 }
 
 // makeCreateWorkflowEndpoint returns an endpoint that calls CreateWorkflow on a flow.WorkflowService.
@@ -81,6 +87,9 @@ func makeCreateWorkflowEndpoint(s flow.WorkflowService) endpoint.Endpoint {
 		workflow := flow.Workflow{
 			Name:        req.Name,
 			Description: req.Description,
+			ErrorMode:   req.ErrorMode,
+
+			TimeoutSeconds: req.Timeout,
 		}
 		err := s.CreateWorkflow(ctx, &workflow)
 		return workflow, err
@@ -107,6 +116,8 @@ type updateWorkflowRequest struct {
 	ID          uuid.UUID `json:"id"`
 	Name        *string   `json:"name"`
 	Description *string   `json:"description"`
+	ErrorMode   *string   `json:"error_mode"`
+	Timeout     *int      `json:"timeout_seconds"`
 }
 
 // makeUpdateWorkflowEndpoint returns an endpoint that calls UpdateWorkflow on a flow.WorkflowService.
@@ -116,6 +127,9 @@ func makeUpdateWorkflowEndpoint(s flow.WorkflowService) endpoint.Endpoint {
 		upd := flow.WorkflowUpdate{
 			Name:        req.Name,
 			Description: req.Description,
+			ErrorMode:   req.ErrorMode,
+
+			TimeoutSeconds: req.Timeout,
 		}
 		return s.UpdateWorkflow(ctx, req.ID, upd)
 	}
@@ -0,0 +1,95 @@
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// DeliveryService is an in-memory implementation of flow.DeliveryService.
+// Deliveries are only deduplicated within a single process.
+type DeliveryService struct {
+	mu         sync.Mutex
+	deliveries map[string]*flow.Delivery
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewDeliveryService returns a new instance of DeliveryService.
+func NewDeliveryService() *DeliveryService {
+	return &DeliveryService{
+		deliveries: make(map[string]*flow.Delivery),
+		Now:        time.Now,
+	}
+}
+
+func (s *DeliveryService) GetDeliveryByKey(ctx context.Context, key string) (*flow.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.get(key)
+	if d == nil {
+		return nil, flow.Errorf(flow.ENOTFOUND, "Delivery not found.")
+	}
+	other := *d
+	return &other, nil
+}
+
+func (s *DeliveryService) CreateDelivery(ctx context.Context, delivery *flow.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge()
+	if s.get(delivery.Key) != nil {
+		return flow.Errorf(flow.ECONFLICT, "Delivery has already been received.")
+	}
+
+	delivery.CreatedAt = s.Now()
+	other := *delivery
+	s.deliveries[delivery.Key] = &other
+	return nil
+}
+
+func (s *DeliveryService) UpdateDelivery(ctx context.Context, key string, response json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.get(key)
+	if d == nil {
+		return flow.Errorf(flow.ENOTFOUND, "Delivery not found.")
+	}
+	d.Response = &response
+	return nil
+}
+
+func (s *DeliveryService) DeleteDelivery(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deliveries, key)
+	return nil
+}
+
+// get returns an unexpired delivery by key. Must be called with the lock held.
+func (s *DeliveryService) get(key string) *flow.Delivery {
+	d, ok := s.deliveries[key]
+	if !ok || !d.ExpiresAt.After(s.Now()) {
+		return nil
+	}
+	return d
+}
+
+// purge removes expired deliveries. Must be called with the lock held.
+func (s *DeliveryService) purge() {
+	now := s.Now()
+	for k, d := range s.deliveries {
+		if !d.ExpiresAt.After(now) {
+			delete(s.deliveries, k)
+		}
+	}
+}
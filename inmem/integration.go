@@ -2,6 +2,8 @@ package inmem
 
 import (
 	"context"
+	"net/http"
+
 	"github.com/openmesh/flow"
 )
 
@@ -13,155 +15,755 @@ func NewIntegrationService() flow.IntegrationService {
 }
 
 func (s integrationService) GetIntegrations(ctx context.Context, req flow.GetIntegrationsRequest) ([]*flow.Integration, int, error) {
-	return apps, len(apps), nil
+	matches, total := flow.FilterIntegrations(apps, req)
+	return matches, total, nil
+}
+
+func (s integrationService) GetIntegrationByKey(ctx context.Context, key string) (*flow.Integration, error) {
+	for _, app := range apps {
+		if app.Key == key {
+			return app, nil
+		}
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Integration '%s' not found.", key)
 }
 
+// GetIntegrationVersion returns a built-in integration. Built-in integrations
+// only have their current version.
+func (s integrationService) GetIntegrationVersion(ctx context.Context, key string, version int) (*flow.Integration, error) {
+	app, err := s.GetIntegrationByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if app.Version != version {
+		return nil, flow.Errorf(flow.ENOTFOUND, "Version %d of integration '%s' not found.", version, key)
+	}
+	return app, nil
+}
+
+// apps are the integrations implemented by the application itself. Other
+// integrations are defined by files loaded by file.IntegrationService.
 var apps = []*flow.Integration{
 	{
-		Label:       "Twitter",
-		Description: "Integrate with the Twitter V1 API.",
-		Key:         "TWITTER_V1",
-		BaseURL:     "https://api.twitter.com/1.1",
-		Triggers:    []flow.Trigger{
-			{
-				Key:         "MY_TWEET",
-				Label:       "My Tweet",
-				Description: "Triggers when you tweet something new.",
-				Endpoint:    "",
-				Method:      "",
-				Inputs:      nil,
-				Outputs:     nil,
-			},
-		},
+		Label:       "Core",
+		Description: "Built-in nodes that control how a workflow executes.",
+		Key:         flow.IntegrationCore,
+		Version:     flow.DefaultIntegrationVersion,
+		Category:    "logic",
 		Actions: []flow.Action{
 			{
-				Key:         "CREATE_TWEET",
-				Label:       "Create Tweet",
-				Description: "Updates the authenticating user's current status, also known as Tweeting.",
-				Endpoint:    "/statuses/update.json",
-				Method:      "JSON_HTTP_POST",
+				Key:         flow.ActionRespond,
+				Label:       "Respond to Webhook",
+				Description: "Sets the HTTP response returned to the caller of a synchronous hook.",
 				Inputs: []flow.InputField{
 					{
 						Key:         "status",
 						Label:       "Status",
-						Description: "The text of the status update. URL encode as necessary. t.co link wrapping will affect character counts.",
-						Required:    true,
-						Type:        "string",
+						Description: "The HTTP status code of the response.",
+						Required:    false,
+						Type:        flow.FieldTypeNumber,
+						Default:     "200",
 					},
 					{
-						Key:         "in_reply_to_status_id",
-						Label:       "In reply to status ID",
-						Description: "The ID of an existing status that the update is in reply to. Note: This parameter will be ignored unless the author of the Tweet this parameter references is mentioned within the status text. Therefore, you must include @username, where username is the author of the referenced Tweet, within the update.",
+						Key:         "headers",
+						Label:       "Headers",
+						Description: "An object of HTTP headers to include in the response.",
 						Required:    false,
-						Type:        "number",
+						Type:        flow.FieldTypeComplex,
 					},
 					{
-						Key:         "auto_populate_reply_metadata",
-						Label:       "Auto-populate reply metadata",
-						Description: "If set to true and used with in_reply_to_status_id, leading @mentions will be looked up from the original Tweet, and added to the new Tweet from there. This wil append @mentions into the metadata of an extended Tweet as a reply chain grows, until the limit on @mentions is reached. In cases where the original Tweet has been deleted, the reply will fail.",
+						Key:         "body",
+						Label:       "Body",
+						Description: "The body of the response. Encoded as JSON.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "true",
+						Type:        flow.FieldTypeComplex,
 					},
+				},
+			},
+			{
+				Key:         flow.ActionIf,
+				Label:       "If",
+				Description: "Follows the edges labeled \"true\" if a condition holds and those labeled \"false\" otherwise.",
+				Inputs: []flow.InputField{
 					{
-						Key:         "exclude_reply_user_ids",
-						Label:       "Exclude reply user IDs",
-						Description: "When used with auto_populate_reply_metadata, a comma-separated list of user ids which will be removed from the server-generated @mentions prefix on an extended Tweet. Note that the leading @mention cannot be removed as it would break the in-reply-to-status-id semantics. Attempting to remove it will be silently ignored.",
+						Key:         "condition",
+						Label:       "Condition",
+						Description: "A condition whose paths reference the trigger payload or the outputs of previous nodes.",
 						Required:    false,
-						Type:        "string",
-						Example:     "786491,54931584",
+						Type:        flow.FieldTypeComplex,
+						Example:     `{"path": "trigger.body.action", "operator": "equals", "value": "opened"}`,
 					},
 					{
-						Key:         "attachment_url",
-						Label:       "Attachment URL",
-						Description: "In order for a URL to not be counted in the status body of an extended Tweet, provide a URL as a Tweet attachment. This URL must be a Tweet permalink, or Direct Message deep link. Arbitrary, non-Twitter URLs must remain in the status text. URLs passed to the attachment_url parameter not matching either a Tweet permalink or Direct Message deep link will fail at Tweet creation and cause an exception.",
+						Key:         "value",
+						Label:       "Value",
+						Description: "The value to compare when no condition is given. Usually a reference.",
 						Required:    false,
-						Type:        "string",
-						Example:     "https://twitter.com/andypiper/status/903615884664725505",
+						Type:        flow.FieldTypeComplex,
 					},
 					{
-						Key:         "media_ids",
-						Label:       "Media IDs",
-						Description: "A comma-delimited list of media_ids to associate with the Tweet. You may include up to 4 photos or 1 animated GIF or 1 video in a Tweet.",
+						Key:         "operator",
+						Label:       "Operator",
+						Description: "One of equals, not_equals, contains, regex, gt, gte, lt, lte, exists or not_exists.",
 						Required:    false,
-						Type:        "string",
-						Example:     "471592142565957632",
+						Type:        flow.FieldTypeString,
 					},
 					{
-						Key:         "possibly_sensitive",
-						Label:       "Possibly sensitive",
-						Description: "If you upload Tweet media that might be considered sensitive content such as nudity, or medical procedures, you must set this value to true.",
+						Key:         "compare",
+						Label:       "Compare To",
+						Description: "The value compared with.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "true",
+						Type:        flow.FieldTypeComplex,
 					},
+				},
+				Outputs: []flow.OutputField{
+					{
+						Label:       "Result",
+						Key:         "result",
+						Description: "Whether the condition holds.",
+						Type:        flow.FieldTypeBoolean,
+					},
+					{
+						Label:       "Branch",
+						Key:         "branch",
+						Description: "The label of the edges that were followed.",
+						Type:        flow.FieldTypeString,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionSwitch,
+				Label:       "Switch",
+				Description: "Follows the edges labeled with a value, or those labeled \"default\" if no edge matches.",
+				Inputs: []flow.InputField{
 					{
-						Key:         "lat",
-						Label:       "Latitude",
-						Description: "The latitude of the location this Tweet refers to. This parameter will be ignored unless it is inside the range -90.0 to +90.0 (North is positive) inclusive. It will also be ignored if there is no corresponding long parameter.",
+						Key:         "value",
+						Label:       "Value",
+						Description: "The value to switch on. Usually a reference.",
+						Required:    true,
+						Type:        flow.FieldTypeComplex,
+					},
+				},
+				Outputs: []flow.OutputField{
+					{
+						Label:       "Value",
+						Key:         "value",
+						Description: "The value switched on.",
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Label:       "Branch",
+						Key:         "branch",
+						Description: "The label of the edges that were followed.",
+						Type:        flow.FieldTypeString,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionForEach,
+				Label:       "For Each",
+				Description: "Runs the nodes of the edges labeled \"each\" once for every item of an array and collects their results.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "items",
+						Label:       "Items",
+						Description: "The array to iterate over. Usually a reference to the output of a previous node.",
+						Required:    true,
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Key:         "concurrency",
+						Label:       "Concurrency",
+						Description: "The number of items processed at once.",
 						Required:    false,
-						Type:        "number",
-						Example:     "37.7821120598956",
+						Type:        flow.FieldTypeNumber,
+						Default:     "1",
 					},
+				},
+				Outputs: []flow.OutputField{
 					{
-						Key:         "long",
-						Label:       "Longitude",
-						Description: "The longitude of the location this Tweet refers to. The valid ranges for longitude are -180.0 to +180.0 (East is positive) inclusive. This parameter will be ignored if outside that range, if it is not a number, if geo_enabled is turned off, or if there no corresponding lat parameter.",
+						Label:       "Results",
+						Key:         "results",
+						Description: "The result of each item in order. Items that failed have a null result.",
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Label:       "Errors",
+						Key:         "errors",
+						Description: "The index and error of each item that failed.",
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Label:       "Count",
+						Key:         "count",
+						Description: "The number of items.",
+						Type:        flow.FieldTypeNumber,
+					},
+					{
+						Label:       "Failed",
+						Key:         "failed",
+						Description: "The number of items that failed.",
+						Type:        flow.FieldTypeNumber,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionDelay,
+				Label:       "Delay",
+				Description: "Pauses the run for a duration or until a point in time.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "duration",
+						Label:       "Duration",
+						Description: "How long to pause for, such as \"90s\" or \"48h\", or a number of seconds.",
 						Required:    false,
-						Type:        "number",
-						Example:     "-122.400612831116",
+						Type:        flow.FieldTypeString,
 					},
 					{
-						Key:         "place_id",
-						Label:       "Place ID",
-						Description: "A place in the world.",
+						Key:         "until",
+						Label:       "Until",
+						Description: "The time to pause until in RFC 3339 format. Usually a reference. Takes precedence over the duration.",
 						Required:    false,
-						Type:        "string",
-						Example:     "df51dec6f4ee2b2c",
+						Type:        flow.FieldTypeDateTime,
 					},
+				},
+				Outputs: []flow.OutputField{
 					{
-						Key:         "display_coordinates",
-						Label:       "Display coordinates",
-						Description: "Whether or not to put a pin on the exact coordinates a Tweet has been sent from.",
+						Label:       "Until",
+						Key:         "until",
+						Description: "The time the delay ended.",
+						Type:        flow.FieldTypeDateTime,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionWaitForEvent,
+				Label:       "Wait For Event",
+				Description: "Pauses the run until an event of a trigger matches a condition, or until the wait times out.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "integration",
+						Label:       "Integration",
+						Description: "The key of the integration whose trigger publishes the event.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Key:         "trigger",
+						Label:       "Trigger",
+						Description: "The key of the trigger that publishes the event.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Key:         "condition",
+						Label:       "Condition",
+						Description: "A condition the payload of the event must match.",
+						Required:    false,
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Key:         "match_path",
+						Label:       "Match Path",
+						Description: "The path of a value within the payload of the event that must equal the match value.",
+						Required:    false,
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Key:         "match_value",
+						Label:       "Match Value",
+						Description: "The value that correlates the event with the run. Usually a reference.",
+						Required:    false,
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Key:         "timeout",
+						Label:       "Timeout",
+						Description: "How long to wait for, such as \"15m\", or a number of seconds. Waits without a timeout last until an event matches.",
+						Required:    false,
+						Type:        flow.FieldTypeString,
+					},
+				},
+				Outputs: []flow.OutputField{
+					{
+						Label:       "Event",
+						Key:         "event",
+						Description: "The payload of the event that matched. Null if the wait timed out.",
+						Type:        flow.FieldTypeComplex,
+					},
+					{
+						Label:       "Timed Out",
+						Key:         "timed_out",
+						Description: "Whether the wait timed out before an event matched.",
+						Type:        flow.FieldTypeBoolean,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionSubWorkflow,
+				Label:       "Sub-Workflow",
+				Description: "Runs another workflow with an input and outputs its result.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "workflow_id",
+						Label:       "Workflow",
+						Description: "The ID of the workflow to run.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Key:         "trigger_node_id",
+						Label:       "Trigger Node",
+						Description: "The ID of the node the workflow runs from. Required if the workflow has several source nodes.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "true",
+						Type:        flow.FieldTypeString,
 					},
 					{
-						Key:         "trim_user",
-						Label:       "Trim user",
-						Description: "When set to either true, t or 1, the response will include a user object including only the author's ID. Omit this parameter to receive the complete user object.",
+						Key:         "input",
+						Label:       "Input",
+						Description: "The payload the workflow runs with. Usually built from references.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "true",
+						Type:        flow.FieldTypeComplex,
 					},
 					{
-						Key:         "enable_dmcommands",
-						Label:       "Enable direct message commands",
-						Description: "When set to true, enables shortcode commands for sending Direct Messages as part of the status text to send a Direct Message to a user. When set to false, it turns off this behavior and includes any leading characters in the status text that is posted.",
+						Key:         "mode",
+						Label:       "Mode",
+						Description: "Either \"sync\" to wait for the workflow to finish, or \"async\" to start it without waiting.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "true",
+						Type:        flow.FieldTypeString,
+						Default:     flow.SubWorkflowModeSync,
+					},
+				},
+				Outputs: []flow.OutputField{
+					{
+						Label:       "Run ID",
+						Key:         "run_id",
+						Description: "The ID of the workflow's run.",
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Label:       "Status",
+						Key:         "status",
+						Description: "The status of the workflow's run.",
+						Type:        flow.FieldTypeString,
+					},
+					{
+						Label:       "Output",
+						Key:         "output",
+						Description: "The output of the workflow's respond node, or else the outputs of its last nodes keyed by node ID. Only set in sync mode.",
+						Type:        flow.FieldTypeComplex,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionTransform,
+				Label:       "Transform",
+				Description: "Reshapes data with a JMESPath expression and outputs the result.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "expression",
+						Label:       "Expression",
+						Description: "The JMESPath expression to evaluate. Validated when the node is saved.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+						Example:     "items[?price > `10`].{name: name, total: price}",
+					},
+					{
+						Key:         "input",
+						Label:       "Input",
+						Description: "The document to evaluate the expression against. Usually a reference. Defaults to the run's scope, in which the trigger's payload is \"trigger\" and the outputs of earlier nodes are keyed by node ID.",
+						Required:    false,
+						Type:        flow.FieldTypeComplex,
+					},
+				},
+				Outputs: []flow.OutputField{
+					{
+						Label:       "Result",
+						Key:         "result",
+						Description: "The result of the expression.",
+						Type:        flow.FieldTypeComplex,
+					},
+				},
+			},
+			{
+				Key:         flow.ActionCode,
+				Label:       "Code",
+				Description: "Runs a Starlark script in a sandbox without access to the filesystem or network. Every input other than the code and outputs is available to the script as a global of the same name, and printed lines are logged to the run.",
+				Inputs: []flow.InputField{
+					{
+						Key:         flow.CodeParamCode,
+						Label:       "Code",
+						Description: "The Starlark script to run. Validated when the node is saved. Scripts are limited in time, steps & memory, and may use the \"json\" module to encode & decode JSON.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+						Example:     "total = sum([item[\"price\"] for item in items])\nprint(\"total:\", total)",
+					},
+					{
+						Key:         flow.CodeParamOutputs,
+						Label:       "Outputs",
+						Description: "Comma-separated names of the globals set by the script that become the outputs of the node.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+						Example:     "total",
+					},
+				},
+			},
+		},
+	},
+	{
+		Label:       "Schedule",
+		Description: "Triggers that start runs on a schedule.",
+		Key:         flow.IntegrationSchedule,
+		Version:     flow.DefaultIntegrationVersion,
+		Category:    "utility",
+		Triggers: []flow.Trigger{
+			{
+				Key:         flow.TriggerCron,
+				Label:       "Cron",
+				Description: "Triggers according to a cron expression.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "expression",
+						Label:       "Expression",
+						Description: "A cron expression with an optional seconds field, or a descriptor such as @daily.",
+						Required:    true,
+						Type:        flow.FieldTypeString,
+						Example:     "0 9 * * MON-FRI",
 					},
 					{
-						Key:         "fail_dmcommands",
-						Label:       "Fail direct message commands",
-						Description: "When set to true, causes any status text that starts with shortcode commands to return an API error. When set to false, allows shortcode commands to be sent in the status text and acted on by the API.",
+						Key:         "timezone",
+						Label:       "Time Zone",
+						Description: "The IANA time zone the expression is evaluated in. Defaults to UTC.",
 						Required:    false,
-						Type:        "boolean",
-						Example:     "false",
+						Type:        flow.FieldTypeString,
+						Example:     "Europe/London",
 					},
+					catchUpInput,
+				},
+				Outputs: scheduleOutputs,
+			},
+			{
+				Key:         flow.TriggerInterval,
+				Label:       "Interval",
+				Description: "Triggers at a fixed interval.",
+				Inputs: []flow.InputField{
 					{
-						Key:         "card_uri",
-						Label:       "Card URI",
-						Description: "Associate an ads card with the Tweet using the card_uri value from any ads card response.",
+						Key:         "interval_seconds",
+						Label:       "Interval",
+						Description: "The number of seconds between runs.",
+						Required:    true,
+						Type:        flow.FieldTypeNumber,
+						Example:     "3600",
+					},
+					catchUpInput,
+				},
+				Outputs: scheduleOutputs,
+			},
+			{
+				Key:         flow.TriggerOnce,
+				Label:       "Once",
+				Description: "Triggers once at a given time.",
+				Inputs: []flow.InputField{
+					{
+						Key:         "at",
+						Label:       "Time",
+						Description: "The time of the run. Times without an offset are in the time zone.",
+						Required:    true,
+						Type:        flow.FieldTypeDateTime,
+						Example:     "2021-01-01T09:00:00",
+					},
+					{
+						Key:         "timezone",
+						Label:       "Time Zone",
+						Description: "The IANA time zone of the time. Defaults to UTC.",
 						Required:    false,
-						Type:        "string",
-						Example:     "card://853503245793641682",
+						Type:        flow.FieldTypeString,
 					},
+					catchUpInput,
 				},
-				Outputs: nil,
+				Outputs: scheduleOutputs,
 			},
 		},
 	},
+	{
+		Label:       "HTTP",
+		Description: "Sends HTTP requests to any public URL. Private addresses can only be called if allowed by the instance's configuration.",
+		Key:         flow.IntegrationHTTP,
+		Version:     flow.DefaultIntegrationVersion,
+		Category:    "developer",
+		Actions: []flow.Action{
+			requestAction(http.MethodGet, "GET Request", false),
+			requestAction(http.MethodPost, "POST Request", true),
+			requestAction(http.MethodPut, "PUT Request", true),
+			requestAction(http.MethodPatch, "PATCH Request", true),
+			requestAction(http.MethodDelete, "DELETE Request", true),
+		},
+	},
+}
+
+// catchUpInput is the input of schedule triggers that sets their catch-up
+// policy.
+var catchUpInput = flow.InputField{
+	Key:         "catch_up",
+	Label:       "Catch Up",
+	Description: "What happens to runs missed while the scheduler was down: skip, once or all.",
+	Required:    false,
+	Type:        flow.FieldTypeString,
+	Example:     flow.CatchUpOnce,
+}
+
+// scheduleOutputs are the outputs of schedule triggers.
+var scheduleOutputs = []flow.OutputField{
+	{
+		Label:       "Scheduled At",
+		Key:         "scheduled_at",
+		Description: "The time the run was scheduled for.",
+		Type:        flow.FieldTypeDateTime,
+		Path:        "scheduled_at",
+	},
+	{
+		Label:       "Fired At",
+		Key:         "fired_at",
+		Description: "The time the run was started.",
+		Type:        flow.FieldTypeDateTime,
+		Path:        "fired_at",
+	},
+}
+
+// requestAction returns an action of the HTTP integration that sends requests
+// with the given method. Actions with a body also accept a body or a body
+// template.
+func requestAction(method, label string, body bool) flow.Action {
+	inputs := []flow.InputField{
+		{
+			Key:         "url",
+			Label:       "URL",
+			Description: "The http or https URL to send the request to.",
+			Required:    true,
+			Type:        flow.FieldTypeString,
+			Example:     "https://api.example.com/v1/orders",
+		},
+		{
+			Key:         "query",
+			Label:       "Query",
+			Description: "An object of query params added to the URL. Arrays are sent as repeated params.",
+			Required:    false,
+			Type:        flow.FieldTypeComplex,
+		},
+		{
+			Key:         "headers",
+			Label:       "Headers",
+			Description: "An object of HTTP headers to send.",
+			Required:    false,
+			Type:        flow.FieldTypeComplex,
+		},
+	}
+	if body {
+		inputs = append(inputs, requestBodyInputs...)
+	}
+	inputs = append(inputs, requestInputs...)
+
+	return flow.Action{
+		Key:         method,
+		Label:       label,
+		Description: "Sends a " + method + " request and outputs the status, headers & parsed body of the response.",
+		Method:      method,
+		Inputs:      inputs,
+		Outputs:     requestOutputs,
+	}
+}
+
+// requestBodyInputs are the inputs of HTTP actions that set the request body.
+var requestBodyInputs = []flow.InputField{
+	{
+		Key:         "body",
+		Label:       "Body",
+		Description: "The body of the request. Encoded as JSON unless it is a string, which is sent as is.",
+		Required:    false,
+		Type:        flow.FieldTypeComplex,
+	},
+	{
+		Key:         "body_template",
+		Label:       "Body Template",
+		Description: "A template the body is rendered from instead, with placeholders such as {{ trigger.user.id }} replaced by the values at their path within the run's scope. Values are encoded as JSON within JSON bodies.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Example:     `{"user": {{ trigger.user.id }}, "note": {{ trigger.note }}}`,
+	},
+	{
+		Key:         "content_type",
+		Label:       "Content Type",
+		Description: "The content type of the body.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     "application/json",
+	},
+}
+
+// requestInputs are the inputs shared by every HTTP action that set the
+// authentication of the request, how its response is parsed and how its pages
+// are fetched.
+var requestInputs = []flow.InputField{
+	{
+		Key:         "auth",
+		Label:       "Auth",
+		Description: "How the request is authenticated: none, basic (username & password), bearer (token), header (auth_header & auth_value) or integration (auth_integration).",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     flow.RequestAuthNone,
+	},
+	{
+		Key:         "username",
+		Label:       "Username",
+		Description: "The username of basic auth.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Key:         "password",
+		Label:       "Password",
+		Description: "The password of basic auth.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Key:         "token",
+		Label:       "Token",
+		Description: "The token of bearer auth.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Key:         "auth_header",
+		Label:       "Auth Header",
+		Description: "The name of the header of header auth.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Example:     "X-API-Key",
+	},
+	{
+		Key:         "auth_value",
+		Label:       "Auth Value",
+		Description: "The value of the header of header auth.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Key:         "auth_integration",
+		Label:       "Auth Integration",
+		Description: "The key of a connected integration whose access token is sent as a bearer token.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Key:         "response_format",
+		Label:       "Response Format",
+		Description: "How the response body is parsed: auto (by content type), json, text or binary (base64 encoded).",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     flow.ResponseFormatAuto,
+	},
+	{
+		Key:         "success_status",
+		Label:       "Success Status",
+		Description: "Comma-separated status codes or classes the request succeeds with. Other statuses fail the node.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     string(flow.DefaultStatusRule),
+		Example:     "2xx,404",
+	},
+	{
+		Key:         "pagination",
+		Label:       "Pagination",
+		Description: "How further pages are fetched: none, link (the next link of the Link header), cursor (cursor_path & cursor_param) or page (page_param & page_start). The items of every page are collected.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     flow.PaginationNone,
+	},
+	{
+		Key:         "items_path",
+		Label:       "Items Path",
+		Description: "The path of the items within each page. Defaults to the page itself.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Example:     "data.items",
+	},
+	{
+		Key:         "cursor_path",
+		Label:       "Cursor Path",
+		Description: "The path of the cursor of the next page within each page. Pagination ends once it is empty.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Example:     "meta.next_cursor",
+	},
+	{
+		Key:         "cursor_param",
+		Label:       "Cursor Param",
+		Description: "The query param the cursor is sent as.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Example:     "cursor",
+	},
+	{
+		Key:         "page_param",
+		Label:       "Page Param",
+		Description: "The query param the page number is sent as. Pagination ends once a page has no items.",
+		Required:    false,
+		Type:        flow.FieldTypeString,
+		Default:     "page",
+	},
+	{
+		Key:         "page_start",
+		Label:       "Page Start",
+		Description: "The number of the first page.",
+		Required:    false,
+		Type:        flow.FieldTypeNumber,
+		Default:     "1",
+	},
+	{
+		Key:         "max_pages",
+		Label:       "Max Pages",
+		Description: "The maximum number of pages fetched.",
+		Required:    false,
+		Type:        flow.FieldTypeNumber,
+		Default:     "10",
+	},
+}
+
+// requestOutputs are the outputs of HTTP actions.
+var requestOutputs = []flow.OutputField{
+	{
+		Label:       "Status",
+		Key:         "status",
+		Description: "The status code of the response.",
+		Type:        flow.FieldTypeNumber,
+	},
+	{
+		Label:       "Headers",
+		Key:         "headers",
+		Description: "The headers of the response.",
+		Type:        flow.FieldTypeComplex,
+	},
+	{
+		Label:       "Content Type",
+		Key:         "content_type",
+		Description: "The content type of the response.",
+		Type:        flow.FieldTypeString,
+	},
+	{
+		Label:       "Body",
+		Key:         "body",
+		Description: "The parsed body of the response, or of the last page of paginated requests.",
+		Type:        flow.FieldTypeComplex,
+	},
+	{
+		Label:       "Items",
+		Key:         "items",
+		Description: "The items of every page of paginated requests.",
+		Type:        flow.FieldTypeComplex,
+	},
+	{
+		Label:       "Pages",
+		Key:         "pages",
+		Description: "The number of pages fetched by paginated requests.",
+		Type:        flow.FieldTypeNumber,
+	},
 }
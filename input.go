@@ -0,0 +1,202 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoerceInputs validates the inputs of an action or trigger against its input
+// fields. Values are coerced to the type of their field, such as "true" to a
+// boolean or "42" to a number, and missing values are set to the field's
+// default. Returns EINVALID with an error for each invalid field. Inputs
+// without a field are returned as they are.
+//
+// Numbers given as text are returned as json.Number so that large integers,
+// such as IDs, keep their precision. Datetimes are returned as RFC 3339
+// strings so that they are sent to APIs as they are.
+func CoerceInputs(fields []InputField, inputs map[string]interface{}) (map[string]interface{}, error) {
+	return coerceInputs(fields, inputs, nil)
+}
+
+// ValidateParams validates the params of a node against the input fields of
+// its action or trigger when the node is saved. Value params are checked as
+// by CoerceInputs. Reference params can only be resolved when the node runs,
+// so they are only counted as setting their field.
+func ValidateParams(fields []InputField, params []*Param) error {
+	inputs := make(map[string]interface{}, len(params))
+	references := make(map[string]bool)
+	for _, p := range params {
+		if p.Type == ParamTypeReference {
+			references[p.Key] = true
+			continue
+		}
+		inputs[p.Key] = p.Value
+	}
+	_, err := coerceInputs(fields, inputs, references)
+	return err
+}
+
+// coerceInputs implements CoerceInputs. Fields set by references are skipped.
+func coerceInputs(fields []InputField, inputs map[string]interface{}, references map[string]bool) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(inputs))
+	for k, v := range inputs {
+		coerced[k] = v
+	}
+
+	var errs []FieldError
+	for _, f := range fields {
+		if references[f.Key] {
+			continue
+		}
+
+		v, ok := inputs[f.Key]
+		if !ok || isBlank(v) {
+			switch {
+			case f.Default != "":
+				v = f.Default
+			case f.Required:
+				errs = append(errs, FieldError{Field: f.Key, Message: "is required"})
+				continue
+			case ok && f.Type != FieldTypeString:
+				// Blank values of optional fields other than text
+				// are treated as unset.
+				delete(coerced, f.Key)
+				continue
+			default:
+				continue
+			}
+		}
+
+		value, err := coerce(f.Type, v)
+		if err != nil {
+			errs = append(errs, FieldError{Field: f.Key, Message: err.Error()})
+			continue
+		}
+		coerced[f.Key] = value
+	}
+
+	if len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Field + " " + e.Message
+		}
+		return nil, &Error{
+			Code:    EINVALID,
+			Message: fmt.Sprintf("Invalid inputs: %s.", strings.Join(messages, "; ")),
+			Fields:  errs,
+		}
+	}
+	return coerced, nil
+}
+
+// isBlank returns true if a value is nil or a string of whitespace.
+func isBlank(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+// numberRegexp matches numbers in the format of JSON.
+var numberRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// coerce converts a value to a field type. Values of complex fields and of
+// unknown types are returned as they are.
+func coerce(typ FieldType, v interface{}) (interface{}, error) {
+	switch typ {
+	case FieldTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case bool, int, int64, json.Number:
+			return fmt.Sprint(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return nil, fmt.Errorf("must be text")
+
+	case FieldTypeNumber:
+		switch v := v.(type) {
+		case float64, int, int64, json.Number:
+			return v, nil
+		case string:
+			if s := strings.TrimSpace(v); numberRegexp.MatchString(s) {
+				return json.Number(s), nil
+			}
+		}
+		return nil, fmt.Errorf("must be a number")
+
+	case FieldTypeBoolean:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "t", "1", "yes", "y", "on":
+				return true, nil
+			case "false", "f", "0", "no", "n", "off":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+
+	case FieldTypeDateTime:
+		if t, ok := parseDateTime(v); ok {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return nil, fmt.Errorf("must be an RFC 3339 date & time")
+	}
+	return v, nil
+}
+
+// dateTimeLayouts are the layouts datetimes given as text are parsed with.
+// Datetimes without a time zone are in UTC.
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDateTime parses a datetime given as a time, as text in one of
+// dateTimeLayouts or as a Unix timestamp in seconds.
+func parseDateTime(v interface{}) (time.Time, bool) {
+	var seconds float64
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = f
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range dateTimeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+		if !numberRegexp.MatchString(s) {
+			return time.Time{}, false
+		}
+		seconds, _ = strconv.ParseFloat(s, 64)
+	default:
+		return time.Time{}, false
+	}
+
+	sec, frac := int64(seconds), seconds-float64(int64(seconds))
+	return time.Unix(sec, int64(frac*float64(time.Second))).UTC(), true
+}
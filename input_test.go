@@ -0,0 +1,149 @@
+package flow
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCoerceInputs(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		fields []InputField
+		inputs map[string]interface{}
+		want   map[string]interface{}
+		errors []string // fields with errors
+	}{
+		{
+			name:   "Number",
+			fields: []InputField{{Key: "n", Type: FieldTypeNumber}},
+			inputs: map[string]interface{}{"n": " 12345678901234567890 "},
+			want:   map[string]interface{}{"n": json.Number("12345678901234567890")},
+		},
+		{
+			name:   "NumberKept",
+			fields: []InputField{{Key: "n", Type: FieldTypeNumber}},
+			inputs: map[string]interface{}{"n": 1.5},
+			want:   map[string]interface{}{"n": 1.5},
+		},
+		{
+			name:   "NumberInvalid",
+			fields: []InputField{{Key: "n", Type: FieldTypeNumber}},
+			inputs: map[string]interface{}{"n": "1e"},
+			errors: []string{"n"},
+		},
+		{
+			name:   "Boolean",
+			fields: []InputField{{Key: "a", Type: FieldTypeBoolean}, {Key: "b", Type: FieldTypeBoolean}},
+			inputs: map[string]interface{}{"a": "Yes", "b": "0"},
+			want:   map[string]interface{}{"a": true, "b": false},
+		},
+		{
+			name:   "BooleanInvalid",
+			fields: []InputField{{Key: "a", Type: FieldTypeBoolean}},
+			inputs: map[string]interface{}{"a": "maybe"},
+			errors: []string{"a"},
+		},
+		{
+			name:   "String",
+			fields: []InputField{{Key: "a", Type: FieldTypeString}, {Key: "b", Type: FieldTypeString}, {Key: "c", Type: FieldTypeString}},
+			inputs: map[string]interface{}{"a": 10.5, "b": true, "c": " "},
+			want:   map[string]interface{}{"a": "10.5", "b": "true", "c": " "},
+		},
+		{
+			name:   "StringInvalid",
+			fields: []InputField{{Key: "a", Type: FieldTypeString}},
+			inputs: map[string]interface{}{"a": []interface{}{"x"}},
+			errors: []string{"a"},
+		},
+		{
+			name:   "DateTime",
+			fields: []InputField{{Key: "a", Type: FieldTypeDateTime}, {Key: "b", Type: FieldTypeDateTime}, {Key: "c", Type: FieldTypeDateTime}},
+			inputs: map[string]interface{}{"a": "2021-03-04", "b": "2021-03-04T05:06:07+02:00", "c": float64(1614834367)},
+			want:   map[string]interface{}{"a": "2021-03-04T00:00:00Z", "b": "2021-03-04T05:06:07+02:00", "c": "2021-03-04T05:06:07Z"},
+		},
+		{
+			name:   "DateTimeInvalid",
+			fields: []InputField{{Key: "a", Type: FieldTypeDateTime}},
+			inputs: map[string]interface{}{"a": "yesterday"},
+			errors: []string{"a"},
+		},
+		{
+			name:   "Complex",
+			fields: []InputField{{Key: "a", Type: FieldTypeComplex}},
+			inputs: map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			want:   map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+		},
+		{
+			name:   "Default",
+			fields: []InputField{{Key: "n", Type: FieldTypeNumber, Default: "10"}, {Key: "s", Type: FieldTypeString, Default: "x"}},
+			inputs: map[string]interface{}{"s": ""},
+			want:   map[string]interface{}{"n": json.Number("10"), "s": "x"},
+		},
+		{
+			name:   "Required",
+			fields: []InputField{{Key: "a", Type: FieldTypeString, Required: true}, {Key: "b", Type: FieldTypeNumber, Required: true}},
+			inputs: map[string]interface{}{"b": nil},
+			errors: []string{"a", "b"},
+		},
+		{
+			name:   "BlankOptional",
+			fields: []InputField{{Key: "n", Type: FieldTypeNumber}, {Key: "s", Type: FieldTypeString}},
+			inputs: map[string]interface{}{"n": " ", "s": ""},
+			want:   map[string]interface{}{"s": ""},
+		},
+		{
+			name:   "Unknown",
+			fields: []InputField{{Key: "a", Type: FieldTypeString}},
+			inputs: map[string]interface{}{"other": 1.0},
+			want:   map[string]interface{}{"other": 1.0},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceInputs(tt.fields, tt.inputs)
+			if len(tt.errors) > 0 {
+				e, ok := err.(*Error)
+				if !ok || e.Code != EINVALID {
+					t.Fatalf("error = %v, want %s", err, EINVALID)
+				}
+				var fields []string
+				for _, f := range e.Fields {
+					fields = append(fields, f.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.errors) {
+					t.Fatalf("fields with errors = %v, want %v", fields, tt.errors)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CoerceInputs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	fields := []InputField{
+		{Key: "repo", Type: FieldTypeString, Required: true},
+		{Key: "limit", Type: FieldTypeNumber},
+	}
+	for _, tt := range []struct {
+		name   string
+		params []*Param
+		code   string
+	}{
+		{"Values", []*Param{{Key: "repo", Value: "flow"}, {Key: "limit", Value: "10"}}, ""},
+		{"Reference", []*Param{{Key: "repo", Value: "trigger.repo", Type: ParamTypeReference}}, ""},
+		{"ReferenceNotCoerced", []*Param{{Key: "repo", Value: "flow"}, {Key: "limit", Value: "trigger.limit", Type: ParamTypeReference}}, ""},
+		{"Missing", []*Param{{Key: "limit", Value: "10"}}, EINVALID},
+		{"Invalid", []*Param{{Key: "repo", Value: "flow"}, {Key: "limit", Value: "ten"}}, EINVALID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ErrorCode(ValidateParams(fields, tt.params)); code != tt.code {
+				t.Fatalf("error code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
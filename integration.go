@@ -1,6 +1,79 @@
 package flow
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IntegrationCore is the key of the built-in integration whose actions are
+// executed by flow itself rather than by calling an external API.
+const IntegrationCore = "CORE"
+
+// Actions of the core integration.
+const (
+	// Passes its inputs through as its output. Sync hooks respond with the
+	// output of a respond node.
+	ActionRespond = "RESPOND"
+	// Evaluates a condition and follows the edges labeled with BranchTrue
+	// or BranchFalse.
+	ActionIf = "IF"
+	// Follows the edges labeled with the value of its input, or the edges
+	// labeled with BranchDefault if no edge matches.
+	ActionSwitch = "SWITCH"
+	// Executes the nodes of the edges labeled with BranchEach once for each
+	// item of an array and collects their results.
+	ActionForEach = "FOR_EACH"
+	// Pauses the run for a duration or until a point in time.
+	ActionDelay = "DELAY"
+	// Pauses the run until an event matching a condition arrives on a
+	// trigger topic or the wait times out.
+	ActionWaitForEvent = "WAIT_FOR_EVENT"
+	// Runs another workflow of the same user with the node's input and
+	// outputs its result, or starts it without waiting for it to finish.
+	ActionSubWorkflow = "SUB_WORKFLOW"
+	// Evaluates a JMESPath expression against the outputs of earlier nodes
+	// and outputs its result.
+	ActionTransform = "TRANSFORM"
+	// Runs a sandboxed Starlark script with the node's inputs and outputs
+	// the globals it sets.
+	ActionCode = "CODE"
+)
+
+// Modes of sub-workflow nodes.
+const (
+	// The node waits for the called workflow to finish and outputs its
+	// result. The run pauses if the called workflow pauses.
+	SubWorkflowModeSync = "sync"
+	// The node starts the called workflow and outputs the ID of its run.
+	SubWorkflowModeAsync = "async"
+)
+
+// MaxCallDepth is the maximum number of sub-workflow calls that may be nested
+// below a run started by a trigger.
+const MaxCallDepth = 10
+
+// Edge labels of condition nodes.
+const (
+	BranchTrue    = "true"
+	BranchFalse   = "false"
+	BranchDefault = "default"
+	BranchEach    = "each"
+
+	// Edges labeled as error edges are only followed when their tail fails.
+	// The failure is then handled by the branch rather than failing the run.
+	BranchError = "error"
+)
+
+// MaxForEachConcurrency is the maximum number of items a for-each node
+// executes at once.
+const MaxForEachConcurrency = 20
 
 type Integration struct {
 	Label       string    `json:"label"`
@@ -9,6 +82,99 @@ type Integration struct {
 	BaseURL     string    `json:"base_url"`
 	Triggers    []Trigger `json:"triggers"`
 	Actions     []Action  `json:"actions"`
+
+	// Category the integration is listed under in the catalog, such as
+	// "social" or "developer".
+	Category string `json:"category,omitempty"`
+
+	// Version of the definition, increased whenever its triggers, actions
+	// or inputs change. Nodes are pinned to the version they were built
+	// against. Definitions without a version are DefaultIntegrationVersion.
+	Version int `json:"version,omitempty"`
+
+	// Verification describes how webhook deliveries for the integration's
+	// triggers are authenticated. Triggers may override it.
+	Verification *WebhookVerification `json:"verification,omitempty"`
+
+	// Auth describes how requests to the integration's API are authenticated
+	// with the access token of the user's connection. Tokens are sent as
+	// bearer tokens if nil.
+	Auth *IntegrationAuth `json:"auth,omitempty"`
+}
+
+// DefaultIntegrationVersion is the version of definitions that do not set
+// one, and of nodes created before definitions were versioned.
+const DefaultIntegrationVersion = 1
+
+// Authentication schemes of integration APIs.
+const (
+	// OAuth 2 access tokens sent as bearer tokens.
+	AuthSchemeOAuth2 = "oauth2"
+	// Tokens sent as bearer tokens.
+	AuthSchemeBearer = "bearer"
+	// Base64 encoded "username:password" credentials sent as basic auth.
+	AuthSchemeBasic = "basic"
+	// API keys sent in a header or query param.
+	AuthSchemeAPIKey = "api_key"
+	// Integrations that do not call an API on behalf of users, such as the
+	// built-in integrations.
+	AuthSchemeNone = "none"
+)
+
+// IntegrationAuth describes how an integration's API is authenticated.
+type IntegrationAuth struct {
+	// One of the AuthScheme constants.
+	Scheme string `json:"scheme"`
+
+	// Where API keys are sent, either "header" or "query", and the name of
+	// the header or query param.
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
+
+	// Endpoints & scopes of OAuth 2 authorization.
+	AuthorizationURL string   `json:"authorization_url,omitempty"`
+	TokenURL         string   `json:"token_url,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+}
+
+// Apply authenticates a request with an access token according to the
+// scheme. Nil schemes send the token as a bearer token.
+func (a *IntegrationAuth) Apply(req *http.Request, token string) {
+	if token == "" {
+		return
+	}
+	switch {
+	case a == nil, a.Scheme == AuthSchemeOAuth2, a.Scheme == AuthSchemeBearer:
+		req.Header.Set("Authorization", "Bearer "+token)
+	case a.Scheme == AuthSchemeBasic:
+		req.Header.Set("Authorization", "Basic "+token)
+	case a.Scheme == AuthSchemeAPIKey && a.In == "query":
+		q := req.URL.Query()
+		q.Set(a.Name, token)
+		req.URL.RawQuery = q.Encode()
+	case a.Scheme == AuthSchemeAPIKey:
+		req.Header.Set(a.Name, token)
+	}
+}
+
+// validate returns an error if the scheme of an auth is unknown or an API
+// key auth does not say where keys are sent.
+func (a *IntegrationAuth) validate(path string) error {
+	if a == nil {
+		return nil
+	}
+	switch a.Scheme {
+	case AuthSchemeOAuth2, AuthSchemeBearer, AuthSchemeBasic:
+	case AuthSchemeAPIKey:
+		if a.In != "header" && a.In != "query" {
+			return definitionError(path+".in", "'%s' must be header or query.", a.In)
+		} else if a.Name == "" {
+			return definitionError(path+".name", "is required for API keys.")
+		}
+	default:
+		return definitionError(path+".scheme", "'%s' is not an auth scheme.", a.Scheme)
+	}
+	return nil
 }
 
 type Trigger struct {
@@ -17,7 +183,23 @@ type Trigger struct {
 	Description string        `json:"description"`
 	Endpoint    string        `json:"endpoint"`
 	Method      string        `json:"method"`
+	Inputs      []InputField  `json:"inputs"`
 	Outputs     []OutputField `json:"outputs"`
+
+	// Verification overrides the integration's webhook verification scheme
+	// for this trigger.
+	Verification *WebhookVerification `json:"verification,omitempty"`
+
+	// Idempotency identifies retried deliveries of the same event so that
+	// they only trigger runs once.
+	Idempotency *IdempotencyKey `json:"idempotency,omitempty"`
+
+	// Polling is set for triggers that are polled rather than delivered by
+	// webhooks.
+	Polling *Polling `json:"polling,omitempty"`
+
+	// Keys of triggers of earlier versions that were renamed to this one.
+	Replaces []string `json:"replaces,omitempty"`
 }
 
 type Action struct {
@@ -28,6 +210,9 @@ type Action struct {
 	Method      string        `json:"method"`
 	Inputs      []InputField  `json:"inputs"`
 	Outputs     []OutputField `json:"outputs"`
+
+	// Keys of actions of earlier versions that were renamed to this one.
+	Replaces []string `json:"replaces,omitempty"`
 }
 
 type InputField struct {
@@ -38,6 +223,9 @@ type InputField struct {
 	Type        FieldType `json:"type"`
 	Default     string    `json:"default"`
 	Example     string    `json:"example"`
+
+	// Keys of inputs of earlier versions that were renamed to this one.
+	Replaces []string `json:"replaces,omitempty"`
 }
 
 type OutputField struct {
@@ -48,52 +236,83 @@ type OutputField struct {
 	Path        string    `json:"path"`
 }
 
-//// Integration as interface?
-//type Integration interface {
-//	Describer
-//	// It would be nice if these methods were encapsulated somewhere else
-//	GetActions() []Action
-//	GetTriggers() []Trigger
-//}
-
+// Describer describes a native integration: its metadata, triggers, actions
+// & their fields, in the same format as the definitions of declarative
+// integrations. Descriptions must not change while the application runs.
 type Describer interface {
-	Describe() Metadata
+	Describe() *Integration
+}
+
+// Handler runs the actions of a native integration.
+type Handler interface {
+	// Runs an action with its inputs and returns its outputs, keyed by the
+	// keys of the action's output fields. Must return when ctx is done.
+	// Returns ENOTFOUND if the integration has no such action and EINVALID
+	// if a required input is missing.
+	Run(ctx context.Context, req ActionRequest) (map[string]interface{}, error)
 }
 
-type Metadata struct {
-	Name        string `json:"name"`
-	Ref         string `json:"ref"`
-	Description string `json:"description"`
+// Emitter delivers the events of a native integration's triggers.
+type Emitter interface {
+	// Emits the events of a trigger for a trigger node until ctx is done,
+	// then returns ctx.Err(). Any other error ends the subscription, which
+	// is restarted after a delay. Returns ENOTFOUND if the integration has
+	// no such trigger.
+	Subscribe(ctx context.Context, req SubscribeRequest, emit func(payload interface{}) error) error
 }
 
-type Installation struct {
-	IntegrationReference string `json:"integration_reference`
-	// TODO this probably needs config and stuff
+// NativeIntegration is an integration implemented in Go rather than by
+// calling an API described by a definition, for APIs that need custom
+// pagination, binary protocols or multi-step auth. Integrations with triggers
+// must also implement Emitter.
+type NativeIntegration interface {
+	Describer
+	Handler
 }
 
-//type Trigger interface {
-//	Describer
-//	Emitter
-//	// Fields []Field `json:"fields"`
-//}
-//
-//type Action interface {
-//	Describer
-//	Runner
-//	Handler
-//	Emitter
-//	//InputFields  []Field `json:"input_fields"`
-//	//OutputFields []Field `json:"output_fields"`
-//}
+// ActionRequest is a request to run an action of a native integration.
+type ActionRequest struct {
+	Action string
+	Inputs map[string]interface{}
 
-type Handler interface {
-	Handle(inputs []Field) error
-	DefineInputs() []Field
+	// Access token of the workflow owner's connection to the integration.
+	// Empty if the owner has not connected it.
+	Token string
+
+	WorkflowID uuid.UUID
+	NodeID     uuid.UUID
 }
 
-type Emitter interface {
-	Emit(outputs []Field) error
-	DefineOutputs() []Field
+// SubscribeRequest is a request to emit the events of a trigger of a native
+// integration for a trigger node.
+type SubscribeRequest struct {
+	Trigger string
+
+	// Params of the trigger node, keyed by input key.
+	Params map[string]string
+
+	// Access token of the workflow owner's connection to the integration.
+	// Empty if the owner has not connected it.
+	Token string
+
+	WorkflowID uuid.UUID
+	NodeID     uuid.UUID
+}
+
+// NativeRegistry looks up the native integrations the application was built
+// with.
+type NativeRegistry interface {
+	// Returns the native integration with the given key. Returns ENOTFOUND
+	// if no native integration has the key.
+	GetNativeIntegration(key string) (NativeIntegration, error)
+
+	// Returns every native integration, ordered by key.
+	GetNativeIntegrations() []NativeIntegration
+}
+
+type Installation struct {
+	IntegrationReference string `json:"integration_reference"`
+	// TODO this probably needs config and stuff
 }
 
 type FieldType string
@@ -117,7 +336,361 @@ type Field struct {
 
 type IntegrationService interface {
 	GetIntegrations(ctx context.Context, req GetIntegrationsRequest) ([]*Integration, int, error)
+
+	// Retrieves an integration by its key. Returns ENOTFOUND if no
+	// integration with the given key exists.
+	GetIntegrationByKey(ctx context.Context, key string) (*Integration, error)
+
+	// Retrieves a version of an integration, which may be older than the
+	// current one. Returns ENOTFOUND if the version is not defined.
+	GetIntegrationVersion(ctx context.Context, key string, version int) (*Integration, error)
 }
 
+// GetIntegrationsRequest filters & paginates the integrations of the catalog.
 type GetIntegrationsRequest struct {
+	// Restricts results to integrations whose labels or descriptions, or
+	// those of their triggers & actions, contain every word of the query.
+	// Matching ignores case.
+	Search *string `json:"search"`
+
+	// Restricts results to integrations of the category, ignoring case.
+	Category *string `json:"category"`
+
+	// Restricts results to integrations with the auth scheme, as returned by
+	// Integration.AuthScheme.
+	AuthScheme *string `json:"auth_scheme"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// FilterIntegrations returns the integrations matching a request, sorted by
+// label, along with the total number of matches before pagination.
+func FilterIntegrations(apps []*Integration, req GetIntegrationsRequest) ([]*Integration, int) {
+	var terms []string
+	if req.Search != nil {
+		terms = strings.Fields(strings.ToLower(*req.Search))
+	}
+
+	var matches []*Integration
+	for _, app := range apps {
+		if req.Category != nil && !strings.EqualFold(app.Category, *req.Category) {
+			continue
+		} else if req.AuthScheme != nil && !strings.EqualFold(app.AuthScheme(), *req.AuthScheme) {
+			continue
+		} else if len(terms) > 0 && !app.matches(terms) {
+			continue
+		}
+		matches = append(matches, app)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return strings.ToLower(matches[i].Label) < strings.ToLower(matches[j].Label)
+	})
+
+	total := len(matches)
+	if req.Limit > 0 {
+		offset := 0
+		if req.Page > 1 {
+			offset = (req.Page - 1) * req.Limit
+		}
+		if offset > len(matches) {
+			offset = len(matches)
+		}
+		matches = matches[offset:]
+		if len(matches) > req.Limit {
+			matches = matches[:req.Limit]
+		}
+	}
+	return matches, total
+}
+
+// matches returns true if every term appears in the labels or descriptions of
+// an integration, its triggers or its actions. Terms must be lower case.
+func (i *Integration) matches(terms []string) bool {
+	texts := []string{i.Label, i.Description}
+	for _, t := range i.Triggers {
+		texts = append(texts, t.Label, t.Description)
+	}
+	for _, a := range i.Actions {
+		texts = append(texts, a.Label, a.Description)
+	}
+	text := strings.ToLower(strings.Join(texts, "\n"))
+
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthScheme returns the scheme requests to the integration's API are
+// authenticated with. Returns AuthSchemeNone if the integration has no API.
+func (i *Integration) AuthScheme() string {
+	if i.Auth != nil {
+		return i.Auth.Scheme
+	} else if i.BaseURL != "" {
+		return AuthSchemeBearer
+	}
+	return AuthSchemeNone
+}
+
+// GetTrigger returns the trigger with the given key. Returns ENOTFOUND if the
+// integration does not define the trigger.
+func (i *Integration) GetTrigger(key string) (*Trigger, error) {
+	for j := range i.Triggers {
+		if i.Triggers[j].Key == key {
+			return &i.Triggers[j], nil
+		}
+	}
+	return nil, Errorf(ENOTFOUND, "Trigger '%s' not found for integration '%s'.", key, i.Key)
+}
+
+// GetAction returns the action with the given key. Returns ENOTFOUND if the
+// integration does not define the action.
+func (i *Integration) GetAction(key string) (*Action, error) {
+	for j := range i.Actions {
+		if i.Actions[j].Key == key {
+			return &i.Actions[j], nil
+		}
+	}
+	return nil, Errorf(ENOTFOUND, "Action '%s' not found for integration '%s'.", key, i.Key)
+}
+
+// WebhookVerification returns the verification scheme that applies to
+// deliveries for the given trigger. The trigger's scheme takes precedence over
+// the integration's. Returns nil if deliveries are not verified.
+func (i *Integration) WebhookVerification(trigger *Trigger) *WebhookVerification {
+	if trigger != nil && trigger.Verification != nil {
+		return trigger.Verification
+	}
+	return i.Verification
+}
+
+// integrationKeyRegexp matches the keys of integrations, triggers & actions,
+// such as "TWITTER_V1".
+var integrationKeyRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// inputKeyRegexp matches the keys of input fields, which may be the names of
+// API params such as "page-size" or "filter.name".
+var inputKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// outputKeyRegexp matches the keys of output fields.
+var outputKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate returns an error if an integration definition is invalid. Errors
+// name the field at fault, such as "actions[0].inputs[2].type".
+func (i *Integration) Validate() error {
+	if !integrationKeyRegexp.MatchString(i.Key) {
+		return definitionError("key", "'%s' must be upper case letters, digits & underscores.", i.Key)
+	} else if i.Label == "" {
+		return definitionError("label", "is required.")
+	}
+	if i.Version < 0 {
+		return definitionError("version", "cannot be negative.")
+	}
+	if i.BaseURL != "" {
+		if u, err := url.Parse(i.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return definitionError("base_url", "'%s' must be an absolute http or https URL.", i.BaseURL)
+		}
+	}
+	if err := i.Verification.validate("verification"); err != nil {
+		return err
+	} else if err := i.Auth.validate("auth"); err != nil {
+		return err
+	}
+
+	keys := make(map[string]bool)
+	for j, t := range i.Triggers {
+		path := fmt.Sprintf("triggers[%d]", j)
+		if err := validateEndpoint(path, t.Key, t.Label, t.Method, keys); err != nil {
+			return err
+		} else if err := validateInputs(path, t.Inputs); err != nil {
+			return err
+		} else if err := validateOutputs(path, t.Outputs); err != nil {
+			return err
+		} else if err := t.Verification.validate(path + ".verification"); err != nil {
+			return err
+		}
+		if k := t.Idempotency; k != nil {
+			if err := validatePath(path+".idempotency.path", k.Path); err != nil {
+				return err
+			} else if k.Header == "" && k.Path == "" {
+				return definitionError(path+".idempotency", "requires a header or path.")
+			}
+		}
+		if p := t.Polling; p != nil {
+			if i.BaseURL == "" || t.Endpoint == "" {
+				return definitionError(path+".polling", "requires a base URL and an endpoint.")
+			} else if p.IntervalSeconds < 0 {
+				return definitionError(path+".polling.interval_seconds", "cannot be negative.")
+			}
+			for field, v := range map[string]string{"items_path": p.ItemsPath, "id_path": p.IDPath, "cursor_path": p.CursorPath} {
+				if err := validatePath(path+".polling."+field, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	keys = make(map[string]bool)
+	for j, a := range i.Actions {
+		path := fmt.Sprintf("actions[%d]", j)
+		if err := validateEndpoint(path, a.Key, a.Label, a.Method, keys); err != nil {
+			return err
+		} else if err := validateInputs(path, a.Inputs); err != nil {
+			return err
+		} else if err := validateOutputs(path, a.Outputs); err != nil {
+			return err
+		} else if i.BaseURL == "" && a.Endpoint != "" {
+			return definitionError(path+".endpoint", "requires the integration to have a base URL.")
+		}
+	}
+
+	// Renamed keys are checked once every key is known.
+	keys, replaced := make(map[string]bool), make(map[string]bool)
+	for _, t := range i.Triggers {
+		keys[t.Key] = true
+	}
+	for j, t := range i.Triggers {
+		if err := validateReplaces(fmt.Sprintf("triggers[%d]", j), t.Replaces, keys, replaced); err != nil {
+			return err
+		}
+	}
+	keys, replaced = make(map[string]bool), make(map[string]bool)
+	for _, a := range i.Actions {
+		keys[a.Key] = true
+	}
+	for j, a := range i.Actions {
+		if err := validateReplaces(fmt.Sprintf("actions[%d]", j), a.Replaces, keys, replaced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateReplaces returns an error if a renamed key is still in use or was
+// renamed more than once.
+func validateReplaces(path string, replaces []string, keys, replaced map[string]bool) error {
+	for j, key := range replaces {
+		keyPath := fmt.Sprintf("%s.replaces[%d]", path, j)
+		if keys[key] {
+			return definitionError(keyPath, "'%s' is still in use.", key)
+		} else if replaced[key] {
+			return definitionError(keyPath, "'%s' is replaced more than once.", key)
+		}
+		replaced[key] = true
+	}
+	return nil
+}
+
+// validateEndpoint returns an error if the key, label or method of a trigger
+// or action is invalid, or if its key has already been used.
+func validateEndpoint(path, key, label, method string, keys map[string]bool) error {
+	if !integrationKeyRegexp.MatchString(key) {
+		return definitionError(path+".key", "'%s' must be upper case letters, digits & underscores.", key)
+	} else if keys[key] {
+		return definitionError(path+".key", "'%s' is not unique.", key)
+	} else if label == "" {
+		return definitionError(path+".label", "is required.")
+	}
+	keys[key] = true
+
+	// Methods may be prefixed as in "JSON_HTTP_POST".
+	m := strings.TrimPrefix(strings.TrimPrefix(method, "JSON_"), "HTTP_")
+	switch strings.ToUpper(m) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead:
+		return nil
+	}
+	return definitionError(path+".method", "'%s' is not an HTTP method.", method)
+}
+
+// validateInputs returns an error if an input field is invalid or its key is
+// not unique.
+func validateInputs(path string, fields []InputField) error {
+	keys := make(map[string]bool, len(fields))
+	for j, f := range fields {
+		fieldPath := fmt.Sprintf("%s.inputs[%d]", path, j)
+		if !inputKeyRegexp.MatchString(f.Key) {
+			return definitionError(fieldPath+".key", "'%s' must be letters, digits, underscores, dashes & dots.", f.Key)
+		} else if err := validateField(fieldPath, f.Key, f.Type, keys); err != nil {
+			return err
+		}
+		if f.Default != "" {
+			if _, err := coerce(f.Type, f.Default); err != nil {
+				return definitionError(fieldPath+".default", "'%s' %s.", f.Default, err)
+			}
+		}
+	}
+
+	replaced := make(map[string]bool)
+	for j, f := range fields {
+		if err := validateReplaces(fmt.Sprintf("%s.inputs[%d]", path, j), f.Replaces, keys, replaced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOutputs returns an error if an output field is invalid, its key is
+// not unique or its path cannot be parsed.
+func validateOutputs(path string, fields []OutputField) error {
+	keys := make(map[string]bool, len(fields))
+	for j, f := range fields {
+		fieldPath := fmt.Sprintf("%s.outputs[%d]", path, j)
+		if !outputKeyRegexp.MatchString(f.Key) {
+			return definitionError(fieldPath+".key", "'%s' must be letters, digits & underscores.", f.Key)
+		} else if err := validateField(fieldPath, f.Key, f.Type, keys); err != nil {
+			return err
+		} else if err := validatePath(fieldPath+".path", f.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField returns an error if the key of a field is not unique or its
+// type is unknown.
+func validateField(path, key string, typ FieldType, keys map[string]bool) error {
+	if keys[key] {
+		return definitionError(path+".key", "'%s' is not unique.", key)
+	}
+	keys[key] = true
+
+	switch typ {
+	case FieldTypeNumber, FieldTypeString, FieldTypeBoolean, FieldTypeDateTime, FieldTypeComplex:
+		return nil
+	}
+	return definitionError(path+".type", "'%s' must be one of %s, %s, %s, %s or %s.",
+		typ, FieldTypeNumber, FieldTypeString, FieldTypeBoolean, FieldTypeDateTime, FieldTypeComplex)
+}
+
+// validatePath returns an error if a path is set but cannot be parsed.
+func validatePath(path, v string) error {
+	if _, err := ParsePath(v); err != nil {
+		return definitionError(path, "%s", ErrorMessage(err))
+	}
+	return nil
+}
+
+// validate returns an error if the scheme of a verification is unknown.
+func (v *WebhookVerification) validate(path string) error {
+	if v == nil {
+		return nil
+	}
+	switch v.Scheme {
+	case VerificationSchemeGitHub, VerificationSchemeStripe, VerificationSchemeSlack, VerificationSchemeSharedSecret, VerificationSchemeTwitter:
+	default:
+		return definitionError(path+".scheme", "'%s' is not a verification scheme.", v.Scheme)
+	}
+	if v.ToleranceSeconds < 0 {
+		return definitionError(path+".tolerance_seconds", "cannot be negative.")
+	}
+	return nil
+}
+
+// definitionError returns an EINVALID error for a field of an integration
+// definition.
+func definitionError(path, format string, args ...interface{}) error {
+	return Errorf(EINVALID, "%s: %s", path, fmt.Sprintf(format, args...))
 }
@@ -0,0 +1,286 @@
+// Package conformance checks that a native integration implements the
+// contract of flow.NativeIntegration. Authors of native integrations run it
+// from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, myintegration.New(), conformance.Options{})
+//	}
+package conformance
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// DefaultTimeout is the time an integration has to return from a call that
+// must return promptly, such as a call with a canceled context.
+const DefaultTimeout = 5 * time.Second
+
+// Options configures the checks run against an integration.
+type Options struct {
+	// Inputs of the actions run by the kit, keyed by action key. Actions
+	// without inputs are run with the examples & defaults of their fields.
+	Inputs map[string]map[string]interface{}
+
+	// Actions that are not run with their inputs, such as actions that call
+	// APIs that cannot be reached from tests. Their error handling is still
+	// checked.
+	Skip []string
+
+	// Params of the triggers subscribed to by the kit, keyed by trigger key.
+	// Triggers without params are subscribed to with the examples &
+	// defaults of their fields.
+	Params map[string]map[string]string
+
+	// Time a subscription has to emit its first event. Emitting is not
+	// checked if zero.
+	EmitTimeout time.Duration
+
+	// Time an integration has to return from calls that must return
+	// promptly. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Run checks an integration as subtests of t.
+func Run(t *testing.T, app flow.NativeIntegration, opts Options) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	def := app.Describe()
+	if def == nil {
+		t.Fatal("Describe() returned nil")
+	}
+
+	t.Run("Describe", func(t *testing.T) {
+		if err := def.Validate(); err != nil {
+			t.Fatalf("invalid description: %s", flow.ErrorMessage(err))
+		}
+		if !reflect.DeepEqual(def, app.Describe()) {
+			t.Fatal("description changed between calls")
+		}
+		if _, ok := app.(flow.Emitter); !ok && len(def.Triggers) > 0 {
+			t.Fatal("integration has triggers but does not implement flow.Emitter")
+		}
+	})
+
+	t.Run("UnknownAction", func(t *testing.T) {
+		_, err := call(t, opts.Timeout, func() (map[string]interface{}, error) {
+			return app.Run(context.Background(), flow.ActionRequest{Action: "NO_SUCH_ACTION"})
+		})
+		if code := flow.ErrorCode(err); code != flow.ENOTFOUND {
+			t.Fatalf("error code = %q, want %q (err: %v)", code, flow.ENOTFOUND, err)
+		}
+	})
+
+	for _, action := range def.Actions {
+		action := action
+		t.Run("Action/"+action.Key, func(t *testing.T) {
+			testAction(t, app, action, opts)
+		})
+	}
+
+	for _, trigger := range def.Triggers {
+		trigger := trigger
+		emitter, ok := app.(flow.Emitter)
+		if !ok {
+			break
+		}
+		t.Run("Trigger/"+trigger.Key, func(t *testing.T) {
+			testTrigger(t, emitter, trigger, opts)
+		})
+	}
+
+	if emitter, ok := app.(flow.Emitter); ok {
+		t.Run("UnknownTrigger", func(t *testing.T) {
+			err := subscribe(t, opts.Timeout, func() error {
+				return emitter.Subscribe(context.Background(), flow.SubscribeRequest{Trigger: "NO_SUCH_TRIGGER"}, func(interface{}) error { return nil })
+			})
+			if code := flow.ErrorCode(err); code != flow.ENOTFOUND {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, flow.ENOTFOUND, err)
+			}
+		})
+	}
+}
+
+// testAction checks that an action runs with its inputs and returns its
+// declared outputs, rejects missing required inputs and respects
+// cancellation.
+func testAction(t *testing.T, app flow.NativeIntegration, action flow.Action, opts Options) {
+	inputs, ok := opts.Inputs[action.Key]
+	if !ok {
+		inputs = make(map[string]interface{})
+		for _, f := range action.Inputs {
+			if v := sample(f); v != "" {
+				inputs[f.Key] = v
+			}
+		}
+	}
+
+	if !contains(opts.Skip, action.Key) {
+		t.Run("Run", func(t *testing.T) {
+			outputs, err := call(t, opts.Timeout, func() (map[string]interface{}, error) {
+				return app.Run(context.Background(), flow.ActionRequest{Action: action.Key, Inputs: inputs})
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, f := range action.Outputs {
+				if _, ok := outputs[f.Key]; !ok {
+					t.Errorf("declared output %q is missing", f.Key)
+				}
+			}
+		})
+	}
+
+	for _, f := range action.Inputs {
+		if !f.Required {
+			continue
+		}
+		f := f
+		t.Run("MissingInput/"+f.Key, func(t *testing.T) {
+			partial := make(map[string]interface{}, len(inputs))
+			for k, v := range inputs {
+				if k != f.Key {
+					partial[k] = v
+				}
+			}
+			_, err := call(t, opts.Timeout, func() (map[string]interface{}, error) {
+				return app.Run(context.Background(), flow.ActionRequest{Action: action.Key, Inputs: partial})
+			})
+			if code := flow.ErrorCode(err); code != flow.EINVALID {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, flow.EINVALID, err)
+			}
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := call(t, opts.Timeout, func() (map[string]interface{}, error) {
+			return app.Run(ctx, flow.ActionRequest{Action: action.Key, Inputs: inputs})
+		}); err == nil {
+			t.Fatal("expected an error for a canceled context")
+		}
+	})
+}
+
+// testTrigger checks that a subscription returns once its context is done
+// and, if opts.EmitTimeout is set, that it emits an event.
+func testTrigger(t *testing.T, emitter flow.Emitter, trigger flow.Trigger, opts Options) {
+	params, ok := opts.Params[trigger.Key]
+	if !ok {
+		params = make(map[string]string)
+		for _, f := range trigger.Inputs {
+			if v := sample(f); v != "" {
+				params[f.Key] = v
+			}
+		}
+	}
+	req := flow.SubscribeRequest{Trigger: trigger.Key, Params: params}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := subscribe(t, opts.Timeout, func() error {
+			return emitter.Subscribe(ctx, req, func(interface{}) error { return nil })
+		}); err == nil {
+			t.Fatal("expected an error for a canceled context")
+		}
+	})
+
+	if opts.EmitTimeout == 0 {
+		return
+	}
+	t.Run("Emit", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := make(chan interface{}, 1)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- emitter.Subscribe(ctx, req, func(payload interface{}) error {
+				select {
+				case events <- payload:
+				default:
+				}
+				return nil
+			})
+		}()
+
+		select {
+		case payload := <-events:
+			if payload == nil {
+				t.Error("emitted a nil payload")
+			}
+		case err := <-errs:
+			t.Fatalf("subscription ended before emitting: %v", err)
+		case <-time.After(opts.EmitTimeout):
+			t.Fatalf("no event emitted within %s", opts.EmitTimeout)
+		}
+
+		cancel()
+		select {
+		case <-errs:
+		case <-time.After(opts.Timeout):
+			t.Fatalf("subscription did not return within %s of its context being canceled", opts.Timeout)
+		}
+	})
+}
+
+// call runs an action and fails the test if it does not return in time.
+func call(t *testing.T, timeout time.Duration, fn func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	t.Helper()
+	type result struct {
+		outputs map[string]interface{}
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		outputs, err := fn()
+		ch <- result{outputs, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.outputs, r.err
+	case <-time.After(timeout):
+		t.Fatalf("did not return within %s", timeout)
+		return nil, nil
+	}
+}
+
+// subscribe runs a subscription and fails the test if it does not return in
+// time.
+func subscribe(t *testing.T, timeout time.Duration, fn func() error) error {
+	t.Helper()
+	ch := make(chan error, 1)
+	go func() { ch <- fn() }()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("did not return within %s", timeout)
+		return nil
+	}
+}
+
+// sample returns the example of a field, or its default if it has none.
+func sample(f flow.InputField) string {
+	if f.Example != "" {
+		return f.Example
+	}
+	return f.Default
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
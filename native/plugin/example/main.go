@@ -0,0 +1,159 @@
+// Command example is an example plugin. It implements a text integration
+// whose actions transform text, and a trigger that emits a counter at an
+// interval. Add it to the [[plugins]] of the config after building it:
+//
+//	go build -o flow-text-plugin ./native/plugin/example
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmesh/flow"
+	"github.com/openmesh/flow/native/plugin"
+)
+
+func main() {
+	if err := plugin.Serve(&integration{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// integration implements the text integration.
+type integration struct{}
+
+func (i *integration) Describe() *flow.Integration {
+	return definition
+}
+
+func (i *integration) Run(ctx context.Context, req flow.ActionRequest) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	text, ok := req.Inputs["text"].(string)
+	switch req.Action {
+	case "UPPERCASE":
+		if !ok {
+			return nil, flow.Errorf(flow.EINVALID, "Input 'text' is required.")
+		}
+		return map[string]interface{}{"text": strings.ToUpper(text)}, nil
+	case "WORD_COUNT":
+		if !ok {
+			return nil, flow.Errorf(flow.EINVALID, "Input 'text' is required.")
+		}
+		return map[string]interface{}{"count": len(strings.Fields(text))}, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Action '%s' not found for integration 'TEXT'.", req.Action)
+}
+
+func (i *integration) Subscribe(ctx context.Context, req flow.SubscribeRequest, emit func(payload interface{}) error) error {
+	if req.Trigger != "COUNTER" {
+		return flow.Errorf(flow.ENOTFOUND, "Trigger '%s' not found for integration 'TEXT'.", req.Trigger)
+	}
+
+	interval := time.Second
+	if v := req.Params["interval_ms"]; v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 1 {
+			return flow.Errorf(flow.EINVALID, "Input 'interval_ms' must be a positive whole number.")
+		}
+		interval = time.Duration(ms) * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for count := 1; ; count++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := emit(map[string]interface{}{"count": count}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var definition = &flow.Integration{
+	Label:       "Text",
+	Description: "Transforms text. An example plugin.",
+	Key:         "TEXT",
+	Version:     flow.DefaultIntegrationVersion,
+	Category:    "utility",
+	Triggers: []flow.Trigger{
+		{
+			Key:         "COUNTER",
+			Label:       "Counter",
+			Description: "Emits an increasing count at an interval.",
+			Inputs: []flow.InputField{
+				{
+					Key:         "interval_ms",
+					Label:       "Interval",
+					Description: "The number of milliseconds between events.",
+					Type:        flow.FieldTypeNumber,
+					Default:     "1000",
+				},
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label: "Count",
+					Key:   "count",
+					Type:  flow.FieldTypeNumber,
+					Path:  "count",
+				},
+			},
+		},
+	},
+	Actions: []flow.Action{
+		{
+			Key:         "UPPERCASE",
+			Label:       "Uppercase",
+			Description: "Converts text to upper case.",
+			Inputs: []flow.InputField{
+				{
+					Key:      "text",
+					Label:    "Text",
+					Required: true,
+					Type:     flow.FieldTypeString,
+					Example:  "hello",
+				},
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label: "Text",
+					Key:   "text",
+					Type:  flow.FieldTypeString,
+					Path:  "text",
+				},
+			},
+		},
+		{
+			Key:         "WORD_COUNT",
+			Label:       "Count Words",
+			Description: "Counts the words of a text.",
+			Inputs: []flow.InputField{
+				{
+					Key:      "text",
+					Label:    "Text",
+					Required: true,
+					Type:     flow.FieldTypeString,
+					Example:  "hello world",
+				},
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label: "Count",
+					Key:   "count",
+					Type:  flow.FieldTypeNumber,
+					Path:  "count",
+				},
+			},
+		},
+	},
+}
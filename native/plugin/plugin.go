@@ -0,0 +1,548 @@
+// Package plugin runs native integrations as separate executables that speak
+// JSON-RPC 2.0 over their standard input & output, one JSON value per line.
+// Plugins can be written in any language. Plugins written in Go implement
+// flow.NativeIntegration and call Serve.
+//
+// The host starts the executable and sends a "describe" request, answered
+// with the protocol version & the flow.Integration definition of the plugin.
+// Actions are run with "run" requests and triggers are subscribed to with
+// "subscribe" requests, after which the plugin sends "emit" notifications
+// until it receives an "unsubscribe" request. The host checks the health of
+// the plugin with "health" requests and restarts it according to its
+// restart policy when it exits or stops answering.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// Restart policies of plugins.
+const (
+	// Plugins are restarted whenever they exit.
+	RestartAlways = "always"
+	// Plugins are restarted when they exit with an error or fail their
+	// health checks.
+	RestartOnFailure = "on-failure"
+	// Plugins are never restarted. Their actions & triggers are unavailable
+	// once they exit.
+	RestartNever = "never"
+)
+
+// Default timeouts & intervals of plugins.
+const (
+	// Time a plugin has to answer a request.
+	DefaultTimeout = 30 * time.Second
+
+	// Time a plugin has to describe itself once started.
+	DefaultStartTimeout = 10 * time.Second
+
+	// Interval & timeout of health checks, and the number of consecutive
+	// failed checks after which a plugin is restarted.
+	DefaultHealthInterval = 10 * time.Second
+	DefaultHealthTimeout  = 5 * time.Second
+	DefaultHealthFailures = 3
+
+	// Delay before a plugin is restarted. The delay doubles with each
+	// consecutive restart up to MaxRestartDelay.
+	DefaultRestartDelay = time.Second
+	MaxRestartDelay     = time.Minute
+
+	// Time a plugin has to exit once its input is closed before it is
+	// killed.
+	StopTimeout = 5 * time.Second
+)
+
+// Plugin runs a plugin executable and implements flow.NativeIntegration &
+// flow.Emitter by forwarding calls to it. Register it with a native.Registry
+// once opened.
+type Plugin struct {
+	// Path & arguments of the executable.
+	Path string
+	Args []string
+
+	// Environment variables set in addition to those of the host, as
+	// "KEY=value", and the working directory of the executable.
+	Env []string
+	Dir string
+
+	// Time the plugin has to answer a request & to describe itself once
+	// started.
+	Timeout      time.Duration
+	StartTimeout time.Duration
+
+	// Interval & timeout of health checks, and the number of consecutive
+	// failed checks after which the plugin is restarted.
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	HealthFailures int
+
+	// One of the Restart constants. Defaults to RestartOnFailure.
+	Restart string
+
+	// Maximum number of consecutive restarts, after which the plugin is
+	// left stopped. Restarts are consecutive until the plugin passes a
+	// health check. Zero means no limit.
+	MaxRestarts int
+
+	// Delay before the plugin is restarted.
+	RestartDelay time.Duration
+
+	Logger log.Logger
+
+	def *flow.Integration
+
+	mu   sync.RWMutex
+	proc *process // nil while the plugin is not running
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new instance of Plugin that runs an executable.
+func New(path string, args ...string) *Plugin {
+	return &Plugin{
+		Path:           path,
+		Args:           args,
+		Timeout:        DefaultTimeout,
+		StartTimeout:   DefaultStartTimeout,
+		HealthInterval: DefaultHealthInterval,
+		HealthTimeout:  DefaultHealthTimeout,
+		HealthFailures: DefaultHealthFailures,
+		Restart:        RestartOnFailure,
+		RestartDelay:   DefaultRestartDelay,
+		Logger:         log.NewNopLogger(),
+		done:           make(chan struct{}),
+	}
+}
+
+// Open starts the plugin and reads its definition. Returns an error if the
+// plugin cannot be started or does not describe itself in time.
+func (p *Plugin) Open() error {
+	switch p.Restart {
+	case RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return fmt.Errorf("invalid restart policy: %q", p.Restart)
+	}
+
+	proc, def, err := p.start()
+	if err != nil {
+		return err
+	}
+	p.def, p.proc = def, proc
+
+	p.wg.Add(1)
+	go func() { defer p.wg.Done(); p.supervise(proc) }()
+	return nil
+}
+
+// Close stops the plugin. Runs & subscriptions in progress fail.
+func (p *Plugin) Close() error {
+	close(p.done)
+
+	p.mu.Lock()
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+	if proc != nil {
+		proc.stop()
+	}
+
+	p.wg.Wait()
+	return nil
+}
+
+// Describe returns the definition the plugin described itself with when it
+// was opened.
+func (p *Plugin) Describe() *flow.Integration {
+	return p.def
+}
+
+// Run runs an action of the plugin. Returns EUNAVAILABLE if the plugin is not
+// running or does not answer within its timeout.
+func (p *Plugin) Run(ctx context.Context, req flow.ActionRequest) (map[string]interface{}, error) {
+	proc, err := p.process()
+	if err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	var outputs map[string]interface{}
+	if err := proc.conn.call(callCtx, MethodRun, runParams{
+		Action:     req.Action,
+		Inputs:     req.Inputs,
+		Token:      req.Token,
+		WorkflowID: req.WorkflowID,
+		NodeID:     req.NodeID,
+	}, &outputs); err != nil {
+		return nil, p.callError(ctx, err)
+	}
+	return outputs, nil
+}
+
+// Subscribe subscribes to a trigger of the plugin and emits its events until
+// ctx is done. Returns an error when the plugin ends the subscription or
+// exits, in which case the subscription is restarted by the caller.
+func (p *Plugin) Subscribe(ctx context.Context, req flow.SubscribeRequest, emit func(payload interface{}) error) error {
+	proc, err := p.process()
+	if err != nil {
+		return err
+	}
+
+	id := uuid.New().String()
+	sub := proc.addSubscription(id)
+	defer proc.removeSubscription(id)
+
+	callCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	if err := proc.conn.call(callCtx, MethodSubscribe, subscribeParams{
+		Subscription: id,
+		Trigger:      req.Trigger,
+		Params:       req.Params,
+		Token:        req.Token,
+		WorkflowID:   req.WorkflowID,
+		NodeID:       req.NodeID,
+	}, nil); err != nil {
+		return p.callError(ctx, err)
+	}
+
+	for {
+		select {
+		case payload := <-sub.events:
+			if err := emit(payload); err != nil {
+				p.unsubscribe(proc, id)
+				return err
+			}
+		case err := <-sub.ended:
+			return err
+		case <-proc.conn.done:
+			return flow.Errorf(flow.EUNAVAILABLE, "Integration '%s' exited.", p.def.Key)
+		case <-ctx.Done():
+			p.unsubscribe(proc, id)
+			return ctx.Err()
+		}
+	}
+}
+
+// unsubscribe ends a subscription of the plugin. Errors are only logged, as
+// the plugin ends its subscriptions when it exits.
+func (p *Plugin) unsubscribe(proc *process, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+	if err := proc.conn.call(ctx, MethodUnsubscribe, unsubscribeParams{Subscription: id}, nil); err != nil {
+		_ = p.Logger.Log("msg", "cannot unsubscribe from plugin", "plugin", p.Path, "err", err)
+	}
+}
+
+// process returns the running process of the plugin. Returns EUNAVAILABLE
+// while the plugin is not running.
+func (p *Plugin) process() (*process, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.proc == nil {
+		return nil, flow.Errorf(flow.EUNAVAILABLE, "Integration '%s' is unavailable.", p.def.Key)
+	}
+	return p.proc, nil
+}
+
+// callError converts the error of a call that failed because the plugin
+// exited or timed out. Errors of the caller's ctx are returned as they are.
+func (p *Plugin) callError(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, errClosed):
+		return flow.Errorf(flow.EUNAVAILABLE, "Integration '%s' exited.", p.def.Key)
+	case errors.Is(err, context.DeadlineExceeded):
+		return flow.Errorf(flow.EUNAVAILABLE, "Integration '%s' did not respond within %s.", p.def.Key, p.Timeout)
+	}
+	return err
+}
+
+// start starts the executable and reads its definition.
+func (p *Plugin) start() (*process, *flow.Integration, error) {
+	cmd := exec.Command(p.Path, p.Args...)
+	cmd.Dir = p.Dir
+	if len(p.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.Env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("cannot start plugin %s: %w", p.Path, err)
+	}
+
+	proc := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		conn:   newConn(stdout, stdin),
+		subs:   make(map[string]*subscription),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		logged := make(chan struct{})
+		go func() { defer close(logged); p.logOutput(stderr) }()
+		if err := proc.conn.serve(proc.handle); err != nil {
+			_ = p.Logger.Log("msg", "cannot read from plugin", "plugin", p.Path, "err", err)
+		}
+		<-logged
+
+		// Pipes must be read until they are closed before waiting.
+		proc.err = cmd.Wait()
+		close(proc.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.StartTimeout)
+	defer cancel()
+
+	var res describeResult
+	if err := proc.conn.call(ctx, MethodDescribe, describeParams{ProtocolVersion: ProtocolVersion}, &res); err != nil {
+		proc.kill()
+		return nil, nil, fmt.Errorf("cannot describe plugin %s: %w", p.Path, err)
+	} else if res.ProtocolVersion != ProtocolVersion {
+		proc.kill()
+		return nil, nil, fmt.Errorf("plugin %s implements protocol version %d, not %d", p.Path, res.ProtocolVersion, ProtocolVersion)
+	} else if res.Integration == nil {
+		proc.kill()
+		return nil, nil, fmt.Errorf("plugin %s did not describe an integration", p.Path)
+	}
+	return proc, res.Integration, nil
+}
+
+// logOutput logs each line the plugin writes to its standard error.
+func (p *Plugin) logOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		_ = p.Logger.Log("plugin", p.Path, "output", scanner.Text())
+	}
+}
+
+// supervise checks the health of the plugin's process and restarts it
+// according to the restart policy when it exits or fails its health checks,
+// until the plugin is closed.
+func (p *Plugin) supervise(proc *process) {
+	ticker := time.NewTicker(p.HealthInterval)
+	defer ticker.Stop()
+
+	restarts, failures, unhealthy := 0, 0, false
+	for {
+		select {
+		case <-p.done:
+			return
+
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), p.HealthTimeout)
+			err := proc.conn.call(ctx, MethodHealth, nil, nil)
+			cancel()
+			if err == nil {
+				restarts, failures = 0, 0
+				continue
+			}
+
+			failures++
+			_ = p.Logger.Log("msg", "plugin health check failed", "plugin", p.Path, "failures", failures, "err", err)
+			if failures >= p.HealthFailures && !unhealthy {
+				unhealthy = true
+				proc.kill()
+			}
+
+		case <-proc.exited:
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+			p.mu.Lock()
+			p.proc = nil
+			p.mu.Unlock()
+
+			failed := unhealthy || proc.err != nil
+			_ = p.Logger.Log("msg", "plugin exited", "plugin", p.Path, "unhealthy", unhealthy, "err", proc.err)
+			if proc = p.restart(failed, &restarts); proc == nil {
+				return
+			}
+			failures, unhealthy = 0, false
+		}
+	}
+}
+
+// restart starts the plugin again unless the restart policy or the maximum
+// number of restarts prevents it. Failed starts are retried with a growing
+// delay. Returns nil if the plugin is left stopped.
+func (p *Plugin) restart(failed bool, restarts *int) *process {
+	for {
+		if p.Restart == RestartNever || (p.Restart == RestartOnFailure && !failed) {
+			_ = p.Logger.Log("msg", "plugin not restarted", "plugin", p.Path, "restart", p.Restart)
+			return nil
+		} else if p.MaxRestarts > 0 && *restarts >= p.MaxRestarts {
+			_ = p.Logger.Log("msg", "plugin not restarted after too many restarts", "plugin", p.Path, "restarts", *restarts)
+			return nil
+		}
+
+		delay := p.RestartDelay
+		for i := 0; i < *restarts && delay < MaxRestartDelay; i++ {
+			delay *= 2
+		}
+		if delay > MaxRestartDelay {
+			delay = MaxRestartDelay
+		}
+		*restarts++
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-p.done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		proc, def, err := p.start()
+		if err != nil {
+			_ = p.Logger.Log("msg", "cannot restart plugin", "plugin", p.Path, "err", err)
+			failed = true
+			continue
+		}
+		if !reflect.DeepEqual(def, p.def) {
+			_ = p.Logger.Log("msg", "plugin definition changed, restart the application to serve it", "plugin", p.Path)
+		}
+
+		p.mu.Lock()
+		select {
+		case <-p.done:
+			p.mu.Unlock()
+			proc.stop()
+			return nil
+		default:
+		}
+		p.proc = proc
+		p.mu.Unlock()
+		_ = p.Logger.Log("msg", "plugin restarted", "plugin", p.Path, "restarts", *restarts)
+		return proc
+	}
+}
+
+// process is a running plugin executable.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.Closer
+	conn  *conn
+
+	mu   sync.Mutex
+	subs map[string]*subscription // by subscription ID
+
+	// Closed once the executable exited, after err is set.
+	exited chan struct{}
+	err    error
+}
+
+// subscription receives the notifications of a subscription.
+type subscription struct {
+	events chan interface{}
+	ended  chan error
+	done   chan struct{}
+}
+
+// handle handles a notification of the plugin.
+func (proc *process) handle(msg *message) {
+	switch msg.Method {
+	case MethodEmit:
+		var params emitParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return
+		}
+		if sub := proc.subscription(params.Subscription); sub != nil {
+			select {
+			case sub.events <- params.Payload:
+			case <-sub.done:
+			}
+		}
+
+	case MethodEnded:
+		var params endedParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return
+		}
+		if sub := proc.subscription(params.Subscription); sub != nil {
+			var err error
+			if params.Error != nil {
+				err = params.Error.err()
+			}
+			select {
+			case sub.ended <- err:
+			default:
+			}
+		}
+	}
+}
+
+func (proc *process) addSubscription(id string) *subscription {
+	sub := &subscription{
+		events: make(chan interface{}, 64),
+		ended:  make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	proc.subs[id] = sub
+	return sub
+}
+
+func (proc *process) subscription(id string) *subscription {
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	return proc.subs[id]
+}
+
+func (proc *process) removeSubscription(id string) {
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	if sub, ok := proc.subs[id]; ok {
+		close(sub.done)
+		delete(proc.subs, id)
+	}
+}
+
+// stop closes the input of the executable and waits for it to exit. It is
+// killed if it does not exit in time.
+func (proc *process) stop() {
+	_ = proc.stdin.Close()
+	timer := time.NewTimer(StopTimeout)
+	defer timer.Stop()
+	select {
+	case <-proc.exited:
+		return
+	case <-timer.C:
+	}
+	proc.kill()
+	<-proc.exited
+}
+
+// kill kills the executable.
+func (proc *process) kill() {
+	_ = proc.cmd.Process.Kill()
+}
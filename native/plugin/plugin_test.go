@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+// helperEnv is set when the test binary is started as a plugin by the tests.
+const helperEnv = "FLOW_PLUGIN_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) != "" {
+		if err := Serve(&hangingIntegration{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPlugin_Run(t *testing.T) {
+	p := openExample(t)
+
+	if def := p.Describe(); def == nil || def.Key != "TEXT" {
+		t.Fatalf("Describe() = %+v, want the TEXT integration", def)
+	}
+
+	outputs, err := p.Run(context.Background(), flow.ActionRequest{
+		Action: "UPPERCASE",
+		Inputs: map[string]interface{}{"text": "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if got := outputs["text"]; got != "HELLO" {
+		t.Fatalf("text = %v, want HELLO", got)
+	}
+
+	outputs, err = p.Run(context.Background(), flow.ActionRequest{
+		Action: "WORD_COUNT",
+		Inputs: map[string]interface{}{"text": "hello plugin world"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if got := outputs["count"]; got != float64(3) {
+		t.Fatalf("count = %v, want 3", got)
+	}
+
+	_, err = p.Run(context.Background(), flow.ActionRequest{Action: "UPPERCASE"})
+	if code := flow.ErrorCode(err); code != flow.EINVALID {
+		t.Fatalf("missing input: error code = %q, want %q (err: %v)", code, flow.EINVALID, err)
+	}
+
+	_, err = p.Run(context.Background(), flow.ActionRequest{Action: "NO_SUCH_ACTION"})
+	if code := flow.ErrorCode(err); code != flow.ENOTFOUND {
+		t.Fatalf("unknown action: error code = %q, want %q (err: %v)", code, flow.ENOTFOUND, err)
+	}
+}
+
+func TestPlugin_Subscribe(t *testing.T) {
+	p := openExample(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var counts []interface{}
+	err := p.Subscribe(ctx, flow.SubscribeRequest{
+		Trigger: "COUNTER",
+		Params:  map[string]string{"interval_ms": "10"},
+	}, func(payload interface{}) error {
+		counts = append(counts, payload.(map[string]interface{})["count"])
+		if len(counts) == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("Subscribe() = %v, want %v", err, context.Canceled)
+	}
+	for i, count := range counts {
+		if count != float64(i+1) {
+			t.Fatalf("counts = %v, want [1 2 3]", counts)
+		}
+	}
+
+	err = p.Subscribe(context.Background(), flow.SubscribeRequest{
+		Trigger: "COUNTER",
+		Params:  map[string]string{"interval_ms": "never"},
+	}, func(payload interface{}) error { return nil })
+	if code := flow.ErrorCode(err); code != flow.EINVALID {
+		t.Fatalf("invalid params: error code = %q, want %q (err: %v)", code, flow.EINVALID, err)
+	}
+}
+
+func TestPlugin_Restart(t *testing.T) {
+	p := openExample(t)
+
+	proc, err := p.process()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid := proc.cmd.Process.Pid
+	proc.kill()
+
+	// Calls fail until the plugin is restarted.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		outputs, err := p.Run(context.Background(), flow.ActionRequest{
+			Action: "UPPERCASE",
+			Inputs: map[string]interface{}{"text": "again"},
+		})
+		if err == nil {
+			if got := outputs["text"]; got != "AGAIN" {
+				t.Fatalf("text = %v, want AGAIN", got)
+			}
+			break
+		} else if code := flow.ErrorCode(err); code != flow.EUNAVAILABLE {
+			t.Fatalf("error code = %q, want %q (err: %v)", code, flow.EUNAVAILABLE, err)
+		} else if time.Now().After(deadline) {
+			t.Fatal("plugin was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if proc, err = p.process(); err != nil {
+		t.Fatal(err)
+	} else if proc.cmd.Process.Pid == pid {
+		t.Fatal("plugin was not restarted")
+	}
+}
+
+func TestPlugin_Timeout(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(exe)
+	p.Env = []string{helperEnv + "=1"}
+	p.Timeout = 100 * time.Millisecond
+	open(t, p)
+
+	start := time.Now()
+	_, err = p.Run(context.Background(), flow.ActionRequest{Action: "WAIT"})
+	if code := flow.ErrorCode(err); code != flow.EUNAVAILABLE {
+		t.Fatalf("error code = %q, want %q (err: %v)", code, flow.EUNAVAILABLE, err)
+	} else if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Run() returned after %s, want about %s", d, p.Timeout)
+	}
+
+	// The caller's context takes precedence over the plugin's timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Run(ctx, flow.ActionRequest{Action: "WAIT"}); err != context.DeadlineExceeded {
+		t.Fatalf("Run() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestConn_WriteClosed(t *testing.T) {
+	r, w := io.Pipe()
+	_ = r.Close()
+	c := newConn(strings.NewReader(""), w)
+
+	err := c.call(context.Background(), MethodHealth, nil, nil)
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("call() = %v, want %v", err, errClosed)
+	}
+	p := New("plugin")
+	p.def = &flow.Integration{Key: "TEST"}
+	if code := flow.ErrorCode(p.callError(context.Background(), err)); code != flow.EUNAVAILABLE {
+		t.Fatalf("error code = %q, want %q", code, flow.EUNAVAILABLE)
+	}
+}
+
+// openExample builds the example plugin and opens it. The plugin is closed
+// when the test ends.
+func openExample(t *testing.T) *Plugin {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+
+	bin := filepath.Join(t.TempDir(), "example")
+	if out, err := exec.Command("go", "build", "-o", bin, "./example").CombinedOutput(); err != nil {
+		t.Fatalf("cannot build example: %s\n%s", err, out)
+	}
+
+	p := New(bin)
+	p.RestartDelay = 10 * time.Millisecond
+	open(t, p)
+	return p
+}
+
+func open(t *testing.T, p *Plugin) {
+	t.Helper()
+	if err := p.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// hangingIntegration is served by the test binary. Its actions do not return
+// until they are canceled.
+type hangingIntegration struct{}
+
+func (i *hangingIntegration) Describe() *flow.Integration {
+	return &flow.Integration{
+		Label:   "Hang",
+		Key:     "HANG",
+		Version: flow.DefaultIntegrationVersion,
+		Actions: []flow.Action{{Key: "WAIT", Label: "Wait"}},
+	}
+}
+
+func (i *hangingIntegration) Run(ctx context.Context, req flow.ActionRequest) (map[string]interface{}, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
@@ -0,0 +1,300 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// ProtocolVersion is the version of the plugin protocol. Plugins report the
+// version they implement when described and are rejected if it differs.
+const ProtocolVersion = 1
+
+// Methods of the plugin protocol. Requests are sent by the host & answered by
+// the plugin. The host notifies the plugin of requests it no longer awaits,
+// and the plugin notifies the host of the events of subscriptions.
+const (
+	// Request for the plugin's protocol version & definition.
+	MethodDescribe = "describe"
+	// Request that succeeds while the plugin is able to serve requests.
+	MethodHealth = "health"
+	// Request to run an action. Answered with the action's outputs.
+	MethodRun = "run"
+	// Request to start emitting the events of a trigger. Answered once the
+	// subscription is started.
+	MethodSubscribe = "subscribe"
+	// Request to end a subscription.
+	MethodUnsubscribe = "unsubscribe"
+	// Notification from the host that it no longer awaits the response to
+	// a request, such as when it timed out.
+	MethodCancel = "cancel"
+
+	// Notification of an event of a subscription.
+	MethodEmit = "emit"
+	// Notification that a subscription ended with an error.
+	MethodEnded = "ended"
+)
+
+// JSON-RPC error codes.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+
+	// Errors of the application, whose flow error code is in their data.
+	codeApplicationError = -32000
+)
+
+type describeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+type describeResult struct {
+	ProtocolVersion int               `json:"protocol_version"`
+	Integration     *flow.Integration `json:"integration"`
+}
+
+type runParams struct {
+	Action     string                 `json:"action"`
+	Inputs     map[string]interface{} `json:"inputs"`
+	Token      string                 `json:"token,omitempty"`
+	WorkflowID uuid.UUID              `json:"workflow_id"`
+	NodeID     uuid.UUID              `json:"node_id"`
+}
+
+type subscribeParams struct {
+	Subscription string            `json:"subscription"`
+	Trigger      string            `json:"trigger"`
+	Params       map[string]string `json:"params"`
+	Token        string            `json:"token,omitempty"`
+	WorkflowID   uuid.UUID         `json:"workflow_id"`
+	NodeID       uuid.UUID         `json:"node_id"`
+}
+
+type unsubscribeParams struct {
+	Subscription string `json:"subscription"`
+}
+
+type cancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+type emitParams struct {
+	Subscription string      `json:"subscription"`
+	Payload      interface{} `json:"payload"`
+}
+
+type endedParams struct {
+	Subscription string     `json:"subscription"`
+	Error        *wireError `json:"error"`
+}
+
+// message is a JSON-RPC 2.0 request, notification or response as read from
+// the other end of a connection.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *wireError      `json:"error,omitempty"`
+}
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type resultResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *wireError      `json:"error"`
+}
+
+// wireError is a JSON-RPC error. Application errors carry their flow error
+// code & field errors so that they keep them across the connection.
+type wireError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    *wireErrorData `json:"data,omitempty"`
+}
+
+type wireErrorData struct {
+	Code   string            `json:"code"`
+	Fields []flow.FieldError `json:"fields,omitempty"`
+}
+
+// newWireError converts an error returned by an integration. Application
+// errors keep their code & message. Other errors are sent as internal errors
+// with their text, which the host only logs.
+func newWireError(err error) *wireError {
+	var e *flow.Error
+	if errors.As(err, &e) {
+		return &wireError{Code: codeApplicationError, Message: e.Message, Data: &wireErrorData{Code: e.Code, Fields: e.Fields}}
+	}
+	return &wireError{Code: codeInternalError, Message: err.Error()}
+}
+
+// err converts an error received from the other end of a connection.
+// Application errors become flow errors with the same code.
+func (e *wireError) err() error {
+	if e.Data != nil && e.Data.Code != "" {
+		return &flow.Error{Code: e.Data.Code, Message: e.Message, Fields: e.Data.Fields}
+	}
+	return fmt.Errorf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// errClosed is returned by calls on a connection whose other end went away.
+var errClosed = errors.New("plugin connection closed")
+
+// conn is a JSON-RPC 2.0 connection exchanging one JSON value per line.
+type conn struct {
+	wmu sync.Mutex
+	w   io.Writer
+	dec *json.Decoder
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *message
+
+	done chan struct{}
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		w:       w,
+		dec:     json.NewDecoder(r),
+		pending: make(map[uint64]chan *message),
+		done:    make(chan struct{}),
+	}
+}
+
+// serve reads messages until the connection ends. Responses are returned to
+// their callers & other messages are passed to handle. Returns the error that
+// ended the connection, or nil at the end of the input.
+func (c *conn) serve(handle func(*message)) error {
+	var err error
+	for {
+		var msg message
+		if err = c.dec.Decode(&msg); err != nil {
+			break
+		}
+		if msg.Method != "" {
+			handle(&msg)
+			continue
+		}
+
+		var id uint64
+		if json.Unmarshal(msg.ID, &id) != nil {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- &msg
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	c.mu.Lock()
+	c.pending = nil
+	c.mu.Unlock()
+	close(c.done)
+	return err
+}
+
+// call sends a request and decodes the result of its response into result.
+// If ctx is done first, the other end is told to cancel the request.
+func (c *conn) call(ctx context.Context, method string, params, result interface{}) error {
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.pending == nil {
+		c.mu.Unlock()
+		return errClosed
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(request{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		c.forget(id)
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error.err()
+		} else if result != nil {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return errClosed
+	case <-ctx.Done():
+		c.forget(id)
+		buf, _ := json.Marshal(id)
+		_ = c.notify(MethodCancel, cancelParams{ID: buf})
+		return ctx.Err()
+	}
+}
+
+// forget stops waiting for the response to a request.
+func (c *conn) forget(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// notify sends a notification.
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// reply sends the response to a request.
+func (c *conn) reply(id json.RawMessage, result interface{}, err *wireError) error {
+	if err != nil {
+		return c.write(errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+	}
+	return c.write(resultResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// write sends a message. Returns errClosed if the message cannot be written
+// because the other end went away.
+func (c *conn) write(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := c.w.Write(append(buf, '\n')); err != nil {
+		return fmt.Errorf("%w: %s", errClosed, err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/openmesh/flow"
+)
+
+// Serve serves a native integration as a plugin over the standard input &
+// output of the process until the host closes its input. Plugins written in
+// Go call it from their main function. Anything the plugin logs must be
+// written to standard error, which the host logs.
+func Serve(app flow.NativeIntegration) error {
+	return ServeConn(context.Background(), app, os.Stdin, os.Stdout)
+}
+
+// ServeConn serves a native integration as a plugin over a connection until
+// the input ends or ctx is done. Runs & subscriptions in progress are then
+// canceled.
+func ServeConn(ctx context.Context, app flow.NativeIntegration, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &server{
+		app:      app,
+		conn:     newConn(r, w),
+		requests: make(map[string]context.CancelFunc),
+		subs:     make(map[string]context.CancelFunc),
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.conn.serve(func(msg *message) { s.handle(ctx, msg) }) }()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+	cancel()
+	s.wg.Wait()
+	return err
+}
+
+// server handles the requests of a host.
+type server struct {
+	app  flow.NativeIntegration
+	conn *conn
+
+	mu       sync.Mutex
+	requests map[string]context.CancelFunc // by request ID
+	subs     map[string]context.CancelFunc // by subscription ID
+
+	wg sync.WaitGroup
+}
+
+func (s *server) handle(ctx context.Context, msg *message) {
+	switch msg.Method {
+	case MethodDescribe:
+		_ = s.conn.reply(msg.ID, describeResult{ProtocolVersion: ProtocolVersion, Integration: s.app.Describe()}, nil)
+
+	case MethodHealth:
+		_ = s.conn.reply(msg.ID, struct{}{}, nil)
+
+	case MethodRun:
+		var params runParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.conn.reply(msg.ID, nil, &wireError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		ctx := s.track(ctx, s.requests, string(msg.ID))
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(s.requests, string(msg.ID))
+			outputs, err := s.app.Run(ctx, flow.ActionRequest{
+				Action:     params.Action,
+				Inputs:     params.Inputs,
+				Token:      params.Token,
+				WorkflowID: params.WorkflowID,
+				NodeID:     params.NodeID,
+			})
+			if err != nil {
+				_ = s.conn.reply(msg.ID, nil, newWireError(err))
+				return
+			}
+			_ = s.conn.reply(msg.ID, outputs, nil)
+		}()
+
+	case MethodSubscribe:
+		var params subscribeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.conn.reply(msg.ID, nil, &wireError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		emitter, ok := s.app.(flow.Emitter)
+		if !ok {
+			_ = s.conn.reply(msg.ID, nil, newWireError(flow.Errorf(flow.ENOTFOUND, "Trigger '%s' not found.", params.Trigger)))
+			return
+		}
+		ctx := s.track(ctx, s.subs, params.Subscription)
+		_ = s.conn.reply(msg.ID, struct{}{}, nil)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(s.subs, params.Subscription)
+			err := emitter.Subscribe(ctx, flow.SubscribeRequest{
+				Trigger:    params.Trigger,
+				Params:     params.Params,
+				Token:      params.Token,
+				WorkflowID: params.WorkflowID,
+				NodeID:     params.NodeID,
+			}, func(payload interface{}) error {
+				return s.conn.notify(MethodEmit, emitParams{Subscription: params.Subscription, Payload: payload})
+			})
+			if ctx.Err() == nil {
+				ended := endedParams{Subscription: params.Subscription}
+				if err != nil {
+					ended.Error = newWireError(err)
+				}
+				_ = s.conn.notify(MethodEnded, ended)
+			}
+		}()
+
+	case MethodUnsubscribe:
+		var params unsubscribeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			_ = s.conn.reply(msg.ID, nil, &wireError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		s.cancel(s.subs, params.Subscription)
+		_ = s.conn.reply(msg.ID, struct{}{}, nil)
+
+	case MethodCancel:
+		var params cancelParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			s.cancel(s.requests, string(params.ID))
+		}
+
+	default:
+		if msg.ID != nil {
+			_ = s.conn.reply(msg.ID, nil, &wireError{Code: codeMethodNotFound, Message: "Method '" + msg.Method + "' not found."})
+		}
+	}
+}
+
+// track returns a context that is canceled when the request or subscription
+// with the ID is canceled.
+func (s *server) track(ctx context.Context, m map[string]context.CancelFunc, id string) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := m[id]; ok {
+		prev()
+	}
+	m[id] = cancel
+	return ctx
+}
+
+func (s *server) untrack(m map[string]context.CancelFunc, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := m[id]; ok {
+		cancel()
+		delete(m, id)
+	}
+}
+
+func (s *server) cancel(m map[string]context.CancelFunc, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := m[id]; ok {
+		cancel()
+	}
+}
@@ -0,0 +1,161 @@
+// Package native hosts integrations implemented in Go. Their definitions are
+// served as part of the catalog, their actions are run by the executor and
+// their triggers are subscribed to by the Subscriber.
+package native
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/openmesh/flow"
+)
+
+// Registry holds the native integrations the application was built with. It
+// serves their definitions along with the integrations of Builtin.
+type Registry struct {
+	// Serves the integrations implemented by the application itself, such
+	// as the core integration. Native integrations cannot use their keys.
+	Builtin flow.IntegrationService
+
+	mu   sync.RWMutex
+	apps map[string]flow.NativeIntegration
+	defs map[string]*flow.Integration
+}
+
+// NewRegistry returns a new, empty instance of Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		apps: make(map[string]flow.NativeIntegration),
+		defs: make(map[string]*flow.Integration),
+	}
+}
+
+// Register adds a native integration. Returns EINVALID if its description is
+// not a valid definition or it has triggers but does not implement
+// flow.Emitter, and ECONFLICT if its key is already used.
+func (r *Registry) Register(app flow.NativeIntegration) error {
+	def := app.Describe()
+	if def == nil {
+		return flow.Errorf(flow.EINVALID, "Native integration has no description.")
+	} else if err := def.Validate(); err != nil {
+		return err
+	} else if _, ok := app.(flow.Emitter); !ok && len(def.Triggers) > 0 {
+		return flow.Errorf(flow.EINVALID, "Native integration '%s' has triggers but cannot emit events.", def.Key)
+	}
+	if def.Version == 0 {
+		d := *def
+		d.Version = flow.DefaultIntegrationVersion
+		def = &d
+	}
+
+	if r.Builtin != nil {
+		if _, err := r.Builtin.GetIntegrationByKey(context.Background(), def.Key); err == nil {
+			return flow.Errorf(flow.ECONFLICT, "Integration '%s' is built in.", def.Key)
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.apps[def.Key]; ok {
+		return flow.Errorf(flow.ECONFLICT, "Native integration '%s' is already registered.", def.Key)
+	}
+	r.apps[def.Key], r.defs[def.Key] = app, def
+	return nil
+}
+
+// MustRegister adds a native integration and panics if it cannot be
+// registered.
+func (r *Registry) MustRegister(app flow.NativeIntegration) {
+	if err := r.Register(app); err != nil {
+		panic(err)
+	}
+}
+
+func (r *Registry) GetNativeIntegration(key string) (flow.NativeIntegration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if app, ok := r.apps[key]; ok {
+		return app, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Native integration '%s' not found.", key)
+}
+
+func (r *Registry) GetNativeIntegrations() []flow.NativeIntegration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	apps := make([]flow.NativeIntegration, 0, len(r.apps))
+	for _, key := range r.keys() {
+		apps = append(apps, r.apps[key])
+	}
+	return apps
+}
+
+func (r *Registry) GetIntegrations(ctx context.Context, req flow.GetIntegrationsRequest) ([]*flow.Integration, int, error) {
+	var apps []*flow.Integration
+	if r.Builtin != nil {
+		builtin, _, err := r.Builtin.GetIntegrations(ctx, flow.GetIntegrationsRequest{})
+		if err != nil {
+			return nil, 0, err
+		}
+		apps = append(apps, builtin...)
+	}
+
+	r.mu.RLock()
+	for _, key := range r.keys() {
+		apps = append(apps, r.defs[key])
+	}
+	r.mu.RUnlock()
+
+	matches, total := flow.FilterIntegrations(apps, req)
+	return matches, total, nil
+}
+
+func (r *Registry) GetIntegrationByKey(ctx context.Context, key string) (*flow.Integration, error) {
+	if r.Builtin != nil {
+		if app, err := r.Builtin.GetIntegrationByKey(ctx, key); err == nil {
+			return app, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if def, ok := r.defs[key]; ok {
+		return def, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Integration '%s' not found.", key)
+}
+
+// GetIntegrationVersion returns a version of an integration. Native
+// integrations only have the version they were built with.
+func (r *Registry) GetIntegrationVersion(ctx context.Context, key string, version int) (*flow.Integration, error) {
+	if r.Builtin != nil {
+		if app, err := r.Builtin.GetIntegrationVersion(ctx, key, version); err == nil {
+			return app, nil
+		} else if flow.ErrorCode(err) != flow.ENOTFOUND {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if def, ok := r.defs[key]; ok && def.Version == version {
+		return def, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Version %d of integration '%s' not found.", version, key)
+}
+
+// keys returns the keys of the registered integrations in order. The caller
+// must hold the lock.
+func (r *Registry) keys() []string {
+	keys := make([]string, 0, len(r.apps))
+	for key := range r.apps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,234 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// Default intervals of the Subscriber.
+const (
+	// Interval at which subscriptions are matched to the trigger nodes of
+	// workflows.
+	DefaultSyncInterval = 30 * time.Second
+
+	// Delay before a subscription that ended is restarted. The delay
+	// doubles with each consecutive failure up to MaxRestartDelay.
+	DefaultRestartDelay = 5 * time.Second
+	MaxRestartDelay     = 5 * time.Minute
+)
+
+// Subscriber subscribes to the triggers of native integrations for each
+// workflow with a trigger node listening on them, using the connection of the
+// workflow's owner. Emitted events are published to the event bus addressed
+// to the workflow's trigger node.
+type Subscriber struct {
+	Registry        flow.NativeRegistry
+	EventBus        flow.EventBus
+	WorkflowService flow.WorkflowService
+	AuthService     flow.AuthService
+
+	Logger log.Logger
+
+	// Interval at which subscriptions are matched to trigger nodes.
+	SyncInterval time.Duration
+
+	// Delay before an ended subscription is restarted.
+	RestartDelay time.Duration
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]*subscription // by node ID
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// subscription is a running subscription for a trigger node.
+type subscription struct {
+	fingerprint string // changes when the subscription must be restarted
+	cancel      context.CancelFunc
+}
+
+// NewSubscriber returns a new instance of Subscriber.
+func NewSubscriber() *Subscriber {
+	return &Subscriber{
+		Logger:       log.NewNopLogger(),
+		SyncInterval: DefaultSyncInterval,
+		RestartDelay: DefaultRestartDelay,
+		subs:         make(map[uuid.UUID]*subscription),
+		done:         make(chan struct{}),
+	}
+}
+
+// Open subscribes to the triggers of existing trigger nodes and begins
+// matching subscriptions to trigger nodes as workflows change.
+func (s *Subscriber) Open() error {
+	if err := s.Sync(flow.NewSystemContext(context.Background())); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() { defer s.wg.Done(); s.loop() }()
+	return nil
+}
+
+// Close ends every subscription.
+func (s *Subscriber) Close() error {
+	close(s.done)
+
+	s.mu.Lock()
+	for id, sub := range s.subs {
+		sub.cancel()
+		delete(s.subs, id)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Subscriber) loop() {
+	ticker := time.NewTicker(s.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.Sync(flow.NewSystemContext(context.Background())); err != nil {
+				_ = s.Logger.Log("msg", "cannot sync native subscriptions", "err", err)
+			}
+		}
+	}
+}
+
+// Sync starts a subscription for each trigger node of a native trigger that
+// has none, restarts the subscriptions of nodes whose params or owner changed
+// and ends the subscriptions of nodes that no longer exist.
+func (s *Subscriber) Sync(ctx context.Context) error {
+	seen := make(map[uuid.UUID]bool)
+	for _, app := range s.Registry.GetNativeIntegrations() {
+		emitter, ok := app.(flow.Emitter)
+		if !ok {
+			continue
+		}
+		def := app.Describe()
+
+		for _, trigger := range def.Triggers {
+			topic := flow.TriggerTopic(def.Key, trigger.Key)
+			workflows, _, err := s.WorkflowService.GetWorkflows(ctx, flow.WorkflowFilter{Trigger: &topic})
+			if err != nil {
+				return err
+			}
+
+			for _, wf := range workflows {
+				for _, node := range wf.Nodes {
+					if node.Integration != def.Key || node.Action != trigger.Key || len(node.ParentIDs) > 0 {
+						continue
+					}
+					seen[node.ID] = true
+					s.subscribe(emitter, def.Key, topic, wf, node)
+				}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sub := range s.subs {
+		if !seen[id] {
+			sub.cancel()
+			delete(s.subs, id)
+		}
+	}
+	return nil
+}
+
+// subscribe starts a subscription for a trigger node unless an identical one
+// is running.
+func (s *Subscriber) subscribe(emitter flow.Emitter, integration, topic string, wf *flow.Workflow, node *flow.Node) {
+	req := flow.SubscribeRequest{
+		Trigger:    node.Action,
+		Params:     make(map[string]string, len(node.Params)),
+		WorkflowID: wf.ID,
+		NodeID:     node.ID,
+	}
+	for _, param := range node.Params {
+		req.Params[param.Key] = param.Value
+	}
+	buf, _ := json.Marshal(req.Params)
+	fingerprint := wf.UserID.String() + ":" + node.Action + ":" + string(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if sub, ok := s.subs[node.ID]; ok {
+		if sub.fingerprint == fingerprint {
+			return
+		}
+		sub.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(flow.NewSystemContext(context.Background()))
+	s.subs[node.ID] = &subscription{fingerprint: fingerprint, cancel: cancel}
+	s.wg.Add(1)
+	go func() { defer s.wg.Done(); s.run(ctx, emitter, integration, topic, wf, req) }()
+}
+
+// run keeps a subscription running until ctx is done, restarting it after a
+// delay whenever it ends.
+func (s *Subscriber) run(ctx context.Context, emitter flow.Emitter, integration, topic string, wf *flow.Workflow, req flow.SubscribeRequest) {
+	emit := func(payload interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.EventBus.Publish(topic, &flow.NodeEvent{
+			WorkflowID: req.WorkflowID,
+			NodeID:     req.NodeID,
+			Payload:    payload,
+		})
+	}
+
+	failures := 0
+	for {
+		var err error
+		if req.Token, err = flow.AccessToken(ctx, s.AuthService, wf.UserID, integration); err == nil {
+			err = emitter.Subscribe(ctx, req, emit)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := s.RestartDelay
+		if err != nil {
+			_ = s.Logger.Log("msg", "native subscription failed", "topic", topic, "workflow", req.WorkflowID, "node", req.NodeID, "err", err)
+			failures++
+			for i := 1; i < failures && delay < MaxRestartDelay; i++ {
+				delay *= 2
+			}
+			if delay > MaxRestartDelay {
+				delay = MaxRestartDelay
+			}
+		} else {
+			failures = 0
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
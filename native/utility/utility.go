@@ -0,0 +1,349 @@
+// Package utility implements the utility integration, a native integration of
+// small actions that have no API to call. It is the reference implementation
+// of flow.NativeIntegration.
+package utility
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+// Key is the key of the utility integration.
+const Key = "UTILITY"
+
+// Actions & triggers of the utility integration.
+const (
+	ActionHash         = "HASH"
+	ActionHMAC         = "HMAC"
+	ActionUUID         = "UUID"
+	ActionRandomNumber = "RANDOM_NUMBER"
+
+	TriggerTick = "TICK"
+)
+
+// DefaultTickInterval is the interval of tick triggers that do not set one.
+const DefaultTickInterval = time.Minute
+
+// Integration implements the utility integration.
+type Integration struct {
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// New returns a new instance of Integration.
+func New() *Integration {
+	return &Integration{Now: time.Now}
+}
+
+func (i *Integration) Describe() *flow.Integration {
+	return definition
+}
+
+func (i *Integration) Run(ctx context.Context, req flow.ActionRequest) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch req.Action {
+	case ActionHash:
+		newHash, err := hashFunc(req.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		text, err := required(req.Inputs, "text")
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		h.Write([]byte(text))
+		return map[string]interface{}{"hash": hex.EncodeToString(h.Sum(nil))}, nil
+
+	case ActionHMAC:
+		secret, err := required(req.Inputs, "secret")
+		if err != nil {
+			return nil, err
+		}
+		text, err := required(req.Inputs, "text")
+		if err != nil {
+			return nil, err
+		}
+		newHash, err := hashFunc(req.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write([]byte(text))
+		return map[string]interface{}{"signature": hex.EncodeToString(mac.Sum(nil))}, nil
+
+	case ActionUUID:
+		return map[string]interface{}{"uuid": uuid.New().String()}, nil
+
+	case ActionRandomNumber:
+		min, err := integer(req.Inputs, "min", 0)
+		if err != nil {
+			return nil, err
+		}
+		max, err := integer(req.Inputs, "max", 100)
+		if err != nil {
+			return nil, err
+		}
+		if max < min {
+			return nil, flow.Errorf(flow.EINVALID, "Input 'max' must not be less than 'min'.")
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(max-min+1))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"number": min + n.Int64()}, nil
+	}
+	return nil, flow.Errorf(flow.ENOTFOUND, "Action '%s' not found for integration '%s'.", req.Action, Key)
+}
+
+// Subscribe emits an event with the current time at the interval of a tick
+// trigger until ctx is done.
+func (i *Integration) Subscribe(ctx context.Context, req flow.SubscribeRequest, emit func(payload interface{}) error) error {
+	if req.Trigger != TriggerTick {
+		return flow.Errorf(flow.ENOTFOUND, "Trigger '%s' not found for integration '%s'.", req.Trigger, Key)
+	}
+
+	interval := DefaultTickInterval
+	if v := req.Params["interval_seconds"]; v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 1 {
+			return flow.Errorf(flow.EINVALID, "Input 'interval_seconds' must be a positive whole number.")
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := emit(map[string]interface{}{"time": i.Now().UTC().Format(time.RFC3339)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// hashFunc returns the constructor of the hash named by the algorithm input.
+// Defaults to SHA-256.
+func hashFunc(inputs map[string]interface{}) (func() hash.Hash, error) {
+	switch algorithm := optional(inputs, "algorithm"); algorithm {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "md5":
+		return md5.New, nil
+	default:
+		return nil, flow.Errorf(flow.EINVALID, "Input 'algorithm' must be sha256, sha1 or md5, not '%s'.", algorithm)
+	}
+}
+
+// optional returns an input as a string, or an empty string if it is not set.
+func optional(inputs map[string]interface{}, key string) string {
+	v, ok := inputs[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// required returns an input as a string. Returns EINVALID if it is not set.
+func required(inputs map[string]interface{}, key string) (string, error) {
+	if _, ok := inputs[key]; !ok || inputs[key] == nil {
+		return "", flow.Errorf(flow.EINVALID, "Input '%s' is required.", key)
+	}
+	return optional(inputs, key), nil
+}
+
+// integer returns an input as an integer, or def if it is not set. Returns
+// EINVALID if it is not a whole number.
+func integer(inputs map[string]interface{}, key string, def int64) (int64, error) {
+	switch v := inputs[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), nil
+		}
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	default:
+		if s := fmt.Sprint(v); s == "" {
+			return def, nil
+		} else if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, flow.Errorf(flow.EINVALID, "Input '%s' must be a whole number.", key)
+}
+
+var algorithmInput = flow.InputField{
+	Key:         "algorithm",
+	Label:       "Algorithm",
+	Description: "One of sha256, sha1 or md5.",
+	Required:    false,
+	Type:        flow.FieldTypeString,
+	Default:     "sha256",
+}
+
+var definition = &flow.Integration{
+	Label:       "Utility",
+	Description: "Hashes, signatures, identifiers, random numbers & timers.",
+	Key:         Key,
+	Version:     flow.DefaultIntegrationVersion,
+	Category:    "utility",
+	Triggers: []flow.Trigger{
+		{
+			Key:         TriggerTick,
+			Label:       "Tick",
+			Description: "Triggers the workflow at a fixed interval.",
+			Inputs: []flow.InputField{
+				{
+					Key:         "interval_seconds",
+					Label:       "Interval",
+					Description: "The number of seconds between runs.",
+					Required:    false,
+					Type:        flow.FieldTypeNumber,
+					Default:     "60",
+				},
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label:       "Time",
+					Key:         "time",
+					Description: "The time of the tick.",
+					Type:        flow.FieldTypeDateTime,
+					Path:        "time",
+				},
+			},
+		},
+	},
+	Actions: []flow.Action{
+		{
+			Key:         ActionHash,
+			Label:       "Hash Text",
+			Description: "Computes the hex encoded digest of a text.",
+			Inputs: []flow.InputField{
+				{
+					Key:         "text",
+					Label:       "Text",
+					Description: "The text to hash.",
+					Required:    true,
+					Type:        flow.FieldTypeString,
+					Example:     "hello",
+				},
+				algorithmInput,
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label:       "Hash",
+					Key:         "hash",
+					Description: "The hex encoded digest.",
+					Type:        flow.FieldTypeString,
+					Path:        "hash",
+				},
+			},
+		},
+		{
+			Key:         ActionHMAC,
+			Label:       "Sign Text",
+			Description: "Computes the hex encoded HMAC of a text with a secret.",
+			Inputs: []flow.InputField{
+				{
+					Key:         "text",
+					Label:       "Text",
+					Description: "The text to sign.",
+					Required:    true,
+					Type:        flow.FieldTypeString,
+					Example:     "hello",
+				},
+				{
+					Key:         "secret",
+					Label:       "Secret",
+					Description: "The key of the HMAC.",
+					Required:    true,
+					Type:        flow.FieldTypeString,
+					Example:     "secret",
+				},
+				algorithmInput,
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label:       "Signature",
+					Key:         "signature",
+					Description: "The hex encoded HMAC.",
+					Type:        flow.FieldTypeString,
+					Path:        "signature",
+				},
+			},
+		},
+		{
+			Key:         ActionUUID,
+			Label:       "Generate UUID",
+			Description: "Generates a random UUID.",
+			Outputs: []flow.OutputField{
+				{
+					Label:       "UUID",
+					Key:         "uuid",
+					Description: "The generated UUID.",
+					Type:        flow.FieldTypeString,
+					Path:        "uuid",
+				},
+			},
+		},
+		{
+			Key:         ActionRandomNumber,
+			Label:       "Random Number",
+			Description: "Generates a random whole number between a minimum and maximum, inclusive.",
+			Inputs: []flow.InputField{
+				{
+					Key:         "min",
+					Label:       "Minimum",
+					Description: "The smallest number that may be generated.",
+					Required:    false,
+					Type:        flow.FieldTypeNumber,
+					Default:     "0",
+				},
+				{
+					Key:         "max",
+					Label:       "Maximum",
+					Description: "The largest number that may be generated.",
+					Required:    false,
+					Type:        flow.FieldTypeNumber,
+					Default:     "100",
+				},
+			},
+			Outputs: []flow.OutputField{
+				{
+					Label:       "Number",
+					Key:         "number",
+					Description: "The generated number.",
+					Type:        flow.FieldTypeNumber,
+					Path:        "number",
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,18 @@
+package utility_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openmesh/flow/native/conformance"
+	"github.com/openmesh/flow/native/utility"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, utility.New(), conformance.Options{
+		Params: map[string]map[string]string{
+			utility.TriggerTick: {"interval_seconds": "1"},
+		},
+		EmitTimeout: 3 * time.Second,
+	})
+}
@@ -16,6 +16,48 @@ type Node struct {
 	Params      []*Param     `json:"params" db:"-"`
 	ParentIDs   []*uuid.UUID `json:"parent_ids" db:"-"`
 	ChildrenIDs []*uuid.UUID `json:"children_ids" db:"-"`
+
+	// Version of the integration the node was built against. The node is
+	// executed against that version of the definition while it is defined.
+	IntegrationVersion int `json:"integration_version" db:"integration_version"`
+
+	// Labels of the edges to the node's children, keyed by child ID. The
+	// edges of condition nodes are labeled with the branch they belong to.
+	// Unlabeled edges have no entry.
+	EdgeLabels map[uuid.UUID]string `json:"edge_labels" db:"-"`
+
+	// Filter is evaluated against the events of trigger nodes. Events that
+	// do not match do not start a run.
+	Filter *Condition `json:"filter" db:"filter"`
+
+	// Retry describes how the node is retried after it fails. Nodes without
+	// a policy are attempted once.
+	Retry *RetryPolicy `json:"retry" db:"retry"`
+
+	// Time in seconds each attempt at executing the node may take. Zero
+	// means no limit.
+	TimeoutSeconds int `json:"timeout_seconds" db:"timeout_seconds"`
+}
+
+// Timeout returns the time each attempt at executing the node may take.
+// Returns zero if attempts are not limited.
+func (n *Node) Timeout() time.Duration {
+	return time.Duration(n.TimeoutSeconds) * time.Second
+}
+
+// ValidateParams returns an error if the params of a core node that are
+// checked when the node is saved are invalid.
+func (n *Node) ValidateParams(params []*Param) error {
+	if n.Integration != IntegrationCore {
+		return nil
+	}
+	switch n.Action {
+	case ActionTransform:
+		return validateTransformParams(params)
+	case ActionCode:
+		return validateCodeParams(params)
+	}
+	return nil
 }
 
 type Edge struct {
@@ -23,6 +65,7 @@ type Edge struct {
 	Head   *Node     `json:"head" db:"-"`
 	TailID uuid.UUID `json:"tail_id" db:"tail_id"`
 	Tail   *Node     `json:"tail" db:"-"`
+	Label  *string   `json:"label" db:"label"`
 }
 
 type ParamType string
@@ -34,6 +77,7 @@ const (
 
 type Param struct {
 	ID        uuid.UUID `json:"id" db:"id"`
+	NodeID    uuid.UUID `json:"node_id" db:"node_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	Key       string    `json:"key" db:"key"`
@@ -52,8 +96,20 @@ type NodeService interface {
 type NodeUpdate struct {
 	Integration string       `json:"integration"`
 	Action      string       `json:"action"`
+	Params      []*Param     `json:"params" db:"-"`
 	ParentIDs   []*uuid.UUID `json:"parent_ids" db:"-"`
 	ChildrenIDs []*uuid.UUID `json:"children_ids" db:"-"`
+	Filter      *Condition   `json:"filter" db:"-"`
+	Retry       *RetryPolicy `json:"retry" db:"-"`
+
+	TimeoutSeconds *int `json:"timeout_seconds" db:"-"`
+
+	// Pins the node to another version of its integration.
+	IntegrationVersion *int `json:"integration_version" db:"-"`
+
+	// Replaces the labels of the node's outgoing edges. Labels are kept
+	// when only the children are updated.
+	EdgeLabels map[uuid.UUID]string `json:"edge_labels" db:"-"`
 }
 
 type ParamUpdate struct {
@@ -63,7 +119,9 @@ type ParamUpdate struct {
 }
 
 type NodeFilter struct {
-	WorkflowID *uuid.UUID `json:"workflow_id"`
-	Page       int        `json:"page"`
-	Limit      int        `json:"limit"`
+	ID          *uuid.UUID `json:"id"`
+	WorkflowID  *uuid.UUID `json:"workflow_id"`
+	Integration *string    `json:"integration"`
+	Page        int        `json:"page"`
+	Limit       int        `json:"limit"`
 }
@@ -0,0 +1,719 @@
+// Package openapi converts OpenAPI 3 & Swagger 2 documents into integration
+// definitions.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/openmesh/flow"
+	"sigs.k8s.io/yaml"
+)
+
+// maxOutputDepth is the depth of nested objects whose properties become
+// separate output fields. Deeper objects are output as complex fields.
+const maxOutputDepth = 2
+
+// maxRefs is the maximum number of references followed to resolve a value, so
+// that circular references end.
+const maxRefs = 32
+
+// methods are the operation methods that become actions, in the order their
+// actions are listed for each path.
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Options configures how a document is imported.
+type Options struct {
+	// Key of the integration. Derived from the title of the document if
+	// empty.
+	Key string
+
+	// Base URL of the API. Taken from the servers of the document if empty.
+	BaseURL string
+}
+
+// Import converts an OpenAPI 3 or Swagger 2 document, given as YAML or JSON,
+// into an integration definition. Operations become actions, their params &
+// request bodies become inputs and the schemas of their successful responses
+// become outputs. The API's auth scheme is detected from its security
+// schemes. Also returns warnings about the parts of the document that could
+// not be converted. Returns EINVALID if the document cannot be converted into
+// a valid definition.
+func Import(data []byte, opts Options) (*flow.Integration, []string, error) {
+	buf, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Cannot parse document: %s", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Cannot parse document: %s", err)
+	}
+
+	imp := &importer{doc: doc}
+	if v, _ := doc["swagger"].(string); v == "2.0" {
+		imp.swagger = true
+	} else if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, nil, flow.Errorf(flow.EINVALID, "Document is not an OpenAPI 3 or Swagger 2 document.")
+	}
+
+	integration, err := imp.integration(opts)
+	if err != nil {
+		return nil, imp.warnings, err
+	}
+	if err := integration.Validate(); err != nil {
+		return nil, imp.warnings, err
+	}
+	return integration, imp.warnings, nil
+}
+
+// importer converts a decoded document.
+type importer struct {
+	doc      map[string]interface{}
+	swagger  bool
+	warnings []string
+}
+
+func (imp *importer) warn(format string, args ...interface{}) {
+	imp.warnings = append(imp.warnings, fmt.Sprintf(format, args...))
+}
+
+func (imp *importer) integration(opts Options) (*flow.Integration, error) {
+	info := imp.object(imp.doc["info"])
+	title, _ := info["title"].(string)
+
+	integration := &flow.Integration{
+		Key:         opts.Key,
+		Label:       title,
+		Description: firstLine(str(info["description"])),
+		BaseURL:     opts.BaseURL,
+	}
+	if integration.Key == "" {
+		if integration.Key = toKey(title); integration.Key == "" {
+			return nil, flow.Errorf(flow.EINVALID, "Document has no title to derive a key from. A key is required.")
+		}
+	}
+	if integration.Label == "" {
+		integration.Label = integration.Key
+	}
+	if integration.BaseURL == "" {
+		integration.BaseURL = imp.baseURL()
+		if integration.BaseURL == "" {
+			return nil, flow.Errorf(flow.EINVALID, "Document has no absolute server URL. A base URL is required.")
+		}
+	}
+	integration.Auth = imp.auth()
+
+	paths := imp.object(imp.doc["paths"])
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
+	}
+	sort.Strings(names)
+
+	keys := make(map[string]bool)
+	for _, path := range names {
+		item := imp.object(paths[path])
+		for _, method := range methods {
+			op := imp.object(item[strings.ToLower(method)])
+			if op == nil {
+				continue
+			}
+			action := imp.action(path, method, item, op)
+			action.Key = unique(action.Key, keys)
+			integration.Actions = append(integration.Actions, action)
+		}
+	}
+	if len(integration.Actions) == 0 {
+		imp.warn("document has no operations")
+	}
+	return integration, nil
+}
+
+// baseURL returns the URL of the first server of an OpenAPI 3 document, with
+// its variables set to their defaults, or the URL of the host & base path of
+// a Swagger 2 document. Returns an empty string if the URL is not absolute.
+func (imp *importer) baseURL() string {
+	var s string
+	if imp.swagger {
+		host, _ := imp.doc["host"].(string)
+		if host == "" {
+			return ""
+		}
+		scheme := "https"
+		if schemes, _ := imp.doc["schemes"].([]interface{}); len(schemes) > 0 && !contains(schemes, "https") {
+			scheme = str(schemes[0])
+		}
+		s = scheme + "://" + host + str(imp.doc["basePath"])
+	} else {
+		servers, _ := imp.doc["servers"].([]interface{})
+		if len(servers) == 0 {
+			return ""
+		}
+		server := imp.object(servers[0])
+		s = str(server["url"])
+		for name, v := range imp.object(server["variables"]) {
+			s = strings.Replace(s, "{"+name+"}", str(imp.object(v)["default"]), -1)
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		imp.warn("server URL %q is not absolute", s)
+		return ""
+	}
+	return strings.TrimSuffix(s, "/")
+}
+
+// auth returns the auth scheme of the API. The scheme required by the
+// document's top-level security requirement is preferred over other schemes.
+// Returns nil if the document has no supported security scheme.
+func (imp *importer) auth() *flow.IntegrationAuth {
+	var schemes map[string]interface{}
+	if imp.swagger {
+		schemes = imp.object(imp.doc["securityDefinitions"])
+	} else {
+		schemes = imp.object(imp.object(imp.doc["components"])["securitySchemes"])
+	}
+
+	var names []string
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if security, _ := imp.doc["security"].([]interface{}); len(security) > 0 {
+		var required []string
+		for name := range imp.object(security[0]) {
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		names = append(required, names...)
+	}
+
+	var auth *flow.IntegrationAuth
+	var unsupported []string
+	for _, name := range names {
+		scheme := imp.object(schemes[name])
+		if scheme == nil {
+			continue
+		}
+		a := imp.securityScheme(scheme)
+		if a == nil {
+			unsupported = append(unsupported, name)
+		} else if auth == nil {
+			auth = a
+		}
+	}
+	for _, name := range unsupported {
+		imp.warn("security scheme %q is not supported", name)
+	}
+	return auth
+}
+
+// securityScheme converts a security scheme. Returns nil if the scheme is not
+// supported.
+func (imp *importer) securityScheme(scheme map[string]interface{}) *flow.IntegrationAuth {
+	switch str(scheme["type"]) {
+	case "oauth2":
+		auth := &flow.IntegrationAuth{Scheme: flow.AuthSchemeOAuth2}
+		flows := []map[string]interface{}{scheme}
+		if !imp.swagger {
+			f := imp.object(scheme["flows"])
+			flows = nil
+			for _, name := range []string{"authorizationCode", "implicit", "clientCredentials", "password"} {
+				if v := imp.object(f[name]); v != nil {
+					flows = append(flows, v)
+				}
+			}
+		}
+		if len(flows) > 0 {
+			auth.AuthorizationURL = str(flows[0]["authorizationUrl"])
+			auth.TokenURL = str(flows[0]["tokenUrl"])
+			for scope := range imp.object(flows[0]["scopes"]) {
+				auth.Scopes = append(auth.Scopes, scope)
+			}
+			sort.Strings(auth.Scopes)
+		}
+		return auth
+	case "openIdConnect":
+		return &flow.IntegrationAuth{Scheme: flow.AuthSchemeOAuth2}
+	case "basic":
+		return &flow.IntegrationAuth{Scheme: flow.AuthSchemeBasic}
+	case "http":
+		switch strings.ToLower(str(scheme["scheme"])) {
+		case "bearer":
+			return &flow.IntegrationAuth{Scheme: flow.AuthSchemeBearer}
+		case "basic":
+			return &flow.IntegrationAuth{Scheme: flow.AuthSchemeBasic}
+		}
+	case "apiKey":
+		if in := str(scheme["in"]); in == "header" || in == "query" {
+			return &flow.IntegrationAuth{Scheme: flow.AuthSchemeAPIKey, In: in, Name: str(scheme["name"])}
+		}
+	}
+	return nil
+}
+
+// action converts an operation.
+func (imp *importer) action(path, method string, item, op map[string]interface{}) flow.Action {
+	operationID := str(op["operationId"])
+	name := method + " " + path
+
+	action := flow.Action{
+		Key:         toKey(operationID),
+		Label:       str(op["summary"]),
+		Description: firstLine(str(op["description"])),
+		Endpoint:    path,
+		Method:      method,
+	}
+	if action.Key == "" {
+		action.Key = toKey(method + "_" + path)
+	}
+	if action.Label == "" {
+		action.Label = humanize(operationID)
+		if action.Label == "" {
+			action.Label = name
+		}
+	}
+	if action.Description == "" {
+		action.Description = str(op["summary"])
+	}
+
+	keys := make(map[string]bool)
+	var query bool
+	for _, param := range imp.params(item, op) {
+		in := str(param["in"])
+		switch in {
+		case "path", "query":
+		case "body":
+			imp.bodyInputs(name, imp.resolve(param["schema"]), "application/json", &action, keys)
+			continue
+		case "formData":
+			action.Method = "HTTP_" + method
+		default:
+			imp.warn("%s: %s param %q is not supported", name, in, str(param["name"]))
+			continue
+		}
+		query = query || in == "query"
+
+		schema := imp.resolve(param["schema"])
+		if imp.swagger && schema == nil {
+			schema = param
+		}
+		field := imp.input(str(param["name"]), schema, in == "path" || param["required"] == true)
+		if d := firstLine(str(param["description"])); d != "" {
+			field.Description = d
+		}
+		if v, ok := param["example"]; ok {
+			field.Example = format(v)
+		}
+		imp.addInput(name, field, &action, keys)
+	}
+
+	if body := imp.resolve(op["requestBody"]); body != nil {
+		content := imp.object(body["content"])
+		contentType := mediaType(content, "application/json", "+json", "application/x-www-form-urlencoded")
+		if contentType == "" {
+			imp.warn("%s: request body is not JSON or form encoded", name)
+		} else {
+			imp.bodyInputs(name, imp.resolve(imp.object(content[contentType])["schema"]), contentType, &action, keys)
+		}
+	}
+	if query && method != http.MethodGet && method != http.MethodDelete {
+		imp.warn("%s: query params are sent in the request body", name)
+	}
+
+	action.Outputs = imp.outputs(name, op)
+	return action
+}
+
+// params returns the params of an operation, including the params of its path
+// that the operation does not override.
+func (imp *importer) params(item, op map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	seen := make(map[string]bool)
+	for _, list := range []interface{}{op["parameters"], item["parameters"]} {
+		values, _ := list.([]interface{})
+		for _, v := range values {
+			param := imp.resolve(v)
+			if param == nil {
+				continue
+			}
+			id := str(param["in"]) + ":" + str(param["name"])
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// bodyInputs adds the properties of a request body's schema as inputs. Bodies
+// that are not objects are not supported, as actions send their inputs as the
+// properties of an object.
+func (imp *importer) bodyInputs(name string, schema map[string]interface{}, contentType string, action *flow.Action, keys map[string]bool) {
+	properties, required := imp.properties(schema)
+	if properties == nil {
+		imp.warn("%s: request body is not an object", name)
+		return
+	}
+
+	if strings.Contains(contentType, "json") {
+		action.Method = "JSON_HTTP_" + strings.TrimPrefix(action.Method, "HTTP_")
+	} else {
+		action.Method = "HTTP_" + strings.TrimPrefix(action.Method, "HTTP_")
+	}
+	for _, prop := range sortedKeys(properties) {
+		imp.addInput(name, imp.input(prop, imp.resolve(properties[prop]), required[prop]), action, keys)
+	}
+}
+
+// addInput adds an input to an action unless its key is invalid or already
+// used.
+func (imp *importer) addInput(name string, field flow.InputField, action *flow.Action, keys map[string]bool) {
+	if !inputKeyRegexp.MatchString(field.Key) {
+		imp.warn("%s: param %q cannot be used as an input key", name, field.Key)
+		return
+	} else if keys[field.Key] {
+		imp.warn("%s: param %q is defined more than once", name, field.Key)
+		return
+	}
+	keys[field.Key] = true
+	action.Inputs = append(action.Inputs, field)
+}
+
+// input converts a param or property into an input field.
+func (imp *importer) input(key string, schema map[string]interface{}, required bool) flow.InputField {
+	field := flow.InputField{
+		Key:         key,
+		Label:       humanize(key),
+		Description: firstLine(str(schema["description"])),
+		Required:    required,
+		Type:        imp.fieldType(schema),
+	}
+	if v, ok := schema["default"]; ok {
+		field.Default = format(v)
+	}
+	if v, ok := schema["example"]; ok {
+		field.Example = format(v)
+	}
+	return field
+}
+
+// outputs converts the schema of an operation's successful JSON response into
+// output fields. The properties of objects are output separately, as are the
+// properties of nested objects up to maxOutputDepth.
+func (imp *importer) outputs(name string, op map[string]interface{}) []flow.OutputField {
+	responses := imp.object(op["responses"])
+	var response map[string]interface{}
+	for _, status := range []string{"200", "201", "202", "2XX", "2xx", "default"} {
+		if response = imp.resolve(responses[status]); response != nil {
+			break
+		}
+	}
+	if response == nil {
+		return nil
+	}
+
+	var schema map[string]interface{}
+	if imp.swagger {
+		schema = imp.resolve(response["schema"])
+	} else {
+		content := imp.object(response["content"])
+		if contentType := mediaType(content, "application/json", "+json"); contentType != "" {
+			schema = imp.resolve(imp.object(content[contentType])["schema"])
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	var fields []flow.OutputField
+	keys := make(map[string]bool)
+	if properties, _ := imp.properties(schema); properties != nil {
+		imp.objectOutputs(properties, "", "", 1, &fields, keys)
+	} else {
+		// Responses that are not objects, such as lists, are output whole.
+		field := flow.OutputField{
+			Label:       "Body",
+			Key:         "body",
+			Description: firstLine(str(schema["description"])),
+			Type:        imp.fieldType(schema),
+		}
+		if str(schema["type"]) == "array" {
+			field.Label, field.Key = "Items", "items"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func (imp *importer) objectOutputs(properties map[string]interface{}, path, key string, depth int, fields *[]flow.OutputField, keys map[string]bool) {
+	for _, prop := range sortedKeys(properties) {
+		// Properties whose names cannot be part of a path are skipped.
+		if prop == "" || strings.ContainsAny(prop, ".[]") {
+			continue
+		}
+		schema := imp.resolve(properties[prop])
+		propPath, propKey := prop, toFieldKey(prop)
+		if path != "" {
+			propPath, propKey = path+"."+prop, key+"_"+propKey
+		}
+
+		if nested, _ := imp.properties(schema); nested != nil && depth < maxOutputDepth {
+			imp.objectOutputs(nested, propPath, propKey, depth+1, fields, keys)
+			continue
+		}
+		*fields = append(*fields, flow.OutputField{
+			Label:       humanize(strings.Replace(propPath, ".", " ", -1)),
+			Key:         unique(propKey, keys),
+			Description: firstLine(str(schema["description"])),
+			Type:        imp.fieldType(schema),
+			Path:        propPath,
+		})
+	}
+}
+
+// properties returns the properties of an object schema, merging those of
+// "allOf" schemas, and the set of required properties. Returns nil if the
+// schema is not an object.
+func (imp *importer) properties(schema map[string]interface{}) (map[string]interface{}, map[string]bool) {
+	return imp.mergeProperties(schema, make(map[string]bool))
+}
+
+// mergeProperties implements properties. Refs holds the references of the
+// "allOf" schemas that have been merged, which are true while they are being
+// merged. Schemas are merged once, so that schemas that include themselves
+// end and schemas included many times are not merged again.
+func (imp *importer) mergeProperties(schema map[string]interface{}, refs map[string]bool) (map[string]interface{}, map[string]bool) {
+	if schema == nil {
+		return nil, nil
+	}
+	properties := make(map[string]interface{})
+	required := make(map[string]bool)
+	isObject := str(schema["type"]) == "object" || schema["properties"] != nil
+
+	for k, v := range imp.object(schema["properties"]) {
+		properties[k] = v
+	}
+	if list, _ := schema["required"].([]interface{}); list != nil {
+		for _, v := range list {
+			required[str(v)] = true
+		}
+	}
+	if all, _ := schema["allOf"].([]interface{}); all != nil {
+		for _, v := range all {
+			ref := str(imp.object(v)["$ref"])
+			if merging, ok := refs[ref]; ok && merging {
+				imp.warn("schema %q includes itself with allOf", ref)
+				continue
+			} else if ok {
+				continue
+			} else if ref != "" {
+				refs[ref] = true
+			}
+			props, req := imp.mergeProperties(imp.resolve(v), refs)
+			if ref != "" {
+				refs[ref] = false
+			}
+			if props == nil {
+				continue
+			}
+			isObject = true
+			for k, v := range props {
+				properties[k] = v
+			}
+			for k := range req {
+				required[k] = true
+			}
+		}
+	}
+	if !isObject {
+		return nil, nil
+	}
+	return properties, required
+}
+
+// fieldType returns the field type of a schema.
+func (imp *importer) fieldType(schema map[string]interface{}) flow.FieldType {
+	typ := schema["type"]
+	// OpenAPI 3.1 types may be a list such as ["string", "null"].
+	if list, ok := typ.([]interface{}); ok {
+		typ = nil
+		for _, v := range list {
+			if v != "null" {
+				typ = v
+				break
+			}
+		}
+	}
+
+	switch typ {
+	case "integer", "number":
+		return flow.FieldTypeNumber
+	case "boolean":
+		return flow.FieldTypeBoolean
+	case "string":
+		if f := str(schema["format"]); f == "date-time" || f == "date" {
+			return flow.FieldTypeDateTime
+		}
+		return flow.FieldTypeString
+	case nil:
+		if schema["properties"] == nil && schema["allOf"] == nil && schema["oneOf"] == nil && schema["anyOf"] == nil && schema["items"] == nil {
+			return flow.FieldTypeString
+		}
+	}
+	return flow.FieldTypeComplex
+}
+
+// resolve returns the object a value refers to with "$ref", or the value
+// itself if it is not a reference. Only references within the document are
+// resolved. Returns nil if the value is not an object or cannot be resolved.
+func (imp *importer) resolve(v interface{}) map[string]interface{} {
+	m := imp.object(v)
+	for i := 0; m != nil && m["$ref"] != nil; i++ {
+		ref := str(m["$ref"])
+		if i >= maxRefs || !strings.HasPrefix(ref, "#/") {
+			imp.warn("reference %q cannot be resolved", ref)
+			return nil
+		}
+
+		var target interface{} = imp.doc
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+			if unescaped, err := url.PathUnescape(token); err == nil {
+				token = unescaped
+			}
+			target = imp.object(target)[token]
+		}
+		if m = imp.object(target); m == nil {
+			imp.warn("reference %q cannot be resolved", ref)
+		}
+	}
+	return m
+}
+
+func (imp *importer) object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// inputKeyRegexp matches the keys of input fields.
+var inputKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// mediaType returns the first media type of content that equals or ends with
+// one of the given types.
+func mediaType(content map[string]interface{}, types ...string) string {
+	for _, t := range types {
+		for _, contentType := range sortedKeys(content) {
+			mt := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+			if mt == t || (strings.HasPrefix(t, "+") && strings.HasSuffix(mt, t)) {
+				return contentType
+			}
+		}
+	}
+	return ""
+}
+
+// toKey converts a name such as "createTweet" or "POST /users/{id}" into a
+// key such as "CREATE_TWEET" or "POST_USERS_ID".
+func toKey(s string) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if r > unicode.MaxASCII {
+				r = '_'
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteRune('_')
+		}
+		prev = r
+	}
+
+	key := strings.Trim(regexp.MustCompile(`_+`).ReplaceAllString(b.String(), "_"), "_")
+	if key != "" && !unicode.IsLetter(rune(key[0])) {
+		key = "API_" + key
+	}
+	return key
+}
+
+// toFieldKey converts a property name into an output key such as "user_id".
+func toFieldKey(s string) string {
+	key := strings.ToLower(toKey(s))
+	if key == "" {
+		key = "field"
+	}
+	return key
+}
+
+// humanize converts a name such as "created_at" or "createdAt" into a label
+// such as "Created at".
+func humanize(s string) string {
+	words := strings.Fields(strings.ToLower(strings.Replace(toKey(s), "_", " ", -1)))
+	if len(words) == 0 {
+		return ""
+	}
+	words[0] = strings.Title(words[0])
+	return strings.Join(words, " ")
+}
+
+// unique returns a key that has not been used yet by adding a number to it if
+// needed, and marks it as used.
+func unique(key string, keys map[string]bool) string {
+	k := key
+	for n := 2; keys[k]; n++ {
+		k = fmt.Sprintf("%s_%d", key, n)
+	}
+	keys[k] = true
+	return k
+}
+
+// format formats a default or example value as the string of a field.
+// Strings are used as is and other values are encoded as JSON.
+func format(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func contains(list []interface{}, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
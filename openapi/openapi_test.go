@@ -0,0 +1,116 @@
+package openapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openmesh/flow/openapi"
+)
+
+func TestImport_AllOf(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		schemas string
+		outputs []string
+		warning string
+	}{
+		{
+			name: "Merged",
+			schemas: `
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            age: {type: integer}
+    Named:
+      type: object
+      properties:
+        name: {type: string}`,
+			outputs: []string{"age", "name"},
+		},
+		{
+			name: "Self",
+			schemas: `
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Pet'
+        - type: object
+          properties:
+            age: {type: integer}`,
+			outputs: []string{"age"},
+			warning: "includes itself",
+		},
+		{
+			name: "Cycle",
+			schemas: `
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Animal'
+        - type: object
+          properties:
+            age: {type: integer}
+    Animal:
+      allOf:
+        - $ref: '#/components/schemas/Pet'
+        - type: object
+          properties:
+            legs: {type: integer}`,
+			outputs: []string{"age", "legs"},
+			warning: "includes itself",
+		},
+		{
+			name: "Diamond",
+			schemas: `
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/A'
+        - $ref: '#/components/schemas/A'
+    A:
+      allOf:
+        - $ref: '#/components/schemas/B'
+        - $ref: '#/components/schemas/B'
+    B:
+      type: object
+      properties:
+        name: {type: string}`,
+			outputs: []string{"name"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := `
+openapi: 3.0.0
+info: {title: Pets}
+servers: [{url: 'https://example.com'}]
+paths:
+  /pet:
+    get:
+      operationId: getPet
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:` + tt.schemas
+
+			integration, warnings, err := openapi.Import([]byte(doc), openapi.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var outputs []string
+			for _, field := range integration.Actions[0].Outputs {
+				outputs = append(outputs, field.Key)
+			}
+			if got, want := strings.Join(outputs, ","), strings.Join(tt.outputs, ","); got != want {
+				t.Fatalf("outputs = %s, want %s", got, want)
+			}
+
+			warned := strings.Contains(strings.Join(warnings, "\n"), "includes itself")
+			if want := tt.warning != ""; warned != want {
+				t.Fatalf("warnings = %q", warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package flow
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PathSegment is a single step of a parsed path. A segment either selects a
+// key of an object, an index of an array or, when Wildcard is set, every
+// element of an array.
+type PathSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// ParsePath parses a path such as "data.items[0].id" or "data.items[*].id"
+// into its segments. Paths are used by OutputField.Path and reference params
+// to select values from JSON documents.
+func ParsePath(path string) ([]PathSegment, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	var segments []PathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []string
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if rest[0] != '[' || end < 0 {
+					return nil, Errorf(EINVALID, "Invalid path '%s': unterminated index.", path)
+				}
+				indexes = append(indexes, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if key == "" && len(indexes) == 0 {
+			return nil, Errorf(EINVALID, "Invalid path '%s': empty segment.", path)
+		}
+		if key != "" {
+			segments = append(segments, PathSegment{Key: key})
+		}
+
+		for _, index := range indexes {
+			if index == "*" {
+				segments = append(segments, PathSegment{Wildcard: true})
+				continue
+			}
+			n, err := strconv.Atoi(index)
+			if err != nil || n < 0 {
+				return nil, Errorf(EINVALID, "Invalid path '%s': bad index '%s'.", path, index)
+			}
+			segments = append(segments, PathSegment{Index: n, IsIndex: true})
+		}
+	}
+	return segments, nil
+}
+
+// Lookup returns the value at a path within a decoded JSON document. The
+// second return value is false if the path does not exist. A wildcard segment
+// returns an array of the values found for each element.
+func Lookup(v interface{}, path string) (interface{}, bool, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := lookup(v, segments)
+	return value, ok, nil
+}
+
+func lookup(v interface{}, segments []PathSegment) (interface{}, bool) {
+	for i, seg := range segments {
+		switch {
+		case seg.Wildcard:
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			results := make([]interface{}, 0, len(arr))
+			for _, elem := range arr {
+				if value, ok := lookup(elem, segments[i+1:]); ok {
+					results = append(results, value)
+				}
+			}
+			return results, true
+
+		case seg.IsIndex:
+			arr, ok := v.([]interface{})
+			if !ok || seg.Index >= len(arr) {
+				return nil, false
+			}
+			v = arr[seg.Index]
+
+		default:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[seg.Key]; !ok {
+				return nil, false
+			}
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,64 @@
+package flow
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"data": {
+			"items": [
+				{"id": 1, "tags": ["a", "b"]},
+				{"id": 2, "tags": []},
+				{"name": "no id"}
+			],
+			"count": 3,
+			"next": null
+		},
+		"matrix": [[1, 2], [3, 4]]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		path  string
+		want  interface{}
+		found bool
+		code  string
+	}{
+		{"Empty", "", doc, true, ""},
+		{"Key", "data.count", float64(3), true, ""},
+		{"Null", "data.next", nil, true, ""},
+		{"Index", "data.items[1].id", float64(2), true, ""},
+		{"NestedIndex", "matrix[1][0]", float64(3), true, ""},
+		{"RootIndex", "[0]", nil, false, ""},
+		{"Wildcard", "data.items[*].id", []interface{}{float64(1), float64(2)}, true, ""},
+		{"WildcardNested", "data.items[*].tags[0]", []interface{}{"a"}, true, ""},
+		{"WildcardAll", "matrix[*]", []interface{}{[]interface{}{float64(1), float64(2)}, []interface{}{float64(3), float64(4)}}, true, ""},
+		{"MissingKey", "data.total", nil, false, ""},
+		{"IndexOutOfRange", "data.items[3]", nil, false, ""},
+		{"KeyOfArray", "data.items.id", nil, false, ""},
+		{"IndexOfObject", "data[0]", nil, false, ""},
+		{"KeyOfScalar", "data.count.value", nil, false, ""},
+		{"WildcardOfObject", "data[*]", nil, false, ""},
+		{"EmptySegment", "data..count", nil, false, EINVALID},
+		{"Unterminated", "data.items[0", nil, false, EINVALID},
+		{"NegativeIndex", "data.items[-1]", nil, false, EINVALID},
+		{"BadIndex", "data.items[a]", nil, false, EINVALID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := Lookup(doc, tt.path)
+			if code := ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			} else if found != tt.found {
+				t.Fatalf("found = %v, want %v", found, tt.found)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lookup() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
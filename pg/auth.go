@@ -226,7 +226,7 @@ func getAuths(ctx context.Context, tx *Tx, filter flow.AuthFilter) ([]*flow.Auth
 		` + formatLimitOffset(filter.Limit, filter.Page)
 
 	auths := make([]*flow.Auth, 0)
-	if err := tx.Select(&auths, query); err != nil {
+	if err := tx.Select(&auths, query, args...); err != nil {
 		return auths, n, err
 	}
 
@@ -0,0 +1,131 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openmesh/flow"
+)
+
+type deliveryService struct {
+	db *DB
+}
+
+func NewDeliveryService(db *DB) flow.DeliveryService {
+	return deliveryService{db}
+}
+
+func (s deliveryService) GetDeliveryByKey(ctx context.Context, key string) (*flow.Delivery, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getDeliveryByKey(ctx, tx, key)
+}
+
+func (s deliveryService) CreateDelivery(ctx context.Context, delivery *flow.Delivery) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createDelivery(ctx, tx, delivery); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s deliveryService) UpdateDelivery(ctx context.Context, key string, response json.RawMessage) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := getDeliveryByKey(ctx, tx, key); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE deliveries SET response = $1, locked_until = NULL WHERE key = $2`, response, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s deliveryService) DeleteDelivery(ctx context.Context, key string) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deliveries WHERE key = $1`, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getDeliveryByKey is a helper function to fetch an unexpired delivery by key.
+// Returns ENOTFOUND if the delivery does not exist or has expired.
+func getDeliveryByKey(ctx context.Context, tx *Tx, key string) (*flow.Delivery, error) {
+	var delivery flow.Delivery
+	if err := tx.GetContext(ctx, &delivery, `
+		SELECT * FROM deliveries WHERE key = $1 AND expires_at > $2
+	`, key, tx.now); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, flow.Errorf(flow.ENOTFOUND, "Delivery not found.")
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// createDelivery inserts a delivery unless an unexpired delivery with the same
+// key exists. Expired deliveries, and deliveries whose processing stopped
+// before they got a response, are purged first so that their keys can be
+// reused.
+func createDelivery(ctx context.Context, tx *Tx, delivery *flow.Delivery) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM
+			deliveries
+		WHERE
+			expires_at <= $1
+			OR (response IS NULL AND (locked_until IS NULL OR locked_until <= $1))
+	`, tx.now); err != nil {
+		return err
+	}
+
+	delivery.CreatedAt = tx.now
+	res, err := tx.NamedExecContext(ctx, `
+		INSERT INTO
+			deliveries
+				(
+					key,
+					response,
+					locked_until,
+					expires_at,
+					created_at
+				)
+		VALUES
+			(
+				:key,
+				:response,
+				:locked_until,
+				:expires_at,
+				:created_at
+			)
+		ON CONFLICT (key) DO NOTHING
+	`, delivery)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return flow.Errorf(flow.ECONFLICT, "Delivery has already been received.")
+	}
+	return nil
+}
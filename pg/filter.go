@@ -0,0 +1,108 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openmesh/flow"
+)
+
+type filterDecisionService struct {
+	db *DB
+}
+
+func NewFilterDecisionService(db *DB) flow.FilterDecisionService {
+	return filterDecisionService{db}
+}
+
+func (s filterDecisionService) GetFilterDecisions(ctx context.Context, filter flow.FilterDecisionFilter) ([]*flow.FilterDecision, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getFilterDecisions(ctx, tx, filter)
+}
+
+func (s filterDecisionService) CreateFilterDecision(ctx context.Context, decision *flow.FilterDecision) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createFilterDecision(ctx, tx, decision); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getFilterDecisions returns a list of decisions that match a filter, most
+// recent first. Unless called by an internal process, only decisions of the
+// current user's workflows are returned.
+func getFilterDecisions(ctx context.Context, tx *Tx, filter flow.FilterDecisionFilter) ([]*flow.FilterDecision, int, error) {
+	var where []string
+	var args []interface{}
+
+	if !flow.IsSystemContext(ctx) {
+		where = append(where, "workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)")
+		args = append(args, flow.UserIDFromContext(ctx))
+	}
+	if v := filter.WorkflowID; v != nil {
+		where, args = append(where, fmt.Sprintf("workflow_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.NodeID; v != nil {
+		where, args = append(where, fmt.Sprintf("node_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.Matched; v != nil {
+		where, args = append(where, fmt.Sprintf("matched = $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM filter_decisions %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY created_at DESC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	decisions := make([]*flow.FilterDecision, 0)
+	if err := tx.Select(&decisions, query, args...); err != nil {
+		return decisions, n, err
+	}
+	return decisions, n, nil
+}
+
+func createFilterDecision(ctx context.Context, tx *Tx, decision *flow.FilterDecision) error {
+	decision.CreatedAt = tx.now
+
+	return tx.QueryRowxContext(ctx, `
+		INSERT INTO
+			filter_decisions
+				(
+					workflow_id,
+					node_id,
+					topic,
+					payload,
+					matched,
+					error,
+					created_at
+				)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING
+			id
+	`,
+		decision.WorkflowID,
+		decision.NodeID,
+		decision.Topic,
+		[]byte(decision.Payload),
+		decision.Matched,
+		decision.Error,
+		decision.CreatedAt,
+	).Scan(&decision.ID)
+}
@@ -0,0 +1,303 @@
+package pg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+	"io"
+)
+
+type hookService struct {
+	db *DB
+}
+
+func NewHookService(db *DB) flow.HookService {
+	return hookService{db}
+}
+
+func (s hookService) GetHookByToken(ctx context.Context, token string) (*flow.Hook, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var hook flow.Hook
+	if err := tx.GetContext(ctx, &hook, `SELECT * FROM hooks WHERE token = $1`, token); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, flow.Errorf(flow.ENOTFOUND, "Hook not found.")
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s hookService) GetHooks(ctx context.Context, filter flow.HookFilter) ([]*flow.Hook, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getHooks(ctx, tx, filter)
+}
+
+func (s hookService) CreateHook(ctx context.Context, hook *flow.Hook) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createHook(ctx, tx, hook); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s hookService) UpdateHook(ctx context.Context, workflowID, id uuid.UUID, upd flow.HookUpdate) (*flow.Hook, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hook, err := updateHook(ctx, tx, workflowID, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	return hook, tx.Commit()
+}
+
+func (s hookService) RotateHook(ctx context.Context, workflowID, id uuid.UUID) (*flow.Hook, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hook, err := rotateHook(ctx, tx, workflowID, id)
+	if err != nil {
+		return nil, err
+	}
+	return hook, tx.Commit()
+}
+
+func (s hookService) DeleteHook(ctx context.Context, workflowID, id uuid.UUID) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteHook(ctx, tx, workflowID, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getHookByID is a helper function to fetch a workflow's hook by ID. Returns
+// ENOTFOUND if the hook does not exist, belongs to another workflow or belongs
+// to another user's workflow.
+func getHookByID(ctx context.Context, tx *Tx, workflowID, id uuid.UUID) (*flow.Hook, error) {
+	hooks, _, err := getHooks(ctx, tx, flow.HookFilter{ID: &id, WorkflowID: &workflowID})
+	if err != nil {
+		return nil, err
+	} else if len(hooks) == 0 {
+		return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Hook not found."}
+	}
+	return hooks[0], nil
+}
+
+// getHooks returns a list of hooks that match a filter. Only hooks of
+// workflows owned by the current user are returned.
+func getHooks(ctx context.Context, tx *Tx, filter flow.HookFilter) ([]*flow.Hook, int, error) {
+	where := []string{"workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)"}
+	args := []interface{}{flow.UserIDFromContext(ctx)}
+
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.WorkflowID; v != nil {
+		where, args = append(where, fmt.Sprintf("workflow_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.NodeID; v != nil {
+		where, args = append(where, fmt.Sprintf("node_id = $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM hooks %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY created_at ASC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	hooks := make([]*flow.Hook, 0)
+	if err := tx.Select(&hooks, query, args...); err != nil {
+		return hooks, n, err
+	}
+	return hooks, n, nil
+}
+
+func createHook(ctx context.Context, tx *Tx, hook *flow.Hook) error {
+	if hook.Mode == "" {
+		hook.Mode = flow.HookModeAsync
+	}
+	if err := hook.Validate(); err != nil {
+		return err
+	}
+
+	// Verify that the current user owns the workflow and that the nodes
+	// belong to it.
+	workflow, err := getWorkflowByID(ctx, tx, hook.WorkflowID)
+	if err != nil {
+		return err
+	} else if !flow.CanEditWorkflow(ctx, workflow) {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Only the workflow owner can create hooks.")
+	}
+	if err := checkHookNodes(workflow, hook); err != nil {
+		return err
+	}
+
+	if hook.Token, err = generateHookToken(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			hooks
+				(
+					workflow_id,
+					node_id,
+					token,
+					mode,
+					respond_node_id
+				)
+		VALUES
+			(
+				:workflow_id,
+				:node_id,
+				:token,
+				:mode,
+				:respond_node_id
+			)
+		RETURNING
+			*
+	`)
+	if err != nil {
+		return err
+	}
+	var res flow.Hook
+	if err := stmt.Get(&res, hook); err != nil {
+		return err
+	}
+	hook.ID = res.ID
+	hook.CreatedAt = res.CreatedAt
+	hook.UpdatedAt = res.UpdatedAt
+
+	return nil
+}
+
+func updateHook(ctx context.Context, tx *Tx, workflowID, id uuid.UUID, upd flow.HookUpdate) (*flow.Hook, error) {
+	hook, err := getHookByID(ctx, tx, workflowID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upd.Mode != nil {
+		hook.Mode = *upd.Mode
+	}
+	if upd.RespondNodeID != nil {
+		hook.RespondNodeID = upd.RespondNodeID
+	}
+	if err := hook.Validate(); err != nil {
+		return hook, err
+	}
+
+	workflow, err := getWorkflowByID(ctx, tx, hook.WorkflowID)
+	if err != nil {
+		return hook, err
+	} else if err := checkHookNodes(workflow, hook); err != nil {
+		return hook, err
+	}
+
+	hook.UpdatedAt = tx.now
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			hooks
+		SET
+			mode = $1,
+			respond_node_id = $2,
+			updated_at = $3
+		WHERE
+			id = $4
+			AND workflow_id = $5
+	`,
+		hook.Mode,
+		hook.RespondNodeID,
+		hook.UpdatedAt,
+		hook.ID,
+		hook.WorkflowID,
+	); err != nil {
+		return hook, err
+	}
+	return hook, nil
+}
+
+func rotateHook(ctx context.Context, tx *Tx, workflowID, id uuid.UUID) (*flow.Hook, error) {
+	hook, err := getHookByID(ctx, tx, workflowID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook.Token, err = generateHookToken(); err != nil {
+		return hook, err
+	}
+	hook.UpdatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE hooks SET token = $1, updated_at = $2 WHERE id = $3 AND workflow_id = $4
+	`, hook.Token, hook.UpdatedAt, hook.ID, hook.WorkflowID); err != nil {
+		return hook, err
+	}
+	return hook, nil
+}
+
+func deleteHook(ctx context.Context, tx *Tx, workflowID, id uuid.UUID) error {
+	// Ownership is checked by only finding hooks of the user's workflows.
+	if _, err := getHookByID(ctx, tx, workflowID, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hooks WHERE id = $1 AND workflow_id = $2`, id, workflowID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkHookNodes returns an error if the nodes referenced by a hook are not
+// part of its workflow.
+func checkHookNodes(workflow *flow.Workflow, hook *flow.Hook) error {
+	if workflow.GetNode(hook.NodeID) == nil {
+		return flow.Errorf(flow.EINVALID, "Node %s is not part of the workflow.", hook.NodeID)
+	}
+	if hook.RespondNodeID != nil && workflow.GetNode(*hook.RespondNodeID) == nil {
+		return flow.Errorf(flow.EINVALID, "Node %s is not part of the workflow.", *hook.RespondNodeID)
+	}
+	return nil
+}
+
+// generateHookToken returns a random, URL safe hook token.
+func generateHookToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
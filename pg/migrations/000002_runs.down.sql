@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS node_runs;
+DROP TABLE IF EXISTS runs;
+ALTER TABLE params DROP COLUMN IF EXISTS node_id;
@@ -0,0 +1,58 @@
+ALTER TABLE params
+    ADD COLUMN node_id UUID
+        CONSTRAINT params_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE;
+
+CREATE TABLE runs
+(
+    id              UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT runs_pkey
+            PRIMARY KEY,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    workflow_id     UUID        NOT NULL
+        CONSTRAINT runs_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    trigger_node_id UUID        NOT NULL
+        CONSTRAINT runs_nodes_trigger_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    input           JSONB       NULL,
+    status          VARCHAR     NOT NULL,
+    error           VARCHAR     NULL,
+    started_at      TIMESTAMPTZ NULL,
+    finished_at     TIMESTAMPTZ NULL
+);
+
+CREATE INDEX runs_workflow_id_idx ON runs (workflow_id);
+
+CREATE TRIGGER runs_set_updated_at
+    BEFORE UPDATE
+    ON runs
+    FOR EACH ROW
+EXECUTE PROCEDURE trigger_set_updated_at();
+
+CREATE TABLE node_runs
+(
+    id          UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT node_runs_pkey
+            PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    run_id      UUID        NOT NULL
+        CONSTRAINT node_runs_runs_run
+            REFERENCES runs
+            ON DELETE CASCADE,
+    node_id     UUID        NOT NULL
+        CONSTRAINT node_runs_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    status      VARCHAR     NOT NULL,
+    output      JSONB       NULL,
+    error       VARCHAR     NULL,
+    started_at  TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX node_runs_run_id_idx ON node_runs (run_id);
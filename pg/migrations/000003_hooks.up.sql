@@ -0,0 +1,30 @@
+CREATE TABLE hooks
+(
+    id              UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT hooks_pkey
+            PRIMARY KEY,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    workflow_id     UUID        NOT NULL
+        CONSTRAINT hooks_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    node_id         UUID        NOT NULL
+        CONSTRAINT hooks_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    token           VARCHAR     NOT NULL
+        CONSTRAINT hooks_token_key
+            UNIQUE,
+    mode            VARCHAR     NOT NULL,
+    respond_node_id UUID        NULL
+        CONSTRAINT hooks_nodes_respond_node
+            REFERENCES nodes
+            ON DELETE SET NULL
+);
+
+CREATE TRIGGER hooks_set_updated_at
+    BEFORE UPDATE
+    ON hooks
+    FOR EACH ROW
+EXECUTE PROCEDURE trigger_set_updated_at();
@@ -0,0 +1,18 @@
+CREATE TABLE attachments
+(
+    id           UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT attachments_pkey
+            PRIMARY KEY,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    run_id       UUID        NOT NULL
+        CONSTRAINT attachments_runs_run
+            REFERENCES runs
+            ON DELETE CASCADE,
+    field        VARCHAR     NOT NULL,
+    filename     VARCHAR     NOT NULL,
+    content_type VARCHAR     NOT NULL,
+    size         BIGINT      NOT NULL,
+    data         BYTEA       NOT NULL
+);
+
+CREATE INDEX attachments_run_id_idx ON attachments (run_id);
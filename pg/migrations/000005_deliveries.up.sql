@@ -0,0 +1,11 @@
+CREATE TABLE deliveries
+(
+    key        VARCHAR     NOT NULL
+        CONSTRAINT deliveries_pkey
+            PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    response   JSONB
+);
+
+CREATE INDEX deliveries_expires_at_idx ON deliveries (expires_at);
@@ -0,0 +1,20 @@
+CREATE TABLE poll_states
+(
+    node_id      UUID        NOT NULL
+        CONSTRAINT poll_states_pkey
+            PRIMARY KEY
+        CONSTRAINT poll_states_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    workflow_id  UUID        NOT NULL
+        CONSTRAINT poll_states_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    cursor       VARCHAR     NULL,
+    seen_ids     TEXT[]      NOT NULL DEFAULT '{}',
+    failures     INTEGER     NOT NULL DEFAULT 0,
+    error        VARCHAR     NULL,
+    polled_at    TIMESTAMPTZ NULL,
+    next_poll_at TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
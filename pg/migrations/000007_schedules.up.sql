@@ -0,0 +1,32 @@
+CREATE TABLE schedules
+(
+    node_id          UUID        NOT NULL
+        CONSTRAINT schedules_pkey
+            PRIMARY KEY
+        CONSTRAINT schedules_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    workflow_id      UUID        NOT NULL
+        CONSTRAINT schedules_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    trigger_key      VARCHAR     NOT NULL,
+    expression       VARCHAR     NOT NULL DEFAULT '',
+    timezone         VARCHAR     NOT NULL DEFAULT '',
+    interval_seconds INTEGER     NOT NULL DEFAULT 0,
+    at               TIMESTAMPTZ NULL,
+    catch_up         VARCHAR     NOT NULL,
+    next_fire_at     TIMESTAMPTZ NULL,
+    last_fired_at    TIMESTAMPTZ NULL,
+    locked_until     TIMESTAMPTZ NULL
+);
+
+CREATE INDEX schedules_next_fire_at_idx ON schedules (next_fire_at);
+
+CREATE TRIGGER schedules_set_updated_at
+    BEFORE UPDATE
+    ON schedules
+    FOR EACH ROW
+EXECUTE PROCEDURE trigger_set_updated_at();
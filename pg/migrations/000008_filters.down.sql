@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS filter_decisions;
+
+ALTER TABLE nodes
+    DROP COLUMN IF EXISTS filter;
@@ -0,0 +1,24 @@
+ALTER TABLE nodes
+    ADD COLUMN filter JSONB NULL;
+
+CREATE TABLE filter_decisions
+(
+    id          UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT filter_decisions_pkey
+            PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    workflow_id UUID        NOT NULL
+        CONSTRAINT filter_decisions_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    node_id     UUID        NOT NULL
+        CONSTRAINT filter_decisions_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    topic       VARCHAR     NOT NULL,
+    payload     JSONB       NOT NULL,
+    matched     BOOLEAN     NOT NULL,
+    error       VARCHAR     NULL
+);
+
+CREATE INDEX filter_decisions_workflow_id_idx ON filter_decisions (workflow_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE edges
+    ADD COLUMN label VARCHAR NULL;
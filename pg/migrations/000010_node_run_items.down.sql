@@ -0,0 +1,2 @@
+ALTER TABLE node_runs
+    DROP COLUMN IF EXISTS item;
@@ -0,0 +1,2 @@
+ALTER TABLE node_runs
+    ADD COLUMN item INTEGER NULL;
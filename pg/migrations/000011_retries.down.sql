@@ -0,0 +1,8 @@
+ALTER TABLE workflows
+    DROP COLUMN IF EXISTS error_mode;
+
+ALTER TABLE node_runs
+    DROP COLUMN IF EXISTS attempts;
+
+ALTER TABLE nodes
+    DROP COLUMN IF EXISTS retry;
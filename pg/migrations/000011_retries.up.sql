@@ -0,0 +1,8 @@
+ALTER TABLE nodes
+    ADD COLUMN retry JSONB NULL;
+
+ALTER TABLE node_runs
+    ADD COLUMN attempts JSONB NULL;
+
+ALTER TABLE workflows
+    ADD COLUMN error_mode VARCHAR NOT NULL DEFAULT 'fail_fast';
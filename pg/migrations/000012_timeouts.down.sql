@@ -0,0 +1,5 @@
+ALTER TABLE workflows
+    DROP COLUMN IF EXISTS timeout_seconds;
+
+ALTER TABLE nodes
+    DROP COLUMN IF EXISTS timeout_seconds;
@@ -0,0 +1,5 @@
+ALTER TABLE nodes
+    ADD COLUMN timeout_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE workflows
+    ADD COLUMN timeout_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+CREATE TABLE run_queue
+(
+    run_id        UUID        NOT NULL
+        CONSTRAINT run_queue_pkey
+            PRIMARY KEY
+        CONSTRAINT run_queue_runs_run
+            REFERENCES runs
+            ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_by    VARCHAR     NULL,
+    claimed_until TIMESTAMPTZ NULL
+);
+
+CREATE INDEX run_queue_claimed_until_idx ON run_queue (claimed_until);
+
+-- Runs that were unfinished before the queue existed are resumed.
+INSERT INTO run_queue (run_id, created_at)
+SELECT id, created_at
+FROM runs
+WHERE status IN ('pending', 'running', 'interrupted');
@@ -0,0 +1,12 @@
+CREATE TABLE workers
+(
+    id           VARCHAR     NOT NULL
+        CONSTRAINT workers_pkey
+            PRIMARY KEY,
+    concurrency  INTEGER     NOT NULL DEFAULT 0,
+    active_runs  INTEGER     NOT NULL DEFAULT 0,
+    started_at   TIMESTAMPTZ NOT NULL,
+    heartbeat_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX workers_heartbeat_at_idx ON workers (heartbeat_at);
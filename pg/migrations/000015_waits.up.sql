@@ -0,0 +1,30 @@
+CREATE TABLE waits
+(
+    id          UUID                 DEFAULT uuid_generate_v4()
+        CONSTRAINT waits_pkey
+            PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    run_id      UUID        NOT NULL
+        CONSTRAINT waits_runs_run
+            REFERENCES runs
+            ON DELETE CASCADE,
+    workflow_id UUID        NOT NULL
+        CONSTRAINT waits_workflows_workflow
+            REFERENCES workflows
+            ON DELETE CASCADE,
+    node_id     UUID        NOT NULL
+        CONSTRAINT waits_nodes_node
+            REFERENCES nodes
+            ON DELETE CASCADE,
+    item        INTEGER     NULL,
+    topic       VARCHAR     NULL,
+    condition   JSONB       NULL,
+    resume_at   TIMESTAMPTZ NULL,
+    event       JSONB       NULL,
+    timed_out   BOOLEAN     NOT NULL DEFAULT FALSE,
+    resolved_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX waits_run_id_idx ON waits (run_id);
+CREATE INDEX waits_topic_idx ON waits (topic) WHERE resolved_at IS NULL;
+CREATE INDEX waits_resume_at_idx ON waits (resume_at) WHERE resolved_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE waits
+    DROP COLUMN IF EXISTS child_run_id;
+
+ALTER TABLE runs
+    DROP COLUMN IF EXISTS parent_run_id,
+    DROP COLUMN IF EXISTS parent_node_id,
+    DROP COLUMN IF EXISTS parent_item;
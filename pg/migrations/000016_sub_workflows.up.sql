@@ -0,0 +1,20 @@
+ALTER TABLE runs
+    ADD COLUMN parent_run_id  UUID    NULL
+        CONSTRAINT runs_runs_parent_run
+            REFERENCES runs
+            ON DELETE SET NULL,
+    ADD COLUMN parent_node_id UUID    NULL
+        CONSTRAINT runs_nodes_parent_node
+            REFERENCES nodes
+            ON DELETE SET NULL,
+    ADD COLUMN parent_item    INTEGER NULL;
+
+CREATE INDEX runs_parent_run_id_idx ON runs (parent_run_id);
+
+ALTER TABLE waits
+    ADD COLUMN child_run_id UUID NULL
+        CONSTRAINT waits_runs_child_run
+            REFERENCES runs
+            ON DELETE CASCADE;
+
+CREATE INDEX waits_child_run_id_idx ON waits (child_run_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE nodes
+    DROP COLUMN IF EXISTS integration_version;
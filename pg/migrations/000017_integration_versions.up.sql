@@ -0,0 +1,2 @@
+ALTER TABLE nodes
+    ADD COLUMN integration_version INTEGER NOT NULL DEFAULT 1;
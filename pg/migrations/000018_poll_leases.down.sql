@@ -0,0 +1,2 @@
+ALTER TABLE poll_states
+    DROP COLUMN IF EXISTS locked_until;
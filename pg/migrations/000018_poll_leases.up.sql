@@ -0,0 +1,2 @@
+ALTER TABLE poll_states
+    ADD COLUMN locked_until TIMESTAMPTZ NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE deliveries
+    ADD COLUMN locked_until TIMESTAMPTZ NULL;
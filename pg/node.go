@@ -2,33 +2,511 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/openmesh/flow"
 )
 
+type nodeService struct {
+	db *DB
+}
+
+func NewNodeService(db *DB) flow.NodeService {
+	return nodeService{db}
+}
+
+func (s nodeService) GetNodeByID(ctx context.Context, id uuid.UUID) (*flow.Node, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getNodeByID(ctx, tx, id)
+}
+
+func (s nodeService) GetNodes(ctx context.Context, filter flow.NodeFilter) ([]*flow.Node, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	nodes, n, err := getNodes(ctx, tx, filter)
+	if err != nil {
+		return nodes, n, err
+	}
+	for _, node := range nodes {
+		if err := attachNodeAssociations(ctx, tx, node); err != nil {
+			return nodes, n, err
+		}
+	}
+	return nodes, n, nil
+}
+
+func (s nodeService) CreateNode(ctx context.Context, node *flow.Node) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createNode(ctx, tx, node); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s nodeService) UpdateNode(ctx context.Context, id uuid.UUID, upd flow.NodeUpdate) (*flow.Node, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	node, err := updateNode(ctx, tx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	return node, tx.Commit()
+}
+
+func (s nodeService) DeleteNode(ctx context.Context, id uuid.UUID) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteNode(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+// getNodeByID is a helper function to fetch a node by ID along with its params
+// and edges. Returns ENOTFOUND if the node does not exist.
+func getNodeByID(ctx context.Context, tx *Tx, id uuid.UUID) (*flow.Node, error) {
+	nodes, _, err := getNodes(ctx, tx, flow.NodeFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(nodes) == 0 {
+		return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Node not found."}
+	}
+	if err := attachNodeAssociations(ctx, tx, nodes[0]); err != nil {
+		return nil, err
+	}
+	return nodes[0], nil
+}
+
+// getNodes returns a list of nodes that match a filter. Unless called by an
+// internal process, only nodes of the current user's workflows are returned.
+func getNodes(ctx context.Context, tx *Tx, filter flow.NodeFilter) ([]*flow.Node, int, error) {
+	var where []string
+	var args []interface{}
+
+	if !flow.IsSystemContext(ctx) {
+		where = append(where, "workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)")
+		args = append(args, flow.UserIDFromContext(ctx))
+	}
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.WorkflowID; v != nil {
+		where, args = append(where, fmt.Sprintf("workflow_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.Integration; v != nil {
+		where, args = append(where, fmt.Sprintf("integration = $%d", len(args)+1)), append(args, *v)
+	}
 
-func getNodes(ctx context.Context, tx *Tx, req flow.NodeFilter) ([]*flow.Node, int, error) {
-	// build query to be executed
-	baseQuery := fmt.Sprintf(
-		"SELECT * FROM nodes %s",
-		where("workflow_id = '%s'", req.WorkflowID),
-	)
+	baseQuery := fmt.Sprintf("SELECT * FROM nodes %s", buildWhereClause(where))
 
 	// Get count of base query.
 	var count int
-	err := tx.Get(&count, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery))
+	err := tx.Get(&count, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Append limit and offset to query if required.
-	paginatedQuery := fmt.Sprintf("%s %s", baseQuery, formatLimitOffset(req.Limit, req.Page))
+	paginatedQuery := fmt.Sprintf("%s ORDER BY created_at ASC %s", baseQuery, formatLimitOffset(filter.Limit, filter.Page))
 	nodes := make([]*flow.Node, 0)
 
-	if err := tx.Select(&nodes, paginatedQuery); err != nil {
+	if err := tx.Select(&nodes, paginatedQuery, args...); err != nil {
 		return nodes, 0, err
 	}
 
 	return nodes, count, nil
 }
+
+func createNode(ctx context.Context, tx *Tx, node *flow.Node) error {
+	// Verify that the workflow exists and that the current user is the owner.
+	workflow, err := getWorkflowByID(ctx, tx, node.WorkflowID)
+	if err != nil {
+		return err
+	} else if !flow.CanEditWorkflow(ctx, workflow) {
+		return flow.Errorf(flow.EUNAUTHORIZED, "Only the workflow owner can add nodes.")
+	}
+
+	if node.Filter != nil {
+		if err := node.Filter.Validate(); err != nil {
+			return err
+		}
+	}
+	if node.Retry != nil {
+		if err := node.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := flow.ValidateTimeout(node.TimeoutSeconds); err != nil {
+		return err
+	}
+	if err := node.ValidateParams(node.Params); err != nil {
+		return err
+	}
+	filter, err := conditionJSON(node.Filter)
+	if err != nil {
+		return err
+	}
+	if node.IntegrationVersion == 0 {
+		node.IntegrationVersion = flow.DefaultIntegrationVersion
+	}
+
+	if err := tx.GetContext(ctx, node, `
+		INSERT INTO
+			nodes
+				(
+					workflow_id,
+					integration,
+					action,
+					filter,
+					retry,
+					timeout_seconds,
+					integration_version
+				)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING
+			*
+	`,
+		node.WorkflowID,
+		node.Integration,
+		node.Action,
+		filter,
+		node.Retry,
+		node.TimeoutSeconds,
+		node.IntegrationVersion,
+	); err != nil {
+		return err
+	}
+
+	if err := replaceNodeParams(ctx, tx, node.ID, node.Params); err != nil {
+		return err
+	}
+	return replaceNodeEdges(ctx, tx, node, node.ParentIDs, node.ChildrenIDs, node.EdgeLabels)
+}
+
+func updateNode(ctx context.Context, tx *Tx, id uuid.UUID, upd flow.NodeUpdate) (*flow.Node, error) {
+	node, err := getEditableNode(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := upd.Integration; v != "" {
+		node.Integration = v
+	}
+	if v := upd.Action; v != "" {
+		node.Action = v
+	}
+	if v := upd.Filter; v != nil {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		node.Filter = v
+	}
+	if v := upd.Retry; v != nil {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		node.Retry = v
+	}
+	if v := upd.TimeoutSeconds; v != nil {
+		if err := flow.ValidateTimeout(*v); err != nil {
+			return nil, err
+		}
+		node.TimeoutSeconds = *v
+	}
+	if v := upd.IntegrationVersion; v != nil {
+		if *v < 1 {
+			return nil, flow.Errorf(flow.EINVALID, "Integration version must be positive.")
+		}
+		node.IntegrationVersion = *v
+	}
+	params := node.Params
+	if upd.Params != nil {
+		params = upd.Params
+	}
+	if err := node.ValidateParams(params); err != nil {
+		return nil, err
+	}
+	filter, err := conditionJSON(node.Filter)
+	if err != nil {
+		return nil, err
+	}
+	node.UpdatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			nodes
+		SET
+			integration = $1,
+			action = $2,
+			filter = $3,
+			retry = $4,
+			timeout_seconds = $5,
+			integration_version = $6,
+			updated_at = $7
+		WHERE
+			id = $8
+	`,
+		node.Integration,
+		node.Action,
+		filter,
+		node.Retry,
+		node.TimeoutSeconds,
+		node.IntegrationVersion,
+		node.UpdatedAt,
+		node.ID,
+	); err != nil {
+		return nil, err
+	}
+
+	// Params & edges are replaced as a whole when provided.
+	if upd.Params != nil {
+		if err := replaceNodeParams(ctx, tx, node.ID, upd.Params); err != nil {
+			return nil, err
+		}
+	}
+	if upd.ParentIDs != nil || upd.ChildrenIDs != nil || upd.EdgeLabels != nil {
+		parentIDs, childrenIDs, labels := node.ParentIDs, node.ChildrenIDs, node.EdgeLabels
+		if upd.ParentIDs != nil {
+			parentIDs = upd.ParentIDs
+		}
+		if upd.ChildrenIDs != nil {
+			childrenIDs = upd.ChildrenIDs
+			// Labels of removed children are dropped.
+			kept := make(map[uuid.UUID]string, len(labels))
+			for _, id := range childrenIDs {
+				if label, ok := labels[*id]; ok {
+					kept[*id] = label
+				}
+			}
+			labels = kept
+		}
+		if upd.EdgeLabels != nil {
+			labels = upd.EdgeLabels
+		}
+		if err := replaceNodeEdges(ctx, tx, node, parentIDs, childrenIDs, labels); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := attachNodeAssociations(ctx, tx, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func deleteNode(ctx context.Context, tx *Tx, id uuid.UUID) error {
+	if _, err := getEditableNode(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getEditableNode fetches a node and verifies that the current user owns its
+// workflow.
+func getEditableNode(ctx context.Context, tx *Tx, id uuid.UUID) (*flow.Node, error) {
+	node, err := getNodeByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := getWorkflowByID(ctx, tx, node.WorkflowID)
+	if err != nil {
+		return nil, err
+	} else if !flow.CanEditWorkflow(ctx, workflow) {
+		return nil, flow.Errorf(flow.EUNAUTHORIZED, "Only the workflow owner can edit its nodes.")
+	}
+	return node, nil
+}
+
+// replaceNodeParams replaces the params of a node.
+func replaceNodeParams(ctx context.Context, tx *Tx, nodeID uuid.UUID, params []*flow.Param) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM params WHERE node_id = $1`, nodeID); err != nil {
+		return err
+	}
+
+	for _, p := range params {
+		if p.Type == "" {
+			p.Type = flow.ParamTypeValue
+		}
+		p.NodeID = nodeID
+		if err := tx.GetContext(ctx, p, `
+			INSERT INTO
+				params
+					(
+						node_id,
+						key,
+						value,
+						type
+					)
+			VALUES
+				($1, $2, $3, $4)
+			RETURNING
+				*
+		`, p.NodeID, p.Key, p.Value, p.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceNodeEdges replaces the edges of a node. Connected nodes must belong
+// to the same workflow and labels may only be given for children. The labels
+// of edges from parents are kept.
+func replaceNodeEdges(ctx context.Context, tx *Tx, node *flow.Node, parentIDs, childrenIDs []*uuid.UUID, labels map[uuid.UUID]string) error {
+	parentLabels, err := getEdgeLabels(ctx, tx, "tail_id", "head_id", node.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM edges WHERE head_id = $1 OR tail_id = $1`, node.ID); err != nil {
+		return err
+	}
+
+	insert := func(tailID, headID uuid.UUID, label string) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO edges (tail_id, head_id, label) VALUES ($1, $2, $3)
+		`, tailID, headID, nullString(label))
+		return err
+	}
+
+	for _, ids := range [][]*uuid.UUID{parentIDs, childrenIDs} {
+		for _, id := range ids {
+			if id == nil || *id == node.ID {
+				return flow.Errorf(flow.EINVALID, "A node cannot be connected to itself.")
+			}
+			other, err := getNodeByID(ctx, tx, *id)
+			if flow.ErrorCode(err) == flow.ENOTFOUND || (err == nil && other.WorkflowID != node.WorkflowID) {
+				return flow.Errorf(flow.EINVALID, "Node %s is not part of the workflow.", *id)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	children := make(map[uuid.UUID]bool, len(childrenIDs))
+	for _, id := range childrenIDs {
+		children[*id] = true
+	}
+	for id := range labels {
+		if !children[id] {
+			return flow.Errorf(flow.EINVALID, "Labeled node %s is not a child of the node.", id)
+		}
+	}
+
+	for _, id := range parentIDs {
+		if err := insert(*id, node.ID, parentLabels[*id]); err != nil {
+			return err
+		}
+	}
+	for _, id := range childrenIDs {
+		if err := insert(node.ID, *id, labels[*id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEdgeLabels returns the labels of the edges whose match column equals the
+// node ID, keyed by the key column. Unlabeled edges are omitted.
+func getEdgeLabels(ctx context.Context, tx *Tx, key, match string, nodeID uuid.UUID) (map[uuid.UUID]string, error) {
+	var edges []*flow.Edge
+	if err := tx.SelectContext(ctx, &edges, fmt.Sprintf(`
+		SELECT head_id, tail_id, label FROM edges WHERE %s = $1 AND label IS NOT NULL
+	`, match), nodeID); err != nil {
+		return nil, err
+	}
+
+	labels := make(map[uuid.UUID]string, len(edges))
+	for _, e := range edges {
+		if key == "head_id" {
+			labels[e.HeadID] = *e.Label
+		} else {
+			labels[e.TailID] = *e.Label
+		}
+	}
+	return labels, nil
+}
+
+// nullString converts an empty string to NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// conditionJSON encodes a condition for storage. Returns nil for a nil
+// condition.
+func conditionJSON(c *flow.Condition) (interface{}, error) {
+	if c == nil {
+		return nil, nil
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// attachNodeAssociations is a helper function to fetch and attach the params
+// and edges of a node.
+func attachNodeAssociations(ctx context.Context, tx *Tx, node *flow.Node) error {
+	node.Params = make([]*flow.Param, 0)
+	if err := tx.SelectContext(ctx, &node.Params, `
+		SELECT * FROM params WHERE node_id = $1 ORDER BY created_at ASC
+	`, node.ID); err != nil {
+		return fmt.Errorf("failed to attach node params: %w", err)
+	}
+
+	var parentIDs, childrenIDs []uuid.UUID
+	if err := tx.SelectContext(ctx, &parentIDs, `SELECT tail_id FROM edges WHERE head_id = $1`, node.ID); err != nil {
+		return fmt.Errorf("failed to attach node parents: %w", err)
+	}
+	if err := tx.SelectContext(ctx, &childrenIDs, `SELECT head_id FROM edges WHERE tail_id = $1`, node.ID); err != nil {
+		return fmt.Errorf("failed to attach node children: %w", err)
+	}
+	node.ParentIDs = uuidPointers(parentIDs)
+	node.ChildrenIDs = uuidPointers(childrenIDs)
+
+	labels, err := getEdgeLabels(ctx, tx, "head_id", "tail_id", node.ID)
+	if err != nil {
+		return fmt.Errorf("failed to attach node edge labels: %w", err)
+	}
+	node.EdgeLabels = labels
+
+	return nil
+}
+
+// uuidPointers converts a slice of IDs into a slice of ID pointers.
+func uuidPointers(ids []uuid.UUID) []*uuid.UUID {
+	ptrs := make([]*uuid.UUID, len(ids))
+	for i := range ids {
+		ptrs[i] = &ids[i]
+	}
+	return ptrs
+}
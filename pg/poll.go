@@ -0,0 +1,217 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/openmesh/flow"
+)
+
+type pollService struct {
+	db *DB
+}
+
+func NewPollService(db *DB) flow.PollService {
+	return pollService{db}
+}
+
+func (s pollService) GetPollState(ctx context.Context, nodeID uuid.UUID) (*flow.PollState, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getPollState(ctx, tx, nodeID)
+}
+
+func (s pollService) ClaimPollState(ctx context.Context, nodeID, workflowID uuid.UUID, lease time.Duration) (*flow.PollState, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	state, err := claimPollState(ctx, tx, nodeID, workflowID, lease)
+	if err != nil {
+		return nil, err
+	}
+	return state, tx.Commit()
+}
+
+func (s pollService) SavePollState(ctx context.Context, state *flow.PollState) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := savePollState(ctx, tx, state); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getPollState is a helper function to fetch the poll state of a node.
+// Returns ENOTFOUND if the node has never been polled.
+func getPollState(ctx context.Context, tx *Tx, nodeID uuid.UUID) (*flow.PollState, error) {
+	var state flow.PollState
+	var seenIDs pq.StringArray
+	if err := tx.QueryRowxContext(ctx, `
+		SELECT
+			node_id,
+			workflow_id,
+			cursor,
+			seen_ids,
+			failures,
+			error,
+			polled_at,
+			next_poll_at,
+			locked_until,
+			updated_at
+		FROM
+			poll_states
+		WHERE
+			node_id = $1
+	`, nodeID).Scan(
+		&state.NodeID,
+		&state.WorkflowID,
+		&state.Cursor,
+		&seenIDs,
+		&state.Failures,
+		&state.Error,
+		&state.PolledAt,
+		&state.NextPollAt,
+		&state.LockedUntil,
+		&state.UpdatedAt,
+	); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, flow.Errorf(flow.ENOTFOUND, "Poll state not found.")
+		}
+		return nil, err
+	}
+	state.SeenIDs = seenIDs
+	return &state, nil
+}
+
+// claimPollState locks the poll state of a node if its poll is due and it is
+// not claimed by another poller, and leases it until the lease expires. The
+// state of a node that has never been polled is created due. A row locked by
+// a concurrent claim is skipped rather than waited on.
+func claimPollState(ctx context.Context, tx *Tx, nodeID, workflowID uuid.UUID, lease time.Duration) (*flow.PollState, error) {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			poll_states
+				(
+					node_id,
+					workflow_id,
+					next_poll_at,
+					updated_at
+				)
+		VALUES
+			($1, $2, $3, $3)
+		ON CONFLICT (node_id) DO NOTHING
+	`, nodeID, workflowID, tx.now); err != nil {
+		return nil, err
+	}
+
+	var state flow.PollState
+	var seenIDs pq.StringArray
+	if err := tx.QueryRowxContext(ctx, `
+		UPDATE
+			poll_states
+		SET
+			locked_until = $3
+		WHERE
+			node_id IN (
+				SELECT
+					node_id
+				FROM
+					poll_states
+				WHERE
+					node_id = $1
+					AND next_poll_at <= $2
+					AND (locked_until IS NULL OR locked_until <= $2)
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING
+			node_id,
+			workflow_id,
+			cursor,
+			seen_ids,
+			failures,
+			error,
+			polled_at,
+			next_poll_at,
+			locked_until,
+			updated_at
+	`, nodeID, tx.now, tx.now.Add(lease)).Scan(
+		&state.NodeID,
+		&state.WorkflowID,
+		&state.Cursor,
+		&seenIDs,
+		&state.Failures,
+		&state.Error,
+		&state.PolledAt,
+		&state.NextPollAt,
+		&state.LockedUntil,
+		&state.UpdatedAt,
+	); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, flow.Errorf(flow.ECONFLICT, "Poll is not due or is claimed by another poller.")
+		}
+		return nil, err
+	}
+	state.SeenIDs = seenIDs
+	return &state, nil
+}
+
+func savePollState(ctx context.Context, tx *Tx, state *flow.PollState) error {
+	state.LockedUntil = nil
+	state.UpdatedAt = tx.now
+
+	seenIDs := pq.StringArray(state.SeenIDs)
+	if seenIDs == nil {
+		seenIDs = pq.StringArray{}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			poll_states
+				(
+					node_id,
+					workflow_id,
+					cursor,
+					seen_ids,
+					failures,
+					error,
+					polled_at,
+					next_poll_at,
+					updated_at
+				)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (node_id) DO UPDATE SET
+			cursor = EXCLUDED.cursor,
+			seen_ids = EXCLUDED.seen_ids,
+			failures = EXCLUDED.failures,
+			error = EXCLUDED.error,
+			polled_at = EXCLUDED.polled_at,
+			next_poll_at = EXCLUDED.next_poll_at,
+			locked_until = NULL,
+			updated_at = EXCLUDED.updated_at
+	`,
+		state.NodeID,
+		state.WorkflowID,
+		state.Cursor,
+		seenIDs,
+		state.Failures,
+		state.Error,
+		state.PolledAt,
+		state.NextPollAt,
+		state.UpdatedAt,
+	)
+	return err
+}
@@ -0,0 +1,555 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/openmesh/flow"
+	"time"
+)
+
+type runService struct {
+	db *DB
+}
+
+func NewRunService(db *DB) flow.RunService {
+	return runService{db}
+}
+
+func (s runService) GetRunByID(ctx context.Context, id uuid.UUID) (*flow.Run, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := getRunByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachRunNodes(ctx, tx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s runService) GetRuns(ctx context.Context, filter flow.RunFilter) ([]*flow.Run, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getRuns(ctx, tx, filter)
+}
+
+func (s runService) CreateRun(ctx context.Context, run *flow.Run) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createRun(ctx, tx, run); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s runService) UpdateRun(ctx context.Context, id uuid.UUID, upd flow.RunUpdate) (*flow.Run, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := updateRun(ctx, tx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	return run, tx.Commit()
+}
+
+func (s runService) ClaimRuns(ctx context.Context, owner string, lease time.Duration, limit int) ([]*flow.Run, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	runs, err := claimRuns(ctx, tx, owner, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	return runs, tx.Commit()
+}
+
+func (s runService) RenewRunClaims(ctx context.Context, owner string, ids []uuid.UUID, lease time.Duration) ([]uuid.UUID, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	held, err := renewRunClaims(ctx, tx, owner, ids, lease)
+	if err != nil {
+		return nil, err
+	}
+	return held, tx.Commit()
+}
+
+func (s runService) CreateNodeRun(ctx context.Context, nodeRun *flow.NodeRun) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createNodeRun(ctx, tx, nodeRun); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s runService) GetAttachmentByID(ctx context.Context, id uuid.UUID) (*flow.Attachment, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var attachment flow.Attachment
+	if err := tx.GetContext(ctx, &attachment, `SELECT * FROM attachments WHERE id = $1`, id); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, flow.Errorf(flow.ENOTFOUND, "Attachment not found.")
+		}
+		return nil, err
+	}
+
+	// Verify that the run is visible to the current user.
+	if _, err := getRunByID(ctx, tx, attachment.RunID); err != nil {
+		if flow.ErrorCode(err) == flow.ENOTFOUND {
+			return nil, flow.Errorf(flow.ENOTFOUND, "Attachment not found.")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (s runService) CreateAttachment(ctx context.Context, attachment *flow.Attachment) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createAttachment(ctx, tx, attachment); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getRunByID is a helper function to fetch a run by ID. Returns ENOTFOUND if
+// the run does not exist.
+func getRunByID(ctx context.Context, tx *Tx, id uuid.UUID) (*flow.Run, error) {
+	runs, _, err := getRuns(ctx, tx, flow.RunFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(runs) == 0 {
+		return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Run not found."}
+	}
+	return runs[0], nil
+}
+
+// getRuns returns a list of runs that match a filter. Unless called by an
+// internal process, only runs of the current user's workflows are returned.
+func getRuns(ctx context.Context, tx *Tx, filter flow.RunFilter) ([]*flow.Run, int, error) {
+	var where []string
+	var args []interface{}
+
+	if !flow.IsSystemContext(ctx) {
+		where = append(where, "workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)")
+		args = append(args, flow.UserIDFromContext(ctx))
+	}
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.WorkflowID; v != nil {
+		where, args = append(where, fmt.Sprintf("workflow_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, fmt.Sprintf("status = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.ParentRunID; v != nil {
+		where, args = append(where, fmt.Sprintf("parent_run_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.ParentNodeID; v != nil {
+		where, args = append(where, fmt.Sprintf("parent_node_id = $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM runs %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY created_at DESC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	runs := make([]*flow.Run, 0)
+	if err := tx.Select(&runs, query, args...); err != nil {
+		return runs, n, err
+	}
+	return runs, n, nil
+}
+
+func createRun(ctx context.Context, tx *Tx, run *flow.Run) error {
+	if run.Status == "" {
+		run.Status = flow.RunStatusPending
+	}
+
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			runs
+				(
+					workflow_id,
+					trigger_node_id,
+					input,
+					status,
+					started_at,
+					parent_run_id,
+					parent_node_id,
+					parent_item
+				)
+		VALUES
+			(
+				:workflow_id,
+				:trigger_node_id,
+				:input,
+				:status,
+				:started_at,
+				:parent_run_id,
+				:parent_node_id,
+				:parent_item
+			)
+		RETURNING
+			*
+	`)
+	if err != nil {
+		return err
+	}
+	var res flow.Run
+	if err := stmt.Get(&res, run); err != nil {
+		return err
+	}
+	run.ID = res.ID
+	run.CreatedAt = res.CreatedAt
+	run.UpdatedAt = res.UpdatedAt
+
+	// Queue the run so that it is resumed if it does not finish.
+	var claimedBy *string
+	if run.ClaimedBy != "" {
+		claimedBy = &run.ClaimedBy
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO
+			run_queue
+				(
+					run_id,
+					claimed_by,
+					claimed_until
+				)
+		VALUES
+			($1, $2, $3)
+	`,
+		run.ID,
+		claimedBy,
+		run.ClaimedUntil,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func updateRun(ctx context.Context, tx *Tx, id uuid.UUID, upd flow.RunUpdate) (*flow.Run, error) {
+	run, err := getRunByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := upd.Status; v != nil {
+		run.Status = *v
+	}
+	if v := upd.Error; v != nil {
+		run.Error = v
+	}
+	if v := upd.StartedAt; v != nil {
+		run.StartedAt = v
+	}
+	if v := upd.FinishedAt; v != nil {
+		run.FinishedAt = v
+	}
+	run.UpdatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			runs
+		SET
+			status = $1,
+			error = $2,
+			started_at = $3,
+			finished_at = $4,
+			updated_at = $5
+		WHERE
+			id = $6
+	`,
+		run.Status,
+		run.Error,
+		run.StartedAt,
+		run.FinishedAt,
+		run.UpdatedAt,
+		run.ID,
+	); err != nil {
+		return run, err
+	}
+
+	// Finished runs leave the queue. Interrupted runs are released so that
+	// the next instance to claim them resumes them.
+	switch {
+	case run.Finished():
+		if _, err := tx.ExecContext(ctx, `DELETE FROM run_queue WHERE run_id = $1`, run.ID); err != nil {
+			return run, err
+		}
+	case run.Status == flow.RunStatusWaiting:
+		// Paused runs leave the queue until their wait is resolved. A wait
+		// resolved before the run paused has no node result yet, in which
+		// case the run is released to be resumed straight away.
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM
+				run_queue
+			WHERE
+				run_id = $1
+				AND NOT EXISTS (
+					SELECT
+						1
+					FROM
+						waits
+					WHERE
+						waits.run_id = $1
+						AND waits.resolved_at IS NOT NULL
+						AND NOT EXISTS (
+							SELECT
+								1
+							FROM
+								node_runs
+							WHERE
+								node_runs.run_id = waits.run_id
+								AND node_runs.node_id = waits.node_id
+								AND node_runs.item IS NOT DISTINCT FROM waits.item
+						)
+				)
+		`, run.ID); err != nil {
+			return run, err
+		}
+		fallthrough
+	case run.Status == flow.RunStatusInterrupted:
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE
+				run_queue
+			SET
+				claimed_by = NULL,
+				claimed_until = NULL
+			WHERE
+				run_id = $1
+		`, run.ID); err != nil {
+			return run, err
+		}
+	}
+	return run, nil
+}
+
+// claimRuns claims queued runs whose claim is missing or has expired. Rows
+// locked by concurrent claims are skipped so that each run is claimed once.
+func claimRuns(ctx context.Context, tx *Tx, owner string, lease time.Duration, limit int) ([]*flow.Run, error) {
+	var ids []uuid.UUID
+	if err := tx.SelectContext(ctx, &ids, `
+		UPDATE
+			run_queue
+		SET
+			claimed_by = $1,
+			claimed_until = $2
+		WHERE
+			run_id IN (
+				SELECT
+					run_id
+				FROM
+					run_queue
+				WHERE
+					claimed_until IS NULL
+					OR claimed_until <= $3
+				ORDER BY
+					created_at ASC
+				LIMIT $4
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING
+			run_id
+	`, owner, tx.now.Add(lease), tx.now, limit); err != nil {
+		return nil, err
+	}
+
+	runs := make([]*flow.Run, 0, len(ids))
+	for _, id := range ids {
+		run, err := getRunByID(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := attachRunNodes(ctx, tx, run); err != nil {
+			return nil, err
+		}
+		claimedUntil := tx.now.Add(lease)
+		run.ClaimedBy, run.ClaimedUntil = owner, &claimedUntil
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// renewRunClaims extends the claims of an owner on runs and returns the IDs of
+// the runs it still holds.
+func renewRunClaims(ctx context.Context, tx *Tx, owner string, ids []uuid.UUID, lease time.Duration) ([]uuid.UUID, error) {
+	runIDs := make(pq.StringArray, 0, len(ids))
+	for _, id := range ids {
+		runIDs = append(runIDs, id.String())
+	}
+
+	held := make([]uuid.UUID, 0, len(ids))
+	if err := tx.SelectContext(ctx, &held, `
+		UPDATE
+			run_queue
+		SET
+			claimed_until = $1
+		WHERE
+			claimed_by = $2
+			AND run_id = ANY($3::UUID[])
+		RETURNING
+			run_id
+	`, tx.now.Add(lease), owner, runIDs); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+func createNodeRun(ctx context.Context, tx *Tx, nodeRun *flow.NodeRun) error {
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			node_runs
+				(
+					run_id,
+					node_id,
+					status,
+					output,
+					error,
+					item,
+					attempts,
+					started_at,
+					finished_at
+				)
+		VALUES
+			(
+				:run_id,
+				:node_id,
+				:status,
+				:output,
+				:error,
+				:item,
+				:attempts,
+				:started_at,
+				:finished_at
+			)
+		RETURNING
+			id,
+			created_at
+	`)
+	if err != nil {
+		return err
+	}
+	var res flow.NodeRun
+	if err := stmt.Get(&res, nodeRun); err != nil {
+		return err
+	}
+	nodeRun.ID = res.ID
+	nodeRun.CreatedAt = res.CreatedAt
+
+	return nil
+}
+
+func createAttachment(ctx context.Context, tx *Tx, attachment *flow.Attachment) error {
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			attachments
+				(
+					id,
+					run_id,
+					field,
+					filename,
+					content_type,
+					size,
+					data
+				)
+		VALUES
+			(
+				:id,
+				:run_id,
+				:field,
+				:filename,
+				:content_type,
+				:size,
+				:data
+			)
+		RETURNING
+			created_at
+	`)
+	if err != nil {
+		return err
+	}
+	return stmt.Get(&attachment.CreatedAt, attachment)
+}
+
+// attachRunNodes is a helper function to fetch and attach the node results
+// of a run.
+func attachRunNodes(ctx context.Context, tx *Tx, run *flow.Run) error {
+	run.Nodes = make([]*flow.NodeRun, 0)
+	// Nodes that failed or were skipped have no output, which is read as
+	// JSON null.
+	if err := tx.SelectContext(ctx, &run.Nodes, `
+		SELECT
+			id,
+			run_id,
+			node_id,
+			status,
+			COALESCE(output, 'null') AS output,
+			error,
+			item,
+			attempts,
+			started_at,
+			finished_at,
+			created_at
+		FROM
+			node_runs
+		WHERE
+			run_id = $1
+		ORDER BY
+			started_at ASC
+	`, run.ID); err != nil {
+		return fmt.Errorf("failed to attach run nodes: %w", err)
+	}
+	return nil
+}
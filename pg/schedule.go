@@ -0,0 +1,252 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+type scheduleService struct {
+	db *DB
+}
+
+func NewScheduleService(db *DB) flow.ScheduleService {
+	return scheduleService{db}
+}
+
+func (s scheduleService) GetSchedules(ctx context.Context, filter flow.ScheduleFilter) ([]*flow.Schedule, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getSchedules(ctx, tx, filter)
+}
+
+func (s scheduleService) SaveSchedule(ctx context.Context, schedule *flow.Schedule) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveSchedule(ctx, tx, schedule); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s scheduleService) ClaimDueSchedules(ctx context.Context, lease time.Duration, limit int) ([]*flow.Schedule, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	schedules, err := claimDueSchedules(ctx, tx, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	return schedules, tx.Commit()
+}
+
+func (s scheduleService) UpdateSchedule(ctx context.Context, nodeID uuid.UUID, upd flow.ScheduleUpdate) (*flow.Schedule, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	schedule, err := updateSchedule(ctx, tx, nodeID, upd)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, tx.Commit()
+}
+
+func (s scheduleService) DeleteSchedule(ctx context.Context, nodeID uuid.UUID) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := getScheduleByNodeID(ctx, tx, nodeID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schedules WHERE node_id = $1`, nodeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getScheduleByNodeID is a helper function to fetch the schedule of a node.
+// Returns ENOTFOUND if the schedule does not exist.
+func getScheduleByNodeID(ctx context.Context, tx *Tx, nodeID uuid.UUID) (*flow.Schedule, error) {
+	schedules, _, err := getSchedules(ctx, tx, flow.ScheduleFilter{NodeID: &nodeID})
+	if err != nil {
+		return nil, err
+	} else if len(schedules) == 0 {
+		return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Schedule not found."}
+	}
+	return schedules[0], nil
+}
+
+// getSchedules returns a list of schedules that match a filter. Unless called
+// by an internal process, only schedules of the current user's workflows are
+// returned.
+func getSchedules(ctx context.Context, tx *Tx, filter flow.ScheduleFilter) ([]*flow.Schedule, int, error) {
+	var where []string
+	var args []interface{}
+
+	if !flow.IsSystemContext(ctx) {
+		where = append(where, "workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)")
+		args = append(args, flow.UserIDFromContext(ctx))
+	}
+	if v := filter.NodeID; v != nil {
+		where, args = append(where, fmt.Sprintf("node_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.WorkflowID; v != nil {
+		where, args = append(where, fmt.Sprintf("workflow_id = $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM schedules %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY created_at ASC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	schedules := make([]*flow.Schedule, 0)
+	if err := tx.Select(&schedules, query, args...); err != nil {
+		return schedules, n, err
+	}
+	return schedules, n, nil
+}
+
+// saveSchedule inserts a schedule or replaces the spec & next fire of an
+// existing one. Any claim on the schedule is released.
+func saveSchedule(ctx context.Context, tx *Tx, schedule *flow.Schedule) error {
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			schedules
+				(
+					node_id,
+					workflow_id,
+					trigger_key,
+					expression,
+					timezone,
+					interval_seconds,
+					at,
+					catch_up,
+					next_fire_at
+				)
+		VALUES
+			(
+				:node_id,
+				:workflow_id,
+				:trigger_key,
+				:expression,
+				:timezone,
+				:interval_seconds,
+				:at,
+				:catch_up,
+				:next_fire_at
+			)
+		ON CONFLICT (node_id) DO UPDATE SET
+			trigger_key = EXCLUDED.trigger_key,
+			expression = EXCLUDED.expression,
+			timezone = EXCLUDED.timezone,
+			interval_seconds = EXCLUDED.interval_seconds,
+			at = EXCLUDED.at,
+			catch_up = EXCLUDED.catch_up,
+			next_fire_at = EXCLUDED.next_fire_at,
+			locked_until = NULL
+		RETURNING
+			*
+	`)
+	if err != nil {
+		return err
+	}
+	var res flow.Schedule
+	if err := stmt.Get(&res, schedule); err != nil {
+		return err
+	}
+	*schedule = res
+
+	return nil
+}
+
+// claimDueSchedules locks due schedules that are not claimed by another
+// scheduler and leases them until the lease expires. Rows locked by concurrent
+// claims are skipped rather than waited on.
+func claimDueSchedules(ctx context.Context, tx *Tx, lease time.Duration, limit int) ([]*flow.Schedule, error) {
+	schedules := make([]*flow.Schedule, 0)
+	if err := tx.SelectContext(ctx, &schedules, `
+		UPDATE
+			schedules
+		SET
+			locked_until = $2
+		WHERE
+			node_id IN (
+				SELECT
+					node_id
+				FROM
+					schedules
+				WHERE
+					next_fire_at <= $1
+					AND (locked_until IS NULL OR locked_until <= $1)
+				ORDER BY
+					next_fire_at ASC
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING
+			*
+	`, tx.now, tx.now.Add(lease), limit); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func updateSchedule(ctx context.Context, tx *Tx, nodeID uuid.UUID, upd flow.ScheduleUpdate) (*flow.Schedule, error) {
+	schedule, err := getScheduleByNodeID(ctx, tx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.NextFireAt = upd.NextFireAt
+	if v := upd.LastFiredAt; v != nil {
+		schedule.LastFiredAt = v
+	}
+	schedule.LockedUntil = nil
+	schedule.UpdatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE
+			schedules
+		SET
+			next_fire_at = $1,
+			last_fired_at = $2,
+			locked_until = NULL,
+			updated_at = $3
+		WHERE
+			node_id = $4
+	`,
+		schedule.NextFireAt,
+		schedule.LastFiredAt,
+		schedule.UpdatedAt,
+		schedule.NodeID,
+	); err != nil {
+		return schedule, err
+	}
+	return schedule, nil
+}
@@ -0,0 +1,214 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/openmesh/flow"
+)
+
+type waitService struct {
+	db *DB
+}
+
+func NewWaitService(db *DB) flow.WaitService {
+	return waitService{db}
+}
+
+func (s waitService) GetWaits(ctx context.Context, filter flow.WaitFilter) ([]*flow.Wait, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getWaits(ctx, tx, filter)
+}
+
+func (s waitService) CreateWait(ctx context.Context, wait *flow.Wait) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createWait(ctx, tx, wait); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s waitService) ResolveWait(ctx context.Context, id uuid.UUID, upd flow.WaitUpdate) (*flow.Wait, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	wait, err := resolveWait(ctx, tx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	return wait, tx.Commit()
+}
+
+// getWaitByID is a helper function to fetch a wait by ID. Returns ENOTFOUND if
+// the wait does not exist.
+func getWaitByID(ctx context.Context, tx *Tx, id uuid.UUID) (*flow.Wait, error) {
+	var wait flow.Wait
+	if err := tx.GetContext(ctx, &wait, `SELECT * FROM waits WHERE id = $1`, id); err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Wait not found."}
+		}
+		return nil, err
+	}
+	return &wait, nil
+}
+
+// getWaits returns a list of waits that match a filter. Unless called by an
+// internal process, only waits of the current user's workflows are returned.
+func getWaits(ctx context.Context, tx *Tx, filter flow.WaitFilter) ([]*flow.Wait, int, error) {
+	var where []string
+	var args []interface{}
+
+	if !flow.IsSystemContext(ctx) {
+		where = append(where, "workflow_id IN (SELECT id FROM workflows WHERE user_id = $1)")
+		args = append(args, flow.UserIDFromContext(ctx))
+	}
+	if v := filter.RunID; v != nil {
+		where, args = append(where, fmt.Sprintf("run_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.Topic; v != nil {
+		where, args = append(where, fmt.Sprintf("topic = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.ChildRunID; v != nil {
+		where, args = append(where, fmt.Sprintf("child_run_id = $%d", len(args)+1)), append(args, *v)
+	}
+	if filter.Pending {
+		where = append(where, fmt.Sprintf(
+			"resolved_at IS NULL AND run_id IN (SELECT id FROM runs WHERE status NOT IN ('%s', '%s', '%s'))",
+			flow.RunStatusSucceeded, flow.RunStatusFailed, flow.RunStatusCancelled,
+		))
+	}
+	if v := filter.DueBefore; v != nil {
+		where, args = append(where, fmt.Sprintf("resume_at <= $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM waits %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY created_at ASC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	waits := make([]*flow.Wait, 0)
+	if err := tx.Select(&waits, query, args...); err != nil {
+		return waits, n, err
+	}
+	return waits, n, nil
+}
+
+func createWait(ctx context.Context, tx *Tx, wait *flow.Wait) error {
+	if wait.Condition != nil {
+		if err := wait.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+	condition, err := conditionJSON(wait.Condition)
+	if err != nil {
+		return err
+	}
+
+	return tx.GetContext(ctx, wait, `
+		INSERT INTO
+			waits
+				(
+					run_id,
+					workflow_id,
+					node_id,
+					item,
+					topic,
+					condition,
+					child_run_id,
+					resume_at
+				)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING
+			*
+	`,
+		wait.RunID,
+		wait.WorkflowID,
+		wait.NodeID,
+		wait.Item,
+		wait.Topic,
+		condition,
+		wait.ChildRunID,
+		wait.ResumeAt,
+	)
+}
+
+// resolveWait records the outcome of a wait. The run is locked first so that
+// resolving the wait does not race with the run pausing: runs that have
+// already paused are queued again here, while runs that pause afterwards stay
+// queued as their wait is resolved.
+func resolveWait(ctx context.Context, tx *Tx, id uuid.UUID, upd flow.WaitUpdate) (*flow.Wait, error) {
+	wait, err := getWaitByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var status string
+	if err := tx.GetContext(ctx, &status, `SELECT status FROM runs WHERE id = $1 FOR UPDATE`, wait.RunID); err != nil {
+		return nil, err
+	}
+
+	// Only the first resolution of a wait takes effect.
+	res, err := tx.ExecContext(ctx, `
+		UPDATE
+			waits
+		SET
+			event = $1,
+			timed_out = $2,
+			resolved_at = $3
+		WHERE
+			id = $4
+			AND resolved_at IS NULL
+	`,
+		upd.Event,
+		upd.TimedOut,
+		tx.now,
+		wait.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, flow.Errorf(flow.ECONFLICT, "Wait has already been resolved.")
+	}
+	resolvedAt := tx.now
+	wait.Event, wait.TimedOut, wait.ResolvedAt = upd.Event, upd.TimedOut, &resolvedAt
+
+	if status == flow.RunStatusWaiting {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO
+				run_queue
+					(
+						run_id
+					)
+			VALUES
+				($1)
+			ON CONFLICT (run_id) DO NOTHING
+		`, wait.RunID); err != nil {
+			return nil, err
+		}
+	}
+	return wait, nil
+}
@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openmesh/flow"
+)
+
+type workerService struct {
+	db *DB
+}
+
+func NewWorkerService(db *DB) flow.WorkerService {
+	return workerService{db}
+}
+
+func (s workerService) GetWorkers(ctx context.Context, filter flow.WorkerFilter) ([]*flow.Worker, int, error) {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return getWorkers(ctx, tx, filter)
+}
+
+func (s workerService) SaveWorker(ctx context.Context, worker *flow.Worker) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveWorker(ctx, tx, worker); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s workerService) DeleteWorker(ctx context.Context, id string) error {
+	tx, err := s.db.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	workers, _, err := getWorkers(ctx, tx, flow.WorkerFilter{ID: &id})
+	if err != nil {
+		return err
+	} else if len(workers) == 0 {
+		return flow.Errorf(flow.ENOTFOUND, "Worker not found.")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workers WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getWorkers returns a list of workers that match a filter, most recently
+// started first.
+func getWorkers(ctx context.Context, tx *Tx, filter flow.WorkerFilter) ([]*flow.Worker, int, error) {
+	var where []string
+	var args []interface{}
+
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.HeartbeatAfter; v != nil {
+		where, args = append(where, fmt.Sprintf("heartbeat_at > $%d", len(args)+1)), append(args, *v)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT * FROM workers %s", buildWhereClause(where))
+
+	var n int
+	if err := tx.Get(&n, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count;", baseQuery), args...); err != nil {
+		return nil, n, err
+	}
+
+	query := baseQuery + `
+		ORDER BY started_at DESC
+	` + formatLimitOffset(filter.Limit, filter.Page)
+
+	workers := make([]*flow.Worker, 0)
+	if err := tx.Select(&workers, query, args...); err != nil {
+		return workers, n, err
+	}
+	return workers, n, nil
+}
+
+// saveWorker registers a worker or records its heartbeat. The heartbeat is
+// the time of the transaction.
+func saveWorker(ctx context.Context, tx *Tx, worker *flow.Worker) error {
+	worker.HeartbeatAt = tx.now
+	if worker.StartedAt.IsZero() {
+		worker.StartedAt = tx.now
+	}
+
+	stmt, err := tx.PrepareNamed(`
+		INSERT INTO
+			workers
+				(
+					id,
+					concurrency,
+					active_runs,
+					started_at,
+					heartbeat_at
+				)
+		VALUES
+			(
+				:id,
+				:concurrency,
+				:active_runs,
+				:started_at,
+				:heartbeat_at
+			)
+		ON CONFLICT (id) DO UPDATE SET
+			concurrency = EXCLUDED.concurrency,
+			active_runs = EXCLUDED.active_runs,
+			heartbeat_at = EXCLUDED.heartbeat_at
+		RETURNING
+			started_at
+	`)
+	if err != nil {
+		return err
+	}
+	return stmt.Get(&worker.StartedAt, worker)
+}
@@ -70,6 +70,7 @@ func (s workflowService) GetWorkflowByID(ctx context.Context, id uuid.UUID) (*fl
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	workflow, err := getWorkflowByID(ctx, tx, id)
 	if err != nil {
@@ -111,6 +112,15 @@ func createWorkflow(ctx context.Context, tx *Tx, w *flow.Workflow) error {
 	// Assign user to workflow
 	w.UserID = userID
 
+	if w.ErrorMode == "" {
+		w.ErrorMode = flow.ErrorModeFailFast
+	} else if err := flow.ValidateErrorMode(w.ErrorMode); err != nil {
+		return err
+	}
+	if err := flow.ValidateTimeout(w.TimeoutSeconds); err != nil {
+		return err
+	}
+
 	//Prepare named statement
 	stmt, err := tx.PrepareNamed(`
 		INSERT INTO
@@ -118,13 +128,17 @@ func createWorkflow(ctx context.Context, tx *Tx, w *flow.Workflow) error {
 		    	(
 			    	user_id,
 			    	name,
-			    	description
+			    	description,
+			    	error_mode,
+			    	timeout_seconds
 				)
 		VALUES 
 		    (
 				:user_id,
 			    :name,
-			    :description
+			    :description,
+			    :error_mode,
+			    :timeout_seconds
 			)
 		RETURNING 
 			*
@@ -161,6 +175,18 @@ func updateWorkflow(ctx context.Context, tx *Tx, id uuid.UUID, upd flow.Workflow
 	if upd.Description != nil {
 		workflow.Description = *upd.Description
 	}
+	if upd.ErrorMode != nil {
+		if err := flow.ValidateErrorMode(*upd.ErrorMode); err != nil {
+			return workflow, err
+		}
+		workflow.ErrorMode = *upd.ErrorMode
+	}
+	if upd.TimeoutSeconds != nil {
+		if err := flow.ValidateTimeout(*upd.TimeoutSeconds); err != nil {
+			return workflow, err
+		}
+		workflow.TimeoutSeconds = *upd.TimeoutSeconds
+	}
 
 	workflow.UpdatedAt = tx.now
 
@@ -171,12 +197,16 @@ func updateWorkflow(ctx context.Context, tx *Tx, id uuid.UUID, upd flow.Workflow
 		SET
 			name = $1,
 		    description = $2,
-		    updated_at = $3
+		    error_mode = $3,
+		    timeout_seconds = $4,
+		    updated_at = $5
 		WHERE 
-			id = $4
+			id = $6
 	`,
 		workflow.Name,
 		workflow.Description,
+		workflow.ErrorMode,
+		workflow.TimeoutSeconds,
 		workflow.UpdatedAt,
 		workflow.ID,
 	); err != nil {
@@ -209,23 +239,39 @@ func getWorkflowByID(ctx context.Context, tx *Tx, id uuid.UUID) (*flow.Workflow,
 	} else if len(workflows) == 0 {
 		return nil, &flow.Error{Code: flow.ENOTFOUND, Message: "Workflow not found."}
 	}
-	err = attachWorkflowNodes(ctx, tx, workflows[0])
+	if err := attachWorkflowNodes(ctx, tx, workflows[0]); err != nil {
+		return nil, err
+	}
 	return workflows[0], nil
 }
 
 func getWorkflows(ctx context.Context, tx *Tx, filter flow.WorkflowFilter) ([]*flow.Workflow, int, error) {
-	userID := flow.UserIDFromContext(ctx)
-	where := []string{"user_id = $1"}
-	args := []interface{}{userID}
+	var where []string
+	var args []interface{}
+
+	// Internal processes may access any user's workflows.
+	if !flow.IsSystemContext(ctx) {
+		where, args = append(where, "user_id = $1"), append(args, flow.UserIDFromContext(ctx))
+	}
 
 	if v := filter.ID; v != nil {
-		where, args = append(where, fmt.Sprintf("id = $%d", len(where)+1)), append(args, *v)
+		where, args = append(where, fmt.Sprintf("id = $%d", len(args)+1)), append(args, *v)
 	}
 	if v := filter.Description; v != nil {
-		where, args = append(where, fmt.Sprintf("description = $%d", len(where)+1)), append(args, *v)
+		where, args = append(where, fmt.Sprintf("description = $%d", len(args)+1)), append(args, *v)
 	}
 	if v := filter.Name; v != nil {
-		where, args = append(where, fmt.Sprintf("name = $%d", len(where)+1)), append(args, *v)
+		where, args = append(where, fmt.Sprintf("name = $%d", len(args)+1)), append(args, *v)
+	}
+	if v := filter.Trigger; v != nil {
+		// Match workflows with a source node listening on the trigger.
+		integration, trigger := flow.ParseTriggerTopic(*v)
+		where = append(where, fmt.Sprintf(`id IN (
+			SELECT n.workflow_id FROM nodes n
+			WHERE n.integration = $%d AND n.action = $%d
+			AND NOT EXISTS (SELECT 1 FROM edges e WHERE e.head_id = n.id)
+		)`, len(args)+1, len(args)+2))
+		args = append(args, integration, trigger)
 	}
 
 	baseQuery := fmt.Sprintf("SELECT * FROM workflows %s", buildWhereClause(where))
@@ -255,5 +301,13 @@ func getWorkflows(ctx context.Context, tx *Tx, filter flow.WorkflowFilter) ([]*f
 func attachWorkflowNodes(ctx context.Context, tx *Tx, workflow *flow.Workflow) error {
 	var err error
 	workflow.Nodes, _, err = getNodes(ctx, tx, flow.NodeFilter{WorkflowID: &workflow.ID})
-	return err
+	if err != nil {
+		return err
+	}
+	for _, node := range workflow.Nodes {
+		if err := attachNodeAssociations(ctx, tx, node); err != nil {
+			return err
+		}
+	}
+	return nil
 }
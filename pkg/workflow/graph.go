@@ -61,12 +61,27 @@ func (g *Graph) AddEdge(tail *Node, head *Node) error {
 	return nil
 }
 
+// AddLabeledEdge adds a directed edge with a label between two existing nodes
+// of the graph. Labels allow a node to select which of its edges are
+// followed, such as the branches of a condition.
+func (g *Graph) AddLabeledEdge(tail *Node, head *Node, label string) error {
+	if err := g.AddEdge(tail, head); err != nil {
+		return err
+	}
+	if label != "" {
+		tail.Labels[head.ID] = label
+	}
+
+	return nil
+}
+
 // DeleteEdge deletes a directed edge between two existing nodes from the
 // graph.
 func (g *Graph) DeleteEdge(tail *Node, head *Node) error {
 	for _, child := range tail.Children {
 		if child.ID == head.ID {
 			delete(tail.Children, child.ID)
+			delete(tail.Labels, child.ID)
 		}
 	}
 	return nil
@@ -154,6 +169,62 @@ func (g *Graph) Predecessors(node *Node) ([]*Node, error) {
 	return predecessors, nil
 }
 
+// TopologicalSort returns the nodes of the graph ordered so that every node
+// comes after all of its parents. Returns an error if the graph contains a
+// cycle.
+func (g *Graph) TopologicalSort() ([]*Node, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	inDegree := make(map[uuid.UUID]int, len(g.nodes))
+	var queue []*Node
+	for id, n := range g.nodes {
+		inDegree[id] = n.InDegree()
+		if inDegree[id] == 0 {
+			queue = append(queue, n)
+		}
+	}
+
+	sorted := make([]*Node, 0, len(g.nodes))
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, n)
+
+		for id, child := range n.Children {
+			inDegree[id]--
+			if inDegree[id] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	if len(sorted) != len(g.nodes) {
+		return nil, fmt.Errorf("graph contains a cycle")
+	}
+
+	return sorted, nil
+}
+
+// Descendants returns all nodes that can be reached from a given node by
+// following its edges.
+func (g *Graph) Descendants(node *Node) map[uuid.UUID]*Node {
+	descendants := make(map[uuid.UUID]*Node)
+	queue := []*Node{node}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for id, child := range n.Children {
+			if _, found := descendants[id]; !found {
+				descendants[id] = child
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	return descendants
+}
+
 // String implements stringer interface.
 //
 // Prints an string representation of this instance.
@@ -11,6 +11,9 @@ type Node struct {
 	Value    interface{}
 	Parents  map[uuid.UUID]*Node
 	Children map[uuid.UUID]*Node
+
+	// Labels of the edges to the node's children, keyed by child ID.
+	Labels map[uuid.UUID]string
 }
 
 // NewNode creates a new node.
@@ -19,12 +22,22 @@ func NewNode(value interface{}) *Node {
 		ID:       uuid.New(),
 		Parents:  make(map[uuid.UUID]*Node),
 		Children: make(map[uuid.UUID]*Node),
+		Labels:   make(map[uuid.UUID]string),
 		Value:    value,
 	}
 
 	return n
 }
 
+// NewNodeWithID creates a new node with the given ID. This allows nodes of a
+// persisted workflow to keep their IDs within the graph.
+func NewNodeWithID(id uuid.UUID, value interface{}) *Node {
+	n := NewNode(value)
+	n.ID = id
+
+	return n
+}
+
 // Degree returns the number of parents and children of the node.
 func (n *Node) Degree() int {
 	return len(n.Parents) + len(n.Children)
@@ -50,6 +63,12 @@ func (n *Node) String() string {
 	return result
 }
 
+// Label returns the label of the edge to a child. Returns an empty string if
+// the edge is unlabeled.
+func (n *Node) Label(child *Node) string {
+	return n.Labels[child.ID]
+}
+
 func (n *Node) hasChild(c *Node) bool {
 	for i := range n.Children {
 		if n.Children[i].ID == c.ID {
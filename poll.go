@@ -0,0 +1,113 @@
+package flow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPollInterval is the time between polls of a trigger that does not
+// specify its own interval.
+const DefaultPollInterval = 5 * time.Minute
+
+// MaxPollBackoff is the longest a trigger is backed off for after repeated
+// errors.
+const MaxPollBackoff = time.Hour
+
+// MaxSeenIDs is the number of item IDs remembered per polled node. Older IDs
+// are forgotten first.
+const MaxSeenIDs = 500
+
+// Polling describes how a trigger without webhooks is polled for new items.
+// The trigger's Endpoint & Method are requested with the connection of each
+// workflow's owner.
+type Polling struct {
+	// Time in seconds between polls.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+
+	// Path to the array of items within the response. The response itself
+	// is the array if empty.
+	ItemsPath string `json:"items_path,omitempty"`
+
+	// Path to the unique ID of an item. Items whose IDs have been seen are
+	// not emitted again.
+	IDPath string `json:"id_path,omitempty"`
+
+	// Query parameter that the stored cursor is sent as, e.g. "since_id".
+	CursorParam string `json:"cursor_param,omitempty"`
+
+	// Path to the next cursor within the response. If empty, the ID of the
+	// first item is used as the cursor.
+	CursorPath string `json:"cursor_path,omitempty"`
+}
+
+// Interval returns the time between polls.
+func (p *Polling) Interval() time.Duration {
+	if p.IntervalSeconds > 0 {
+		return time.Duration(p.IntervalSeconds) * time.Second
+	}
+	return DefaultPollInterval
+}
+
+// PollState represents the progress of polling a workflow's trigger node.
+type PollState struct {
+	NodeID     uuid.UUID `json:"node_id" db:"node_id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+
+	// Cursor sent with the next poll & the IDs of the most recent items.
+	Cursor  *string  `json:"cursor" db:"cursor"`
+	SeenIDs []string `json:"seen_ids" db:"-"`
+
+	// Number of consecutive failed polls & the error of the last one.
+	Failures int     `json:"failures" db:"failures"`
+	Error    *string `json:"error" db:"error"`
+
+	// Time of the last successful poll & of the next poll. Items found by
+	// the first successful poll are only recorded as seen.
+	PolledAt   *time.Time `json:"polled_at" db:"polled_at"`
+	NextPollAt time.Time  `json:"next_poll_at" db:"next_poll_at"`
+
+	// Time until which the node is claimed by a poller instance.
+	LockedUntil *time.Time `json:"-" db:"locked_until"`
+
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Seen returns true if an item ID has been seen before.
+func (s *PollState) Seen(id string) bool {
+	for _, v := range s.SeenIDs {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSeen remembers item IDs, forgetting the oldest once more than MaxSeenIDs
+// are remembered.
+func (s *PollState) AddSeen(ids ...string) {
+	s.SeenIDs = append(s.SeenIDs, ids...)
+	if n := len(s.SeenIDs) - MaxSeenIDs; n > 0 {
+		s.SeenIDs = s.SeenIDs[n:]
+	}
+}
+
+// PollService represents a service for persisting the state of polled
+// triggers.
+type PollService interface {
+	// Retrieves the poll state of a trigger node. Returns ENOTFOUND if the
+	// node has never been polled.
+	GetPollState(ctx context.Context, nodeID uuid.UUID) (*PollState, error)
+
+	// Claims a workflow's trigger node if its poll is due, creating its poll
+	// state if the node has never been polled. Claimed nodes cannot be
+	// claimed again until their state is saved or the lease expires, so that
+	// only one poller instance polls each node. Returns ECONFLICT if the poll
+	// is not due or the node is claimed.
+	ClaimPollState(ctx context.Context, nodeID, workflowID uuid.UUID, lease time.Duration) (*PollState, error)
+
+	// Creates or replaces the poll state of a trigger node and releases its
+	// claim.
+	SavePollState(ctx context.Context, state *PollState) error
+}
@@ -0,0 +1,419 @@
+package poller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/openmesh/flow"
+)
+
+// Default settings of a Poller.
+const (
+	DefaultTick    = 10 * time.Second
+	DefaultTimeout = 30 * time.Second
+	DefaultLease   = time.Minute
+)
+
+// Poller periodically requests the endpoint of every polled trigger for each
+// workflow listening on it, using the connection of the workflow's owner. New
+// items are published to the event bus addressed to the workflow's trigger
+// node.
+type Poller struct {
+	EventBus           flow.EventBus
+	IntegrationService flow.IntegrationService
+	WorkflowService    flow.WorkflowService
+	AuthService        flow.AuthService
+	PollService        flow.PollService
+
+	// HTTP client used to request trigger endpoints.
+	Client *http.Client
+
+	Logger log.Logger
+
+	// Interval at which triggers are checked for polls that are due.
+	Tick time.Duration
+
+	// Time a poll of a node may take, including the request of the
+	// trigger's endpoint.
+	Timeout time.Duration
+
+	// Time a claimed node is reserved for. Nodes claimed by an instance
+	// that stops before polling them are polled once it expires. Must be
+	// longer than Timeout so that polls finish before their claim expires.
+	Lease time.Duration
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+
+	done chan struct{}
+}
+
+// New returns a new instance of Poller.
+func New() *Poller {
+	return &Poller{
+		Client:  &http.Client{Timeout: DefaultTimeout},
+		Logger:  log.NewNopLogger(),
+		Tick:    DefaultTick,
+		Timeout: DefaultTimeout,
+		Lease:   DefaultLease,
+		Now:     time.Now,
+		done:    make(chan struct{}),
+	}
+}
+
+// Open begins polling in the background.
+func (p *Poller) Open() error {
+	go p.loop()
+	return nil
+}
+
+// Close stops polling.
+func (p *Poller) Close() error {
+	close(p.done)
+	return nil
+}
+
+func (p *Poller) loop() {
+	ticker := time.NewTicker(p.Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if err := p.pollAll(flow.NewSystemContext(context.Background())); err != nil {
+				_ = p.Logger.Log("msg", "cannot poll triggers", "err", err)
+			}
+		}
+	}
+}
+
+// pollAll polls every trigger node of every polled trigger that is due.
+func (p *Poller) pollAll(ctx context.Context) error {
+	integrations, _, err := p.IntegrationService.GetIntegrations(ctx, flow.GetIntegrationsRequest{})
+	if err != nil {
+		return err
+	}
+
+	for _, integration := range integrations {
+		for i := range integration.Triggers {
+			trigger := &integration.Triggers[i]
+			if trigger.Polling == nil {
+				continue
+			}
+
+			topic := flow.TriggerTopic(integration.Key, trigger.Key)
+			workflows, _, err := p.WorkflowService.GetWorkflows(ctx, flow.WorkflowFilter{Trigger: &topic})
+			if err != nil {
+				return err
+			}
+
+			for _, wf := range workflows {
+				for _, node := range wf.Nodes {
+					if node.Integration != integration.Key || node.Action != trigger.Key || len(node.ParentIDs) > 0 {
+						continue
+					}
+					if err := p.pollNode(ctx, integration, trigger, wf, node); err != nil {
+						_ = p.Logger.Log("msg", "cannot poll node", "workflow", wf.ID, "node", node.ID, "err", err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pollNode polls a workflow's trigger node if it is due and not claimed by
+// another poller, and publishes the items that have not been seen before. The
+// first poll of a node only records the items that already exist so that they
+// do not trigger runs.
+func (p *Poller) pollNode(ctx context.Context, integration *flow.Integration, trigger *flow.Trigger, wf *flow.Workflow, node *flow.Node) error {
+	state, err := p.PollService.ClaimPollState(ctx, node.ID, wf.ID, p.Lease)
+	if flow.ErrorCode(err) == flow.ECONFLICT {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	now := p.Now()
+
+	// The state is saved with the parent context so that failures are
+	// recorded when the request times out.
+	fetchCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	items, cursor, err := p.fetch(fetchCtx, integration, trigger, wf, node, state)
+	if err != nil {
+		return p.saveFailure(ctx, state, trigger, now, err)
+	}
+
+	// Items are listed newest first by most APIs so they are published in
+	// reverse to trigger runs in the order they were created. Each item is
+	// only marked as seen once it is published. If publishing fails part way,
+	// the items published so far are saved as seen so that they do not start
+	// runs again when the poll is retried.
+	primed := state.PolledAt != nil
+	topic := flow.TriggerTopic(integration.Key, trigger.Key)
+	for i := len(items) - 1; i >= 0; i-- {
+		id, err := itemID(trigger.Polling, items[i])
+		if err != nil {
+			return p.saveFailure(ctx, state, trigger, now, err)
+		} else if state.Seen(id) {
+			continue
+		}
+
+		if primed {
+			if err := p.EventBus.Publish(topic, &flow.NodeEvent{
+				WorkflowID: wf.ID,
+				NodeID:     node.ID,
+				Payload:    items[i],
+			}); err != nil {
+				if serr := p.saveFailure(ctx, state, trigger, now, err); serr != nil {
+					return serr
+				}
+				return err
+			}
+		}
+		state.AddSeen(id)
+	}
+
+	if cursor != nil {
+		state.Cursor = cursor
+	}
+	state.Failures, state.Error = 0, nil
+	state.PolledAt = &now
+	state.NextPollAt = now.Add(trigger.Polling.Interval())
+	return p.PollService.SavePollState(ctx, state)
+}
+
+// saveFailure records a failed poll of a node and schedules a retry with
+// backoff. The cursor is kept so that the retry fetches the same items.
+func (p *Poller) saveFailure(ctx context.Context, state *flow.PollState, trigger *flow.Trigger, now time.Time, err error) error {
+	state.Failures++
+	state.Error = stringPtr(err.Error())
+	state.NextPollAt = now.Add(backoff(trigger.Polling.Interval(), state.Failures, err))
+	return p.PollService.SavePollState(ctx, state)
+}
+
+// fetch requests a trigger's endpoint and returns the items of the response
+// along with the next cursor. The node's params are sent as query parameters.
+func (p *Poller) fetch(ctx context.Context, integration *flow.Integration, trigger *flow.Trigger, wf *flow.Workflow, node *flow.Node, state *flow.PollState) ([]interface{}, *string, error) {
+	u, err := url.Parse(integration.BaseURL + trigger.Endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	inputs, err := pollInputs(trigger, node, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := u.Query()
+	for k, v := range inputs {
+		q.Set(k, stringify(v))
+	}
+	if trigger.Polling.CursorParam != "" && state.Cursor != nil {
+		q.Set(trigger.Polling.CursorParam, *state.Cursor)
+	}
+	u.RawQuery = q.Encode()
+
+	method := trigger.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	token, err := flow.AccessToken(ctx, p.AuthService, wf.UserID, integration.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	integration.Auth.Apply(req, token)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, flow.MaxResponseSize+1))
+	if err != nil {
+		return nil, nil, err
+	} else if len(body) > flow.MaxResponseSize {
+		return nil, nil, flow.Errorf(flow.ETOOLARGE, "Response exceeds %d bytes.", flow.MaxResponseSize)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, nil, &rateLimitError{retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), p.Now())}
+	} else if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("%s %s: unexpected status %d", method, u.Path, resp.StatusCode)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("cannot decode response: %w", err)
+	}
+
+	return extractItems(trigger.Polling, doc)
+}
+
+// pollInputs resolves the params of a trigger node into the inputs sent with
+// its polls. As polls happen before there is a trigger payload, reference
+// params are resolved against the poll itself: "poll.cursor" & "poll.polled_at"
+// are the cursor & time of the last successful poll.
+func pollInputs(trigger *flow.Trigger, node *flow.Node, state *flow.PollState) (map[string]interface{}, error) {
+	poll := map[string]interface{}{}
+	if state.Cursor != nil {
+		poll["cursor"] = *state.Cursor
+	}
+	if state.PolledAt != nil {
+		poll["polled_at"] = state.PolledAt.UTC().Format(time.RFC3339)
+	}
+	scope := map[string]interface{}{"poll": poll}
+
+	inputs := make(map[string]interface{}, len(node.Params))
+	for _, param := range node.Params {
+		switch param.Type {
+		case flow.ParamTypeReference:
+			v, ok, err := flow.Lookup(scope, param.Value)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, flow.Errorf(flow.EINVALID, "Reference '%s' of param '%s' could not be resolved.", param.Value, param.Key)
+			}
+			inputs[param.Key] = v
+		default:
+			inputs[param.Key] = param.Value
+		}
+	}
+	return flow.CoerceInputs(trigger.Inputs, inputs)
+}
+
+// extractItems returns the items of a polled response and the next cursor.
+func extractItems(polling *flow.Polling, doc interface{}) ([]interface{}, *string, error) {
+	v := doc
+	if polling.ItemsPath != "" {
+		var ok bool
+		var err error
+		if v, ok, err = flow.Lookup(doc, polling.ItemsPath); err != nil {
+			return nil, nil, err
+		} else if !ok {
+			return nil, nil, nil
+		}
+	}
+
+	items, ok := v.([]interface{})
+	if !ok && v != nil {
+		return nil, nil, fmt.Errorf("items at '%s' are not an array", polling.ItemsPath)
+	}
+
+	if polling.CursorParam == "" {
+		return items, nil, nil
+	}
+
+	// Without a cursor path, the newest item's ID is the cursor.
+	if polling.CursorPath == "" {
+		if len(items) == 0 {
+			return items, nil, nil
+		}
+		id, err := itemID(polling, items[0])
+		return items, &id, err
+	}
+
+	c, ok, err := flow.Lookup(doc, polling.CursorPath)
+	if err != nil || !ok || c == nil {
+		return items, nil, err
+	}
+	cursor := stringify(c)
+	return items, &cursor, nil
+}
+
+// itemID returns the unique ID of an item. Items without an ID path are
+// identified by a hash of their content.
+func itemID(polling *flow.Polling, item interface{}) (string, error) {
+	if polling.IDPath != "" {
+		v, ok, err := flow.Lookup(item, polling.IDPath)
+		if err != nil {
+			return "", err
+		} else if ok && v != nil {
+			return stringify(v), nil
+		}
+	}
+
+	buf, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// stringify converts a JSON value to a string. Numbers are formatted without
+// exponents so that large IDs are preserved.
+func stringify(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// rateLimitError is returned when a trigger endpoint rejects a poll because
+// of rate limiting.
+type rateLimitError struct {
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return "rate limited"
+}
+
+// backoff returns the time to wait before polling again after consecutive
+// failures. Rate limited polls wait for as long as the API requested.
+// Otherwise the interval is doubled with each failure.
+func backoff(interval time.Duration, failures int, err error) time.Duration {
+	if e, ok := err.(*rateLimitError); ok && e.retryAfter > interval {
+		return e.retryAfter
+	}
+
+	d := interval
+	for i := 0; i < failures && d < flow.MaxPollBackoff; i++ {
+		d *= 2
+	}
+	if d > flow.MaxPollBackoff {
+		d = flow.MaxPollBackoff
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date. Returns zero if the header is missing or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,129 @@
+package poller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+func TestPollInputs(t *testing.T) {
+	cursor := "c1"
+	polledAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	trigger := &flow.Trigger{Inputs: []flow.InputField{{Key: "limit", Type: flow.FieldTypeNumber}}}
+
+	tests := []struct {
+		name   string
+		params []*flow.Param
+		state  flow.PollState
+		want   map[string]string
+		code   string
+	}{
+		{
+			name:   "Value",
+			params: []*flow.Param{{Key: "repo", Value: "flow", Type: flow.ParamTypeValue}},
+			want:   map[string]string{"repo": "flow"},
+		},
+		{
+			name:   "Coerced",
+			params: []*flow.Param{{Key: "limit", Value: "10", Type: flow.ParamTypeValue}},
+			want:   map[string]string{"limit": "10"},
+		},
+		{
+			name: "Reference",
+			params: []*flow.Param{
+				{Key: "since", Value: "poll.polled_at", Type: flow.ParamTypeReference},
+				{Key: "after", Value: "poll.cursor", Type: flow.ParamTypeReference},
+			},
+			state: flow.PollState{Cursor: &cursor, PolledAt: &polledAt},
+			want:  map[string]string{"since": "2021-03-04T05:06:07Z", "after": "c1"},
+		},
+		{
+			name:   "UnresolvedReference",
+			params: []*flow.Param{{Key: "since", Value: "poll.polled_at", Type: flow.ParamTypeReference}},
+			code:   flow.EINVALID,
+		},
+		{
+			name:   "InvalidValue",
+			params: []*flow.Param{{Key: "limit", Value: "ten", Type: flow.ParamTypeValue}},
+			code:   flow.EINVALID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := pollInputs(trigger, &flow.Node{Params: tt.params}, &tt.state)
+			if code := flow.ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			} else if err != nil {
+				return
+			}
+			if len(inputs) != len(tt.want) {
+				t.Fatalf("inputs = %v, want %v", inputs, tt.want)
+			}
+			for k, v := range tt.want {
+				if got := stringify(inputs[k]); got != v {
+					t.Fatalf("inputs[%q] = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestPoller_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			_, _ = w.Write([]byte(`[{"id": 1}, {"id": 2}]`))
+		case "/large":
+			_, _ = w.Write([]byte("[" + strings.Repeat(`"item",`, flow.MaxResponseSize/7) + `"item"]`))
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	p := New()
+	p.AuthService = &authService{}
+	p.Timeout = 100 * time.Millisecond
+	integration := &flow.Integration{Key: "TEST", BaseURL: srv.URL}
+	wf, node := &flow.Workflow{}, &flow.Node{}
+	polling := &flow.Polling{IDPath: "id"}
+
+	items, _, err := p.fetch(context.Background(), integration, &flow.Trigger{Endpoint: "/items", Polling: polling}, wf, node, &flow.PollState{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(items) != 2 {
+		t.Fatalf("items = %v, want 2 items", items)
+	}
+
+	_, _, err = p.fetch(context.Background(), integration, &flow.Trigger{Endpoint: "/large", Polling: polling}, wf, node, &flow.PollState{})
+	if code := flow.ErrorCode(err); code != flow.ETOOLARGE {
+		t.Fatalf("error code = %q, want %q (err: %v)", code, flow.ETOOLARGE, err)
+	}
+
+	// Polls are abandoned once they take longer than the timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := p.fetch(ctx, integration, &flow.Trigger{Endpoint: "/slow", Polling: polling}, wf, node, &flow.PollState{}); err == nil {
+		t.Fatal("expected error")
+	} else if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("fetch() returned after %s, want about %s", d, p.Timeout)
+	}
+}
+
+// authService is an AuthService without connections.
+type authService struct {
+	flow.AuthService
+}
+
+func (s *authService) GetAuths(ctx context.Context, filter flow.AuthFilter) ([]*flow.Auth, int, error) {
+	return nil, 0, nil
+}
@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"strconv"
+	"strings"
+)
+
+// IntegrationHTTP is the key of the built-in integration whose actions send
+// HTTP requests to URLs chosen by the user. Its actions are keyed by the
+// request method.
+const IntegrationHTTP = "HTTP"
+
+// Authentication schemes of HTTP requests.
+const (
+	RequestAuthNone = "none"
+	// Sends the "username" & "password" inputs as basic auth.
+	RequestAuthBasic = "basic"
+	// Sends the "token" input as a bearer token.
+	RequestAuthBearer = "bearer"
+	// Sends the "auth_value" input in the header named by "auth_header".
+	RequestAuthHeader = "header"
+	// Sends the workflow owner's access token for the integration named by
+	// "auth_integration" as a bearer token.
+	RequestAuthIntegration = "integration"
+)
+
+// Formats HTTP responses are parsed as.
+const (
+	// Parses the response according to its content type.
+	ResponseFormatAuto = "auto"
+	ResponseFormatJSON = "json"
+	ResponseFormatText = "text"
+	// Outputs the response base64 encoded.
+	ResponseFormatBinary = "binary"
+)
+
+// Pagination schemes of HTTP requests. Paginated requests collect the items of
+// each page into a single output.
+const (
+	PaginationNone = "none"
+	// Follows the "next" link of each response's Link header.
+	PaginationLink = "link"
+	// Sends the value at "cursor_path" of each response as the
+	// "cursor_param" query param of the next request.
+	PaginationCursor = "cursor"
+	// Increments the "page_param" query param until a page has no items.
+	PaginationPage = "page"
+)
+
+// Limits of HTTP requests.
+const (
+	// Maximum size of the responses read by a node, across all pages.
+	MaxResponseSize = 10 << 20
+	// Maximum number of pages a paginated request may fetch, and the number
+	// fetched unless the node sets "max_pages".
+	MaxPages     = 100
+	DefaultPages = 10
+)
+
+// StatusRule is the set of response statuses an HTTP request succeeds with.
+// Rules are comma-separated status codes or classes such as "2xx".
+type StatusRule string
+
+// DefaultStatusRule accepts every status below 400.
+const DefaultStatusRule StatusRule = "1xx,2xx,3xx"
+
+// Validate returns an error if the rule is not a list of status codes or
+// classes.
+func (r StatusRule) Validate() error {
+	for _, s := range strings.Split(string(r), ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+			continue
+		} else if code, err := strconv.Atoi(s); err == nil && code >= 100 && code <= 599 {
+			continue
+		}
+		return Errorf(EINVALID, "Status '%s' must be a status code or class such as 2xx.", s)
+	}
+	return nil
+}
+
+// Matches returns true if the rule accepts a status code. Empty rules accept
+// the statuses of DefaultStatusRule.
+func (r StatusRule) Matches(code int) bool {
+	if strings.TrimSpace(string(r)) == "" {
+		r = DefaultStatusRule
+	}
+	for _, s := range strings.Split(string(r), ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if strings.HasSuffix(s, "xx") && len(s) == 3 && int(s[0]-'0') == code/100 {
+			return true
+		} else if n, err := strconv.Atoi(s); err == nil && n == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package flow
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Defaults & limits of retry policies.
+const (
+	DefaultRetryInterval   = time.Second
+	DefaultRetryMultiplier = 2.0
+	DefaultMaxRetryDelay   = 5 * time.Minute
+	MaxRetryAttempts       = 10
+)
+
+// RetryPolicy describes how often and when a node is retried after it fails.
+type RetryPolicy struct {
+	// Maximum number of attempts including the first. Values below two
+	// disable retries.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Delay before the first retry in milliseconds. Each following delay is
+	// multiplied by Multiplier, up to MaxIntervalMs.
+	InitialIntervalMs int     `json:"initial_interval_ms,omitempty"`
+	MaxIntervalMs     int     `json:"max_interval_ms,omitempty"`
+	Multiplier        float64 `json:"multiplier,omitempty"`
+
+	// Fraction of each delay that is randomized, between 0 and 1, so that
+	// retries of many runs do not hit an API at the same time.
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// Restrict retries to failures with one of these HTTP status codes or
+	// flow.Error codes. Any failure is retried if neither is set.
+	StatusCodes []int    `json:"status_codes,omitempty"`
+	ErrorCodes  []string `json:"error_codes,omitempty"`
+}
+
+// Validate returns an error if the policy is invalid.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxRetryAttempts {
+		return Errorf(EINVALID, "Max attempts must be between 0 and %d.", MaxRetryAttempts)
+	} else if p.InitialIntervalMs < 0 || p.MaxIntervalMs < 0 {
+		return Errorf(EINVALID, "Retry intervals cannot be negative.")
+	} else if p.Multiplier < 0 {
+		return Errorf(EINVALID, "Retry multiplier cannot be negative.")
+	} else if p.Jitter < 0 || p.Jitter > 1 {
+		return Errorf(EINVALID, "Retry jitter must be between 0 and 1.")
+	}
+	return nil
+}
+
+// Retryable returns true if a failure may be retried under the policy.
+func (p *RetryPolicy) Retryable(err error) bool {
+	if len(p.StatusCodes) == 0 && len(p.ErrorCodes) == 0 {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		for _, code := range p.StatusCodes {
+			if code == statusErr.StatusCode {
+				return true
+			}
+		}
+	}
+	code := ErrorCode(err)
+	for _, c := range p.ErrorCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the time to wait before an attempt, given a random number in
+// [0, 1) used for jitter. The first retry is attempt 2.
+func (p *RetryPolicy) Delay(attempt int, random float64) time.Duration {
+	interval := DefaultRetryInterval
+	if p.InitialIntervalMs > 0 {
+		interval = time.Duration(p.InitialIntervalMs) * time.Millisecond
+	}
+	max := DefaultMaxRetryDelay
+	if p.MaxIntervalMs > 0 {
+		max = time.Duration(p.MaxIntervalMs) * time.Millisecond
+	}
+	multiplier := DefaultRetryMultiplier
+	if p.Multiplier > 0 {
+		multiplier = p.Multiplier
+	}
+
+	d := float64(interval) * math.Pow(multiplier, float64(attempt-2))
+	if d > float64(max) {
+		d = float64(max)
+	}
+	// Jitter spreads the delay evenly around its nominal value.
+	d += d * p.Jitter * (2*random - 1)
+	return time.Duration(d)
+}
+
+// Scan implements sql.Scanner so that policies can be read from JSON columns.
+func (p *RetryPolicy) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, p)
+	case string:
+		return json.Unmarshal([]byte(src), p)
+	}
+	return fmt.Errorf("cannot scan %T into retry policy", src)
+}
+
+// Value implements driver.Valuer so that policies can be written to JSON
+// columns. A nil policy is written as NULL.
+func (p *RetryPolicy) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// StatusError is returned by actions whose API responded with an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// Attempt records a single attempt at executing a node.
+type Attempt struct {
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Error of a failed attempt & the HTTP status code that caused it, if
+	// any.
+	Error      *string `json:"error"`
+	StatusCode *int    `json:"status_code,omitempty"`
+
+	// Lines logged by the node's action during the attempt, such as the
+	// output of a code node's print calls.
+	Logs []string `json:"logs,omitempty"`
+}
+
+// Attempts is the attempt history of a node run. It is stored as JSON.
+type Attempts []*Attempt
+
+// Scan implements sql.Scanner. NULL is read as an empty history.
+func (a *Attempts) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, a)
+	case string:
+		return json.Unmarshal([]byte(src), a)
+	}
+	return fmt.Errorf("cannot scan %T into attempts", src)
+}
+
+// Value implements driver.Valuer.
+func (a Attempts) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
@@ -0,0 +1,58 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		random  float64
+		want    time.Duration
+	}{
+		{"FirstRetry", RetryPolicy{}, 2, 0.5, DefaultRetryInterval},
+		{"Backoff", RetryPolicy{}, 4, 0.5, 4 * DefaultRetryInterval},
+		{"DefaultMax", RetryPolicy{}, 20, 0.5, DefaultMaxRetryDelay},
+		{"Initial", RetryPolicy{InitialIntervalMs: 100}, 3, 0.5, 200 * time.Millisecond},
+		{"Multiplier", RetryPolicy{InitialIntervalMs: 100, Multiplier: 3}, 4, 0.5, 900 * time.Millisecond},
+		{"Max", RetryPolicy{InitialIntervalMs: 100, MaxIntervalMs: 250}, 5, 0.5, 250 * time.Millisecond},
+		{"JitterLow", RetryPolicy{InitialIntervalMs: 1000, Jitter: 0.5}, 2, 0, 500 * time.Millisecond},
+		{"JitterHigh", RetryPolicy{InitialIntervalMs: 1000, Jitter: 0.5}, 2, 1, 1500 * time.Millisecond},
+		{"JitterAfterMax", RetryPolicy{InitialIntervalMs: 1000, MaxIntervalMs: 1000, Jitter: 0.1}, 5, 0, 900 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.attempt, tt.random); got != tt.want {
+				t.Fatalf("Delay(%d, %v) = %s, want %s", tt.attempt, tt.random, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy RetryPolicy
+		err    error
+		want   bool
+	}{
+		{"Any", RetryPolicy{}, errors.New("failed"), true},
+		{"Status", RetryPolicy{StatusCodes: []int{429, 503}}, &StatusError{StatusCode: 503}, true},
+		{"WrappedStatus", RetryPolicy{StatusCodes: []int{503}}, fmt.Errorf("request: %w", &StatusError{StatusCode: 503}), true},
+		{"OtherStatus", RetryPolicy{StatusCodes: []int{503}}, &StatusError{StatusCode: 400}, false},
+		{"ErrorCode", RetryPolicy{ErrorCodes: []string{EUNAVAILABLE}}, Errorf(EUNAVAILABLE, "down"), true},
+		{"OtherErrorCode", RetryPolicy{ErrorCodes: []string{EUNAVAILABLE}}, Errorf(EINVALID, "bad"), false},
+		{"InternalErrorCode", RetryPolicy{ErrorCodes: []string{EINTERNAL}}, errors.New("failed"), true},
+		{"StatusOrErrorCode", RetryPolicy{StatusCodes: []int{503}, ErrorCodes: []string{EUNAVAILABLE}}, Errorf(EUNAVAILABLE, "down"), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Retryable(tt.err); got != tt.want {
+				t.Fatalf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
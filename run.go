@@ -0,0 +1,219 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Run statuses.
+const (
+	RunStatusPending   = "pending"
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+
+	// The run was stopped at the request of a user.
+	RunStatusCancelled = "cancelled"
+	// The run was stopped by a shutdown before it finished. Interrupted runs
+	// can be resumed.
+	RunStatusInterrupted = "interrupted"
+	// The run is paused at a delay or wait-for-event node and is resumed
+	// once its wait is resolved.
+	RunStatusWaiting = "waiting"
+)
+
+// Node run statuses.
+const (
+	NodeRunStatusSucceeded = "succeeded"
+	NodeRunStatusFailed    = "failed"
+	NodeRunStatusSkipped   = "skipped"
+	NodeRunStatusCancelled = "cancelled"
+)
+
+// Run represents a single execution of a workflow.
+type Run struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+
+	// The trigger node that started the run and the payload it received.
+	TriggerNodeID uuid.UUID       `json:"trigger_node_id" db:"trigger_node_id"`
+	Input         json.RawMessage `json:"input" db:"input"`
+
+	Status string  `json:"status" db:"status"`
+	Error  *string `json:"error" db:"error"`
+
+	// Timestamps of execution.
+	StartedAt  *time.Time `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at" db:"finished_at"`
+
+	// Timestamps of creation & last update.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Results of the nodes that have been executed.
+	Nodes []*NodeRun `json:"nodes" db:"-"`
+
+	// Run, node & loop item of the sub-workflow node that started the run.
+	// Nil for runs started by a trigger.
+	ParentRunID  *uuid.UUID `json:"parent_run_id" db:"parent_run_id"`
+	ParentNodeID *uuid.UUID `json:"parent_node_id" db:"parent_node_id"`
+	ParentItem   *int       `json:"parent_item" db:"parent_item"`
+
+	// Executor instance that has claimed the run from the queue of
+	// unfinished runs and the time until which the claim holds. Runs created
+	// with a claim are not claimed by other instances until it expires.
+	ClaimedBy    string     `json:"-" db:"-"`
+	ClaimedUntil *time.Time `json:"-" db:"-"`
+}
+
+// Finished returns true if the run has ended and will not be resumed.
+func (r *Run) Finished() bool {
+	switch r.Status {
+	case RunStatusSucceeded, RunStatusFailed, RunStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// NodeRun represents the result of executing a single node within a run.
+type NodeRun struct {
+	ID     uuid.UUID       `json:"id" db:"id"`
+	RunID  uuid.UUID       `json:"run_id" db:"run_id"`
+	NodeID uuid.UUID       `json:"node_id" db:"node_id"`
+	Status string          `json:"status" db:"status"`
+	Output json.RawMessage `json:"output" db:"output"`
+	Error  *string         `json:"error" db:"error"`
+
+	// Index of the loop item the node was executed for. Nil for nodes
+	// outside the body of a for-each node.
+	Item *int `json:"item" db:"item"`
+
+	// Every attempt at executing the node, including retries.
+	Attempts Attempts `json:"attempts" db:"attempts"`
+
+	StartedAt  time.Time `json:"started_at" db:"started_at"`
+	FinishedAt time.Time `json:"finished_at" db:"finished_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Attachment represents a file received by a run's trigger, such as a file
+// uploaded to a webhook.
+type Attachment struct {
+	ID    uuid.UUID `json:"id" db:"id"`
+	RunID uuid.UUID `json:"run_id" db:"run_id"`
+
+	// Form field the file was uploaded as & its original file name.
+	Field    string `json:"field" db:"field"`
+	Filename string `json:"filename" db:"filename"`
+
+	ContentType string `json:"content_type" db:"content_type"`
+	Size        int64  `json:"size" db:"size"`
+	Data        []byte `json:"-" db:"data"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NodeRun returns the result of the given node. Returns nil if the node has
+// not been executed as part of the run.
+func (r *Run) NodeRun(nodeID uuid.UUID) *NodeRun {
+	for _, nr := range r.Nodes {
+		if nr.NodeID == nodeID {
+			return nr
+		}
+	}
+	return nil
+}
+
+// RunService represents a service for managing workflow runs.
+type RunService interface {
+	// Retrieves a run by ID along with its node results. Returns ENOTFOUND if
+	// the run does not exist or belongs to another user's workflow.
+	GetRunByID(ctx context.Context, id uuid.UUID) (*Run, error)
+
+	// Retrieves a list of runs by filter. Also returns the total count of
+	// matching runs which may differ from the returned results if
+	// filter.Limit is specified.
+	GetRuns(ctx context.Context, filter RunFilter) ([]*Run, int, error)
+
+	// Creates a new run and adds it to the queue of unfinished runs. On
+	// success, the run.ID is set to the new run ID.
+	CreateRun(ctx context.Context, run *Run) error
+
+	// Updates the status of a run. Finished runs are removed from the queue
+	// and the claim on interrupted runs is released so that they are resumed
+	// by the next instance that claims them.
+	UpdateRun(ctx context.Context, id uuid.UUID, upd RunUpdate) (*Run, error)
+
+	// Claims up to limit queued runs that are not claimed by another
+	// instance, along with their node results. Claimed runs are not returned
+	// by other claims until the lease expires, so that only one executor
+	// instance executes each run.
+	ClaimRuns(ctx context.Context, owner string, lease time.Duration, limit int) ([]*Run, error)
+
+	// Extends the claims of an instance on runs by the lease. Returns the
+	// IDs of the runs that are still claimed by the instance.
+	RenewRunClaims(ctx context.Context, owner string, ids []uuid.UUID, lease time.Duration) ([]uuid.UUID, error)
+
+	// Records the result of a node within a run.
+	CreateNodeRun(ctx context.Context, nodeRun *NodeRun) error
+
+	// Retrieves an attachment by ID along with its data. Returns ENOTFOUND if
+	// the attachment does not exist or belongs to another user's run.
+	GetAttachmentByID(ctx context.Context, id uuid.UUID) (*Attachment, error)
+
+	// Stores a file received by a run. If attachment.ID is set it is used as
+	// the ID of the new attachment.
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+}
+
+// RunUpdate represents a set of fields to be updated via UpdateRun().
+type RunUpdate struct {
+	Status     *string    `json:"status"`
+	Error      *string    `json:"error"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// RunFilter represents a filter passed to GetRuns().
+type RunFilter struct {
+	ID         *uuid.UUID `json:"id"`
+	WorkflowID *uuid.UUID `json:"workflow_id"`
+	Status     *string    `json:"status"`
+
+	// Restricts results to the runs started by a run's sub-workflow nodes,
+	// or by one of them.
+	ParentRunID  *uuid.UUID `json:"parent_run_id"`
+	ParentNodeID *uuid.UUID `json:"parent_node_id"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// Executor represents a service for executing workflows.
+type Executor interface {
+	// Creates a run for the request and executes it in the background. The
+	// returned run is pending.
+	StartRun(ctx context.Context, req RunRequest) (*Run, error)
+
+	// Creates a run for the request and blocks until it has finished. The
+	// returned run includes the results of each executed node.
+	ExecuteRun(ctx context.Context, req RunRequest) (*Run, error)
+
+	// Stops a run that has not finished. Returns ECONFLICT if the run has
+	// already finished.
+	CancelRun(ctx context.Context, id uuid.UUID) (*Run, error)
+}
+
+// RunRequest describes the trigger of a new run.
+type RunRequest struct {
+	WorkflowID    uuid.UUID   `json:"workflow_id"`
+	TriggerNodeID uuid.UUID   `json:"trigger_node_id"`
+	Payload       interface{} `json:"payload"`
+
+	// Sub-workflow node that requested the run, if any.
+	ParentRunID  *uuid.UUID `json:"parent_run_id"`
+	ParentNodeID *uuid.UUID `json:"parent_node_id"`
+	ParentItem   *int       `json:"parent_item"`
+}
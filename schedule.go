@@ -0,0 +1,130 @@
+package flow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IntegrationSchedule is the key of the built-in integration whose triggers
+// start runs on a schedule.
+const IntegrationSchedule = "SCHEDULE"
+
+// Schedule trigger keys.
+const (
+	// Fires according to a cron expression in a time zone.
+	TriggerCron = "CRON"
+	// Fires at a fixed interval.
+	TriggerInterval = "INTERVAL"
+	// Fires once at a given time.
+	TriggerOnce = "ONCE"
+)
+
+// Catch-up policies decide what happens to fires that were missed while no
+// scheduler was running.
+const (
+	// Missed fires are dropped and the schedule resumes at its next fire.
+	CatchUpSkip = "skip"
+	// A single run is started for any number of missed fires.
+	CatchUpOnce = "once"
+	// A run is started for every missed fire, up to MaxCatchUpFires.
+	CatchUpAll = "all"
+)
+
+// MaxCatchUpFires is the maximum number of missed fires started at once by
+// the CatchUpAll policy.
+const MaxCatchUpFires = 100
+
+// Schedule represents when a workflow's schedule trigger node fires. Schedules
+// are kept in sync with the params of their nodes by the scheduler.
+type Schedule struct {
+	NodeID     uuid.UUID `json:"node_id" db:"node_id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+
+	// One of the schedule trigger keys.
+	Trigger string `json:"trigger" db:"trigger_key"`
+
+	// Cron expression & the time zone it is evaluated in. Time zones are
+	// IANA names such as "Europe/London" and default to UTC.
+	Expression string `json:"expression" db:"expression"`
+	Timezone   string `json:"timezone" db:"timezone"`
+
+	// Time in seconds between fires of interval schedules.
+	IntervalSeconds int `json:"interval_seconds" db:"interval_seconds"`
+
+	// Time of the only fire of a one-off schedule.
+	At *time.Time `json:"at" db:"at"`
+
+	// One of the catch-up policies.
+	CatchUp string `json:"catch_up" db:"catch_up"`
+
+	// Time of the next fire. Nil once a one-off schedule has fired.
+	NextFireAt  *time.Time `json:"next_fire_at" db:"next_fire_at"`
+	LastFiredAt *time.Time `json:"last_fired_at" db:"last_fired_at"`
+
+	// Time until which the schedule is claimed by a scheduler instance.
+	LockedUntil *time.Time `json:"-" db:"locked_until"`
+
+	// Timestamps of creation & last update.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SameSpec returns true if both schedules fire at the same times.
+func (s *Schedule) SameSpec(other *Schedule) bool {
+	return s.Trigger == other.Trigger &&
+		s.Expression == other.Expression &&
+		s.Timezone == other.Timezone &&
+		s.IntervalSeconds == other.IntervalSeconds &&
+		timeEqual(s.At, other.At) &&
+		s.CatchUp == other.CatchUp
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// ScheduleService represents a service for managing the schedules of schedule
+// trigger nodes.
+type ScheduleService interface {
+	// Retrieves a list of schedules by filter. Also returns the total count of
+	// matching schedules which may differ from the returned results if
+	// filter.Limit is specified.
+	GetSchedules(ctx context.Context, filter ScheduleFilter) ([]*Schedule, int, error)
+
+	// Creates the schedule of a node or replaces its spec & next fire if it
+	// exists.
+	SaveSchedule(ctx context.Context, schedule *Schedule) error
+
+	// Claims up to limit schedules whose next fire is due. Claimed schedules
+	// are not returned by other claims until they are updated or the lease
+	// expires, so that only one scheduler instance fires each schedule.
+	ClaimDueSchedules(ctx context.Context, lease time.Duration, limit int) ([]*Schedule, error)
+
+	// Records the fire of a claimed schedule and releases the claim.
+	UpdateSchedule(ctx context.Context, nodeID uuid.UUID, upd ScheduleUpdate) (*Schedule, error)
+
+	// Permanently removes the schedule of a node.
+	DeleteSchedule(ctx context.Context, nodeID uuid.UUID) error
+}
+
+// ScheduleUpdate represents a set of fields to be updated via UpdateSchedule().
+type ScheduleUpdate struct {
+	// Always replaces the next fire. A nil value finishes the schedule.
+	NextFireAt *time.Time `json:"next_fire_at"`
+
+	LastFiredAt *time.Time `json:"last_fired_at"`
+}
+
+// ScheduleFilter represents a filter passed to GetSchedules().
+type ScheduleFilter struct {
+	NodeID     *uuid.UUID `json:"node_id"`
+	WorkflowID *uuid.UUID `json:"workflow_id"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
@@ -0,0 +1,425 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	// Time zones of schedules must resolve on hosts without a zoneinfo
+	// database.
+	_ "time/tzdata"
+
+	"github.com/go-kit/kit/log"
+	"github.com/openmesh/flow"
+	"github.com/robfig/cron/v3"
+)
+
+// Default settings of a Scheduler.
+const (
+	DefaultTick    = 10 * time.Second
+	DefaultLease   = time.Minute
+	DefaultCatchUp = flow.CatchUpOnce
+)
+
+// MisfireThreshold is how late a fire may be before it counts as missed and
+// is subject to the schedule's catch-up policy.
+const MisfireThreshold = time.Minute
+
+// claimLimit is the maximum number of schedules claimed per tick.
+const claimLimit = 100
+
+// parser parses cron expressions with an optional seconds field as well as
+// descriptors such as "@daily" & "@every 1h".
+var parser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Scheduler fires the schedule trigger nodes of workflows. Each tick it syncs
+// the schedules with the params of the nodes and then fires the schedules that
+// are due by publishing an event addressed to their node.
+//
+// Several schedulers may run against the same database. Due schedules are
+// claimed with row locks so that each fire happens on one instance only.
+//
+// The scheduler also resumes runs paused by delays & wait-for-event nodes once
+// their wait is due.
+type Scheduler struct {
+	EventBus        flow.EventBus
+	WorkflowService flow.WorkflowService
+	ScheduleService flow.ScheduleService
+	WaitService     flow.WaitService
+
+	Logger log.Logger
+
+	// Catch-up policy of schedules whose node does not set one.
+	CatchUp string
+
+	// Interval at which schedules are synced & fired.
+	Tick time.Duration
+
+	// Time a claimed schedule is reserved for. Schedules claimed by an
+	// instance that stops before firing them are fired once it expires.
+	Lease time.Duration
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+
+	done chan struct{}
+}
+
+// New returns a new instance of Scheduler.
+func New() *Scheduler {
+	return &Scheduler{
+		Logger:  log.NewNopLogger(),
+		CatchUp: DefaultCatchUp,
+		Tick:    DefaultTick,
+		Lease:   DefaultLease,
+		Now:     time.Now,
+		done:    make(chan struct{}),
+	}
+}
+
+// Open begins firing schedules in the background.
+func (s *Scheduler) Open() error {
+	switch s.CatchUp {
+	case flow.CatchUpSkip, flow.CatchUpOnce, flow.CatchUpAll:
+	default:
+		return fmt.Errorf("invalid catch-up policy: %q", s.CatchUp)
+	}
+
+	go s.loop()
+	return nil
+}
+
+// Close stops firing schedules.
+func (s *Scheduler) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(s.Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			ctx := flow.NewSystemContext(context.Background())
+			if err := s.Sync(ctx); err != nil {
+				_ = s.Logger.Log("msg", "cannot sync schedules", "err", err)
+			}
+			if err := s.Fire(ctx); err != nil {
+				_ = s.Logger.Log("msg", "cannot fire schedules", "err", err)
+			}
+			if err := s.Resume(ctx); err != nil {
+				_ = s.Logger.Log("msg", "cannot resume waiting runs", "err", err)
+			}
+		}
+	}
+}
+
+// Sync creates or updates the schedule of every schedule trigger node and
+// removes schedules whose node is no longer a schedule trigger.
+func (s *Scheduler) Sync(ctx context.Context) error {
+	existing, _, err := s.ScheduleService.GetSchedules(ctx, flow.ScheduleFilter{})
+	if err != nil {
+		return err
+	}
+	stale := make(map[string]*flow.Schedule, len(existing))
+	for _, schedule := range existing {
+		stale[schedule.NodeID.String()] = schedule
+	}
+
+	now := s.Now()
+	for _, trigger := range []string{flow.TriggerCron, flow.TriggerInterval, flow.TriggerOnce} {
+		topic := flow.TriggerTopic(flow.IntegrationSchedule, trigger)
+		workflows, _, err := s.WorkflowService.GetWorkflows(ctx, flow.WorkflowFilter{Trigger: &topic})
+		if err != nil {
+			return err
+		}
+
+		for _, wf := range workflows {
+			for _, node := range wf.Nodes {
+				if node.Integration != flow.IntegrationSchedule || node.Action != trigger || len(node.ParentIDs) > 0 {
+					continue
+				}
+
+				schedule, err := s.scheduleOf(wf, node)
+				if err != nil {
+					_ = s.Logger.Log("msg", "invalid schedule", "workflow", wf.ID, "node", node.ID, "err", err)
+					continue
+				}
+
+				prev := stale[node.ID.String()]
+				delete(stale, node.ID.String())
+				if prev != nil && prev.SameSpec(schedule) {
+					continue
+				}
+
+				if schedule.NextFireAt, err = Next(schedule, now); err != nil {
+					return err
+				}
+				if err := s.ScheduleService.SaveSchedule(ctx, schedule); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, schedule := range stale {
+		if err := s.ScheduleService.DeleteSchedule(ctx, schedule.NodeID); err != nil && flow.ErrorCode(err) != flow.ENOTFOUND {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fire claims the schedules that are due and fires them according to their
+// catch-up policy.
+func (s *Scheduler) Fire(ctx context.Context) error {
+	schedules, err := s.ScheduleService.ClaimDueSchedules(ctx, s.Lease, claimLimit)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		if err := s.fire(ctx, schedule); err != nil {
+			_ = s.Logger.Log("msg", "cannot fire schedule", "node", schedule.NodeID, "err", err)
+		}
+	}
+	return nil
+}
+
+// Resume resolves the pending waits that are due, which queues their runs to
+// be resumed. Waits for an event that are due have timed out. Waits resolved
+// concurrently by another scheduler or by an event are left as they are.
+func (s *Scheduler) Resume(ctx context.Context) error {
+	if s.WaitService == nil {
+		return nil
+	}
+
+	now := s.Now()
+	waits, _, err := s.WaitService.GetWaits(ctx, flow.WaitFilter{Pending: true, DueBefore: &now, Limit: claimLimit})
+	if err != nil {
+		return err
+	}
+
+	for _, wait := range waits {
+		upd := flow.WaitUpdate{TimedOut: wait.Topic != nil}
+		if _, err := s.WaitService.ResolveWait(ctx, wait.ID, upd); err != nil && flow.ErrorCode(err) != flow.ECONFLICT {
+			_ = s.Logger.Log("msg", "cannot resolve wait", "run", wait.RunID, "err", err)
+		}
+	}
+	return nil
+}
+
+// fire publishes an event for each fire of a claimed schedule and advances it
+// to its next fire.
+func (s *Scheduler) fire(ctx context.Context, schedule *flow.Schedule) error {
+	now := s.Now()
+
+	fires, err := Fires(schedule, now)
+	if err != nil {
+		return err
+	}
+
+	topic := flow.TriggerTopic(flow.IntegrationSchedule, schedule.Trigger)
+	for _, t := range fires {
+		if err := s.EventBus.Publish(topic, &flow.NodeEvent{
+			WorkflowID: schedule.WorkflowID,
+			NodeID:     schedule.NodeID,
+			Payload: map[string]interface{}{
+				"scheduled_at": t.Format(time.RFC3339),
+				"fired_at":     now.Format(time.RFC3339),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	next, err := Next(schedule, now)
+	if err != nil {
+		return err
+	}
+	upd := flow.ScheduleUpdate{NextFireAt: next}
+	if len(fires) > 0 {
+		upd.LastFiredAt = &now
+	}
+	_, err = s.ScheduleService.UpdateSchedule(ctx, schedule.NodeID, upd)
+	return err
+}
+
+// scheduleOf builds the schedule of a node from its params.
+func (s *Scheduler) scheduleOf(wf *flow.Workflow, node *flow.Node) (*flow.Schedule, error) {
+	schedule := &flow.Schedule{
+		NodeID:     node.ID,
+		WorkflowID: wf.ID,
+		Trigger:    node.Action,
+		Expression: paramValue(node, "expression"),
+		Timezone:   paramValue(node, "timezone"),
+		CatchUp:    paramValue(node, "catch_up"),
+	}
+	if schedule.CatchUp == "" {
+		schedule.CatchUp = s.CatchUp
+	}
+
+	if v := paramValue(node, "interval_seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %q", v)
+		}
+		schedule.IntervalSeconds = n
+	}
+	if v := paramValue(node, "at"); v != "" {
+		loc, err := location(schedule.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		// Times without an offset are in the schedule's time zone.
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if at, err = time.ParseInLocation("2006-01-02T15:04:05", v, loc); err != nil {
+				return nil, fmt.Errorf("invalid time: %q", v)
+			}
+		}
+		schedule.At = &at
+	}
+
+	return schedule, Validate(schedule)
+}
+
+// Validate returns an error if a schedule's spec cannot be evaluated.
+func Validate(schedule *flow.Schedule) error {
+	switch schedule.CatchUp {
+	case flow.CatchUpSkip, flow.CatchUpOnce, flow.CatchUpAll:
+	default:
+		return fmt.Errorf("invalid catch-up policy: %q", schedule.CatchUp)
+	}
+
+	if _, err := location(schedule.Timezone); err != nil {
+		return err
+	}
+
+	switch schedule.Trigger {
+	case flow.TriggerCron:
+		if _, err := parser.Parse(schedule.Expression); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	case flow.TriggerInterval:
+		if schedule.IntervalSeconds <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	case flow.TriggerOnce:
+		if schedule.At == nil {
+			return fmt.Errorf("time of one-off schedule is required")
+		}
+	default:
+		return fmt.Errorf("unknown schedule trigger: %q", schedule.Trigger)
+	}
+	return nil
+}
+
+// Next returns the first fire of a schedule after a time. Returns nil if the
+// schedule does not fire again.
+func Next(schedule *flow.Schedule, after time.Time) (*time.Time, error) {
+	var next time.Time
+	switch schedule.Trigger {
+	case flow.TriggerCron:
+		spec, err := parser.Parse(schedule.Expression)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := location(schedule.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		next = spec.Next(after.In(loc))
+
+	case flow.TriggerInterval:
+		interval := time.Duration(schedule.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval must be positive")
+		}
+		// Fires stay aligned with the previous fire so that intervals do
+		// not drift by the time taken to fire.
+		next = after.Add(interval)
+		if prev := schedule.NextFireAt; prev != nil && !prev.After(after) {
+			next = prev.Add((after.Sub(*prev)/interval + 1) * interval)
+		}
+
+	case flow.TriggerOnce:
+		if schedule.At == nil {
+			return nil, nil
+		}
+		// A one-off schedule that has never been due fires at its time even
+		// if it has already passed, subject to the catch-up policy.
+		if schedule.At.After(after) || (schedule.NextFireAt == nil && schedule.LastFiredAt == nil) {
+			return schedule.At, nil
+		}
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown schedule trigger: %q", schedule.Trigger)
+	}
+	return &next, nil
+}
+
+// Fires returns the times a due schedule fires for at the given time according
+// to its catch-up policy.
+func Fires(schedule *flow.Schedule, now time.Time) ([]time.Time, error) {
+	if schedule.NextFireAt == nil || schedule.NextFireAt.After(now) {
+		return nil, nil
+	}
+	due := *schedule.NextFireAt
+
+	// Fires that are only slightly late have not been missed.
+	if now.Sub(due) <= MisfireThreshold {
+		return []time.Time{due}, nil
+	}
+
+	switch schedule.CatchUp {
+	case flow.CatchUpSkip:
+		return nil, nil
+	case flow.CatchUpAll:
+		fires := []time.Time{due}
+		for len(fires) < flow.MaxCatchUpFires {
+			next, err := Next(schedule, fires[len(fires)-1])
+			if err != nil {
+				return nil, err
+			} else if next == nil || next.After(now) {
+				break
+			}
+			fires = append(fires, *next)
+		}
+		return fires, nil
+	default:
+		return []time.Time{due}, nil
+	}
+}
+
+// location returns the location of an IANA time zone. Defaults to UTC.
+func location(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %q", tz)
+	}
+	return loc, nil
+}
+
+// paramValue returns the value of a node's param. Returns an empty string if
+// the node does not have the param.
+func paramValue(node *flow.Node, key string) string {
+	for _, p := range node.Params {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
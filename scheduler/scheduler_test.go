@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openmesh/flow"
+)
+
+func TestNext(t *testing.T) {
+	base := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := base.Add(d)
+		return &v
+	}
+
+	for _, tt := range []struct {
+		name     string
+		schedule flow.Schedule
+		after    time.Time
+		want     *time.Time
+	}{
+		{
+			name:     "Cron",
+			schedule: flow.Schedule{Trigger: flow.TriggerCron, Expression: "*/15 * * * *"},
+			after:    base.Add(7 * time.Minute),
+			want:     at(15 * time.Minute),
+		},
+		{
+			name:     "CronSeconds",
+			schedule: flow.Schedule{Trigger: flow.TriggerCron, Expression: "30 * * * * *"},
+			after:    base,
+			want:     at(30 * time.Second),
+		},
+		{
+			name:     "CronDescriptor",
+			schedule: flow.Schedule{Trigger: flow.TriggerCron, Expression: "@daily"},
+			after:    base,
+			want:     at(14 * time.Hour),
+		},
+		{
+			// 09:00 in New York is 14:00 UTC before daylight saving time.
+			name:     "CronTimezone",
+			schedule: flow.Schedule{Trigger: flow.TriggerCron, Expression: "0 9 * * *", Timezone: "America/New_York"},
+			after:    base,
+			want:     at(4 * time.Hour),
+		},
+		{
+			name:     "IntervalFirst",
+			schedule: flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 60},
+			after:    base.Add(5 * time.Second),
+			want:     at(65 * time.Second),
+		},
+		{
+			// Fires stay aligned with the previous fire when firing late.
+			name:     "IntervalAligned",
+			schedule: flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 60, NextFireAt: at(0)},
+			after:    base.Add(5 * time.Second),
+			want:     at(60 * time.Second),
+		},
+		{
+			// Fires missed for several intervals are skipped over.
+			name:     "IntervalAlignedLate",
+			schedule: flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 60, NextFireAt: at(0)},
+			after:    base.Add(150 * time.Second),
+			want:     at(180 * time.Second),
+		},
+		{
+			name:     "IntervalOnFire",
+			schedule: flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 60, NextFireAt: at(0)},
+			after:    base,
+			want:     at(60 * time.Second),
+		},
+		{
+			name:     "IntervalFutureFire",
+			schedule: flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 60, NextFireAt: at(time.Hour)},
+			after:    base,
+			want:     at(60 * time.Second),
+		},
+		{
+			name:     "Once",
+			schedule: flow.Schedule{Trigger: flow.TriggerOnce, At: at(time.Hour)},
+			after:    base,
+			want:     at(time.Hour),
+		},
+		{
+			// One-off schedules that have never been due fire even
+			// when their time has passed.
+			name:     "OncePassed",
+			schedule: flow.Schedule{Trigger: flow.TriggerOnce, At: at(-time.Hour)},
+			after:    base,
+			want:     at(-time.Hour),
+		},
+		{
+			name:     "OnceFired",
+			schedule: flow.Schedule{Trigger: flow.TriggerOnce, At: at(-time.Hour), NextFireAt: at(-time.Hour), LastFiredAt: at(0)},
+			after:    base,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Next(&tt.schedule, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			} else if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Fatalf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		schedule flow.Schedule
+	}{
+		{"Cron", flow.Schedule{Trigger: flow.TriggerCron, Expression: "every minute"}},
+		{"Timezone", flow.Schedule{Trigger: flow.TriggerCron, Expression: "* * * * *", Timezone: "Mars/Olympus"}},
+		{"Interval", flow.Schedule{Trigger: flow.TriggerInterval}},
+		{"Trigger", flow.Schedule{Trigger: "HOURLY"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Next(&tt.schedule, time.Now()); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestFires(t *testing.T) {
+	base := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := base.Add(d)
+		return &v
+	}
+	interval := func(catchUp string, next *time.Time) flow.Schedule {
+		return flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 600, CatchUp: catchUp, NextFireAt: next}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		schedule flow.Schedule
+		now      time.Time
+		want     []time.Time
+	}{
+		{"NotDue", interval(flow.CatchUpAll, at(time.Minute)), base, nil},
+		{"Finished", interval(flow.CatchUpAll, nil), base, nil},
+		{"Due", interval(flow.CatchUpSkip, at(0)), base, []time.Time{base}},
+		{"SlightlyLate", interval(flow.CatchUpSkip, at(0)), base.Add(MisfireThreshold), []time.Time{base}},
+		{"MissedSkip", interval(flow.CatchUpSkip, at(0)), base.Add(35 * time.Minute), nil},
+		{"MissedOnce", interval(flow.CatchUpOnce, at(0)), base.Add(35 * time.Minute), []time.Time{base}},
+		{
+			name:     "MissedAll",
+			schedule: interval(flow.CatchUpAll, at(0)),
+			now:      base.Add(35 * time.Minute),
+			want:     []time.Time{base, base.Add(10 * time.Minute), base.Add(20 * time.Minute), base.Add(30 * time.Minute)},
+		},
+		{
+			name:     "MissedAllCron",
+			schedule: flow.Schedule{Trigger: flow.TriggerCron, Expression: "0 * * * *", CatchUp: flow.CatchUpAll, NextFireAt: at(0)},
+			now:      base.Add(150 * time.Minute),
+			want:     []time.Time{base, base.Add(time.Hour), base.Add(2 * time.Hour)},
+		},
+		{
+			name:     "MissedAllOnce",
+			schedule: flow.Schedule{Trigger: flow.TriggerOnce, At: at(0), CatchUp: flow.CatchUpAll, NextFireAt: at(0)},
+			now:      base.Add(time.Hour),
+			want:     []time.Time{base},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fires(&tt.schedule, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(got) != len(tt.want) {
+				t.Fatalf("Fires() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("Fires() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// Catching up on every missed fire is limited.
+	schedule := flow.Schedule{Trigger: flow.TriggerInterval, IntervalSeconds: 1, CatchUp: flow.CatchUpAll, NextFireAt: at(0)}
+	if got, err := Fires(&schedule, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if len(got) != flow.MaxCatchUpFires {
+		t.Fatalf("len(Fires()) = %d, want %d", len(got), flow.MaxCatchUpFires)
+	}
+}
@@ -0,0 +1,92 @@
+package flow
+
+import (
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// Limits of transform nodes. Expressions are evaluated within the executor's
+// process, so the documents they read & produce are bounded in size, the
+// memory an evaluation can allocate is bounded before it starts, and nodes
+// whose evaluation takes too long fail.
+//
+// JMESPath evaluations cannot be interrupted. An evaluation that times out
+// keeps running in the background until it finishes, so the number of
+// evaluations running at once is limited. As the memory of an evaluation is
+// bounded, so is the time it takes to finish.
+const (
+	// Maximum length of an expression in bytes.
+	MaxTransformExpressionLength = 4 << 10
+	// Maximum size of the JSON document an expression is evaluated against
+	// and of its result.
+	MaxTransformDocumentSize = 1 << 20
+	// Maximum time a node waits for its evaluation, including the time
+	// spent waiting for other evaluations to finish.
+	TransformTimeout = time.Second
+	// Maximum number of evaluations running at once, including those that
+	// timed out and are still running.
+	MaxConcurrentTransforms = 4
+	// Maximum number of bytes an evaluation may allocate, as bounded from
+	// its expression & the size of its document.
+	MaxTransformMemory = 64 << 20
+	// Maximum number of times an expression may grow its document, such as
+	// by duplicating it with "[@, @] | [@, @] | ...".
+	MaxTransformGrowth = 1 << 10
+)
+
+// Transform is a compiled transform expression.
+type Transform struct {
+	*jmespath.JMESPath
+
+	// Size of the values the expression builds when it is evaluated.
+	memory transformSize
+}
+
+// Memory returns the maximum number of bytes the expression allocates when it
+// is evaluated against a document of size bytes encoded as JSON.
+func (t *Transform) Memory(size int) float64 {
+	return t.memory.max(size)
+}
+
+// CompileTransform parses the JMESPath expression of a transform node. Returns
+// EINVALID if the expression is too long, cannot be parsed or can grow its
+// document more than MaxTransformGrowth times.
+func CompileTransform(expression string) (*Transform, error) {
+	if expression == "" {
+		return nil, Errorf(EINVALID, "Transform expression is required.")
+	} else if len(expression) > MaxTransformExpressionLength {
+		return nil, Errorf(EINVALID, "Transform expression cannot be longer than %d bytes.", MaxTransformExpressionLength)
+	}
+
+	compiled, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, Errorf(EINVALID, "Transform expression is invalid: %s", err)
+	}
+	ast, err := jmespath.NewParser().Parse(expression)
+	if err != nil {
+		return nil, Errorf(EINVALID, "Transform expression is invalid: %s", err)
+	}
+
+	t := &Transform{JMESPath: compiled, memory: transformMemory(ast)}
+	if t.memory.f > MaxTransformGrowth {
+		return nil, Errorf(EINVALID, "Transform expression can build values more than %d times larger than its input.", MaxTransformGrowth)
+	}
+	return t, nil
+}
+
+// validateTransformParams returns an error if the expression param of a
+// transform node is missing or invalid. Expressions are validated when the
+// node is saved, so they cannot be references.
+func validateTransformParams(params []*Param) error {
+	for _, p := range params {
+		if p.Key != "expression" {
+			continue
+		} else if p.Type == ParamTypeReference {
+			return Errorf(EINVALID, "Transform expression cannot be a reference.")
+		}
+		_, err := CompileTransform(p.Value)
+		return err
+	}
+	return Errorf(EINVALID, "Transform expression is required.")
+}
@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompileTransform(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		expression string
+		code       string
+	}{
+		{"Field", "foo.bar", ""},
+		{"Projection", "items[*].{id: id, name: name, count: length(tags)}", ""},
+		{"Filter", "items[?price > `10`] | sort_by(@, &price)[0:5]", ""},
+		{"Join", "join(', ', items[*].name)", ""},
+		{"Map", "map(&to_string(@), items)", ""},
+		{"Duplicate", strings.Repeat("[@, @] | ", 5) + "@", ""},
+		{"Empty", "", EINVALID},
+		{"Syntax", "foo[", EINVALID},
+		{"TooLong", strings.Repeat("a.", MaxTransformExpressionLength) + "a", EINVALID},
+		{"DuplicateList", strings.Repeat("[@, @] | ", 11) + "@", EINVALID},
+		{"DuplicateHash", strings.Repeat("{a: @, b: @} | ", 11) + "@", EINVALID},
+		{"JoinSeparator", "join(sep, items)", EINVALID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileTransform(tt.expression)
+			if code := ErrorCode(err); code != tt.code {
+				t.Fatalf("error code = %q, want %q (err: %v)", code, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestTransform_Memory(t *testing.T) {
+	doc := `{"items": [
+		{"id": 1, "name": "a", "price": 12, "tags": ["x"]},
+		{"id": 2, "name": "bb", "price": 5, "tags": []},
+		{"id": 3, "name": "ccc", "price": 30, "tags": ["y", "z"]}
+	], "sep": "--"}`
+
+	for _, expression := range []string{
+		"items",
+		"items[*].{id: id, name: name, count: length(tags)}",
+		"items[?price > `10`].name",
+		"items[*].tags[]",
+		"[@, @] | [@, @] | [@, @]",
+		"join(', ', items[*].name)",
+		"map(&to_string(@), items)",
+		"sort_by(items, &name)[*].[name, `\"literal\"`, `true`]",
+		"items[*].[id, id, id, `[1, 2, 3]`]",
+		"reverse(to_string(@))",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			compiled, err := CompileTransform(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v interface{}
+			if err := json.Unmarshal([]byte(doc), &v); err != nil {
+				t.Fatal(err)
+			}
+			result, err := compiled.Search(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if max := compiled.Memory(len(doc)); float64(len(buf)) > max {
+				t.Fatalf("result of %d bytes exceeds the bound of %.0f bytes", len(buf), max)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package flow
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// The memory of a transform is limited by bounding, when its expression is
+// compiled, the size of the values the expression can build from a document
+// of a given size. Every value JMESPath builds is either part of its document
+// or built by an expression node from the values of its children, so the sum
+// of the largest values each node can build bounds the memory the evaluation
+// allocates. Sizes are those of the values encoded as JSON.
+//
+// The size of a value is bounded by a linear function of the size of the
+// document: nodes that select part of their input, such as fields & slices,
+// are no larger than their input, multi-selects such as [@, @] are as large as
+// all of their elements, and nodes that build values of a fixed size, such as
+// literals or length(@), are counted once for each element they are evaluated
+// against in projections. Expressions whose values grow faster than their
+// document, such as joins with a separator taken from the document, cannot be
+// bounded.
+
+// Sizes of the JSON values that do not depend on the input of the expression
+// nodes that build them.
+const (
+	transformNumberSize = 24 // the longest formatted float64
+	transformBoolSize   = 5  // false
+	transformNullSize   = 4  // null
+	transformTypeSize   = 9  // "boolean"
+)
+
+// transformSize bounds the size of a value built by an expression node as f
+// times the size of the node's input plus c.
+type transformSize struct {
+	f, c float64
+}
+
+// unboundedSize is the size of values that cannot be bounded.
+var unboundedSize = transformSize{math.Inf(1), math.Inf(1)}
+
+func (s transformSize) add(t transformSize) transformSize {
+	return transformSize{s.f + t.f, s.c + t.c}
+}
+
+// of returns the size of a value of size s built from the value of size in.
+func (s transformSize) of(in transformSize) transformSize {
+	return transformSize{s.f * in.f, s.f*in.c + s.c}
+}
+
+// each returns the size of the values of size s built from each element of
+// the value of size in. As each element of an array or object takes at least
+// two bytes with its separator, their number is at most half the size of the
+// value.
+func (s transformSize) each(in transformSize) transformSize {
+	k := s.f + s.c/2
+	return transformSize{k * in.f, k * in.c}
+}
+
+// max returns the largest size of a value of size s built from an input of n
+// bytes.
+func (s transformSize) max(n int) float64 {
+	v := s.f*float64(n) + s.c
+	if math.IsNaN(v) {
+		return math.Inf(1)
+	}
+	return v
+}
+
+// transformMemory returns the size of the values an expression builds when it
+// is evaluated, including its result. The AST of go-jmespath is read through
+// reflection, as its fields are not exported.
+func transformMemory(ast jmespath.ASTNode) transformSize {
+	out, alloc := transformNodeSize(reflect.ValueOf(ast))
+	return alloc.add(out)
+}
+
+// transformNodeSize returns the size of the value an expression node builds
+// and the size of the values it & its children build, relative to the size of
+// the node's input.
+func transformNodeSize(node reflect.Value) (out, alloc transformSize) {
+	typ, value, children := node.FieldByName("nodeType"), node.FieldByName("value"), node.FieldByName("children")
+	if !typ.IsValid() || !value.IsValid() || !children.IsValid() {
+		return unboundedSize, unboundedSize
+	}
+
+	// child returns the sizes of the values a child builds.
+	child := func(i int) (transformSize, transformSize) {
+		if i >= children.Len() {
+			return transformSize{}, transformSize{}
+		}
+		return transformNodeSize(children.Index(i))
+	}
+
+	switch typ.Int() {
+	case int64(jmespath.ASTEmpty):
+		return transformSize{}, transformSize{}
+
+	case int64(jmespath.ASTCurrentNode), int64(jmespath.ASTIdentity),
+		int64(jmespath.ASTField), int64(jmespath.ASTIndex):
+		return transformSize{f: 1}, transformSize{}
+
+	case int64(jmespath.ASTSlice):
+		return transformSize{f: 1}, transformSize{f: 1}
+
+	case int64(jmespath.ASTLiteral):
+		return transformSize{c: literalSize(value)}, transformSize{}
+
+	case int64(jmespath.ASTSubexpression), int64(jmespath.ASTPipe), int64(jmespath.ASTIndexExpression):
+		lo, la := child(0)
+		ro, ra := child(1)
+		return ro.of(lo), la.add(ra.of(lo))
+
+	case int64(jmespath.ASTProjection), int64(jmespath.ASTValueProjection):
+		// The right side is evaluated against each element of the
+		// left side's value.
+		lo, la := child(0)
+		ro, ra := child(1)
+		out = ro.each(lo)
+		return out, la.add(ra.each(lo)).add(out)
+
+	case int64(jmespath.ASTFilterProjection):
+		lo, la := child(0)
+		ro, ra := child(1)
+		co, ca := child(2)
+		out = ro.each(lo)
+		return out, la.add(ra.each(lo)).add(ca.add(co).each(lo)).add(out)
+
+	case int64(jmespath.ASTFlatten):
+		co, ca := child(0)
+		return co, ca.add(co)
+
+	case int64(jmespath.ASTComparator), int64(jmespath.ASTNotExpression):
+		for i := 0; i < children.Len(); i++ {
+			_, ca := child(i)
+			alloc = alloc.add(ca)
+		}
+		out = transformSize{c: transformBoolSize}
+		return out, alloc.add(out)
+
+	case int64(jmespath.ASTOrExpression), int64(jmespath.ASTAndExpression):
+		lo, la := child(0)
+		ro, ra := child(1)
+		return lo.add(ro), la.add(ra)
+
+	case int64(jmespath.ASTMultiSelectList), int64(jmespath.ASTMultiSelectHash):
+		for i := 0; i < children.Len(); i++ {
+			co, ca := child(i)
+			out, alloc = out.add(co), alloc.add(ca)
+		}
+		out = out.add(transformSize{c: float64(children.Len() + 2)})
+		return out, alloc.add(out)
+
+	case int64(jmespath.ASTKeyValPair):
+		co, ca := child(0)
+		return co.add(transformSize{c: literalSize(value) + 1}), ca
+
+	case int64(jmespath.ASTExpRef):
+		return child(0)
+
+	case int64(jmespath.ASTFunctionExpression):
+		return functionSize(value.Elem().String(), children)
+	}
+	return unboundedSize, unboundedSize
+}
+
+// functionSize returns the size of the value a function builds and the size
+// of the values it & its arguments build, relative to the size of its input.
+func functionSize(name string, args reflect.Value) (out, alloc transformSize) {
+	// Expression arguments are evaluated against the elements of other
+	// arguments rather than against the input.
+	sizes := make([]transformSize, args.Len())
+	for i := range sizes {
+		if args.Index(i).FieldByName("nodeType").Int() == int64(jmespath.ASTExpRef) {
+			continue
+		}
+		var a transformSize
+		sizes[i], a = transformNodeSize(args.Index(i))
+		alloc = alloc.add(a)
+	}
+	arg := func(i int) transformSize {
+		if i < len(sizes) {
+			return sizes[i]
+		}
+		return transformSize{}
+	}
+	// expref returns the sizes of the values built by the expression of
+	// an &expression argument, relative to the elements it is evaluated
+	// against.
+	expref := func(i int) (transformSize, transformSize) {
+		if i >= args.Len() || args.Index(i).FieldByName("nodeType").Int() != int64(jmespath.ASTExpRef) {
+			return transformSize{}, transformSize{}
+		}
+		return transformNodeSize(args.Index(i).FieldByName("children").Index(0))
+	}
+
+	switch name {
+	case "abs", "avg", "ceil", "floor", "length", "sum", "to_number":
+		out = transformSize{c: transformNumberSize}
+	case "contains", "ends_with", "starts_with":
+		out = transformSize{c: transformBoolSize}
+	case "type":
+		out = transformSize{c: transformTypeSize}
+
+	case "map":
+		eo, ea := expref(0)
+		out = eo.add(transformSize{c: transformNullSize}).each(arg(1))
+		alloc = alloc.add(ea.each(arg(1)))
+	case "max_by", "min_by", "sort_by":
+		eo, ea := expref(1)
+		out = arg(0)
+		alloc = alloc.add(ea.add(eo).each(arg(0)))
+
+	case "join":
+		// Separators taken from the document are repeated for each of
+		// the elements of the array, so the result cannot be bounded.
+		if args.Len() < 2 || args.Index(0).FieldByName("nodeType").Int() != int64(jmespath.ASTLiteral) {
+			return unboundedSize, unboundedSize
+		}
+		out = arg(1).add(arg(0).each(arg(1)))
+	case "reverse":
+		// Strings are reversed as runes.
+		out = transformSize{4 * arg(0).f, 4 * arg(0).c}
+	case "to_string":
+		// Characters may be escaped as \u0000.
+		out = transformSize{6 * arg(0).f, 6 * arg(0).c}
+
+	default:
+		// The other functions return their arguments or part of them.
+		for _, s := range sizes {
+			out = out.add(s)
+		}
+	}
+	return out, alloc.add(out)
+}
+
+// literalSize returns the size of a literal value of an expression encoded as
+// JSON.
+func literalSize(v reflect.Value) float64 {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return transformNullSize
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		buf, _ := json.Marshal(v.String())
+		return float64(len(buf))
+	case reflect.Bool:
+		return transformBoolSize
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int64:
+		return transformNumberSize
+	case reflect.Slice:
+		n := float64(2 + v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n += literalSize(v.Index(i))
+		}
+		return n
+	case reflect.Map:
+		n := float64(2 + v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			n += literalSize(iter.Key()) + 1 + literalSize(iter.Value())
+		}
+		return n
+	}
+	return transformNullSize
+}
@@ -0,0 +1,88 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Wait represents a run paused at a delay, wait-for-event or sub-workflow
+// node. Paused runs are not held by an executor. They leave the queue of
+// unfinished runs until their wait is resolved, either by the scheduler once it
+// is due, by the dispatcher once a matching event arrives or by the executor
+// once a called workflow finishes, and are then resumed by a worker.
+type Wait struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	RunID      uuid.UUID `json:"run_id" db:"run_id"`
+	WorkflowID uuid.UUID `json:"workflow_id" db:"workflow_id"`
+	NodeID     uuid.UUID `json:"node_id" db:"node_id"`
+
+	// Index of the loop item the node waits for, if any.
+	Item *int `json:"item" db:"item"`
+
+	// Topic of the awaited event and the condition its payload must match.
+	// Delays do not await an event.
+	Topic     *string    `json:"topic" db:"topic"`
+	Condition *Condition `json:"condition" db:"condition"`
+
+	// Run of the called workflow a sub-workflow node waits for.
+	ChildRunID *uuid.UUID `json:"child_run_id" db:"child_run_id"`
+
+	// Time the wait ends unless an event resolves it first. Nil for waits
+	// without a timeout.
+	ResumeAt *time.Time `json:"resume_at" db:"resume_at"`
+
+	// Payload of the event that resolved the wait, or whether the wait timed
+	// out before a matching event arrived.
+	Event    json.RawMessage `json:"event" db:"event"`
+	TimedOut bool            `json:"timed_out" db:"timed_out"`
+
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Resolved returns true if the run may continue past the wait.
+func (w *Wait) Resolved() bool {
+	return w.ResolvedAt != nil
+}
+
+// WaitService represents a service for managing the waits of paused runs.
+type WaitService interface {
+	// Retrieves a list of waits by filter, oldest first. Also returns the
+	// total count of matching waits which may differ from the returned
+	// results if filter.Limit is specified.
+	GetWaits(ctx context.Context, filter WaitFilter) ([]*Wait, int, error)
+
+	// Creates a wait. On success, the wait.ID is set to the new ID.
+	CreateWait(ctx context.Context, wait *Wait) error
+
+	// Resolves a wait and queues its run to be resumed if it has paused.
+	// Returns ECONFLICT if the wait has already been resolved.
+	ResolveWait(ctx context.Context, id uuid.UUID, upd WaitUpdate) (*Wait, error)
+}
+
+// WaitUpdate represents the outcome of a wait passed to ResolveWait().
+type WaitUpdate struct {
+	Event    json.RawMessage `json:"event"`
+	TimedOut bool            `json:"timed_out"`
+}
+
+// WaitFilter represents a filter passed to GetWaits().
+type WaitFilter struct {
+	RunID *uuid.UUID `json:"run_id"`
+	Topic *string    `json:"topic"`
+
+	// Restricts results to the waits for a called workflow's run.
+	ChildRunID *uuid.UUID `json:"child_run_id"`
+
+	// Restricts results to unresolved waits of runs that have not finished.
+	Pending bool `json:"pending"`
+	// Restricts results to waits whose resume time is at or before the given
+	// time.
+	DueBefore *time.Time `json:"due_before"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
@@ -1,6 +1,10 @@
 package flow
 
-import "context"
+import (
+	"context"
+	"strings"
+	"time"
+)
 
 type Webhook struct {
 	Channel string      `json:"channel"`
@@ -16,3 +20,88 @@ type IngestWebhookRequest struct {
 	Source  string      `json:"source"`
 	Payload interface{} `json:"payload"`
 }
+
+// WebhookPayload is the normalized form of a webhook delivery. Deliveries are
+// decoded according to their content type so that workflows can address JSON,
+// form, XML and text bodies in the same way.
+type WebhookPayload struct {
+	Method      string `json:"method"`
+	ContentType string `json:"content_type"`
+
+	// Request headers. Headers that carry credentials or signatures, such
+	// as Authorization or X-Hub-Signature-256, are left out.
+	Headers map[string]string `json:"headers"`
+
+	// Query string parameters. Parameters with multiple values are arrays.
+	Query map[string]interface{} `json:"query"`
+
+	// Decoded body. JSON and XML bodies are objects, form bodies are objects
+	// of their fields and text bodies are strings.
+	Body interface{} `json:"body"`
+
+	// Undecoded body. Not set for multipart bodies.
+	RawBody string `json:"raw_body,omitempty"`
+
+	// Files uploaded with a multipart body. These are stored as attachments
+	// of the runs the delivery triggers.
+	Files []*Attachment `json:"files,omitempty"`
+}
+
+// Webhook verification schemes.
+const (
+	// GitHub style HMAC-SHA256 of the body in the X-Hub-Signature-256 header.
+	VerificationSchemeGitHub = "github"
+	// Stripe style timestamped HMAC-SHA256 in the Stripe-Signature header.
+	VerificationSchemeStripe = "stripe"
+	// Slack signing secrets in the X-Slack-Signature header.
+	VerificationSchemeSlack = "slack"
+	// Static secret sent verbatim in a request header.
+	VerificationSchemeSharedSecret = "shared_secret"
+	// Twitter Account Activity API signatures and CRC challenges.
+	VerificationSchemeTwitter = "twitter"
+)
+
+// DefaultWebhookTolerance is the maximum age of a timestamped webhook delivery
+// when a verification scheme does not specify its own tolerance.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// DefaultSharedSecretHeader is the header checked by the shared secret scheme
+// when a verification scheme does not name one.
+const DefaultSharedSecretHeader = "X-Webhook-Secret"
+
+// WebhookVerification describes how webhook deliveries are authenticated
+// before they are published to the event bus.
+type WebhookVerification struct {
+	// One of the VerificationScheme constants.
+	Scheme string `json:"scheme"`
+
+	// Header carrying the secret. Only used by the shared secret scheme.
+	Header string `json:"header,omitempty"`
+
+	// Maximum age in seconds of a delivery for schemes that sign a timestamp.
+	// Deliveries outside the window are rejected as replays.
+	ToleranceSeconds int `json:"tolerance_seconds,omitempty"`
+}
+
+// Tolerance returns the replay window for timestamped schemes.
+func (v *WebhookVerification) Tolerance() time.Duration {
+	if v.ToleranceSeconds > 0 {
+		return time.Duration(v.ToleranceSeconds) * time.Second
+	}
+	return DefaultWebhookTolerance
+}
+
+// TriggerTopic returns the event bus topic that webhook deliveries for an
+// integration's trigger are published to.
+func TriggerTopic(integration, trigger string) string {
+	return integration + "." + trigger
+}
+
+// ParseTriggerTopic splits a topic into its integration and trigger keys. The
+// trigger key is empty if the topic only names an integration.
+func ParseTriggerTopic(topic string) (integration, trigger string) {
+	if i := strings.Index(topic, "."); i >= 0 {
+		return topic[:i], topic[i+1:]
+	}
+	return topic, ""
+}
@@ -0,0 +1,56 @@
+package flow
+
+import (
+	"context"
+	"time"
+)
+
+// Worker represents an executor instance that executes runs claimed from the
+// queue of unfinished runs. Workers record a heartbeat while they are running
+// and are removed once they shut down.
+type Worker struct {
+	// Identifies the worker as the owner of the runs it claims.
+	ID string `json:"id" db:"id"`
+
+	// Maximum number of runs the worker executes at once. Zero means no
+	// limit.
+	Concurrency int `json:"concurrency" db:"concurrency"`
+
+	// Number of runs executing at the last heartbeat.
+	ActiveRuns int `json:"active_runs" db:"active_runs"`
+
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at" db:"heartbeat_at"`
+}
+
+// Alive returns true if the worker has recorded a heartbeat within the
+// timeout.
+func (w *Worker) Alive(now time.Time, timeout time.Duration) bool {
+	return now.Sub(w.HeartbeatAt) <= timeout
+}
+
+// WorkerService represents a service for tracking the workers that execute
+// runs.
+type WorkerService interface {
+	// Retrieves a list of workers by filter. Also returns the total count of
+	// matching workers which may differ from the returned results if
+	// filter.Limit is specified.
+	GetWorkers(ctx context.Context, filter WorkerFilter) ([]*Worker, int, error)
+
+	// Registers a worker or records its heartbeat if it exists.
+	SaveWorker(ctx context.Context, worker *Worker) error
+
+	// Removes a worker that has shut down.
+	DeleteWorker(ctx context.Context, id string) error
+}
+
+// WorkerFilter represents a filter passed to GetWorkers().
+type WorkerFilter struct {
+	ID *string `json:"id"`
+
+	// Restricts results to workers with a heartbeat after the given time.
+	HeartbeatAfter *time.Time `json:"heartbeat_after"`
+
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
@@ -6,6 +6,16 @@ import (
 	"time"
 )
 
+// Error modes decide what happens to a run when a node fails and the failure
+// is not handled by an error edge.
+const (
+	// The run stops at the first failure.
+	ErrorModeFailFast = "fail_fast"
+	// Nodes that do not depend on the failed node still run. The run fails
+	// once every other node has finished.
+	ErrorModeContinue = "continue"
+)
+
 type Workflow struct {
 	ID          uuid.UUID `json:"id" db:"id,omitempty"`
 	UserID      uuid.UUID `json:"-" db:"user_id"`
@@ -14,13 +24,51 @@ type Workflow struct {
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
+	ErrorMode   string    `json:"error_mode" db:"error_mode"`
 	Nodes       []*Node   `json:"nodes" db:"-"`
+
+	// Time in seconds a run of the workflow may take. Zero means no limit.
+	TimeoutSeconds int `json:"timeout_seconds" db:"timeout_seconds"`
+}
+
+// Timeout returns the time a run of the workflow may take. Returns zero if
+// runs are not limited.
+func (w *Workflow) Timeout() time.Duration {
+	return time.Duration(w.TimeoutSeconds) * time.Second
+}
+
+// ValidateTimeout returns an error if a timeout in seconds is negative.
+func ValidateTimeout(seconds int) error {
+	if seconds < 0 {
+		return Errorf(EINVALID, "Timeout cannot be negative.")
+	}
+	return nil
+}
+
+// ValidateErrorMode returns an error if a mode is not one of the error modes.
+func ValidateErrorMode(mode string) error {
+	switch mode {
+	case ErrorModeFailFast, ErrorModeContinue:
+		return nil
+	}
+	return Errorf(EINVALID, "Error mode '%s' is not supported.", mode)
 }
 
 func CanEditWorkflow(ctx context.Context, workflow *Workflow) bool {
 	return workflow.UserID == UserIDFromContext(ctx)
 }
 
+// GetNode returns the workflow's node with the given ID. Returns nil if the
+// workflow does not contain the node.
+func (w *Workflow) GetNode(id uuid.UUID) *Node {
+	for _, n := range w.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
 type WorkflowService interface {
 	GetWorkflowByID(ctx context.Context, id uuid.UUID) (*Workflow, error)
 	GetWorkflows(ctx context.Context, filter WorkflowFilter) ([]*Workflow, int, error)
@@ -32,6 +80,9 @@ type WorkflowService interface {
 type WorkflowUpdate struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
+	ErrorMode   *string `json:"error_mode"`
+
+	TimeoutSeconds *int `json:"timeout_seconds"`
 	// TODO maybe add nodes to the update
 }
 
@@ -41,4 +92,8 @@ type WorkflowFilter struct {
 	Limit       int        `json:"limit"`
 	Name        *string    `json:"name"`
 	Description *string    `json:"description"`
+
+	// Restricts results to workflows with a source node listening on the
+	// given trigger topic.
+	Trigger *string `json:"trigger"`
 }